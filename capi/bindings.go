@@ -0,0 +1,118 @@
+// Command capi builds hashit as a shared library so other languages can hash without
+// starting a process per file.
+//
+//	go build -buildmode=c-shared -o hashit.so ./capi
+//
+// Every function takes a JSON request and returns a JSON response which must be
+// released with HashitFree.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/boyter/hashit/processor"
+)
+
+// The processor is configured through package level options so calls are run one at a time
+var mutex sync.Mutex
+
+// Options shared by every request
+type request struct {
+	Hashes        []string `json:"hashes"`
+	TextNormalize string   `json:"textNormalize"`
+	// Set for HashitHashFile
+	Path string `json:"path"`
+	// Set for HashitHashBytes and recorded as the file of the result
+	Name string `json:"name"`
+	// Set for HashitAudit to the contents of a manifest
+	Manifest string `json:"manifest"`
+}
+
+// Only the selected hashes are returned as results also hold placeholders for the others
+type response struct {
+	File   string            `json:"file,omitempty"`
+	Bytes  int64             `json:"bytes,omitempty"`
+	Hashes map[string]string `json:"hashes,omitempty"`
+	Audit  map[string]string `json:"audit,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+func main() {}
+
+func encodeResponse(r response) []byte {
+	out, err := json.Marshal(r)
+	if err != nil {
+		out, _ = json.Marshal(response{Error: err.Error()})
+	}
+	return out
+}
+
+func errorResponse(err error) []byte {
+	return encodeResponse(response{Error: err.Error()})
+}
+
+// Decodes the request and applies its options, the caller must hold the mutex
+func applyRequest(data []byte) (request, error) {
+	req := request{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, errors.New("invalid request: " + err.Error())
+	}
+
+	processor.Hash = req.Hashes
+	if len(processor.Hash) == 0 {
+		processor.Hash = []string{"md5", "sha1", "sha256", "sha512"}
+	}
+	processor.TextNormalize = strings.ToLower(req.TextNormalize)
+	return req, nil
+}
+
+func resultResponse(res processor.Result, err error) []byte {
+	if err != nil {
+		return errorResponse(err)
+	}
+	return encodeResponse(response{File: res.File, Bytes: res.Bytes, Hashes: processor.SelectedHashes(res)})
+}
+
+func hashFile(data []byte) []byte {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	req, err := applyRequest(data)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if req.Path == "" {
+		return errorResponse(errors.New("path is required"))
+	}
+	return resultResponse(processor.HashFile(req.Path))
+}
+
+func hashBytes(content []byte, data []byte) []byte {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	req, err := applyRequest(data)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return resultResponse(processor.HashBytes(req.Name, content))
+}
+
+func audit(data []byte) []byte {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	req, err := applyRequest(data)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	status, err := processor.Audit([]byte(req.Manifest))
+	if err != nil {
+		return errorResponse(err)
+	}
+	return encodeResponse(response{Audit: status})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func decode(t *testing.T, data []byte) response {
+	t.Helper()
+	r := response{}
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Invalid response %s: %s", data, err.Error())
+	}
+	return r
+}
+
+func TestHashFileRequest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	req, _ := json.Marshal(request{Path: file, Hashes: []string{"MD5"}})
+	r := decode(t, hashFile(req))
+	if r.Error != "" || r.Bytes != 5 || len(r.Hashes) != 1 || r.Hashes["md5"] != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected response %+v", r)
+	}
+
+	if r := decode(t, hashFile([]byte("{}"))); r.Error == "" {
+		t.Error("Expected error without path")
+	}
+	if r := decode(t, hashFile([]byte("not json"))); r.Error == "" {
+		t.Error("Expected error for invalid request")
+	}
+}
+
+func TestHashBytesRequest(t *testing.T) {
+	r := decode(t, hashBytes([]byte("hello"), []byte(`{"name":"upload","hashes":["sha256"]}`)))
+	if r.File != "upload" || r.Hashes["sha256"] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected response %+v", r)
+	}
+}
+
+func TestAuditRequest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	req, _ := json.Marshal(request{Manifest: "5d41402abc4b2a76b9719d911017c592  " + file + "\n"})
+	r := decode(t, audit(req))
+	if r.Error != "" || r.Audit[file] != "OK" {
+		t.Errorf("Unexpected response %+v", r)
+	}
+}
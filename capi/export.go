@@ -0,0 +1,40 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import "unsafe"
+
+// HashitHashFile hashes the file at path in the request
+//
+//export HashitHashFile
+func HashitHashFile(request *C.char) *C.char {
+	return C.CString(string(hashFile([]byte(C.GoString(request)))))
+}
+
+// HashitHashBytes hashes length bytes starting at content which is only read during the call
+//
+//export HashitHashBytes
+func HashitHashBytes(content unsafe.Pointer, length C.longlong, request *C.char) *C.char {
+	var b []byte
+	if length > 0 {
+		b = unsafe.Slice((*byte)(content), int64(length))
+	}
+	return C.CString(string(hashBytes(b, []byte(C.GoString(request)))))
+}
+
+// HashitAudit checks every file listed in the manifest in the request
+//
+//export HashitAudit
+func HashitAudit(request *C.char) *C.char {
+	return C.CString(string(audit([]byte(C.GoString(request)))))
+}
+
+// HashitFree releases a response returned by any of the other functions
+//
+//export HashitFree
+func HashitFree(response *C.char) {
+	C.free(unsafe.Pointer(response))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+)
+
+// Name of the config file looked for in the home directory and the current directory
+const configFileName = ".hashit.toml"
+
+// Returns the config files to load in order of precedence, lowest first so that the
+// per project file can override the users defaults
+func configFiles() []string {
+	files := []string{}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, configFileName))
+	}
+
+	files = append(files, configFileName)
+	return files
+}
+
+// Loads any config files found setting each key as the default for the flag of the
+// same name. Flags supplied on the command line always win.
+func loadConfig(flags *pflag.FlagSet) error {
+	for _, file := range configFiles() {
+		if err := applyConfigFile(file, flags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyConfigFile(file string, flags *pflag.FlagSet) error {
+	config := map[string]interface{}{}
+	_, err := toml.DecodeFile(file, &config)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("unable to parse config file %s: %w", file, err)
+	}
+
+	for key, value := range config {
+		flag := flags.Lookup(key)
+		if flag == nil {
+			return fmt.Errorf("unknown option %s in config file %s", key, file)
+		}
+
+		if flags.Changed(key) {
+			continue
+		}
+
+		if err := setConfigValue(flag, value); err != nil {
+			return fmt.Errorf("invalid value for %s in config file %s: %w", key, file, err)
+		}
+	}
+
+	return nil
+}
+
+// Converts a decoded TOML value into the form pflag expects and sets it. Slices are
+// replaced rather than appended to so the project file overrides the home file.
+func setConfigValue(flag *pflag.Flag, value interface{}) error {
+	if v, ok := value.([]interface{}); ok {
+		values := []string{}
+		for _, x := range v {
+			values = append(values, fmt.Sprint(x))
+		}
+
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			return sv.Replace(values)
+		}
+		return flag.Value.Set(strings.Join(values, ","))
+	}
+
+	// A scalar for a slice flag such as hash = "md5,sha1" replaces the list as well
+	if sv, ok := flag.Value.(pflag.SliceValue); ok {
+		return sv.Replace(strings.Split(fmt.Sprint(value), ","))
+	}
+	return flag.Value.Set(fmt.Sprint(value))
+}
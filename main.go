@@ -1,23 +1,43 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/boyter/hashit/processor"
 	"github.com/spf13/cobra"
 	"os"
 	"runtime"
 )
 
+// Skip loading of ~/.hashit.toml and ./.hashit.toml
+var noConfig = false
+
 func main() {
 	//f, _ := os.Create("hashit.pprof")
 	//_ = pprof.StartCPUProfile(f)
 	//defer pprof.StopCPUProfile()
 
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// Builds the command line with every flag and subcommand registered
+func newRootCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:     "hashit",
 		Short:   "hashit [FILE or DIRECTORY]",
 		Long:    "Hash It!\nVersion " + processor.Version + "\nBen Boyter <ben@boyter.org>",
 		Version: processor.Version,
+		Args:    cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
+			if !noConfig {
+				if err := loadConfig(cmd.Flags()); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+
 			processor.DirFilePaths = args
 			processor.Process()
 		},
@@ -30,14 +50,56 @@ func main() {
 		"hash",
 		"c",
 		[]string{"md5", "sha1", "sha256", "sha512"},
-		"hashes to be run for each file (set to 'all' for all possible hashes)",
+		"hashes to be run for each file (set to 'all' for all possible hashes, entropy, ssdeep and tlsh must be named)",
+	)
+	flags.StringVar(
+		&processor.SelfVerify,
+		"self-verify",
+		"",
+		"digest such as sha256:<hex> or manifest the hashit executable must match before anything is hashed",
 	)
 	flags.StringVarP(
 		&processor.Format,
 		"format",
 		"f",
 		"text",
-		"set output format [text, json, sum, hashdeep, hashonly]",
+		"set output format [text, json, sum, hashdeep, hashonly, hashit, sri, sri-json, html, template]",
+	)
+	flags.StringVar(
+		&processor.Encoding,
+		"encoding",
+		"hex",
+		"encoding of digests in the output [hex, HEX, base64, base32]",
+	)
+	flags.StringVar(
+		&processor.Template,
+		"template",
+		"",
+		"go text/template rendered for each file with --format template e.g. '{{.Path}} {{.SHA256}} {{.Bytes}}'",
+	)
+	flags.StringVar(
+		&processor.ExecPerFile,
+		"exec-per-file",
+		"",
+		"command run for every file, each argument is a template like --template with {{.Status}} also set e.g. 'notify {{.Path}} {{.SHA256}}'",
+	)
+	flags.StringVar(
+		&processor.ExecOnMismatch,
+		"exec-on-mismatch",
+		"",
+		"command run for every file failing --check or --verify-xattr or changed since --baseline e.g. 'quarantine {{.Path}} {{.Status}}'",
+	)
+	flags.IntVar(
+		&processor.ExecConcurrency,
+		"exec-concurrency",
+		4,
+		"how many --exec-per-file and --exec-on-mismatch commands run at once",
+	)
+	flags.StringVar(
+		&processor.ExecTimeout,
+		"exec-timeout",
+		"",
+		"kill hook commands running longer than this e.g. 30s, counting them as failed",
 	)
 	flags.BoolVarP(
 		&processor.Recursive,
@@ -57,7 +119,55 @@ func main() {
 		"output",
 		"o",
 		"",
-		"output filename (default stdout)",
+		"output filename, or sqlite://file.db to upsert results into a SQLite database (default stdout)",
+	)
+	flags.BoolVar(
+		&processor.Append,
+		"append",
+		false,
+		"add results to the existing --output manifest replacing records for the same paths",
+	)
+	flags.StringVar(
+		&processor.PerRootOutput,
+		"per-root-output",
+		"",
+		"directory to write one output file per supplied file or directory into, named after each of them",
+	)
+	flags.BoolVar(
+		&processor.TeeOutput,
+		"tee-output",
+		false,
+		"stream results to stdout while also writing them to --output",
+	)
+	flags.StringVar(
+		&processor.Compress,
+		"compress",
+		"",
+		"compress the output file as it is written [gzip, zstd], manifests are decompressed automatically when read back",
+	)
+	flags.StringArrayVar(
+		&processor.Encrypt,
+		"encrypt",
+		[]string{},
+		"encrypt the output file to an age:RECIPIENT public key, key file or key name, repeat for more recipients",
+	)
+	flags.StringVar(
+		&processor.OutputMode,
+		"output-mode",
+		"0600",
+		"octal permissions of the output file",
+	)
+	flags.StringVar(
+		&processor.PartialOutput,
+		"partial-output",
+		"",
+		"keep results in memory and write them as JSON to this file if the run aborts",
+	)
+	flags.StringVar(
+		&processor.OutputOwner,
+		"output-owner",
+		"",
+		"user[:group] to own the output file, usually requires running privileged",
 	)
 	flags.BoolVar(
 		&processor.NoStream,
@@ -71,6 +181,12 @@ func main() {
 		1000000,
 		"min size of file in bytes where stream processing starts",
 	)
+	flags.Int64Var(
+		&processor.Blake3ParallelSize,
+		"blake3-parallel-size",
+		128000000,
+		"min size of file in bytes where blake3 splits a single file across --threads workers when few files are queued (0 disables)",
+	)
 	flags.BoolVarP(
 		&processor.Verbose,
 		"verbose",
@@ -109,6 +225,24 @@ func main() {
 		false,
 		"enable mtime output",
 	)
+	flags.StringVar(
+		&processor.ScanID,
+		"scan-id",
+		"",
+		"id recorded in every record, header, database row and json log line of the run (default a random UUID)",
+	)
+	flags.BoolVar(
+		&processor.Provenance,
+		"provenance",
+		false,
+		"record when the scan started, the host, version and command in json and hashdeep output along with when each file was hashed",
+	)
+	flags.BoolVar(
+		&processor.DetectType,
+		"detect-type",
+		false,
+		"detect the MIME type and format of each file from its first bytes and include it in json and hashdeep output",
+	)
 	flags.StringVarP(
 		&processor.FileInput,
 		"input",
@@ -116,8 +250,837 @@ func main() {
 		"",
 		"input file of newline seperated file locations to process",
 	)
+	flags.BoolVar(
+		&processor.ForceStdin,
+		"stdin",
+		false,
+		"hash what is piped to stdin without checking for it, for when detection misfires",
+	)
+	flags.BoolVar(
+		&processor.NoStdin,
+		"no-stdin",
+		false,
+		"never read stdin, scanning the current directory when no files are supplied",
+	)
+	flags.StringVar(
+		&processor.FromManifest,
+		"from-manifest",
+		"",
+		"hash exactly the files listed in this hashit, json, hashdeep or sum manifest in order, reporting any which have vanished",
+	)
+	flags.StringVar(
+		&processor.TextNormalize,
+		"text-normalize",
+		"",
+		"normalize line endings before hashing [lf, crlf]",
+	)
+	flags.BoolVar(
+		&processor.KeepOriginal,
+		"keep-original",
+		false,
+		"also output hashes of the original bytes when using --text-normalize",
+	)
+	flags.StringSliceVar(
+		&processor.Exclude,
+		"exclude",
+		[]string{},
+		"regular expressions of files or directories to exclude",
+	)
+	flags.IntVar(
+		&processor.MaxDepth,
+		"max-depth",
+		0,
+		"only descend this many directories into each root where 1 is just the files in the root (0 for no limit)",
+	)
+	flags.BoolVar(
+		&processor.OneFileSystem,
+		"one-file-system",
+		false,
+		"do not descend into directories on other filesystems such as mount points",
+	)
+	flags.BoolVar(
+		&processor.IncludeVirtualFS,
+		"include-virtual-fs",
+		false,
+		"walk into virtual filesystems such as /proc and /sys which are skipped by default",
+	)
+	flags.StringSliceVar(
+		&processor.Prune,
+		"prune",
+		[]string{},
+		"names of directories to skip wherever they appear such as .git, glob patterns are allowed",
+	)
+	flags.StringVar(
+		&processor.MinSize,
+		"min-size",
+		"",
+		"skip files smaller than this size e.g. 1M (suffixes K, M, G, T are powers of 1024)",
+	)
+	flags.StringVar(
+		&processor.MaxSize,
+		"max-size",
+		"",
+		"skip files larger than this size e.g. 1G (suffixes K, M, G, T are powers of 1024)",
+	)
+	flags.StringVar(
+		&processor.MaxFileSize,
+		"max-file-size",
+		"",
+		"never read files larger than this size e.g. 10G, including files named directly, and record them as skipped",
+	)
+	flags.StringVar(
+		&processor.TimeoutPerFile,
+		"timeout-per-file",
+		"",
+		"give up on a file which takes longer than this to hash e.g. 5m and record it as an error",
+	)
+	flags.IntVar(
+		&processor.MaxOpenFiles,
+		"max-open-files",
+		0,
+		"most files to have open at once, by default just under the process limit (0 for the default)",
+	)
+	flags.BoolVar(
+		&processor.NoAtime,
+		"no-atime",
+		false,
+		"open files with O_NOATIME on Linux so hashing does not update their access times",
+	)
+	flags.StringVar(
+		&processor.ShareMode,
+		"share-mode",
+		"write",
+		"what other applications may do to files hashit has open on Windows [read, write, delete]",
+	)
+	flags.StringVar(
+		&processor.Locked,
+		"locked",
+		"ignore",
+		"files another process holds an exclusive advisory lock on [ignore, skip, wait]",
+	)
+	flags.StringVar(
+		&processor.NewerThan,
+		"newer-than",
+		"",
+		"only hash files modified within this duration e.g. 24h or 7d, or after a date e.g. 2024-01-31",
+	)
+	flags.StringVar(
+		&processor.OlderThan,
+		"older-than",
+		"",
+		"only hash files modified before this duration ago e.g. 30d, or before a date e.g. 2024-01-31",
+	)
+	flags.StringVar(
+		&processor.FileType,
+		"type",
+		"",
+		"only hash regular files (f) or symlinks (l)",
+	)
+	flags.BoolVar(
+		&processor.LowMemory,
+		"low-memory",
+		false,
+		"reduce queue and buffer sizes, disable caching and stream output for memory constrained devices",
+	)
+	flags.StringVar(
+		&processor.QueueMemory,
+		"queue-memory",
+		"64M",
+		"memory the queue of files waiting to be hashed may grow to when the walker or workers stall, 0 keeps it fixed",
+	)
+	flags.BoolVar(
+		&processor.PrimeCache,
+		"prime-cache",
+		false,
+		"read files into the page cache ahead of the hashing workers, helps cold archives on rotational or network storage",
+	)
+	flags.IntVar(
+		&processor.PrimeDepth,
+		"prime-depth",
+		8,
+		"how many files ahead of the hashing workers --prime-cache reads",
+	)
+	flags.BoolVar(
+		&processor.Stats,
+		"stats",
+		false,
+		"report bytes hashed, wall time, per algorithm time and per worker throughput, added to json output or printed to stderr",
+	)
+	flags.StringVar(
+		&processor.Resume,
+		"resume",
+		"",
+		"checkpoint completed files to this state file and on a rerun with the same options skip them, the output still includes every file",
+	)
+	flags.StringVar(
+		&processor.DirCache,
+		"dir-cache",
+		"",
+		"record a signature of each directory's entries in this state file and on later runs reuse the results of directories whose signature is unchanged",
+	)
+	flags.StringVar(
+		&processor.Order,
+		"order",
+		"",
+		"hand files to workers smallest first, newest first or by name as they are found, or largest first once the walk finishes [size, largest-first, mtime, name]",
+	)
+	flags.StringSliceVarP(
+		&processor.Known,
+		"known",
+		"k",
+		[]string{},
+		"manifests of known files in the hashdeep, hashit, json or sum format to match against",
+	)
+	flags.BoolVarP(
+		&processor.Match,
+		"match",
+		"m",
+		false,
+		"only output files which match a known file, text output lists just their names",
+	)
+	flags.BoolVarP(
+		&processor.MatchNegative,
+		"match-negative",
+		"x",
+		false,
+		"only output files which do not match any known file, text output lists just their names",
+	)
+	flags.StringVar(
+		&processor.Baseline,
+		"baseline",
+		"",
+		"manifest from a previous run in the hashit, json, hashdeep or sum format, marks each result new, unchanged or changed since it",
+	)
+	flags.StringVar(
+		&processor.LimitRate,
+		"limit-rate",
+		"",
+		"limit total read throughput in bytes per second with optional K, M, G suffix e.g. 50M",
+	)
+	flags.IntVar(
+		&processor.Partition,
+		"partition",
+		-1,
+		"treat the argument as a raw disk image and hash the files in partition N of its ext2/3/4, NTFS or FAT filesystem without mounting, 0 if the image has no partition table",
+	)
+	flags.StringVar(
+		&processor.LogFormat,
+		"log-format",
+		"text",
+		"set log format [text, json]",
+	)
+	flags.StringVar(
+		&processor.LogFile,
+		"log-file",
+		"",
+		"append verbose, debug, trace and error logs to this file instead of stderr",
+	)
+	flags.StringVar(
+		&processor.Shard,
+		"shard",
+		"",
+		"only hash files whose path falls in shard N of TOTAL e.g. 3/8, so hosts can split one tree",
+	)
+	flags.BoolVar(
+		&processor.NoContent,
+		"no-content",
+		false,
+		"record paths, sizes and mtimes with a digest of the structure without reading file contents",
+	)
+	flags.BoolVar(
+		&processor.ListOnly,
+		"list-only",
+		false,
+		"walk and filter as normal but print the files and total bytes which would be hashed without hashing them",
+	)
+	flags.BoolVar(
+		&processor.NoLinkDedupe,
+		"no-link-dedupe",
+		false,
+		"hash every hard linked path rather than hashing each inode once",
+	)
+	flags.BoolVar(
+		&processor.NoSparse,
+		"no-sparse",
+		false,
+		"disable detection of holes in sparse files which are otherwise hashed as zeros without being read",
+	)
+	flags.Int64Var(
+		&processor.AzureBlockSize,
+		"azure-block-size",
+		0,
+		"block size in bytes to calculate Azure block blob per block MD5s for staged upload verification (0 disables)",
+	)
+	flags.StringVar(
+		&processor.DetailThreshold,
+		"detail-threshold",
+		"",
+		"hash files over this size e.g. 1G by sampling 1 MiB regions into a quick hash rather than in full, each record is marked full or sampled",
+	)
+	flags.IntVar(
+		&processor.DetailSamples,
+		"detail-samples",
+		16,
+		"number of regions spread across a file over --detail-threshold to sample",
+	)
+	flags.BoolVar(
+		&processor.ZstdFrames,
+		"zstd-frames",
+		false,
+		"digest every frame of .zst files in the seekable format so corruption can be found by frame",
+	)
+	flags.Int64Var(
+		&processor.PieceLength,
+		"piece-length",
+		0,
+		"piece length in bytes to calculate per file piece hashes (0 disables)",
+	)
+	flags.StringVar(
+		&processor.PieceHash,
+		"piece-hash",
+		"sha1",
+		"hash used for pieces [sha1, sha256]",
+	)
+	flags.BoolVar(
+		&processor.StoreXattr,
+		"store-xattr",
+		false,
+		"store calculated hashes in extended attributes (user.hashit.<hash>) or NTFS alternate data streams",
+	)
+	flags.BoolVar(
+		&processor.VerifyXattr,
+		"verify-xattr",
+		false,
+		"verify content against hashes previously stored with --store-xattr",
+	)
+	flags.StringVar(
+		&processor.SignKey,
+		"sign",
+		"",
+		"minisign secret key to write a detached signature of the output file to <output>.minisig or embed it in hashit format output, password from HASHIT_SIGN_PASSWORD",
+	)
+	flags.StringVar(
+		&processor.Check,
+		"check",
+		"",
+		"verify files against a manifest produced with the hashit, json or sum format",
+	)
+	flags.StringArrayVar(
+		&processor.CheckRoot,
+		"root",
+		[]string{},
+		"with --check verify against a replica moving paths as old=/orig/path,new=/mnt/backup, can be repeated",
+	)
+	flags.StringVar(
+		&processor.ObjectVersion,
+		"object-version",
+		"latest",
+		"versions of s3:// and gs:// --root objects to check [latest, all, or a version id]",
+	)
+	flags.StringVar(
+		&processor.DeleteMarkers,
+		"delete-markers",
+		"missing",
+		"treat objects hidden by a delete marker as [missing, deleted]",
+	)
+	flags.StringVar(
+		&processor.Proxy,
+		"proxy",
+		"",
+		"proxy url for network requests, direct for none (default from HTTP_PROXY/HTTPS_PROXY/NO_PROXY)",
+	)
+	flags.StringArrayVar(
+		&processor.CACert,
+		"ca-cert",
+		[]string{},
+		"PEM bundle of certificate authorities to trust alongside the system ones, repeatable",
+	)
+	flags.StringVar(
+		&processor.ClientCert,
+		"client-cert",
+		"",
+		"PEM client certificate presented to servers which ask for one",
+	)
+	flags.StringVar(
+		&processor.ClientKey,
+		"client-key",
+		"",
+		"PEM private key for --client-cert",
+	)
+	flags.StringVar(
+		&processor.ConnectTimeout,
+		"connect-timeout",
+		"30s",
+		"time allowed to connect and complete the TLS handshake",
+	)
+	flags.StringVar(
+		&processor.ResponseTimeout,
+		"response-timeout",
+		"",
+		"time allowed for a server to start responding e.g. 1m (default no limit)",
+	)
+	flags.IntVar(
+		&processor.MaxConnsPerHost,
+		"max-conns-per-host",
+		0,
+		"maximum connections open to each host, 0 for no limit",
+	)
+	flags.IntVar(
+		&processor.MaxIdleConns,
+		"max-idle-conns",
+		100,
+		"idle connections kept open to be reused",
+	)
+	flags.BoolVar(
+		&processor.IPv4,
+		"ipv4",
+		false,
+		"only connect to IPv4 addresses",
+	)
+	flags.BoolVar(
+		&processor.IPv6,
+		"ipv6",
+		false,
+		"only connect to IPv6 addresses",
+	)
+	flags.StringVar(
+		&processor.AnonymizePaths,
+		"anonymize-paths",
+		"",
+		"replace paths in the output with a keyed HMAC [hmac], the key is HASHIT_ANONYMIZE_KEY or random for each run",
+	)
+	flags.BoolVar(
+		&processor.NormalizePaths,
+		"normalize-paths",
+		false,
+		"match --check and --baseline paths in any Unicode normalisation form such as the decomposed names macOS writes",
+	)
+	flags.BoolVar(
+		&processor.IgnorePathCase,
+		"ignore-path-case",
+		false,
+		"match --check and --baseline paths without regard to case",
+	)
+	flags.StringVar(
+		&processor.VerifyKey,
+		"verify-key",
+		"",
+		"minisign public key the --check manifest signature must verify with before it is trusted",
+	)
+	flags.BoolVar(
+		&processor.CodeSign,
+		"codesign",
+		false,
+		"report cdhashes and check macOS binaries or .app bundles against their code signature seal",
+	)
+	flags.StringVar(
+		&processor.VerifyPackages,
+		"verify-packages",
+		"",
+		"verify installed files against the package database [auto, dpkg, rpm], an optional path is used as the system root",
+	)
+	flags.BoolVar(
+		&processor.VSS,
+		"vss",
+		false,
+		"scan a volume shadow copy snapshot so locked files can be hashed (Windows, requires administrator)",
+	)
+	flags.BoolVar(
+		&processor.Diff,
+		"diff",
+		false,
+		"compare two directories reporting files only in one and content differences",
+	)
+	flags.StringVar(
+		&processor.TorrentFile,
+		"torrent",
+		"",
+		"verify the supplied directory against a .torrent file reporting failed files and pieces",
+	)
+	flags.StringArrayVar(
+		&processor.Label,
+		"label",
+		[]string{},
+		"key=value attached to every record in structured output, can be repeated",
+	)
+	flags.IntVar(
+		&processor.CacheSize,
+		"cache-size",
+		0,
+		"number of results to cache so unchanged inputs seen again are not rehashed (0 disables)",
+	)
+	flags.BoolVar(
+		&noConfig,
+		"no-config",
+		false,
+		"do not load defaults from ~/.hashit.toml or ./.hashit.toml",
+	)
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	hashesJSON := false
+	hashesCmd := &cobra.Command{
+		Use:   "hashes",
+		Short: "list all supported hashes",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.ListHashes(hashesJSON)
+		},
+	}
+	hashesCmd.Flags().BoolVar(
+		&hashesJSON,
+		"json",
+		false,
+		"output the supported hashes as JSON",
+	)
+	rootCmd.AddCommand(hashesCmd)
+
+	fuzztestJSON := false
+	fuzztestMaxFiles := 100
+	fuzztestHashes := []string{}
+	fuzztestCmd := &cobra.Command{
+		Use:   "fuzztest [dir]",
+		Short: "flip bits in copies of files and confirm every output format detects the corruption",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			processor.Hash = fuzztestHashes
+			processor.FuzzTest(dir, fuzztestMaxFiles, fuzztestJSON)
+		},
+	}
+	fuzztestCmd.Flags().StringSliceVarP(
+		&fuzztestHashes,
+		"hash",
+		"c",
+		[]string{"md5", "sha1", "sha256", "sha512"},
+		"hashes to check detect corruption (set to 'all' for all possible hashes)",
+	)
+	fuzztestCmd.Flags().IntVar(
+		&fuzztestMaxFiles,
+		"max-files",
+		100,
+		"maximum number of files to copy and corrupt",
+	)
+	fuzztestCmd.Flags().BoolVar(
+		&fuzztestJSON,
+		"json",
+		false,
+		"output the results as JSON",
+	)
+	rootCmd.AddCommand(fuzztestCmd)
+
+	conformanceJSON := false
+	conformanceWrite := ""
+	conformanceCmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "check every output format gives byte for byte what coreutils, hashdeep and the other tools reading it expect",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.Conformance(conformanceJSON, conformanceWrite)
+		},
+	}
+	conformanceCmd.Flags().BoolVar(
+		&conformanceJSON,
+		"json",
+		false,
+		"output the results as JSON",
+	)
+	conformanceCmd.Flags().StringVar(
+		&conformanceWrite,
+		"write",
+		"",
+		"directory to write the corpus and expected outputs into so they can be checked with the real tools",
+	)
+	rootCmd.AddCommand(conformanceCmd)
+
+	suggestJSON := false
+	suggestSecurity := processor.SecurityStrong
+	suggestCmd := &cobra.Command{
+		Use:   "suggest [path]",
+		Short: "benchmark hashes against a sample of the files in path and recommend the fastest",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := "."
+			if len(args) == 1 {
+				path = args[0]
+			}
+			processor.Suggest(path, suggestSecurity, suggestJSON)
+		},
+	}
+	suggestCmd.Flags().StringVar(
+		&suggestSecurity,
+		"security",
+		processor.SecurityStrong,
+		"minimum security level of recommended hashes [none, legacy, strong]",
+	)
+	suggestCmd.Flags().BoolVar(
+		&suggestJSON,
+		"json",
+		false,
+		"output the recommendation as JSON",
+	)
+	rootCmd.AddCommand(suggestCmd)
+
+	genTestdataSeed := int64(1)
+	genTestdataHashes := []string{}
+	genTestdataFormat := ""
+	genTestdataCmd := &cobra.Command{
+		Use:   "gen-testdata dir",
+		Short: "write files with known contents covering edge cases to dir and print a manifest of them",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.Hash = genTestdataHashes
+			processor.Format = genTestdataFormat
+			processor.GenTestdata(args[0], genTestdataSeed)
+		},
+	}
+	genTestdataCmd.Flags().StringSliceVarP(
+		&genTestdataHashes,
+		"hash",
+		"c",
+		[]string{"sha256"},
+		"hashes to include in the manifest (set to 'all' for all possible hashes)",
+	)
+	genTestdataCmd.Flags().StringVarP(
+		&genTestdataFormat,
+		"format",
+		"f",
+		"sum",
+		"format of the manifest [text, json, sum, hashdeep, hashit]",
+	)
+	genTestdataCmd.Flags().Int64Var(
+		&genTestdataSeed,
+		"seed",
+		1,
+		"seed for the random file contents, the same seed always writes the same files",
+	)
+	rootCmd.AddCommand(genTestdataCmd)
+
+	benchSizes := []string{}
+	benchThreads := 0
+	benchDuration := ""
+	benchJSON := false
+	benchHashes := []string{}
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "measure the throughput of every hash on this machine at several buffer sizes and thread counts",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.Hash = benchHashes
+			processor.Bench(benchSizes, benchThreads, benchDuration, benchJSON)
+		},
+	}
+	benchCmd.Flags().StringSliceVarP(
+		&benchHashes,
+		"hash",
+		"c",
+		[]string{"all"},
+		"hashes to benchmark (set to 'all' for all possible hashes)",
+	)
+	benchCmd.Flags().StringSliceVar(
+		&benchSizes,
+		"sizes",
+		[]string{"4K", "64K", "1M", "16M"},
+		"buffer sizes to hash (suffixes K, M, G are powers of 1024)",
+	)
+	benchCmd.Flags().IntVar(
+		&benchThreads,
+		"threads",
+		0,
+		"threads to compare against a single thread (0 uses every core)",
+	)
+	benchCmd.Flags().StringVar(
+		&benchDuration,
+		"duration",
+		"200ms",
+		"how long to hash each combination for",
+	)
+	benchCmd.Flags().BoolVar(
+		&benchJSON,
+		"json",
+		false,
+		"output the results as JSON",
+	)
+	rootCmd.AddCommand(benchCmd)
+
+	mergeCmd := &cobra.Command{
+		Use:   "merge manifest...",
+		Short: "combine manifests in the hashit, json, hashdeep or sum formats into one in the selected format",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if !noConfig {
+				if err := loadConfig(cmd.Flags()); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+
+			processor.Merge(args)
+		},
+	}
+	rootCmd.AddCommand(mergeCmd)
+
+	buildFilterCmd := &cobra.Command{
+		Use:   "build-filter output source...",
+		Short: "build a known filter from hash lists too large to load which --known can match against",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.BuildKnownFilter(args[0], args[1:])
+		},
 	}
+	rootCmd.AddCommand(buildFilterCmd)
+
+	keysDir := processor.DefaultKeyDir()
+	keysType := "minisign"
+	keysUnencrypted := false
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "manage the signing keys used by --sign and --verify-key",
+	}
+	keysCmd.PersistentFlags().StringVar(
+		&keysDir,
+		"dir",
+		keysDir,
+		"directory the keys are kept in",
+	)
+	keysGenerateCmd := &cobra.Command{
+		Use:   "generate name",
+		Short: "create a key pair encrypted with a passphrase",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.KeysGenerate(keysDir, args[0], keysType, keysUnencrypted)
+		},
+	}
+	keysGenerateCmd.Flags().StringVar(
+		&keysType,
+		"type",
+		"minisign",
+		"type of key to create [minisign, age]",
+	)
+	keysRotateCmd := &cobra.Command{
+		Use:   "rotate name",
+		Short: "replace a key with a new one keeping the old as retired",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.KeysRotate(keysDir, args[0], keysUnencrypted)
+		},
+	}
+	for _, c := range []*cobra.Command{keysGenerateCmd, keysRotateCmd} {
+		c.Flags().BoolVar(
+			&keysUnencrypted,
+			"unencrypted",
+			false,
+			"store the new secret key without a passphrase",
+		)
+	}
+	keysListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the active and retired keys",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.KeysList(keysDir)
+		},
+	}
+	keysCmd.AddCommand(keysGenerateCmd, keysRotateCmd, keysListCmd)
+	rootCmd.AddCommand(keysCmd)
+
+	lookupManifests := []string{}
+	lookupJSON := false
+	lookupCmd := &cobra.Command{
+		Use:   "lookup digest-or-path...",
+		Short: "find which files in the manifests have a digest or what digests a path has",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			processor.Lookup(lookupManifests, args, lookupJSON)
+		},
+	}
+	lookupCmd.Flags().StringArrayVar(
+		&lookupManifests,
+		"manifest",
+		[]string{},
+		"manifest in the hashit, json, hashdeep or sum format to search, can be repeated",
+	)
+	lookupCmd.Flags().BoolVar(
+		&lookupJSON,
+		"json",
+		false,
+		"output the matching records as JSON",
+	)
+	rootCmd.AddCommand(lookupCmd)
+
+	fetchFrom := ""
+	fetchKeep := ""
+	fetchJSON := false
+	fetchVerifyCmd := &cobra.Command{
+		Use:   "fetch-verify",
+		Short: "download each url in a url,digest[,filename] CSV verifying it against the expected digest",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !noConfig {
+				if err := loadConfig(cmd.Flags()); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+
+			processor.FetchVerify(fetchFrom, fetchKeep, fetchJSON)
+		},
+	}
+	fetchVerifyCmd.Flags().StringVar(
+		&fetchFrom,
+		"from",
+		"-",
+		"CSV of url,digest[,filename] rows where digests may be hex, name:hex or sha384-<base64> (- for stdin)",
+	)
+	fetchVerifyCmd.Flags().StringVar(
+		&fetchKeep,
+		"keep",
+		"",
+		"directory to save downloads into, only those which verify are kept",
+	)
+	fetchVerifyCmd.Flags().BoolVar(
+		&fetchJSON,
+		"json",
+		false,
+		"output the report as JSON",
+	)
+	rootCmd.AddCommand(fetchVerifyCmd)
+
+	serveListen := "127.0.0.1:8080"
+	serveCmd := &cobra.Command{
+		Use:   "serve [root...]",
+		Short: "hash uploaded content and files under the roots over HTTP returning JSON",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !noConfig {
+				if err := loadConfig(cmd.Flags()); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+
+			processor.Serve(serveListen, args)
+		},
+	}
+	serveCmd.Flags().StringVar(
+		&serveListen,
+		"listen",
+		"127.0.0.1:8080",
+		"address to listen on, POST /hash hashes the body and GET /hash?path= a file under a root",
+	)
+	rootCmd.AddCommand(serveCmd)
+
+	_ = rootCmd.RegisterFlagCompletionFunc("hash", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names := []string{"all"}
+		for _, h := range processor.HashInfos {
+			names = append(names, h.Name)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = rootCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return append(processor.Formats, "template"), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return rootCmd
 }
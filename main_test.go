@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/boyter/hashit/processor"
+)
+
+// Subcommands registering their own --hash or --format must not overwrite the defaults
+// of the root command as registering a flag writes its default into the variable
+func TestRootCommandDefaults(t *testing.T) {
+	cmd := newRootCommand()
+
+	hashes := []string{"md5", "sha1", "sha256", "sha512"}
+	if !reflect.DeepEqual(processor.Hash, hashes) {
+		t.Errorf("Expected default hashes %v got %v", hashes, processor.Hash)
+	}
+	if processor.Format != "text" {
+		t.Errorf("Expected default format text got %s", processor.Format)
+	}
+
+	if got := cmd.PersistentFlags().Lookup("hash").DefValue; got != "[md5,sha1,sha256,sha512]" {
+		t.Errorf("Unexpected hash flag default %s", got)
+	}
+	if got := cmd.PersistentFlags().Lookup("format").DefValue; got != "text" {
+		t.Errorf("Unexpected format flag default %s", got)
+	}
+}
+
+// The project config overrides the home config for slice flags whether the value is a
+// list or a single string
+func TestConfigSliceOverride(t *testing.T) {
+	previous := processor.Hash
+	defer func() { processor.Hash = previous }()
+
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home.toml")
+	project := filepath.Join(dir, "project.toml")
+	_ = os.WriteFile(home, []byte(`hash = "md5"`), 0600)
+	_ = os.WriteFile(project, []byte(`hash = "sha1,sha256"`), 0600)
+
+	flags := newRootCommand().PersistentFlags()
+	for _, file := range []string{home, project} {
+		if err := applyConfigFile(file, flags); err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+	}
+
+	expected := []string{"sha1", "sha256"}
+	if !reflect.DeepEqual(processor.Hash, expected) {
+		t.Errorf("Expected %v got %v", expected, processor.Hash)
+	}
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// Environment variable holding the --anonymize-paths key so separate runs produce the same names
+const anonymizeKeyEnv = "HASHIT_ANONYMIZE_KEY"
+
+// Key paths are replaced with, nil when not anonymizing
+var anonymizeKey []byte
+
+// Picks the key for --anonymize-paths, random unless one is supplied so names only
+// match within the run
+func prepareAnonymize() error {
+	anonymizeKey = nil
+	switch strings.ToLower(AnonymizePaths) {
+	case "":
+		return nil
+	case "hmac":
+	default:
+		return fmt.Errorf("invalid --anonymize-paths %s expected hmac", AnonymizePaths)
+	}
+
+	if key := os.Getenv(anonymizeKeyEnv); key != "" {
+		anonymizeKey = []byte(key)
+		return nil
+	}
+	anonymizeKey = make([]byte, 32)
+	if _, err := rand.Read(anonymizeKey); err != nil {
+		return fmt.Errorf("unable to generate a key for --anonymize-paths: %w", err)
+	}
+	return nil
+}
+
+// HMAC-SHA256 of the path so the same path always gets the same name with one key
+func anonymizePath(path string) string {
+	mac := hmac.New(sha256.New, anonymizeKey)
+	mac.Write([]byte(path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// The text recorded for an error, without the path a *fs.PathError carries when anonymizing
+func anonymizeError(err error) string {
+	var pathErr *fs.PathError
+	if anonymizeKey != nil && errors.As(err, &pathErr) {
+		return pathErr.Op + ": " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// Replaces every path in the results with its HMAC just before they are output, so
+// baselines, known files and resume state still see the real paths
+func anonymizePaths(input chan Result) chan Result {
+	if anonymizeKey == nil {
+		return input
+	}
+
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			name := anonymizePath(res.File)
+			if res.Error != "" && res.File != "" {
+				// Errors not built from a *fs.PathError can still name the file
+				res.Error = strings.ReplaceAll(res.Error, res.File, name)
+			}
+			res.File = name
+			if res.HardLinkOf != "" {
+				res.HardLinkOf = anonymizePath(res.HardLinkOf)
+			}
+			if res.Original != nil {
+				original := *res.Original
+				original.File = res.File
+				res.Original = &original
+			}
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
+
+// The directory, host and command line recorded in output headers, left empty when
+// anonymizing as they name what is being hidden
+func runContext() (string, string, []string) {
+	if anonymizeKey != nil {
+		return "", "", nil
+	}
+	pwd, _ := os.Getwd()
+	host, _ := os.Hostname()
+	return pwd, host, os.Args
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrepareAnonymize(t *testing.T) {
+	defer func() {
+		AnonymizePaths = ""
+		_ = prepareAnonymize()
+	}()
+
+	AnonymizePaths = "md5"
+	if err := prepareAnonymize(); err == nil {
+		t.Error("expected an unknown method to be rejected")
+	}
+
+	AnonymizePaths = "hmac"
+	t.Setenv(anonymizeKeyEnv, "")
+	_ = prepareAnonymize()
+	first := anonymizePath("a/b.txt")
+	_ = prepareAnonymize()
+	if anonymizePath("a/b.txt") == first {
+		t.Error("expected a random key for each run")
+	}
+
+	t.Setenv(anonymizeKeyEnv, "secret")
+	_ = prepareAnonymize()
+	first = anonymizePath("a/b.txt")
+	_ = prepareAnonymize()
+	if anonymizePath("a/b.txt") != first {
+		t.Error("expected the same name for the same key")
+	}
+	if len(first) != 64 || strings.Contains(first, "b.txt") {
+		t.Errorf("expected a hex digest got %s", first)
+	}
+}
+
+func TestAnonymizePaths(t *testing.T) {
+	defer func() { anonymizeKey = nil }()
+	anonymizeKey = []byte("secret")
+
+	input := make(chan Result, 2)
+	input <- Result{File: "home/alice/taxes.pdf", MD5: "abc", Original: &Result{File: "home/alice/taxes.pdf"}}
+	input <- Result{File: "home/alice/copy.pdf", HardLinkOf: "home/alice/taxes.pdf"}
+	close(input)
+
+	output := anonymizePaths(input)
+	first := <-output
+	second := <-output
+
+	if strings.Contains(first.File, "alice") || first.MD5 != "abc" {
+		t.Errorf("expected only the path replaced got %+v", first)
+	}
+	if first.Original.File != first.File {
+		t.Errorf("expected the original to share the name got %s", first.Original.File)
+	}
+	if second.HardLinkOf != first.File {
+		t.Errorf("expected the hard link to point at the anonymized name got %s", second.HardLinkOf)
+	}
+}
+
+func TestRunContextAnonymized(t *testing.T) {
+	defer func() { anonymizeKey = nil }()
+
+	pwd, _, command := runContext()
+	if wd, _ := os.Getwd(); pwd != wd || len(command) == 0 {
+		t.Errorf("expected the working directory and command got %s %v", pwd, command)
+	}
+
+	anonymizeKey = []byte("secret")
+	pwd, host, command := runContext()
+	if pwd != "" || host != "" || command != nil {
+		t.Errorf("expected nothing recorded got %s %s %v", pwd, host, command)
+	}
+}
+
+func TestAnonymizePathsErrors(t *testing.T) {
+	defer func() { anonymizeKey = nil }()
+	anonymizeKey = []byte("secret")
+
+	_, err := os.Open("home/alice/missing.pdf")
+	input := make(chan Result, 2)
+	input <- Result{File: "home/alice/missing.pdf", Error: anonymizeError(err)}
+	input <- Result{File: "home/alice/taxes.pdf", Error: "unable to read home/alice/taxes.pdf"}
+	close(input)
+
+	output := anonymizePaths(input)
+	first := <-output
+	second := <-output
+
+	if strings.Contains(first.Error, "alice") || !strings.HasPrefix(first.Error, "open: ") {
+		t.Errorf("expected the path removed from the error got %s", first.Error)
+	}
+	if strings.Contains(second.Error, "alice") || !strings.Contains(second.Error, second.File) {
+		t.Errorf("expected the path in the error anonymized got %s", second.Error)
+	}
+
+	anonymizeKey = nil
+	if !strings.Contains(anonymizeError(err), "alice") {
+		t.Errorf("expected the error untouched when not anonymizing got %s", anonymizeError(err))
+	}
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AzureBlock describes one block of a block blob as it would be staged with Put Block
+type AzureBlock struct {
+	ID     string
+	Offset int64
+	Size   int64
+	MD5    string
+}
+
+// Block IDs must all be the same length within a blob so use a zero padded index
+func azureBlockID(index int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", index)))
+}
+
+// Splits the file into blocks of blockSize computing the Content-MD5 Azure reports for
+// each Put Block along with the MD5 of the whole content which is what the committed
+// blob's Content-MD5 should be set to. Values are base64 encoded to match Azure.
+func computeAzureBlocks(filename string, blockSize int64) ([]AzureBlock, string, error) {
+	file, err := os.Open(longPath(filename))
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	return azureBlocks(limitReader(file), blockSize)
+}
+
+// Streams each block through its own MD5 and the whole content's so memory stays fixed
+// however large the block size is
+func azureBlocks(reader io.Reader, blockSize int64) ([]AzureBlock, string, error) {
+	blocks := []AzureBlock{}
+	whole := md5.New()
+	buf := make([]byte, 32*1024)
+
+	var offset int64
+	for index := 0; ; index++ {
+		block := md5.New()
+		n, err := io.CopyBuffer(io.MultiWriter(block, whole), io.LimitReader(reader, blockSize), buf)
+		if err != nil {
+			return nil, "", err
+		}
+		if n == 0 {
+			break
+		}
+
+		blocks = append(blocks, AzureBlock{
+			ID:     azureBlockID(index),
+			Offset: offset,
+			Size:   n,
+			MD5:    base64.StdEncoding.EncodeToString(block.Sum(nil)),
+		})
+		offset += n
+
+		if n < blockSize {
+			break
+		}
+	}
+
+	return blocks, base64.StdEncoding.EncodeToString(whole.Sum(nil)), nil
+}
@@ -0,0 +1,50 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComputeAzureBlocks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "blob")
+	_ = os.WriteFile(file, []byte("hello world"), 0600)
+
+	blocks, contentMD5, err := computeAzureBlocks(file, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if len(blocks) != 3 || blocks[2].Offset != 10 || blocks[2].Size != 1 {
+		t.Errorf("Unexpected blocks %+v", blocks)
+	}
+
+	// md5 of hello base64 encoded
+	if blocks[0].MD5 != "XUFAKrxLKna5cZ2REBfFkg==" {
+		t.Errorf("Expected XUFAKrxLKna5cZ2REBfFkg== got %s", blocks[0].MD5)
+	}
+
+	if contentMD5 != "XrY7u+Ae7tCTyyK7j1rNww==" {
+		t.Errorf("Expected XrY7u+Ae7tCTyyK7j1rNww== got %s", contentMD5)
+	}
+
+	if blocks[0].ID == blocks[1].ID || len(blocks[0].ID) != len(blocks[1].ID) {
+		t.Error("Expected unique block ids of the same length")
+	}
+}
+
+func TestAzureBlocksExactMultiple(t *testing.T) {
+	blocks, _, err := azureBlocks(strings.NewReader("helloworld"), 5)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(blocks) != 2 || blocks[1].Offset != 5 || blocks[1].Size != 5 {
+		t.Errorf("Unexpected blocks %+v", blocks)
+	}
+
+	blocks, _, _ = azureBlocks(strings.NewReader(""), 5)
+	if len(blocks) != 0 {
+		t.Errorf("Expected no blocks for empty content got %+v", blocks)
+	}
+}
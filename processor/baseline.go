@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// What a baseline manifest recorded about a file, Bytes is -1 for manifests without sizes
+type baselineEntry struct {
+	Digests []manifestDigest
+	Bytes   int64
+	MTime   *time.Time
+	// Set for files sampled with --detail-threshold
+	QuickHash string
+}
+
+// Reads a manifest from a previous run returning what it recorded about each file and
+// when that run happened. Containers record the time they were written, for anything
+// else the modification time of the manifest is used.
+func loadBaseline(path string) (map[string]baselineEntry, time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	seen := fi.ModTime()
+
+	data, err := readManifest(path)
+	if err != nil {
+		return nil, seen, err
+	}
+
+	var results []Result
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case isContainer(data):
+		c, err := readContainer(data)
+		if err != nil {
+			return nil, seen, err
+		}
+		results = c.Results
+		seen = c.Header.Created
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &results); err != nil {
+			return nil, seen, err
+		}
+	default:
+		entries, err := parseTextManifest(trimmed)
+		if err != nil {
+			return nil, seen, err
+		}
+		baseline := map[string]baselineEntry{}
+		for _, e := range entries {
+			baseline[e.File] = baselineEntry{Digests: e.Digests, Bytes: -1}
+		}
+		return baseline, seen, nil
+	}
+
+	// Entries come back in the same order as the results which did not fail
+	entries := manifestFromResults(results)
+	baseline := map[string]baselineEntry{}
+	i := 0
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		baseline[r.File] = baselineEntry{Digests: entries[i].Digests, Bytes: r.Bytes, MTime: r.MTime, QuickHash: r.QuickHash}
+		i++
+	}
+	return baseline, seen, nil
+}
+
+// Compares a result against the baseline returning unchanged, changed or unknown when
+// the baseline has nothing in common with what was calculated
+func changeSince(res Result, entry baselineEntry) string {
+	if entry.Bytes >= 0 && entry.Bytes != res.Bytes {
+		return "changed"
+	}
+
+	compared := false
+	if entry.QuickHash != "" && res.QuickHash != "" {
+		if entry.QuickHash != res.QuickHash {
+			return "changed"
+		}
+		compared = true
+	}
+
+	actual := calculatedHashes(res)
+	for _, d := range entry.Digests {
+		matched, calculated := false, false
+		for _, name := range d.Names {
+			if actual[name] != "" {
+				calculated = true
+				matched = matched || actual[name] == d.Digest
+			}
+		}
+		if calculated && !matched {
+			return "changed"
+		}
+		compared = compared || calculated
+	}
+
+	// Without any digests in common the modification time is the best remaining signal
+	if !compared && entry.MTime != nil && res.MTime != nil && !entry.MTime.IsZero() && !res.MTime.IsZero() {
+		if !entry.MTime.Equal(*res.MTime) {
+			return "changed"
+		}
+		compared = true
+	}
+
+	if !compared && entry.Bytes < 0 {
+		return "unknown"
+	}
+	return "unchanged"
+}
+
+// Passes results through marking each as new, unchanged or changed since the baseline
+func annotateChanges(input chan Result, baseline map[string]baselineEntry, seen time.Time) chan Result {
+	output := make(chan Result, FileListQueueSize)
+
+	go func() {
+		if NormalizePaths || IgnorePathCase {
+			folded := map[string]baselineEntry{}
+			for file, entry := range baseline {
+				folded[comparablePath(file)] = entry
+			}
+			baseline = folded
+		}
+
+		for res := range input {
+			if res.Error == "" {
+				if entry, ok := baseline[comparablePath(res.File)]; ok {
+					res.Change = changeSince(res, entry)
+					lastSeen := seen
+					res.LastSeen = &lastSeen
+				} else {
+					res.Change = "new"
+				}
+			}
+			output <- res
+		}
+		close(output)
+	}()
+
+	return output
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultErrorJSONKey(t *testing.T) {
+	out, _ := json.Marshal(Result{File: "b", Error: "denied"})
+	if !strings.Contains(string(out), `"Error":"denied"`) {
+		t.Errorf("Expected the error under the capitalised key got %s", out)
+	}
+
+	// Manifests written with the older lowercase key still load
+	res := Result{}
+	if err := json.Unmarshal([]byte(`{"File":"b","error":"denied"}`), &res); err != nil || res.Error != "denied" {
+		t.Errorf("Expected the lowercase key to load got %+v %v", res, err)
+	}
+}
+
+func TestLoadBaselineJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	_ = os.WriteFile(path, []byte(`[{"File":"a","MD5":"5d41402abc4b2a76b9719d911017c592","Bytes":5},{"File":"b","Error":"denied"}]`), 0600)
+
+	baseline, seen, err := loadBaseline(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(baseline) != 1 || baseline["a"].Bytes != 5 || len(baseline["a"].Digests) != 1 {
+		t.Errorf("Unexpected baseline %+v", baseline)
+	}
+	if seen.IsZero() {
+		t.Error("Expected the manifest modification time as when it was seen")
+	}
+}
+
+func TestChangeSince(t *testing.T) {
+	Hash = []string{HashNames.MD5}
+	defer func() { Hash = []string{} }()
+
+	hello := []manifestDigest{{Names: []string{HashNames.MD5}, Digest: "5d41402abc4b2a76b9719d911017c592"}}
+	earlier := time.Unix(1000, 0)
+	later := time.Unix(2000, 0)
+
+	cases := []struct {
+		name     string
+		res      Result
+		entry    baselineEntry
+		expected string
+	}{
+		{"same", Result{MD5: "5d41402abc4b2a76b9719d911017c592", Bytes: 5}, baselineEntry{Digests: hello, Bytes: 5}, "unchanged"},
+		{"digest", Result{MD5: "00000000000000000000000000000000", Bytes: 5}, baselineEntry{Digests: hello, Bytes: 5}, "changed"},
+		{"size", Result{MD5: "5d41402abc4b2a76b9719d911017c592", Bytes: 6}, baselineEntry{Digests: hello, Bytes: 5}, "changed"},
+		{"sum", Result{MD5: "5d41402abc4b2a76b9719d911017c592", Bytes: 5}, baselineEntry{Digests: hello, Bytes: -1}, "unchanged"},
+		{"mtime", Result{Bytes: 5, MTime: &later}, baselineEntry{Bytes: 5, MTime: &earlier}, "changed"},
+		{"unknown", Result{Bytes: 5}, baselineEntry{Digests: []manifestDigest{{Names: []string{HashNames.SHA1}, Digest: "x"}}, Bytes: -1}, "unknown"},
+	}
+	for _, c := range cases {
+		if actual := changeSince(c.res, c.entry); actual != c.expected {
+			t.Errorf("%s expected %s got %s", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestAnnotateChanges(t *testing.T) {
+	seen := time.Unix(1000, 0)
+	baseline := map[string]baselineEntry{"a": {Bytes: 5}}
+
+	input := make(chan Result, 3)
+	input <- Result{File: "a", Bytes: 5}
+	input <- Result{File: "b", Bytes: 5}
+	input <- Result{File: "c", Error: "denied"}
+	close(input)
+
+	results := []Result{}
+	for r := range annotateChanges(input, baseline, seen) {
+		results = append(results, r)
+	}
+
+	if results[0].Change != "unchanged" || results[0].LastSeen == nil || !results[0].LastSeen.Equal(seen) {
+		t.Errorf("Unexpected existing result %+v", results[0])
+	}
+	if results[1].Change != "new" || results[1].LastSeen != nil {
+		t.Errorf("Unexpected new result %+v", results[1])
+	}
+	if results[2].Change != "" {
+		t.Errorf("Expected error results to be left alone got %+v", results[2])
+	}
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchResult is the throughput of one algorithm hashing buffers of one size
+type BenchResult struct {
+	Name        string  `json:"name"`
+	Bytes       int64   `json:"bytes"`
+	Threads     int     `json:"threads"`
+	MBPerSecond float64 `json:"mbPerSecond"`
+}
+
+// Times threads goroutines each hashing their own buffer of bufSize bytes with a single
+// algorithm for duration returning their combined MB/s
+func measureHash(name string, bufSize int64, threads int, duration time.Duration) float64 {
+	previous := Hash
+	Hash = []string{name}
+	defer func() { Hash = previous }()
+
+	var hashed int64
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for i := 0; i < threads; i++ {
+		content := make([]byte, bufSize)
+		for j := range content {
+			content[j] = byte(j * 7)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Since(startTime) < duration {
+				_, _ = processReadFile("bench", &content)
+				atomic.AddInt64(&hashed, bufSize)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return float64(hashed) / time.Since(startTime).Seconds() / 1_000_000
+}
+
+// Measures every selected algorithm at each buffer size on one thread and on threads
+func bench(sizes []int64, threads int, duration time.Duration) []BenchResult {
+	counts := []int{1}
+	if threads > 1 {
+		counts = append(counts, threads)
+	}
+
+	results := []BenchResult{}
+	for _, info := range HashInfos {
+		if !hasHash(info.Name) {
+			continue
+		}
+		for _, size := range sizes {
+			for _, n := range counts {
+				results = append(results, BenchResult{
+					Name:        info.Name,
+					Bytes:       size,
+					Threads:     n,
+					MBPerSecond: measureHash(info.Name, size, n, duration),
+				})
+			}
+		}
+	}
+	return results
+}
+
+// Lays the results out with a row per algorithm and a column per size and thread count
+func formatBench(results []BenchResult, sizes []int64, threads int) string {
+	counts := []int{1}
+	if threads > 1 {
+		counts = append(counts, threads)
+	}
+
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("%11s", "MB/s"))
+	for _, size := range sizes {
+		for _, n := range counts {
+			str.WriteString(fmt.Sprintf(" %14s", fmt.Sprintf("%s x%d", formatBytes(size), n)))
+		}
+	}
+	str.WriteString("\n")
+
+	var fastest BenchResult
+	for i, r := range results {
+		if i == 0 || r.Name != results[i-1].Name {
+			if i != 0 {
+				str.WriteString("\n")
+			}
+			str.WriteString(fmt.Sprintf("%11s", r.Name))
+		}
+		str.WriteString(fmt.Sprintf(" %14.1f", r.MBPerSecond))
+		if r.MBPerSecond > fastest.MBPerSecond {
+			fastest = r
+		}
+	}
+	if len(results) != 0 {
+		str.WriteString("\n")
+		unit := "threads"
+		if fastest.Threads == 1 {
+			unit = "thread"
+		}
+		str.WriteString(fmt.Sprintf("fastest: %s at %.1f MB/s with %s buffers on %d %s\n", fastest.Name, fastest.MBPerSecond, formatBytes(fastest.Bytes), fastest.Threads, unit))
+	}
+	return str.String()
+}
+
+// Bench measures the throughput of the selected algorithms on this machine at each
+// buffer size, on a single thread and across threads, and prints a table
+func Bench(sizes []string, threads int, duration string, asJSON bool) {
+	Hash = formatHashInput()
+
+	parsed := []int64{}
+	for _, s := range sizes {
+		size, err := parseSize(s)
+		if err != nil || size <= 0 {
+			printError(fmt.Sprintf("invalid buffer size %s", s))
+			os.Exit(1)
+		}
+		parsed = append(parsed, size)
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		printError(fmt.Sprintf("invalid duration %s expected a duration like 200ms or 1s", duration))
+		os.Exit(1)
+	}
+
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	results := bench(parsed, threads, d)
+	if asJSON {
+		out, _ := json.Marshal(results)
+		fmt.Println(string(out))
+		return
+	}
+	fmt.Print(formatBench(results, parsed, threads))
+}
@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeasureHash(t *testing.T) {
+	if speed := measureHash(HashNames.MD5, 1024, 2, time.Millisecond); speed <= 0 {
+		t.Errorf("expected a positive throughput got %f", speed)
+	}
+}
+
+func TestBenchSelectedHashes(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+
+	results := bench([]int64{1024, 4096}, 2, time.Millisecond)
+	if len(results) != 8 {
+		t.Fatalf("expected 2 hashes by 2 sizes by 2 thread counts got %d", len(results))
+	}
+	if results[0].Name != HashNames.MD5 || results[0].Bytes != 1024 || results[0].Threads != 1 || results[1].Threads != 2 {
+		t.Errorf("unexpected order %+v", results[:2])
+	}
+	if Hash[0] != HashNames.MD5 || len(Hash) != 2 {
+		t.Errorf("expected the selected hashes to be restored got %v", Hash)
+	}
+}
+
+func TestFormatBench(t *testing.T) {
+	results := []BenchResult{
+		{Name: "md5", Bytes: 4096, Threads: 1, MBPerSecond: 400},
+		{Name: "md5", Bytes: 4096, Threads: 4, MBPerSecond: 1600},
+		{Name: "sha256", Bytes: 4096, Threads: 1, MBPerSecond: 900},
+		{Name: "sha256", Bytes: 4096, Threads: 4, MBPerSecond: 3600},
+	}
+
+	out := formatBench(results, []int64{4096}, 4)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header, two rows and the fastest got %q", out)
+	}
+	if !strings.Contains(lines[0], "4.0 KiB x1") || !strings.Contains(lines[0], "4.0 KiB x4") {
+		t.Errorf("unexpected header %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "3600.0") {
+		t.Errorf("unexpected row %s", lines[2])
+	}
+	if lines[3] != "fastest: sha256 at 3600.0 MB/s with 4.0 KiB buffers on 4 threads" {
+		t.Errorf("unexpected fastest %s", lines[3])
+	}
+}
@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Minimal bencode decoder, enough to read .torrent metainfo files. Strings are
+// returned as string, integers as int64, lists as []interface{} and dictionaries
+// as map[string]interface{}.
+type bencodeDecoder struct {
+	data []byte
+	pos  int
+}
+
+func bencodeDecode(data []byte) (interface{}, error) {
+	d := &bencodeDecoder{data: data}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("bencode: trailing data at offset %d", d.pos)
+	}
+	return v, nil
+}
+
+var errBencodeEOF = errors.New("bencode: unexpected end of data")
+
+func (d *bencodeDecoder) decode() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, errBencodeEOF
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		d.pos++
+		end := d.indexFrom('e')
+		if end == -1 {
+			return nil, errBencodeEOF
+		}
+		n, err := strconv.ParseInt(string(d.data[d.pos:end]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bencode: invalid integer at offset %d", d.pos)
+		}
+		d.pos = end + 1
+		return n, nil
+	case c == 'l':
+		d.pos++
+		list := []interface{}{}
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errBencodeEOF
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return list, nil
+			}
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case c == 'd':
+		d.pos++
+		dict := map[string]interface{}{}
+		for {
+			if d.pos >= len(d.data) {
+				return nil, errBencodeEOF
+			}
+			if d.data[d.pos] == 'e' {
+				d.pos++
+				return dict, nil
+			}
+			key, err := d.decodeString()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	default:
+		return nil, fmt.Errorf("bencode: unexpected %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *bencodeDecoder) decodeString() (string, error) {
+	colon := d.indexFrom(':')
+	if colon == -1 {
+		return "", errBencodeEOF
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("bencode: invalid string length at offset %d", d.pos)
+	}
+	start := colon + 1
+	if start+length > len(d.data) {
+		return "", errBencodeEOF
+	}
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *bencodeDecoder) indexFrom(b byte) int {
+	for i := d.pos; i < len(d.data); i++ {
+		if d.data[i] == b {
+			return i
+		}
+	}
+	return -1
+}
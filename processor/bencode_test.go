@@ -0,0 +1,28 @@
+package processor
+
+import "testing"
+
+func TestBencodeDecode(t *testing.T) {
+	v, err := bencodeDecode([]byte("d3:bar4:spam3:fooi42e4:listl1:ai-1eee"))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	dict := v.(map[string]interface{})
+	if dict["bar"] != "spam" || dict["foo"] != int64(42) {
+		t.Errorf("Unexpected dictionary %v", dict)
+	}
+
+	list := dict["list"].([]interface{})
+	if len(list) != 2 || list[0] != "a" || list[1] != int64(-1) {
+		t.Errorf("Unexpected list %v", list)
+	}
+}
+
+func TestBencodeDecodeInvalid(t *testing.T) {
+	for _, input := range []string{"", "i42", "5:abc", "d3:foo", "x", "i1ei2e"} {
+		if _, err := bencodeDecode([]byte(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sync"
+
+	"lukechampine.com/blake3/guts"
+)
+
+// BLAKE3 is a tree hash so unlike the other hashes a single file can be split into
+// subtrees which are hashed independently and then combined. The subtrees are read
+// and compressed concurrently with the SIMD routines from the guts package.
+
+// Size of the left subtree for a node covering length bytes, the largest power of
+// two number of chunks which leaves at least one byte for the right
+func blake3LeftLen(length int64) int64 {
+	fullChunks := (length - 1) / guts.ChunkSize
+	return (int64(1) << (63 - bits.LeadingZeros64(uint64(fullChunks)))) * guts.ChunkSize
+}
+
+// Computes the node of the subtree over data which starts at the supplied chunk counter
+func blake3Subtree(data []byte, counter uint64) guts.Node {
+	if len(data) == guts.MaxSIMD*guts.ChunkSize {
+		return guts.CompressBuffer((*[guts.MaxSIMD * guts.ChunkSize]byte)(data), len(data), &guts.IV, counter, 0)
+	}
+
+	if len(data) < guts.MaxSIMD*guts.ChunkSize {
+		var buf [guts.MaxSIMD * guts.ChunkSize]byte
+		copy(buf[:], data)
+		return guts.CompressBuffer(&buf, len(data), &guts.IV, counter, 0)
+	}
+
+	left := blake3LeftLen(int64(len(data)))
+	l := guts.ChainingValue(blake3Subtree(data[:left], counter))
+	r := guts.ChainingValue(blake3Subtree(data[left:], counter+uint64(left/guts.ChunkSize)))
+	return guts.ParentNode(l, r, &guts.IV, 0)
+}
+
+// Hashes size bytes of r with BLAKE3 splitting the file into subtrees of at most
+// segmentSize bytes which are read and hashed by up to workers goroutines at once
+func blake3Parallel(r io.ReaderAt, size int64, workers int, segmentSize int64) ([]byte, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if segmentSize < guts.ChunkSize {
+		segmentSize = guts.ChunkSize
+	}
+
+	p := &blake3ParallelState{
+		r:           r,
+		segmentSize: segmentSize,
+		sem:         make(chan struct{}, workers),
+	}
+
+	root := p.node(0, size)
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	root.Flags |= guts.FlagRoot
+	words := guts.CompressNode(root)
+
+	sum := make([]byte, 32)
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(sum[i*4:], words[i])
+	}
+	return sum, nil
+}
+
+type blake3ParallelState struct {
+	r           io.ReaderAt
+	segmentSize int64
+	sem         chan struct{}
+	mutex       sync.Mutex
+	err         error
+}
+
+func (p *blake3ParallelState) setErr(err error) {
+	p.mutex.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mutex.Unlock()
+}
+
+// Computes the node covering [offset, offset+length) reading the data once the
+// node is small enough and otherwise splitting it into subtrees concurrently
+func (p *blake3ParallelState) node(offset, length int64) guts.Node {
+	if length <= p.segmentSize || length <= guts.ChunkSize {
+		p.sem <- struct{}{}
+		defer func() { <-p.sem }()
+
+		data := make([]byte, length)
+		n, err := p.r.ReadAt(data, offset)
+		if err != nil && !(err == io.EOF && int64(n) == length) {
+			p.setErr(err)
+			return guts.Node{}
+		}
+		return blake3Subtree(data, uint64(offset/guts.ChunkSize))
+	}
+
+	left := blake3LeftLen(length)
+
+	var l [8]uint32
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		l = guts.ChainingValue(p.node(offset, left))
+		wg.Done()
+	}()
+	r := guts.ChainingValue(p.node(offset+left, length-left))
+	wg.Wait()
+
+	return guts.ParentNode(l, r, &guts.IV, 0)
+}
+
+// Reports if any hash other than BLAKE3 is selected which needs the content streamed to it
+func streamedHashSelected() bool {
+	for _, h := range HashInfos {
+		if h.Name != HashNames.Blake3 && hasHash(h.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/zeebo/blake3"
+)
+
+func TestBlake3ParallelMatchesLibrary(t *testing.T) {
+	sizes := []int{0, 1, 63, 64, 65, 1023, 1024, 1025, 2048, 3072, 4097, 31 * 1024, 100_000}
+
+	for _, size := range sizes {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i % 251)
+		}
+
+		d := blake3.New()
+		d.Write(data)
+		expected := hex.EncodeToString(d.Sum(nil))
+
+		for _, segment := range []int64{1024, 4096, 1 << 20} {
+			res, err := blake3Parallel(bytes.NewReader(data), int64(size), 4, segment)
+			if err != nil {
+				t.Fatalf("Unexpected error %s", err.Error())
+			}
+
+			if hex.EncodeToString(res) != expected {
+				t.Errorf("Expected %s got %x for size %d segment %d", expected, res, size, segment)
+			}
+		}
+	}
+}
+
+// Fails any read so a test can tell the streamed read was skipped
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("unexpected streamed read")
+}
+
+func TestProcessStreamParallelBlake3Only(t *testing.T) {
+	defer func(hash []string) { Hash = hash }(Hash)
+	Hash = []string{HashNames.Blake3}
+
+	data := bytes.Repeat([]byte("hashit"), 10_000)
+	d := blake3.New()
+	d.Write(data)
+	expected := hex.EncodeToString(d.Sum(nil))
+
+	res, err := processStream("test", failingReader{}, bytes.NewReader(data), len(data), nil, "")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if res.Blake3 != expected {
+		t.Errorf("Expected %s got %s", expected, res.Blake3)
+	}
+
+	// Any other hash still needs the content streamed to it
+	Hash = []string{HashNames.Blake3, HashNames.MD5}
+	if _, err := processStream("test", failingReader{}, bytes.NewReader(data), len(data), nil, ""); err == nil {
+		t.Error("Expected the streamed read with md5 selected")
+	}
+}
@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"container/list"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStats holds the metrics for the result cache
+type CacheStats struct {
+	Size      int
+	Capacity  int
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+type cacheEntry struct {
+	key    string
+	result Result
+}
+
+// Size bounded least recently used cache of computed results so repeated requests
+// for the same unchanged input do not need to be hashed again
+type lruCache struct {
+	mutex     sync.Mutex
+	capacity  int
+	items     map[string]*list.Element
+	order     *list.List
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+func newLruCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached result for the key if present marking it as recently used
+func (c *lruCache) Get(key string) (Result, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.hits++
+		c.order.MoveToFront(e)
+		return e.Value.(*cacheEntry).result, true
+	}
+
+	c.misses++
+	return Result{}, false
+}
+
+// Put adds the result to the cache evicting the least recently used entry if full
+func (c *lruCache) Put(key string, result Result) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*cacheEntry).result = result
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, result: result})
+
+	for c.order.Len() > c.capacity {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// Stats returns a snapshot of the cache metrics
+func (c *lruCache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return CacheStats{
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// Shared cache used by the workers, nil when caching is disabled
+var resultCache *lruCache
+
+// GetCacheStats returns the metrics of the result cache, empty if caching is disabled
+func GetCacheStats() CacheStats {
+	if resultCache == nil {
+		return CacheStats{}
+	}
+	return resultCache.Stats()
+}
+
+// Builds the identity of an input, anything that changes the content or the
+// digests we would produce needs to be part of it
+func cacheKey(file string, size int64, modTime time.Time) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+	return fmt.Sprintf("%s|%d|%d|%s|%s|%t|%d|%d|%s|%t|%d|%d", abs, size, modTime.UnixNano(), strings.Join(Hash, ","), TextNormalize, KeepOriginal, AzureBlockSize, PieceLength, PieceHash, ZstdFrames, detailThresholdBytes, DetailSamples)
+}
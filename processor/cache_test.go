@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestLruCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLruCache(2)
+	c.Put("a", Result{MD5: "a"})
+	c.Put("b", Result{MD5: "b"})
+	c.Get("a")
+	c.Put("c", Result{MD5: "c"})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+
+	if r, ok := c.Get("a"); !ok || r.MD5 != "a" {
+		t.Error("Expected a to be cached")
+	}
+
+	stats := c.Stats()
+	if stats.Size != 2 || stats.Evictions != 1 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,328 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A digest from a manifest that any of the named hashes may have produced
+type manifestDigest struct {
+	Names  []string
+	Digest string
+}
+
+// A file listed in a manifest with the digests it is expected to have and its size
+// when the manifest records one
+type manifestEntry struct {
+	File    string
+	Bytes   int64
+	Digests []manifestDigest
+}
+
+// Header line which starts every hashdeep file
+const hashdeepMagic = "%%%% HASHDEEP-1.0"
+
+// Reads a manifest produced with the hashit, json, hashdeep or sum formats
+func parseManifest(data []byte) ([]manifestEntry, error) {
+	data = stripPartialTrailer(data)
+	if isContainer(data) {
+		c, err := readContainer(data)
+		if err != nil {
+			return nil, err
+		}
+		return manifestFromResults(c.Results), nil
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseJSONManifest(trimmed)
+	}
+	return parseTextManifest(trimmed)
+}
+
+// Sum and hashdeep manifests are both plain text told apart by the hashdeep header
+func parseTextManifest(data []byte) ([]manifestEntry, error) {
+	if bytes.HasPrefix(data, []byte(hashdeepMagic)) {
+		return parseHashdeepManifest(data)
+	}
+	return parseSumManifest(data)
+}
+
+// Hashdeep files name their columns in a header. File names may contain commas so the
+// filename column takes whatever fields are left over.
+func parseHashdeepManifest(data []byte) ([]manifestEntry, error) {
+	var columns []string
+	entries := []manifestEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "", strings.HasPrefix(line, "##"), line == hashdeepMagic:
+			continue
+		case strings.HasPrefix(line, "%%%% "):
+			columns = strings.Split(strings.TrimPrefix(line, "%%%% "), ",")
+			if !contains(columns, "filename") {
+				return nil, fmt.Errorf("hashdeep header has no filename column: %s", line)
+			}
+			continue
+		case columns == nil:
+			return nil, errors.New("hashdeep manifest is missing its column header")
+		}
+
+		fields := strings.Split(line, ",")
+		extra := len(fields) - len(columns)
+		if extra < 0 {
+			return nil, fmt.Errorf("invalid manifest line: %s", line)
+		}
+
+		entry := manifestEntry{}
+		offset := 0
+		for i, column := range columns {
+			if column == "filename" {
+				entry.File = strings.Join(fields[i:i+extra+1], ",")
+				offset = extra
+				continue
+			}
+			if column == "size" {
+				entry.Bytes, _ = strconv.ParseInt(fields[i+offset], 10, 64)
+				continue
+			}
+			for _, info := range HashInfos {
+				if info.Name == column && fields[i+offset] != "" {
+					entry.Digests = append(entry.Digests, manifestDigest{Names: []string{column}, Digest: strings.ToLower(fields[i+offset])})
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// JSON manifests are a list of results, or an object holding them in Files when written
+// with --stats, --no-content or --provenance
+func parseJSONManifest(data []byte) ([]manifestEntry, error) {
+	if bytes.HasPrefix(data, []byte("{")) {
+		wrapped := struct{ Files []Result }{}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, err
+		}
+		return manifestFromResults(wrapped.Files), nil
+	}
+
+	results := []Result{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	return manifestFromResults(results), nil
+}
+
+func manifestFromResults(results []Result) []manifestEntry {
+	// Every field is looked at then empty ones dropped as they were not calculated
+	previous := Hash
+	Hash = []string{"all"}
+	defer func() { Hash = previous }()
+
+	entries := []manifestEntry{}
+	for _, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		entry := manifestEntry{File: r.File, Bytes: r.Bytes}
+		for name, value := range calculatedHashes(r) {
+			if value != "" {
+				entry.Digests = append(entry.Digests, manifestDigest{Names: []string{name}, Digest: value})
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Sum lines do not say which hash produced them so the digest length is matched against
+// the selected hashes, a line is expected to match any hash of that length
+func parseSumManifest(data []byte) ([]manifestEntry, error) {
+	lengths := map[int][]string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) {
+			lengths[info.Bits/4] = append(lengths[info.Bits/4], info.Name)
+		}
+	}
+
+	entries := []manifestEntry{}
+	index := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		digest, file, found := strings.Cut(line, "  ")
+		if !found {
+			return nil, fmt.Errorf("invalid manifest line: %s", line)
+		}
+		names, ok := lengths[len(digest)]
+		if !ok {
+			return nil, fmt.Errorf("no selected hash produces a %d character digest: %s", len(digest), line)
+		}
+
+		i, ok := index[file]
+		if !ok {
+			i = len(entries)
+			index[file] = i
+			entries = append(entries, manifestEntry{File: file})
+		}
+		entries[i].Digests = append(entries[i].Digests, manifestDigest{Names: names, Digest: strings.ToLower(digest)})
+	}
+	return entries, scanner.Err()
+}
+
+// Rehashes the entries reporting each file as OK, FAILED or missing
+func checkManifest(entries []manifestEntry) (map[string]string, error) {
+	names := map[string]bool{}
+	files := []string{}
+	// Where each listed file was found on disk when it differs in Unicode form or case
+	resolver := newPathResolver()
+	found := map[string]string{}
+	unresolved := map[string]bool{}
+	for _, e := range entries {
+		for _, d := range e.Digests {
+			for _, name := range d.Names {
+				names[name] = true
+			}
+		}
+		path, err := resolver.resolve(e.File)
+		if err != nil {
+			printError(err.Error())
+			unresolved[e.File] = true
+		}
+		found[e.File] = path
+		files = append(files, path)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("manifest lists no files")
+	}
+
+	previous := Hash
+	Hash = []string{}
+	for name := range names {
+		Hash = append(Hash, name)
+	}
+	sort.Strings(Hash)
+	defer func() { Hash = previous }()
+
+	results := hashFiles(files)
+
+	status := map[string]string{}
+	for _, e := range entries {
+		r := results[found[e.File]]
+		if r.Error != "" || unresolved[e.File] {
+			status[e.File] = "FAILED open or read"
+			continue
+		}
+
+		status[e.File] = "OK"
+		if !digestsMatch(calculatedHashes(r), e.Digests) {
+			status[e.File] = "FAILED"
+		}
+	}
+	return status, nil
+}
+
+// Every listed digest has to match one of the hashes it could have been produced by
+func digestsMatch(actual map[string]string, digests []manifestDigest) bool {
+	for _, d := range digests {
+		matched := false
+		for _, name := range d.Names {
+			matched = matched || actual[name] == d.Digest
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Verifies the manifest signature when a key is supplied then checks every file it lists,
+// returning the exit code
+func processCheck(manifest string) int {
+	raw, data, err := readManifestRaw(manifest)
+	if err != nil {
+		printError(fmt.Sprintf("failed to read manifest: %s, %s", manifest, err.Error()))
+		return 1
+	}
+
+	if VerifyKey != "" {
+		comment, err := verifyManifestSignature(manifest, raw, data)
+		if err != nil {
+			printError(fmt.Sprintf("manifest signature invalid, refusing to trust %s: %s", manifest, err.Error()))
+			return 1
+		}
+		if Verbose {
+			printVerbose(fmt.Sprintf("signature ok: %s", comment))
+		}
+	}
+
+	entries, err := parseManifest(data)
+	if err != nil {
+		printError(fmt.Sprintf("failed to parse manifest: %s, %s", manifest, err.Error()))
+		return 1
+	}
+
+	if len(CheckRoot) != 0 {
+		roots, err := parseCheckRoots(CheckRoot)
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+		return checkReplicas(entries, roots)
+	}
+
+	status, err := checkManifest(entries)
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+
+	failed := 0
+	for _, e := range entries {
+		fmt.Printf("%s: %s\n", e.File, status[e.File])
+		if status[e.File] != "OK" {
+			failed++
+		}
+		hooks.fire(Result{File: e.File, Bytes: e.Bytes}, status[e.File], manifest, expectedDigests(e))
+	}
+	hooksOK := finishHooks()
+
+	if failed != 0 {
+		printError(fmt.Sprintf("%d of %d listed files did NOT match", failed, len(entries)))
+		return 1
+	}
+	if !hooksOK {
+		return 1
+	}
+	return 0
+}
+
+// Containers carry their signature inside the manifest, anything else has it alongside
+// covering raw, the bytes read from disk. The manifest is never read again so what was
+// verified is what gets parsed.
+func verifyManifestSignature(manifest string, raw []byte, data []byte) (string, error) {
+	if !isContainer(data) {
+		return verifyDetached(manifest, raw, VerifyKey)
+	}
+
+	c, err := readContainer(data)
+	if err != nil {
+		return "", err
+	}
+	if len(c.Signature) == 0 {
+		return verifyDetached(manifest, raw, VerifyKey)
+	}
+	return verifyContainerSignature(c, VerifyKey)
+}
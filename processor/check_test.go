@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSumManifest(t *testing.T) {
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	_ = os.WriteFile(a, []byte("hello"), 0600)
+	_ = os.WriteFile(b, []byte("changed"), 0600)
+
+	manifest := "5d41402abc4b2a76b9719d911017c592  " + a + "\n" +
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  " + a + "\n" +
+		"5d41402abc4b2a76b9719d911017c592  " + b + "\n" +
+		"5d41402abc4b2a76b9719d911017c592  " + filepath.Join(dir, "missing") + "\n"
+
+	entries, err := parseManifest([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(entries) != 3 || len(entries[0].Digests) != 2 {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+
+	status, _ := checkManifest(entries)
+	if status[a] != "OK" || status[b] != "FAILED" || status[filepath.Join(dir, "missing")] != "FAILED open or read" {
+		t.Errorf("Unexpected status %v", status)
+	}
+}
+
+func TestCheckJSONManifest(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	_ = os.WriteFile(a, []byte("hello"), 0600)
+
+	entries, err := parseManifest([]byte(`[{"File":"` + a + `","MD5":"5d41402abc4b2a76b9719d911017c592","SHA1":"wrong"}]`))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	status, _ := checkManifest(entries)
+	if status[a] != "FAILED" {
+		t.Errorf("Expected FAILED as sha1 does not match got %s", status[a])
+	}
+}
+
+func TestParseHashdeepManifest(t *testing.T) {
+	manifest := "%%%% HASHDEEP-1.0\n" +
+		"%%%% size,md5,sha256,filename,mtime\n" +
+		"## Invoked from: /tmp\n" +
+		"##\n" +
+		"5,5D41402ABC4B2A76B9719D911017C592,2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824,/tmp/a,b,2024-01-01 00:00:00\n"
+
+	entries, err := parseManifest([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(entries) != 1 || entries[0].File != "/tmp/a,b" || len(entries[0].Digests) != 2 {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	if entries[0].Digests[0].Names[0] != HashNames.MD5 || entries[0].Digests[0].Digest != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected digest %+v", entries[0].Digests[0])
+	}
+
+	if _, err := parseManifest([]byte("%%%% HASHDEEP-1.0\n5,abc,/tmp/a\n")); err == nil {
+		t.Error("expected an error without a column header")
+	}
+}
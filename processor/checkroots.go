@@ -0,0 +1,182 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A replica --check verifies the manifest against with paths under Old moved to New
+type checkRoot struct {
+	Old string
+	New string
+}
+
+// Parses each --root given as old=/orig/path,new=/mnt/backup, old may be left out to
+// resolve relative manifest paths under new
+func parseCheckRoots(values []string) ([]checkRoot, error) {
+	roots := []checkRoot{}
+	for _, v := range values {
+		root := checkRoot{}
+		for _, part := range strings.Split(v, ",") {
+			key, value, found := strings.Cut(part, "=")
+			switch {
+			case !found:
+				return nil, fmt.Errorf("invalid root %s expected old=path,new=path", v)
+			case key == "old":
+				root.Old = strings.TrimRight(value, `/\`)
+			case key == "new":
+				root.New = value
+			default:
+				return nil, fmt.Errorf("invalid root %s unknown key %s", v, key)
+			}
+		}
+		if root.New == "" {
+			return nil, fmt.Errorf("invalid root %s missing new=path", v)
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// Moves a manifest path onto the replica, false when it is not under the old root
+func (r checkRoot) rewrite(path string) (string, bool) {
+	join := filepath.Join
+	if isObjectRoot(r.New) {
+		join = func(elem ...string) string { return joinObjectPath(elem[0], elem[1]) }
+	}
+
+	if r.Old == "" {
+		if filepath.IsAbs(path) {
+			return "", false
+		}
+		return join(r.New, path), true
+	}
+
+	if path == r.Old {
+		return r.New, true
+	}
+	rest := strings.TrimPrefix(path, r.Old)
+	if rest == path || (rest[0] != '/' && rest[0] != filepath.Separator) {
+		return "", false
+	}
+	return join(r.New, rest), true
+}
+
+// Counts of how the files listed in the manifest compared on one replica
+type replicaStats struct {
+	OK       int
+	Failed   int
+	Missing  int
+	Deleted  int
+	Outside  int
+	Replica  string
+	Statuses map[string]string
+}
+
+func (s replicaStats) matched() bool {
+	return s.Failed == 0 && s.Missing == 0 && s.Outside == 0
+}
+
+// Rehashes the entries moved onto a replica returning the status of each by its
+// path on the replica
+func checkReplica(entries []manifestEntry, root checkRoot) (replicaStats, error) {
+	store, prefix, err := parseObjectRoot(root.New)
+	if err != nil {
+		return replicaStats{}, err
+	}
+	if store != nil {
+		return checkObjectReplica(entries, root, store, prefix)
+	}
+
+	stats := replicaStats{Replica: root.New, Statuses: map[string]string{}}
+	moved := []manifestEntry{}
+	for _, e := range entries {
+		path, ok := root.rewrite(e.File)
+		if !ok {
+			stats.Outside++
+			stats.Statuses[e.File] = "FAILED not under " + root.Old
+			continue
+		}
+		e.File = path
+		moved = append(moved, e)
+	}
+	if len(moved) == 0 {
+		return stats, nil
+	}
+
+	status, err := checkManifest(moved)
+	if err != nil {
+		return stats, err
+	}
+	for _, e := range moved {
+		s := status[e.File]
+		switch {
+		case s == "OK":
+			stats.OK++
+		case s == "FAILED":
+			stats.Failed++
+		default:
+			if _, err := os.Lstat(e.File); errors.Is(err, fs.ErrNotExist) {
+				stats.Missing++
+				s = "FAILED missing"
+			} else {
+				stats.Failed++
+			}
+		}
+		stats.Statuses[e.File] = s
+	}
+	return stats, nil
+}
+
+// Checks the manifest against every replica in turn reporting the files and a summary
+// for each, returning the exit code
+func checkReplicas(entries []manifestEntry, roots []checkRoot) int {
+	if err := validateObjectAudit(); err != nil {
+		printError(err.Error())
+		return 1
+	}
+
+	mismatched := 0
+	for _, root := range roots {
+		stats, err := checkReplica(entries, root)
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+
+		for _, e := range entries {
+			path, ok := root.rewrite(e.File)
+			if !ok {
+				path = e.File
+			}
+			fmt.Printf("%s: %s\n", path, stats.Statuses[path])
+			hooks.fire(Result{File: path, Bytes: e.Bytes}, stats.Statuses[path], Check, expectedDigests(e))
+		}
+		summary := fmt.Sprintf("replica %s: %d OK, %d FAILED, %d missing", stats.Replica, stats.OK, stats.Failed, stats.Missing)
+		if stats.Deleted != 0 {
+			summary += fmt.Sprintf(", %d deleted", stats.Deleted)
+		}
+		if stats.Outside != 0 {
+			summary += fmt.Sprintf(", %d not under %s", stats.Outside, root.Old)
+		}
+		fmt.Println(summary)
+
+		if !stats.matched() {
+			mismatched++
+		}
+	}
+
+	hooksOK := finishHooks()
+	if mismatched != 0 {
+		printError(fmt.Sprintf("%d of %d replicas did NOT match", mismatched, len(roots)))
+		return 1
+	}
+	if !hooksOK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCheckRoots(t *testing.T) {
+	roots, err := parseCheckRoots([]string{"old=/orig/path/,new=/mnt/backup", "new=/mnt/mirror"})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(roots) != 2 || roots[0].Old != "/orig/path" || roots[0].New != "/mnt/backup" || roots[1].Old != "" {
+		t.Errorf("Unexpected roots %+v", roots)
+	}
+
+	for _, invalid := range []string{"/mnt/backup", "old=/orig", "old=/orig,new=/mnt,extra=1"} {
+		if _, err := parseCheckRoots([]string{invalid}); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestCheckRootRewrite(t *testing.T) {
+	root := checkRoot{Old: "/orig/path", New: "/mnt/backup"}
+	if path, ok := root.rewrite("/orig/path/a/b"); !ok || path != filepath.Join("/mnt/backup", "a", "b") {
+		t.Errorf("Unexpected rewrite %s %v", path, ok)
+	}
+	if _, ok := root.rewrite("/orig/pathology/a"); ok {
+		t.Error("Expected a sibling sharing the prefix not to be rewritten")
+	}
+
+	relative := checkRoot{New: "/mnt/backup"}
+	if path, ok := relative.rewrite("a/b"); !ok || path != filepath.Join("/mnt/backup", "a", "b") {
+		t.Errorf("Unexpected rewrite %s %v", path, ok)
+	}
+}
+
+func TestCheckReplicas(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	good := t.TempDir()
+	bad := t.TempDir()
+	_ = os.WriteFile(filepath.Join(good, "a"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(good, "b"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(bad, "a"), []byte("changed"), 0600)
+
+	entries, err := parseManifest([]byte("5d41402abc4b2a76b9719d911017c592  /orig/a\n" +
+		"5d41402abc4b2a76b9719d911017c592  /orig/b\n" +
+		"5d41402abc4b2a76b9719d911017c592  /elsewhere/c\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	stats, err := checkReplica(entries, checkRoot{Old: "/orig", New: good})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if stats.OK != 2 || stats.Failed != 0 || stats.Missing != 0 || stats.Outside != 1 || stats.matched() {
+		t.Errorf("Unexpected stats for the good replica %+v", stats)
+	}
+
+	stats, _ = checkReplica(entries[:2], checkRoot{Old: "/orig", New: bad})
+	if stats.OK != 0 || stats.Failed != 1 || stats.Missing != 1 || stats.Statuses[filepath.Join(bad, "b")] != "FAILED missing" {
+		t.Errorf("Unexpected stats for the bad replica %+v", stats)
+	}
+
+	if code := checkReplicas(entries[:2], []checkRoot{{Old: "/orig", New: good}}); code != 0 {
+		t.Errorf("Expected exit code 0 got %d", code)
+	}
+	if code := checkReplicas(entries[:2], []checkRoot{{Old: "/orig", New: good}, {Old: "/orig", New: bad}}); code != 1 {
+		t.Errorf("Expected exit code 1 got %d", code)
+	}
+}
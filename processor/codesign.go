@@ -0,0 +1,632 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"debug/macho"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Code signing structures are always big endian regardless of the architecture
+const (
+	csMagicEmbeddedSignature = 0xfade0cc0
+	csMagicCodeDirectory     = 0xfade0c02
+	csSlotCodeDirectory      = 0
+	csSlotAlternateDirectory = 0x1000
+	loadCmdCodeSignature     = 0x1d
+
+	// Code is signed in 4KB or 16KB pages, anything outside this is not a real signature
+	csMinPageShift = 12
+	csMaxPageShift = 16
+
+	csSpecialInfoPlist    = 1
+	csSpecialResourceDir  = 3
+	csHashTypeSHA1        = 1
+	csHashTypeSHA256      = 2
+	csHashTypeSHA256Trunc = 3
+	csHashTypeSHA384      = 4
+)
+
+// The parts of a CodeDirectory needed to recompute its hashes
+type codeDirectory struct {
+	raw          []byte
+	hashType     uint8
+	hashSize     int
+	pageSize     int64
+	codeLimit    int64
+	specialSlots [][]byte
+	codeSlots    [][]byte
+}
+
+// CodeSignStatus is the result of checking one item covered by a code signature
+type CodeSignStatus struct {
+	Path   string
+	Status string
+}
+
+// CodeSignReport describes the cdhashes of a binary or bundle and anything that no
+// longer matches its seal
+type CodeSignReport struct {
+	Path    string
+	CDHash  []string
+	Items   []CodeSignStatus
+	Invalid bool
+}
+
+func codeDirectoryHash(hashType uint8) (func() hash.Hash, error) {
+	switch hashType {
+	case csHashTypeSHA1:
+		return sha1.New, nil
+	case csHashTypeSHA256, csHashTypeSHA256Trunc:
+		return sha256.New, nil
+	case csHashTypeSHA384:
+		return sha512.New384, nil
+	}
+	return nil, fmt.Errorf("unsupported code directory hash type %d", hashType)
+}
+
+func codeDirectoryHashName(hashType uint8) string {
+	switch hashType {
+	case csHashTypeSHA1:
+		return "sha1"
+	case csHashTypeSHA384:
+		return "sha384"
+	}
+	return "sha256"
+}
+
+func parseCodeDirectory(blob []byte) (codeDirectory, error) {
+	cd := codeDirectory{raw: blob}
+	if len(blob) < 44 || binary.BigEndian.Uint32(blob[0:4]) != csMagicCodeDirectory {
+		return cd, errors.New("invalid code directory")
+	}
+
+	version := binary.BigEndian.Uint32(blob[8:12])
+	hashOffset := int(binary.BigEndian.Uint32(blob[16:20]))
+	nSpecial := int(binary.BigEndian.Uint32(blob[24:28]))
+	nCode := int(binary.BigEndian.Uint32(blob[28:32]))
+	cd.codeLimit = int64(binary.BigEndian.Uint32(blob[32:36]))
+	cd.hashSize = int(blob[36])
+	cd.hashType = blob[37]
+	if blob[39] != 0 {
+		if blob[39] < csMinPageShift || blob[39] > csMaxPageShift {
+			return cd, fmt.Errorf("invalid code directory page size 2^%d", blob[39])
+		}
+		cd.pageSize = 1 << blob[39]
+	}
+
+	// The hashes are sliced out of the digest so cannot be longer than it
+	newHash, err := codeDirectoryHash(cd.hashType)
+	if err != nil {
+		return cd, err
+	}
+	if cd.hashSize == 0 || cd.hashSize > newHash().Size() {
+		return cd, fmt.Errorf("invalid code directory hash size %d", cd.hashSize)
+	}
+	// Files over 4GB store the limit in a later 64 bit field
+	if version >= 0x20300 && len(blob) >= 64 {
+		if limit64 := binary.BigEndian.Uint64(blob[56:64]); limit64 != 0 {
+			cd.codeLimit = int64(limit64)
+		}
+	}
+
+	if nSpecial > len(blob) || nCode > len(blob) || hashOffset-nSpecial*cd.hashSize < 0 || hashOffset+nCode*cd.hashSize > len(blob) {
+		return cd, errors.New("code directory hashes out of range")
+	}
+	for i := 1; i <= nSpecial; i++ {
+		start := hashOffset - i*cd.hashSize
+		cd.specialSlots = append(cd.specialSlots, blob[start:start+cd.hashSize])
+	}
+	for i := 0; i < nCode; i++ {
+		start := hashOffset + i*cd.hashSize
+		cd.codeSlots = append(cd.codeSlots, blob[start:start+cd.hashSize])
+	}
+
+	return cd, nil
+}
+
+// Returns the hash of the special slot such as Info.plist, nil when the slot is unused
+func (cd codeDirectory) special(slot int) []byte {
+	if slot > len(cd.specialSlots) {
+		return nil
+	}
+	h := cd.specialSlots[slot-1]
+	if bytes.Equal(h, make([]byte, len(h))) {
+		return nil
+	}
+	return h
+}
+
+// The cdhash is the code directory's own hash truncated to 20 bytes
+func (cd codeDirectory) cdhash() string {
+	newHash, err := codeDirectoryHash(cd.hashType)
+	if err != nil {
+		return ""
+	}
+	h := newHash()
+	h.Write(cd.raw)
+	return hex.EncodeToString(h.Sum(nil)[:20])
+}
+
+// The hash type and size were checked when parsing so the digest is always long enough
+func (cd codeDirectory) digest(content []byte) []byte {
+	newHash, _ := codeDirectoryHash(cd.hashType)
+	h := newHash()
+	h.Write(content)
+	return h.Sum(nil)[:cd.hashSize]
+}
+
+// Reads the embedded signature of every architecture in a thin or universal Mach-O
+// returning the readers for each slice and their code directories
+func readCodeDirectories(file *os.File) ([]io.ReaderAt, [][]codeDirectory, error) {
+	type slice struct {
+		reader *io.SectionReader
+		file   *macho.File
+	}
+	slices := []slice{}
+
+	fat, err := macho.NewFatFile(file)
+	switch {
+	case err == nil:
+		for _, arch := range fat.Arches {
+			slices = append(slices, slice{reader: io.NewSectionReader(file, int64(arch.Offset), int64(arch.Size)), file: arch.File})
+		}
+	case errors.Is(err, macho.ErrNotFat):
+		fi, err := file.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		thin, err := macho.NewFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		slices = append(slices, slice{reader: io.NewSectionReader(file, 0, fi.Size()), file: thin})
+	default:
+		return nil, nil, err
+	}
+
+	readers := []io.ReaderAt{}
+	directories := [][]codeDirectory{}
+	for _, s := range slices {
+		var signature []byte
+		for _, l := range s.file.Loads {
+			raw := l.Raw()
+			if len(raw) >= 16 && s.file.ByteOrder.Uint32(raw[0:4]) == loadCmdCodeSignature {
+				offset := s.file.ByteOrder.Uint32(raw[8:12])
+				size := s.file.ByteOrder.Uint32(raw[12:16])
+				if int64(offset)+int64(size) > s.reader.Size() {
+					return nil, nil, errors.New("code signature out of range")
+				}
+				signature = make([]byte, size)
+				if _, err := s.reader.ReadAt(signature, int64(offset)); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		if signature == nil {
+			return nil, nil, errors.New("not code signed")
+		}
+
+		cds, err := parseSuperBlob(signature)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, cd := range cds {
+			if cd.codeLimit > s.reader.Size() {
+				return nil, nil, errors.New("code limit out of range")
+			}
+		}
+		readers = append(readers, s.reader)
+		directories = append(directories, cds)
+	}
+
+	return readers, directories, nil
+}
+
+// Pulls the primary and alternate code directories out of an embedded signature
+func parseSuperBlob(blob []byte) ([]codeDirectory, error) {
+	if len(blob) < 12 || binary.BigEndian.Uint32(blob[0:4]) != csMagicEmbeddedSignature {
+		return nil, errors.New("invalid embedded signature")
+	}
+
+	count := int(binary.BigEndian.Uint32(blob[8:12]))
+	cds := []codeDirectory{}
+	for i := 0; i < count; i++ {
+		entry := 12 + i*8
+		if entry+8 > len(blob) {
+			return nil, errors.New("embedded signature index out of range")
+		}
+		slot := binary.BigEndian.Uint32(blob[entry : entry+4])
+		offset := int(binary.BigEndian.Uint32(blob[entry+4 : entry+8]))
+		if slot != csSlotCodeDirectory && (slot < csSlotAlternateDirectory || slot > csSlotAlternateDirectory+4) {
+			continue
+		}
+		if offset+8 > len(blob) {
+			return nil, errors.New("code directory out of range")
+		}
+		length := int(binary.BigEndian.Uint32(blob[offset+4 : offset+8]))
+		if offset+length > len(blob) {
+			return nil, errors.New("code directory out of range")
+		}
+
+		cd, err := parseCodeDirectory(blob[offset : offset+length])
+		if err != nil {
+			return nil, err
+		}
+		cds = append(cds, cd)
+	}
+
+	if len(cds) == 0 {
+		return nil, errors.New("no code directory")
+	}
+	return cds, nil
+}
+
+// Rehashes every page covered by the code directory reporting if they all match
+func verifyCodePages(r io.ReaderAt, cd codeDirectory) (bool, error) {
+	pageSize := cd.pageSize
+	if pageSize == 0 {
+		pageSize = cd.codeLimit
+	}
+
+	buf := make([]byte, pageSize)
+	for i, expected := range cd.codeSlots {
+		offset := int64(i) * pageSize
+		if offset >= cd.codeLimit {
+			return false, errors.New("code directory has more pages than its code limit")
+		}
+		size := pageSize
+		if offset+size > cd.codeLimit {
+			size = cd.codeLimit - offset
+		}
+		n, err := r.ReadAt(buf[:size], offset)
+		if err != nil && !(err == io.EOF && int64(n) == size) {
+			return false, err
+		}
+		if !bytes.Equal(cd.digest(buf[:size]), expected) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Checks a signed Mach-O returning its cdhashes, the code directories of the first
+// architecture for checking bundle resources and whether every page matched
+func checkMachO(path string) ([]string, []codeDirectory, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer file.Close()
+
+	readers, directories, err := readCodeDirectories(file)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	cdhashes := []string{}
+	valid := true
+	for i, cds := range directories {
+		for _, cd := range cds {
+			cdhashes = append(cdhashes, codeDirectoryHashName(cd.hashType)+":"+cd.cdhash())
+			ok, err := verifyCodePages(readers[i], cd)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			valid = valid && ok
+		}
+	}
+	return cdhashes, directories[0], valid, nil
+}
+
+// Checks a file against the hash in a special slot of every code directory
+func checkSpecialSlot(path string, cds []codeDirectory, slot int) string {
+	content, err := os.ReadFile(path)
+	for _, cd := range cds {
+		expected := cd.special(slot)
+		if expected == nil {
+			continue
+		}
+		if err != nil {
+			return "MISSING"
+		}
+		if !bytes.Equal(cd.digest(content), expected) {
+			return "MODIFIED"
+		}
+	}
+	return "OK"
+}
+
+// A CodeResources rule deciding how files matching the pattern are sealed
+type resourceRule struct {
+	pattern *regexp.Regexp
+	omit    bool
+	weight  float64
+}
+
+func parseResourceRules(rules map[string]interface{}) []resourceRule {
+	parsed := []resourceRule{}
+	for pattern, value := range rules {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		rule := resourceRule{pattern: re, weight: 1}
+		if options, ok := value.(map[string]interface{}); ok {
+			rule.omit, _ = options["omit"].(bool)
+			if nested, _ := options["nested"].(bool); nested {
+				// Nested code is sealed by its own signature so is not expected in files2
+				rule.omit = true
+			}
+			switch w := options["weight"].(type) {
+			case int64:
+				rule.weight = float64(w)
+			case float64:
+				rule.weight = w
+			}
+		}
+		parsed = append(parsed, rule)
+	}
+	return parsed
+}
+
+// Reports if the highest weighted rule matching path omits it from the seal
+func resourceOmitted(rules []resourceRule, path string) bool {
+	best := -1.0
+	omit := false
+	for _, r := range rules {
+		if r.weight > best && r.pattern.MatchString(path) {
+			best = r.weight
+			omit = r.omit
+		}
+	}
+	return omit
+}
+
+// Checks every sealed resource listed in CodeResources and looks for unsealed additions
+func checkResources(contents string, codeResources []byte, mainExecutable string) ([]CodeSignStatus, error) {
+	decoded, err := plistDecode(codeResources)
+	if err != nil {
+		return nil, err
+	}
+	seal, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid CodeResources")
+	}
+	files, ok := seal["files2"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("CodeResources has no files2 seal")
+	}
+	rulesDict, _ := seal["rules2"].(map[string]interface{})
+	rules := parseResourceRules(rulesDict)
+
+	items := []CodeSignStatus{}
+	for name, value := range files {
+		entry, _ := value.(map[string]interface{})
+		expected, _ := entry["hash2"].([]byte)
+		optional, _ := entry["optional"].(bool)
+		target := filepath.Join(contents, filepath.FromSlash(name))
+
+		if _, nested := entry["cdhash"]; nested {
+			items = append(items, CodeSignStatus{Path: name, Status: checkNested(target, entry["cdhash"])})
+			continue
+		}
+		if symlink, ok := entry["symlink"].(string); ok {
+			status := "OK"
+			if actual, err := os.Readlink(target); err != nil {
+				status = "MISSING"
+			} else if actual != symlink {
+				status = "MODIFIED"
+			}
+			items = append(items, CodeSignStatus{Path: name, Status: status})
+			continue
+		}
+
+		content, err := os.ReadFile(target)
+		switch {
+		case err != nil && optional:
+			continue
+		case err != nil:
+			items = append(items, CodeSignStatus{Path: name, Status: "MISSING"})
+		case expected == nil:
+			items = append(items, CodeSignStatus{Path: name, Status: "UNVERIFIED"})
+		default:
+			sum := sha256.Sum256(content)
+			status := "OK"
+			if !bytes.Equal(sum[:], expected) {
+				status = "MODIFIED"
+			}
+			items = append(items, CodeSignStatus{Path: name, Status: status})
+		}
+	}
+
+	// Anything else in the bundle should either be sealed or omitted by the rules
+	err = filepath.WalkDir(contents, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(contents, p)
+		rel = filepath.ToSlash(rel)
+		if _, sealed := files[rel]; sealed || rel == "Info.plist" || rel == mainExecutable || strings.HasPrefix(rel, "_CodeSignature/") {
+			return nil
+		}
+		// Nested bundles are sealed as a single entry
+		for name := range files {
+			if strings.HasPrefix(rel, name+"/") {
+				return nil
+			}
+		}
+		if !resourceOmitted(rules, rel) {
+			items = append(items, CodeSignStatus{Path: rel, Status: "ADDED"})
+		}
+		return nil
+	})
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, err
+}
+
+// Nested code is sealed by cdhash so a signed binary can be compared directly, nested
+// bundles are reported as unverified
+func checkNested(path string, expected interface{}) string {
+	want, _ := expected.([]byte)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "MISSING"
+	}
+	if fi.IsDir() {
+		return "UNVERIFIED"
+	}
+
+	cdhashes, _, _, err := checkMachO(path)
+	if err != nil {
+		return "MODIFIED"
+	}
+	for _, c := range cdhashes {
+		_, value, _ := strings.Cut(c, ":")
+		if value == hex.EncodeToString(want) {
+			return "OK"
+		}
+	}
+	return "MODIFIED"
+}
+
+// Finds the main executable of a bundle from Info.plist falling back to the only file in MacOS
+func bundleExecutable(contents string) (string, error) {
+	if data, err := os.ReadFile(filepath.Join(contents, "Info.plist")); err == nil {
+		if decoded, err := plistDecode(data); err == nil {
+			if info, ok := decoded.(map[string]interface{}); ok {
+				if name, ok := info["CFBundleExecutable"].(string); ok {
+					return "MacOS/" + name, nil
+				}
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(contents, "MacOS"))
+	if err != nil || len(entries) != 1 {
+		return "", errors.New("unable to determine the bundle executable")
+	}
+	return "MacOS/" + entries[0].Name(), nil
+}
+
+// Checks a signed Mach-O binary or an application bundle against its code signature
+func checkCodeSign(path string) (CodeSignReport, error) {
+	report := CodeSignReport{Path: path}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return report, err
+	}
+
+	if !fi.IsDir() {
+		cdhashes, _, valid, err := checkMachO(path)
+		if err != nil {
+			return report, err
+		}
+		report.CDHash = cdhashes
+		report.Items = append(report.Items, CodeSignStatus{Path: filepath.Base(path), Status: validStatus(valid)})
+		report.Invalid = !valid
+		return report, nil
+	}
+
+	contents := filepath.Join(path, "Contents")
+	executable, err := bundleExecutable(contents)
+	if err != nil {
+		return report, err
+	}
+
+	cdhashes, cds, valid, err := checkMachO(filepath.Join(contents, filepath.FromSlash(executable)))
+	if err != nil {
+		return report, err
+	}
+	report.CDHash = cdhashes
+	report.Items = append(report.Items,
+		CodeSignStatus{Path: executable, Status: validStatus(valid)},
+		CodeSignStatus{Path: "Info.plist", Status: checkSpecialSlot(filepath.Join(contents, "Info.plist"), cds, csSpecialInfoPlist)},
+	)
+
+	codeResourcesPath := filepath.Join(contents, "_CodeSignature", "CodeResources")
+	report.Items = append(report.Items, CodeSignStatus{Path: "_CodeSignature/CodeResources", Status: checkSpecialSlot(codeResourcesPath, cds, csSpecialResourceDir)})
+
+	if codeResources, err := os.ReadFile(codeResourcesPath); err == nil {
+		items, err := checkResources(contents, codeResources, executable)
+		if err != nil {
+			return report, err
+		}
+		report.Items = append(report.Items, items...)
+	}
+
+	for _, item := range report.Items {
+		if item.Status != "OK" && item.Status != "UNVERIFIED" {
+			report.Invalid = true
+		}
+	}
+	return report, nil
+}
+
+func validStatus(valid bool) string {
+	if valid {
+		return "OK"
+	}
+	return "MODIFIED"
+}
+
+// Checks each path against its code signature printing what no longer matches,
+// returning the exit code. The CMS signature itself is not validated.
+func processCodeSign(paths []string) int {
+	exitCode := 0
+	reports := []CodeSignReport{}
+
+	for _, p := range paths {
+		report, err := checkCodeSign(filepath.Clean(p))
+		if err != nil {
+			printError(fmt.Sprintf("unable to check code signature of %s: %s", p, err.Error()))
+			exitCode = 1
+			continue
+		}
+		if report.Invalid {
+			exitCode = 1
+		}
+		reports = append(reports, report)
+	}
+
+	if strings.ToLower(Format) == "json" {
+		out, _ := json.Marshal(reports)
+		fmt.Println(string(out))
+		return exitCode
+	}
+
+	for _, report := range reports {
+		fmt.Println(report.Path)
+		for _, c := range report.CDHash {
+			fmt.Printf("  cdhash %s\n", c)
+		}
+		for _, item := range report.Items {
+			// Only show what is wrong unless asked for everything
+			if item.Status != "OK" || Verbose {
+				fmt.Printf("  %s: %s\n", item.Path, item.Status)
+			}
+		}
+		if report.Invalid {
+			fmt.Println("  seal: INVALID")
+		} else {
+			fmt.Println("  seal: OK")
+		}
+	}
+	return exitCode
+}
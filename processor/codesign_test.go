@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Builds a thin 64 bit Mach-O with an embedded signature holding a single SHA-256
+// code directory over 4096 byte pages
+func makeSignedMachO(infoPlist []byte, codeResources []byte) []byte {
+	const codeLimit = 5008
+	file := make([]byte, codeLimit)
+	le := binary.LittleEndian
+	le.PutUint32(file[0:], 0xfeedfacf)
+	le.PutUint32(file[4:], 0x01000007)
+	le.PutUint32(file[8:], 3)
+	le.PutUint32(file[12:], 2)
+	le.PutUint32(file[16:], 1)
+	le.PutUint32(file[20:], 16)
+	for i := 48; i < codeLimit; i++ {
+		file[i] = byte(i)
+	}
+
+	hashSize := 32
+	nSpecial := 3
+	nCode := 2
+	ident := []byte("test\x00")
+	hashOffset := 48 + len(ident) + nSpecial*hashSize
+	cd := make([]byte, hashOffset+nCode*hashSize)
+	be := binary.BigEndian
+	be.PutUint32(cd[0:], csMagicCodeDirectory)
+	be.PutUint32(cd[4:], uint32(len(cd)))
+	be.PutUint32(cd[8:], 0x20100)
+	be.PutUint32(cd[16:], uint32(hashOffset))
+	be.PutUint32(cd[20:], 48)
+	be.PutUint32(cd[24:], uint32(nSpecial))
+	be.PutUint32(cd[28:], uint32(nCode))
+	be.PutUint32(cd[32:], codeLimit)
+	cd[36] = byte(hashSize)
+	cd[37] = csHashTypeSHA256
+	cd[39] = 12
+	copy(cd[48:], ident)
+
+	special := map[int][]byte{csSpecialInfoPlist: infoPlist, csSpecialResourceDir: codeResources}
+	for slot, content := range special {
+		sum := sha256.Sum256(content)
+		copy(cd[hashOffset-slot*hashSize:], sum[:])
+	}
+
+	superBlob := make([]byte, 20)
+	be.PutUint32(superBlob[0:], csMagicEmbeddedSignature)
+	be.PutUint32(superBlob[4:], uint32(20+len(cd)))
+	be.PutUint32(superBlob[8:], 1)
+	be.PutUint32(superBlob[12:], csSlotCodeDirectory)
+	be.PutUint32(superBlob[16:], 20)
+
+	le.PutUint32(file[32:], loadCmdCodeSignature)
+	le.PutUint32(file[36:], 16)
+	le.PutUint32(file[40:], codeLimit)
+	le.PutUint32(file[44:], uint32(len(superBlob)+len(cd)))
+
+	// Page hashes cover everything before the signature including the load commands
+	for i := 0; i < nCode; i++ {
+		end := (i + 1) * 4096
+		if end > codeLimit {
+			end = codeLimit
+		}
+		sum := sha256.Sum256(file[i*4096 : end])
+		copy(cd[hashOffset+i*hashSize:], sum[:])
+	}
+
+	return append(append(file, superBlob...), cd...)
+}
+
+func makeTestBundle(t *testing.T) string {
+	app := filepath.Join(t.TempDir(), "Test.app")
+	contents := filepath.Join(app, "Contents")
+	_ = os.MkdirAll(filepath.Join(contents, "MacOS"), 0700)
+	_ = os.MkdirAll(filepath.Join(contents, "Resources"), 0700)
+	_ = os.MkdirAll(filepath.Join(contents, "_CodeSignature"), 0700)
+
+	infoPlist := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>CFBundleExecutable</key><string>Test</string></dict></plist>`)
+	resource := []byte("resource")
+	sum := sha256.Sum256(resource)
+	codeResources := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>files2</key><dict><key>Resources/a.txt</key><dict><key>hash2</key><data>` + base64.StdEncoding.EncodeToString(sum[:]) + `</data></dict></dict>
+<key>rules2</key><dict><key>^.*</key><true/><key>^(.*/)?\.DS_Store$</key><dict><key>omit</key><true/><key>weight</key><integer>2000</integer></dict></dict>
+</dict></plist>`)
+
+	_ = os.WriteFile(filepath.Join(contents, "Info.plist"), infoPlist, 0600)
+	_ = os.WriteFile(filepath.Join(contents, "Resources", "a.txt"), resource, 0600)
+	_ = os.WriteFile(filepath.Join(contents, "_CodeSignature", "CodeResources"), codeResources, 0600)
+	_ = os.WriteFile(filepath.Join(contents, "MacOS", "Test"), makeSignedMachO(infoPlist, codeResources), 0700)
+	return app
+}
+
+func statusOf(report CodeSignReport, path string) string {
+	for _, item := range report.Items {
+		if item.Path == path {
+			return item.Status
+		}
+	}
+	return ""
+}
+
+func TestCheckCodeSignBundle(t *testing.T) {
+	app := makeTestBundle(t)
+	contents := filepath.Join(app, "Contents")
+
+	report, err := checkCodeSign(app)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if report.Invalid || len(report.CDHash) != 1 {
+		t.Errorf("Expected a valid seal got %+v", report)
+	}
+
+	_ = os.WriteFile(filepath.Join(contents, "Resources", "a.txt"), []byte("tampered"), 0600)
+	_ = os.WriteFile(filepath.Join(contents, "Resources", "new"), []byte("new"), 0600)
+	_ = os.WriteFile(filepath.Join(contents, "Resources", ".DS_Store"), []byte("omitted"), 0600)
+
+	report, _ = checkCodeSign(app)
+	if !report.Invalid || statusOf(report, "Resources/a.txt") != "MODIFIED" || statusOf(report, "Resources/new") != "ADDED" {
+		t.Errorf("Expected modified and added resources got %+v", report.Items)
+	}
+	if statusOf(report, "Resources/.DS_Store") != "" {
+		t.Error("Expected .DS_Store to be omitted by the rules")
+	}
+}
+
+func TestCheckCodeSignModifiedExecutable(t *testing.T) {
+	app := makeTestBundle(t)
+	executable := filepath.Join(app, "Contents", "MacOS", "Test")
+
+	content, _ := os.ReadFile(executable)
+	content[4100] ^= 0x01
+	_ = os.WriteFile(executable, content, 0700)
+
+	report, err := checkCodeSign(app)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if statusOf(report, "MacOS/Test") != "MODIFIED" || !report.Invalid {
+		t.Errorf("Expected modified executable got %+v", report.Items)
+	}
+}
+
+func TestParseCodeDirectoryInvalid(t *testing.T) {
+	signed := makeSignedMachO([]byte("info"), []byte("resources"))
+	cd := signed[5008+20:]
+
+	corrupt := map[string]func([]byte){
+		"hash size":      func(b []byte) { b[36] = 64 },
+		"zero hash size": func(b []byte) { b[36] = 0 },
+		"hash type":      func(b []byte) { b[37] = 9 },
+		"page size":      func(b []byte) { b[39] = 64 },
+		"small page":     func(b []byte) { b[39] = 1 },
+		"code slots":     func(b []byte) { binary.BigEndian.PutUint32(b[28:], 0xffffffff) },
+	}
+	for name, f := range corrupt {
+		blob := append([]byte{}, cd...)
+		f(blob)
+		if _, err := parseCodeDirectory(blob); err == nil {
+			t.Errorf("Expected error for invalid %s", name)
+		}
+	}
+
+	if _, err := parseCodeDirectory(cd); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+}
+
+func TestCheckCodeSignSignatureOutOfRange(t *testing.T) {
+	signed := makeSignedMachO([]byte("info"), []byte("resources"))
+	binary.LittleEndian.PutUint32(signed[44:], 0xffffffff)
+	path := filepath.Join(t.TempDir(), "binary")
+	_ = os.WriteFile(path, signed, 0700)
+
+	if _, err := checkCodeSign(path); err == nil {
+		t.Error("Expected error for a signature larger than the file")
+	}
+}
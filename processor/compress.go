@@ -0,0 +1,155 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Check the Compress option is one compressWriter understands
+func validateCompress() error {
+	switch strings.ToLower(Compress) {
+	case "", "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("invalid compression %s expected gzip or zstd", Compress)
+	}
+	if Compress != "" && FileOutput == "" && PerRootOutput == "" {
+		return fmt.Errorf("--compress requires --output or --per-root-output")
+	}
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Wraps the output file so results are compressed as they are written, closing it
+// finishes the stream without closing w. Streams have no size limit so manifests well
+// over 4 GiB are fine.
+func compressWriter(w io.Writer) (io.WriteCloser, error) {
+	switch strings.ToLower(Compress) {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// Decompresses a manifest written with --compress, anything else is returned as is
+func decompressManifest(data []byte) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(data, zstdMagic):
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return data, nil
+	}
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress manifest: %w", err)
+	}
+	return out, nil
+}
+
+// Reads a manifest decompressing it if it was written with --compress
+func readManifest(path string) ([]byte, error) {
+	_, data, err := readManifestRaw(path)
+	return data, err
+}
+
+// Reads a manifest returning the bytes on disk, which a detached signature covers, along
+// with them decompressed. Both come from the one read so they cannot differ.
+func readManifestRaw(path string) ([]byte, []byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkEncryptedManifest(raw); err != nil {
+		return nil, nil, err
+	}
+	data, err := decompressManifest(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, data, nil
+}
+
+type manifestStream struct {
+	io.Reader
+	closers []func() error
+}
+
+func (m *manifestStream) Close() error {
+	var err error
+	for _, c := range m.closers {
+		if e := c(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Opens a manifest for streaming so sources too large to hold in memory can be read,
+// decompressing it like readManifest
+func openManifestStream(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stream := &manifestStream{closers: []func() error{file.Close}}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(ageMagic))
+	if err := checkEncryptedManifest(magic); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		stream.Reader = zr
+		stream.closers = append([]func() error{zr.Close}, stream.closers...)
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		stream.Reader = zr
+		stream.closers = append([]func() error{func() error { zr.Close(); return nil }}, stream.closers...)
+	default:
+		stream.Reader = buffered
+	}
+	return stream, nil
+}
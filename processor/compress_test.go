@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	previous := Compress
+	defer func() { Compress = previous }()
+
+	content := []byte("d41d8cd98f00b204e9800998ecf8427e  empty\n")
+	for _, c := range []string{"gzip", "zstd"} {
+		Compress = c
+		var buf bytes.Buffer
+		w, err := compressWriter(&buf)
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+		_, _ = w.Write(content)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+
+		if bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("Expected %s output to be compressed", c)
+		}
+		out, err := decompressManifest(buf.Bytes())
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+		if !bytes.Equal(out, content) {
+			t.Errorf("Expected %s round trip to give %q got %q", c, content, out)
+		}
+	}
+}
+
+func TestDecompressManifestPassesThroughPlain(t *testing.T) {
+	content := []byte(`[{"File":"a"}]`)
+	out, err := decompressManifest(content)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if !bytes.Equal(out, content) {
+		t.Errorf("Expected plain manifest unchanged got %q", out)
+	}
+}
+
+func TestDecompressManifestTruncated(t *testing.T) {
+	_, err := decompressManifest([]byte{0x1f, 0x8b, 0x08})
+	if err == nil {
+		t.Error("Expected error for truncated gzip manifest")
+	}
+}
+
+func TestValidateCompress(t *testing.T) {
+	previousCompress, previousOutput := Compress, FileOutput
+	defer func() { Compress, FileOutput = previousCompress, previousOutput }()
+
+	Compress, FileOutput = "bzip2", "out.txt"
+	if validateCompress() == nil {
+		t.Error("Expected error for unknown compression")
+	}
+	Compress, FileOutput = "zstd", ""
+	if validateCompress() == nil {
+		t.Error("Expected error when not writing to a file")
+	}
+	Compress, FileOutput = "gzip", "out.txt.gz"
+	if err := validateCompress(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+}
+
+func TestOutputStreamCompressed(t *testing.T) {
+	previousOutput, previousCompress, previousHash, previousFormat := FileOutput, Compress, Hash, Format
+	defer func() {
+		FileOutput, Compress, Hash, Format = previousOutput, previousCompress, previousHash, previousFormat
+	}()
+
+	FileOutput = filepath.Join(t.TempDir(), "out.txt.zst")
+	Compress = "zstd"
+	Format = "sum"
+	Hash = []string{HashNames.MD5}
+
+	stream, err := openOutputStream()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	_, _ = io.WriteString(outputWriter, "5d41402abc4b2a76b9719d911017c592  a\n")
+	if err := stream.close(""); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	raw, _ := os.ReadFile(FileOutput)
+	if !bytes.HasPrefix(raw, zstdMagic) {
+		t.Errorf("Expected the output file to be zstd compressed")
+	}
+	results, err := loadManifestResults(FileOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(results) != 1 || results[0].File != "a" || results[0].MD5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Expected the compressed manifest to read back got %v", results)
+	}
+}
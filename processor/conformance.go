@@ -0,0 +1,471 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A file of the built-in corpus every conformance case hashes
+type conformanceFile struct {
+	path    string
+	content string
+}
+
+// Small enough to check by hand yet covering empty files, names with spaces, unicode and
+// directories. The digests of abc and the quick brown fox are the published test vectors.
+var conformanceCorpus = []conformanceFile{
+	{path: "abc", content: "abc"},
+	{path: "café.txt", content: "unicode\n"},
+	{path: "dir/quick.txt", content: "The quick brown fox jumps over the lazy dog"},
+	{path: "empty", content: ""},
+	{path: "hello world.txt", content: "hello world\n"},
+}
+
+// A format and hash selection with the exact output the tools reading it expect
+type conformanceCase struct {
+	Name     string
+	Consumer string
+	Format   string
+	Hash     []string
+	// Readable is set for formats --check reads, which are parsed back as well
+	Readable bool
+	Golden   string
+}
+
+// Where the output of a case is written, formats behave differently when streaming to a
+// file or compressing so each is checked
+var conformanceSinks = []string{"buffered", "file", "gzip", "zstd"}
+
+// The hashdeep header records where and how hashit was run, which is replaced with these
+// so the output can be compared
+const (
+	conformanceInvoked = "## Invoked from: /conformance"
+	conformanceCommand = "## $ hashit conformance"
+)
+
+// Output checked against md5sum, sha256sum, b2sum and friends, and hashdeep. The sum
+// format separates files with a blank line which the coreutils tools skip when checking.
+var conformanceCases = []conformanceCase{
+	{
+		Name: "md5sum", Consumer: "md5sum -c", Format: "sum", Hash: []string{"md5"}, Readable: true,
+		Golden: `900150983cd24fb0d6963f7d28e17f72  abc
+
+9d53135f39f58186a35830ea0f58a661  café.txt
+
+9e107d9d372bb6826bd81d3542a419d6  dir/quick.txt
+
+d41d8cd98f00b204e9800998ecf8427e  empty
+
+6f5902ac237024bdd0c176cb93063dc4  hello world.txt
+`,
+	},
+	{
+		Name: "sha1sum", Consumer: "sha1sum -c", Format: "sum", Hash: []string{"sha1"}, Readable: true,
+		Golden: `a9993e364706816aba3e25717850c26c9cd0d89d  abc
+
+449e7d699f5b9f65514e26ed8f66f845f852f85b  café.txt
+
+2fd4e1c67a2d28fced849ee1bb76e7391b93eb12  dir/quick.txt
+
+da39a3ee5e6b4b0d3255bfef95601890afd80709  empty
+
+22596363b3de40b06f981fb85d82312e8c0ed511  hello world.txt
+`,
+	},
+	{
+		Name: "sha224sum", Consumer: "sha224sum -c", Format: "sum", Hash: []string{"sha224"}, Readable: true,
+		Golden: `23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7  abc
+
+eba97b20bd7c785cc04e954f0516b687e991278bd7192fd07ede79b4  café.txt
+
+730e109bd7a8a32b1cb9d9a09aa2325d2430587ddbc0c38bad911525  dir/quick.txt
+
+d14a028c2a3a2bc9476102bb288234c415a2b01f828ea62ac5b3e42f  empty
+
+95041dd60ab08c0bf5636d50be85fe9790300f39eb84602858a9b430  hello world.txt
+`,
+	},
+	{
+		Name: "sha256sum", Consumer: "sha256sum -c", Format: "sum", Hash: []string{"sha256"}, Readable: true,
+		Golden: `ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  abc
+
+ebc45fabefbabdd06424b3c476b11e93fec784069ff10844e7383d59f491f8cb  café.txt
+
+d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592  dir/quick.txt
+
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty
+
+a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447  hello world.txt
+`,
+	},
+	{
+		Name: "sha384sum", Consumer: "sha384sum -c", Format: "sum", Hash: []string{"sha384"}, Readable: true,
+		Golden: `cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7  abc
+
+181b08a0ce500c02bf698757049b3c108de71f9896516ac7434feae1929879b5fac7e4f6cda40cd93bb175953d103962  café.txt
+
+ca737f1014a48f4c0b6dd43cb177b0afd9e5169367544c494011e3317dbf9a509cb1e5dc1e85a941bbee3d7f2afbc9b1  dir/quick.txt
+
+38b060a751ac96384cd9327eb1b1e36a21fdb71114be07434c0cc7bf63f6e1da274edebfe76f65fbd51ad2f14898b95b  empty
+
+6b3b69ff0a404f28d75e98a066d3fc64fffd9940870cc68bece28545b9a75086b343d7a1366838083e4b8f3ca6fd3c80  hello world.txt
+`,
+	},
+	{
+		Name: "sha512sum", Consumer: "sha512sum -c", Format: "sum", Hash: []string{"sha512"}, Readable: true,
+		Golden: `ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f  abc
+
+3bc26de7798ad721b6e9a2be78615eae74ebccaea190545cdb04d1c30fa70414a1ebb862ee62e637c5c4e1998f376332775412723ebe4cfec9ddb16532132329  café.txt
+
+07e547d9586f6a73f73fbac0435ed76951218fb7d0c8d788a309d785436bbb642e93a252a954f23912547d1e8a3b5ed6e1bfd7097821233fa0538f3db854fee6  dir/quick.txt
+
+cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e  empty
+
+db3974a97f2407b7cae1ae637c0030687a11913274d578492558e39c16c017de84eacdc8c62fe34ee4e12b4b1428817f09b6a2760c3f8a664ceae94d2434a593  hello world.txt
+`,
+	},
+	{
+		Name: "b2sum", Consumer: "b2sum -c", Format: "sum", Hash: []string{"blake2b512"}, Readable: true,
+		Golden: `ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923  abc
+
+f4edb654256448c0a3471012383d913d982c8c8653ad20c8087480b2ac49fb63fbe5b8ed847ec5a5f0e140d476ef602d84bbe92fb13afd99bc1e0e63d1844de9  café.txt
+
+a8add4bdddfd93e4877d2746e62817b116364a1fa7bc148d95090bc7333b3673f82401cf7aa2e4cb1ecd90296e3f14cb5413f8ed77be73045b13914cdcd6a918  dir/quick.txt
+
+786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce  empty
+
+fec91c70284c72d0d4e3684788a90de9338a5b2f47f01fedbe203cafd68708718ae5672d10eca804a8121904047d40d1d6cf11e7a76419357a9469af41f22d01  hello world.txt
+`,
+	},
+	{
+		Name: "b3sum", Consumer: "b3sum -c", Format: "sum", Hash: []string{"blake3"}, Readable: true,
+		Golden: `6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85  abc
+
+015e7659a02245cbd6c186360cdde325ba5d00d020f4ceda87c915d865b66c81  café.txt
+
+2f1514181aadccd913abd94cfa592701a5686ab23f8df1dff1b74710febc6d4a  dir/quick.txt
+
+af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262  empty
+
+dc5a4edb8240b018124052c330270696f96771a63b45250a5c17d3000e823355  hello world.txt
+`,
+	},
+	{
+		Name: "hashdeep-md5", Consumer: "hashdeep -a -k", Format: "hashdeep", Hash: []string{"md5"}, Readable: true,
+		Golden: `%%%% HASHDEEP-1.0
+%%%% size,md5,filename
+## Invoked from: /conformance
+## $ hashit conformance
+##
+3,900150983cd24fb0d6963f7d28e17f72,abc
+8,9d53135f39f58186a35830ea0f58a661,café.txt
+43,9e107d9d372bb6826bd81d3542a419d6,dir/quick.txt
+0,d41d8cd98f00b204e9800998ecf8427e,empty
+12,6f5902ac237024bdd0c176cb93063dc4,hello world.txt
+`,
+	},
+	{
+		Name: "hashdeep", Consumer: "hashdeep -a -k", Format: "hashdeep", Hash: []string{"md5", "sha256"}, Readable: true,
+		Golden: `%%%% HASHDEEP-1.0
+%%%% size,md5,sha256,filename
+## Invoked from: /conformance
+## $ hashit conformance
+##
+3,900150983cd24fb0d6963f7d28e17f72,ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad,abc
+8,9d53135f39f58186a35830ea0f58a661,ebc45fabefbabdd06424b3c476b11e93fec784069ff10844e7383d59f491f8cb,café.txt
+43,9e107d9d372bb6826bd81d3542a419d6,d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592,dir/quick.txt
+0,d41d8cd98f00b204e9800998ecf8427e,e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855,empty
+12,6f5902ac237024bdd0c176cb93063dc4,a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447,hello world.txt
+`,
+	},
+	{
+		Name: "json", Consumer: "hashit --check", Format: "json", Hash: []string{"md5", "sha256"}, Readable: true,
+		Golden: `[{"File":"abc","CRC32":"","XxHash64":"","MD4":"","MD5":"900150983cd24fb0d6963f7d28e17f72","SHA1":"","SHA256":"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad","SHA512":"","Blake2b256":"","Blake2b512":"","Blake3":"","Sha3224":"","Sha3256":"","Sha3384":"","Sha3512":"","Sha224":"","Sha384":"","Sha512256":"","Ripemd160":"","Whirlpool":"","SM3":"","Streebog256":"","Streebog512":"","Entropy":"","Ssdeep":"","TLSH":"","Bytes":3,"MTime":null},{"File":"café.txt","CRC32":"","XxHash64":"","MD4":"","MD5":"9d53135f39f58186a35830ea0f58a661","SHA1":"","SHA256":"ebc45fabefbabdd06424b3c476b11e93fec784069ff10844e7383d59f491f8cb","SHA512":"","Blake2b256":"","Blake2b512":"","Blake3":"","Sha3224":"","Sha3256":"","Sha3384":"","Sha3512":"","Sha224":"","Sha384":"","Sha512256":"","Ripemd160":"","Whirlpool":"","SM3":"","Streebog256":"","Streebog512":"","Entropy":"","Ssdeep":"","TLSH":"","Bytes":8,"MTime":null},{"File":"dir/quick.txt","CRC32":"","XxHash64":"","MD4":"","MD5":"9e107d9d372bb6826bd81d3542a419d6","SHA1":"","SHA256":"d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592","SHA512":"","Blake2b256":"","Blake2b512":"","Blake3":"","Sha3224":"","Sha3256":"","Sha3384":"","Sha3512":"","Sha224":"","Sha384":"","Sha512256":"","Ripemd160":"","Whirlpool":"","SM3":"","Streebog256":"","Streebog512":"","Entropy":"","Ssdeep":"","TLSH":"","Bytes":43,"MTime":null},{"File":"empty","CRC32":"","XxHash64":"","MD4":"","MD5":"d41d8cd98f00b204e9800998ecf8427e","SHA1":"","SHA256":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855","SHA512":"","Blake2b256":"","Blake2b512":"","Blake3":"","Sha3224":"","Sha3256":"","Sha3384":"","Sha3512":"","Sha224":"","Sha384":"","Sha512256":"","Ripemd160":"","Whirlpool":"","SM3":"","Streebog256":"","Streebog512":"","Entropy":"","Ssdeep":"","TLSH":"","Bytes":0,"MTime":null},{"File":"hello world.txt","CRC32":"","XxHash64":"","MD4":"","MD5":"6f5902ac237024bdd0c176cb93063dc4","SHA1":"","SHA256":"a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447","SHA512":"","Blake2b256":"","Blake2b512":"","Blake3":"","Sha3224":"","Sha3256":"","Sha3384":"","Sha3512":"","Sha224":"","Sha384":"","Sha512256":"","Ripemd160":"","Whirlpool":"","SM3":"","Streebog256":"","Streebog512":"","Entropy":"","Ssdeep":"","TLSH":"","Bytes":12,"MTime":null}]`,
+	},
+	{
+		Name: "hashonly", Consumer: "one digest per line", Format: "hashonly", Hash: []string{"sha256"},
+		Golden: `ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+ebc45fabefbabdd06424b3c476b11e93fec784069ff10844e7383d59f491f8cb
+d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447
+`,
+	},
+	{
+		Name: "sri", Consumer: "subresource integrity", Format: "sri", Hash: []string{"sha384"},
+		Golden: `sha384-ywB1P0WjXou1oD1pmsZQBycsMqsO3tFjGotgWkP/W+2AhgcroefMI1i67KE0yCWn  abc
+sha384-GBsIoM5QDAK/aYdXBJs8EI3nH5iWUWrHQ0/q4ZKYebX6x+T2zaQM2TuxdZU9EDli  café.txt
+sha384-ynN/EBSkj0wLbdQ8sXewr9nlFpNnVExJQBHjMX2/mlCcseXcHoWpQbvuPX8q+8mx  dir/quick.txt
+sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb  empty
+sha384-aztp/wpATyjXXpigZtP8ZP/9mUCHDMaL7OKFRbmnUIazQ9ehNmg4CD5Ljzym/TyA  hello world.txt
+`,
+	},
+	{
+		Name: "sri-json", Consumer: "subresource integrity", Format: "sri-json", Hash: []string{"sha384"},
+		Golden: `{"abc":"sha384-ywB1P0WjXou1oD1pmsZQBycsMqsO3tFjGotgWkP/W+2AhgcroefMI1i67KE0yCWn","café.txt":"sha384-GBsIoM5QDAK/aYdXBJs8EI3nH5iWUWrHQ0/q4ZKYebX6x+T2zaQM2TuxdZU9EDli","dir/quick.txt":"sha384-ynN/EBSkj0wLbdQ8sXewr9nlFpNnVExJQBHjMX2/mlCcseXcHoWpQbvuPX8q+8mx","empty":"sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb","hello world.txt":"sha384-aztp/wpATyjXXpigZtP8ZP/9mUCHDMaL7OKFRbmnUIazQ9ehNmg4CD5Ljzym/TyA"}`,
+	},
+	{
+		Name: "text", Consumer: "hashit text", Format: "text", Hash: []string{"md5", "sha256"},
+		Golden: `abc (3 bytes)
+        MD5 900150983cd24fb0d6963f7d28e17f72
+     SHA256 ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad
+
+café.txt (8 bytes)
+        MD5 9d53135f39f58186a35830ea0f58a661
+     SHA256 ebc45fabefbabdd06424b3c476b11e93fec784069ff10844e7383d59f491f8cb
+
+dir/quick.txt (43 bytes)
+        MD5 9e107d9d372bb6826bd81d3542a419d6
+     SHA256 d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592
+
+empty (0 bytes)
+        MD5 d41d8cd98f00b204e9800998ecf8427e
+     SHA256 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
+
+hello world.txt (12 bytes)
+        MD5 6f5902ac237024bdd0c176cb93063dc4
+     SHA256 a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447
+`,
+	},
+}
+
+// ConformanceResult is whether a case gave exactly its golden output through one sink
+type ConformanceResult struct {
+	Case     string `json:"case"`
+	Consumer string `json:"consumer"`
+	Sink     string `json:"sink"`
+	Passed   bool   `json:"passed"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Hashes the corpus with the hashes of the case in corpus order
+func conformanceResults() ([]Result, error) {
+	results := []Result{}
+	for _, f := range conformanceCorpus {
+		content := []byte(f.content)
+		res, err := processReadFile(f.path, &content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to hash %s: %w", f.path, err)
+		}
+		res.File = f.path
+		res.Bytes = int64(len(content))
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// Replaces the lines of hashdeep output which depend on where hashit was run
+func normalizeConformance(out string) string {
+	lines := strings.SplitAfter(out, "\n")
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "## Invoked from: "):
+			lines[i] = conformanceInvoked + "\n"
+		case strings.HasPrefix(l, "## $ "):
+			lines[i] = conformanceCommand + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// Describes the first line which differs so a failure can be understood without a diff
+func conformanceDifference(expected string, actual string) string {
+	e, a := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for i := 0; i < len(e) || i < len(a); i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if el != al || i >= len(e) || i >= len(a) {
+			return fmt.Sprintf("line %d expected %q got %q", i+1, el, al)
+		}
+	}
+	return "output differs"
+}
+
+// Formats the results of one case through the sink returning what was written
+func renderConformance(c conformanceCase, sink string, results []Result, dir string) (string, error) {
+	Format, Hash = c.Format, c.Hash
+	input := make(chan Result, len(results))
+	for _, res := range results {
+		input <- res
+	}
+	close(input)
+
+	if sink == "buffered" {
+		FileOutput, Compress = "", ""
+		out, _ := fileSummarize(input)
+		return out, nil
+	}
+
+	FileOutput = filepath.Join(dir, c.Name+"."+sink)
+	Compress = ""
+	if sink != "file" {
+		Compress = sink
+	}
+	stream, err := openOutputStream()
+	if err != nil {
+		return "", err
+	}
+	rest, _ := fileSummarize(input)
+	if err := stream.close(rest); err != nil {
+		return "", err
+	}
+	out, err := readManifest(FileOutput)
+	return string(out), err
+}
+
+// Parses output --check reads confirming every corpus file comes back with its digests
+func readBackConformance(out string, results []Result) error {
+	entries, err := parseManifest([]byte(out))
+	if err != nil {
+		return err
+	}
+	if len(entries) != len(results) {
+		return fmt.Errorf("read back %d files expected %d", len(entries), len(results))
+	}
+	for i, e := range entries {
+		if e.File != results[i].File || !digestsMatch(calculatedHashes(results[i]), e.Digests) {
+			return fmt.Errorf("read back %s did not match %s", e.File, results[i].File)
+		}
+	}
+	return nil
+}
+
+// Runs every case through every sink. Options the formats read are set for each case and
+// put back afterwards.
+func runConformance() ([]ConformanceResult, error) {
+	previousFormat, previousHash, previousOutput, previousCompress := Format, Hash, FileOutput, Compress
+	previousStream, previousTee, previousPerRoot := NoStream, TeeOutput, PerRootOutput
+	previousMTime, previousType, previousProvenance, previousList := MTime, DetectType, Provenance, ListOnly
+	previousMatch, previousNegative, previousContent := Match, MatchNegative, NoContent
+	previousScanID, previousLabels, previousRecipients := scanID, labels, encryptRecipients
+	defer func() {
+		Format, Hash, FileOutput, Compress = previousFormat, previousHash, previousOutput, previousCompress
+		NoStream, TeeOutput, PerRootOutput = previousStream, previousTee, previousPerRoot
+		MTime, DetectType, Provenance, ListOnly = previousMTime, previousType, previousProvenance, previousList
+		Match, MatchNegative, NoContent = previousMatch, previousNegative, previousContent
+		scanID, labels, encryptRecipients = previousScanID, previousLabels, previousRecipients
+	}()
+	NoStream, TeeOutput, PerRootOutput = true, false, ""
+	MTime, DetectType, Provenance, ListOnly = false, false, false, false
+	Match, MatchNegative, NoContent = false, false, false
+	scanID, labels, encryptRecipients = "", map[string]string{}, nil
+
+	dir, err := os.MkdirTemp("", "hashit-conformance")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	report := []ConformanceResult{}
+	for _, c := range conformanceCases {
+		Hash = c.Hash
+		results, err := conformanceResults()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sink := range conformanceSinks {
+			r := ConformanceResult{Case: c.Name, Consumer: c.Consumer, Sink: sink}
+			out, err := renderConformance(c, sink, results, dir)
+			out = normalizeConformance(out)
+			switch {
+			case err != nil:
+				r.Reason = err.Error()
+			case out != c.Golden:
+				r.Reason = conformanceDifference(c.Golden, out)
+			case c.Readable:
+				if err := readBackConformance(out, results); err != nil {
+					r.Reason = err.Error()
+				}
+			}
+			r.Passed = r.Reason == ""
+			report = append(report, r)
+		}
+	}
+	return report, nil
+}
+
+// Writes the corpus and every golden output into dir so they can be fed to the real tools
+func writeConformance(dir string) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) != 0 {
+		return fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, f := range conformanceCorpus {
+		path := filepath.Join(dir, "corpus", filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			return err
+		}
+	}
+	for _, c := range conformanceCases {
+		if err := os.WriteFile(filepath.Join(dir, c.Name+".txt"), []byte(c.Golden), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Conformance runs the built-in corpus through the formats and output sinks and compares
+// the output byte for byte with what the tools reading each format expect, exiting 1 when
+// any differ. With writeDir the corpus and expected outputs are also written out so they
+// can be checked with those tools directly.
+func Conformance(asJSON bool, writeDir string) {
+	report, err := runConformance()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, r := range report {
+		if !r.Passed {
+			failed++
+		}
+	}
+
+	if asJSON {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+	} else {
+		for _, r := range report {
+			status := "PASS"
+			if !r.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("%s %s (%s) via %s", status, r.Case, r.Consumer, r.Sink)
+			if r.Reason != "" {
+				fmt.Printf(": %s", r.Reason)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("%d of %d passed\n", len(report)-failed, len(report))
+	}
+
+	if writeDir != "" {
+		if err := writeConformance(writeDir); err != nil {
+			printError(fmt.Sprintf("unable to write conformance files to %s: %s", writeDir, err.Error()))
+			os.Exit(1)
+		}
+		if !asJSON {
+			fmt.Printf("corpus and expected outputs written to %s, run the tools from %s\n", writeDir, filepath.Join(writeDir, "corpus"))
+		}
+	}
+
+	if failed != 0 {
+		os.Exit(1)
+	}
+}
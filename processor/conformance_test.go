@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	report, err := runConformance()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(report) != len(conformanceCases)*len(conformanceSinks) {
+		t.Errorf("Expected every case through every sink got %d results", len(report))
+	}
+	for _, r := range report {
+		if !r.Passed {
+			t.Errorf("Expected %s via %s to match its golden output: %s", r.Case, r.Sink, r.Reason)
+		}
+	}
+}
+
+func TestConformanceRestoresOptions(t *testing.T) {
+	previousFormat, previousHash := Format, Hash
+	defer func() { Format, Hash = previousFormat, previousHash }()
+	Format, Hash = "text", []string{"sha1"}
+
+	if _, err := runConformance(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if Format != "text" || len(Hash) != 1 || Hash[0] != "sha1" {
+		t.Errorf("Expected options to be put back got %s %v", Format, Hash)
+	}
+}
+
+func TestConformanceDifference(t *testing.T) {
+	if d := conformanceDifference("a\nb\n", "a\nc\n"); !strings.Contains(d, "line 2") {
+		t.Errorf("Expected the second line to be reported got %s", d)
+	}
+	if d := conformanceDifference("a\n", "a\nextra\n"); !strings.Contains(d, "extra") {
+		t.Errorf("Expected the extra line to be reported got %s", d)
+	}
+}
+
+func TestNormalizeConformance(t *testing.T) {
+	out := normalizeConformance("%%%% HASHDEEP-1.0\n## Invoked from: /home/user\n## $ hashit -f hashdeep .\n##\n")
+	if !strings.Contains(out, conformanceInvoked+"\n") || !strings.Contains(out, conformanceCommand+"\n") {
+		t.Errorf("Expected the run context to be replaced got %q", out)
+	}
+}
+
+func TestWriteConformance(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := writeConformance(dir); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "corpus", "dir", "quick.txt"))
+	if err != nil || string(content) != "The quick brown fox jumps over the lazy dog" {
+		t.Errorf("Expected the corpus to be written got %q %v", content, err)
+	}
+	golden, err := os.ReadFile(filepath.Join(dir, "md5sum.txt"))
+	if err != nil || !strings.Contains(string(golden), "9e107d9d372bb6826bd81d3542a419d6  dir/quick.txt") {
+		t.Errorf("Expected the md5sum golden to be written got %q %v", golden, err)
+	}
+
+	if err := writeConformance(dir); err == nil {
+		t.Error("Expected a directory which is not empty to be refused")
+	}
+}
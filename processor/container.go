@@ -0,0 +1,203 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A .hashit container is laid out as
+//
+//	HASHIT-MANIFEST <schema>\n
+//	<header json>\n
+//	<body of BodyLength bytes, gzipped json lines of results>
+//	<optional minisign signature of everything above>
+//
+// Readers reject schemas newer than they understand and ignore unknown header and result
+// fields, so a container stays readable by the hashit version that wrote it and every later one.
+
+// Magic that starts the first line of every container
+const containerMagic = "HASHIT-MANIFEST"
+
+// Schema written by this version and the newest one it is able to read
+const containerSchema = 1
+
+// Describes the run that produced a container and how its body is stored
+type containerHeader struct {
+	ScanID      string `json:",omitempty"`
+	Version     string
+	Hashes      []string
+	Created     time.Time
+	Command     []string
+	Directory   string
+	Host        string
+	Labels      map[string]string `json:",omitempty"`
+	Files       int
+	Compression string
+	BodyLength  int
+}
+
+// A decoded container along with the bytes its signature covers
+type container struct {
+	Schema    int
+	Header    containerHeader
+	Results   []Result
+	Signed    []byte
+	Signature []byte
+}
+
+// Check if the data starts like a container rather than a text manifest
+func isContainer(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(containerMagic+" "))
+}
+
+// Writes every result into an unsigned container
+func toContainer(input chan Result) string {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	encoder := json.NewEncoder(zw)
+
+	files := 0
+	for res := range input {
+		if len(labels) != 0 {
+			res.Labels = labels
+		}
+		_ = encoder.Encode(res)
+		files++
+	}
+	_ = zw.Close()
+
+	hashes := []string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) {
+			hashes = append(hashes, info.Name)
+		}
+	}
+
+	pwd, host, command := runContext()
+	header := containerHeader{
+		ScanID:      scanID,
+		Version:     Version,
+		Hashes:      hashes,
+		Created:     time.Now().UTC(),
+		Command:     command,
+		Directory:   pwd,
+		Host:        host,
+		Labels:      labels,
+		Files:       files,
+		Compression: "gzip",
+	}
+	return string(encodeContainer(header, body.Bytes()))
+}
+
+func encodeContainer(header containerHeader, body []byte) []byte {
+	header.BodyLength = len(body)
+	encoded, _ := json.Marshal(header)
+
+	var out bytes.Buffer
+	out.WriteString(fmt.Sprintf("%s %d\n", containerMagic, containerSchema))
+	out.Write(encoded)
+	out.WriteString("\n")
+	out.Write(body)
+	return out.Bytes()
+}
+
+// Appends a signature over the whole container so it travels with the manifest
+func signContainer(data []byte, keyFile string) ([]byte, error) {
+	if !isContainer(data) {
+		return nil, errors.New("not a hashit container")
+	}
+	key, err := readSecretKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "stdout"
+	if FileOutput != "" {
+		name = filepath.Base(FileOutput)
+	}
+	trustedComment := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), name)
+	return append(data, minisignSign(key, data, trustedComment)...), nil
+}
+
+// Splits a container into its header, results and signature
+func readContainer(data []byte) (container, error) {
+	c := container{}
+
+	magic, rest, found := bytes.Cut(data, []byte("\n"))
+	if !found || !isContainer(magic) {
+		return c, errors.New("not a hashit container")
+	}
+	schema, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(string(magic), containerMagic)))
+	if err != nil {
+		return c, fmt.Errorf("invalid container schema %q", magic)
+	}
+	if schema > containerSchema {
+		return c, fmt.Errorf("container schema %d is newer than the %d supported by hashit %s, upgrade to read it", schema, containerSchema, Version)
+	}
+	c.Schema = schema
+
+	headerLine, rest, found := bytes.Cut(rest, []byte("\n"))
+	if !found {
+		return c, errors.New("container header is truncated")
+	}
+	if err := json.Unmarshal(headerLine, &c.Header); err != nil {
+		return c, fmt.Errorf("invalid container header: %w", err)
+	}
+	if c.Header.BodyLength < 0 || c.Header.BodyLength > len(rest) {
+		return c, errors.New("container body is truncated")
+	}
+
+	signedLength := len(data) - len(rest) + c.Header.BodyLength
+	c.Signed = data[:signedLength]
+	c.Signature = bytes.TrimSpace(data[signedLength:])
+
+	var body io.Reader = bytes.NewReader(rest[:c.Header.BodyLength])
+	switch c.Header.Compression {
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return c, fmt.Errorf("invalid container body: %w", err)
+		}
+		defer zr.Close()
+		body = zr
+	case "", "none":
+	default:
+		return c, fmt.Errorf("unsupported container compression %s", c.Header.Compression)
+	}
+
+	decoder := json.NewDecoder(body)
+	for {
+		var r Result
+		if err := decoder.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return c, fmt.Errorf("invalid container body: %w", err)
+		}
+		c.Results = append(c.Results, r)
+	}
+
+	if len(c.Results) != c.Header.Files {
+		return c, fmt.Errorf("container lists %d files but holds %d", c.Header.Files, len(c.Results))
+	}
+	return c, nil
+}
+
+// Checks the signature embedded in a container returning the trusted comment
+func verifyContainerSignature(c container, keyFile string) (string, error) {
+	if len(c.Signature) == 0 {
+		return "", errors.New("container is not signed")
+	}
+	key, err := readPublicKey(keyFile)
+	if err != nil {
+		return "", err
+	}
+	return minisignVerify(key, c.Signed, c.Signature)
+}
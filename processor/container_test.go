@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContainerRoundTrip(t *testing.T) {
+	Hash = []string{HashNames.MD5}
+	defer func() { Hash = []string{} }()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	_ = os.WriteFile(a, []byte("hello"), 0600)
+	_ = os.WriteFile(b, []byte("changed"), 0600)
+
+	input := make(chan Result, 3)
+	input <- Result{File: a, Bytes: 5, MD5: "5d41402abc4b2a76b9719d911017c592"}
+	input <- Result{File: b, Bytes: 5, MD5: "5d41402abc4b2a76b9719d911017c592"}
+	input <- Result{File: filepath.Join(dir, "missing"), Error: "no such file"}
+	close(input)
+
+	data := []byte(toContainer(input))
+	if !strings.HasPrefix(string(data), "HASHIT-MANIFEST 1\n") {
+		t.Fatalf("Unexpected container start %q", data[:20])
+	}
+
+	c, err := readContainer(data)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if c.Header.Files != 3 || len(c.Results) != 3 || strings.Join(c.Header.Hashes, ",") != "md5" || c.Header.Version != Version {
+		t.Errorf("Unexpected container %+v", c)
+	}
+	if len(c.Signature) != 0 {
+		t.Error("Expected unsigned container")
+	}
+
+	entries, err := parseManifest(data)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	status, _ := checkManifest(entries)
+	if len(status) != 2 || status[a] != "OK" || status[b] != "FAILED" {
+		t.Errorf("Unexpected status %v", status)
+	}
+}
+
+func TestContainerNewerSchema(t *testing.T) {
+	if _, err := readContainer([]byte("HASHIT-MANIFEST 2\n{}\n")); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected newer schema error got %v", err)
+	}
+}
+
+func TestContainerTruncated(t *testing.T) {
+	data := encodeContainer(containerHeader{Compression: "none", Files: 1}, []byte(`{"File":"a"}`+"\n"))
+	if _, err := readContainer(data); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := readContainer(data[:len(data)-4]); err == nil {
+		t.Error("Expected truncated body to fail")
+	}
+}
+
+func TestContainerUnknownFields(t *testing.T) {
+	header := `{"Files":1,"Compression":"none","BodyLength":30,"Future":true}`
+	data := []byte("HASHIT-MANIFEST 1\n" + header + "\n" + `{"File":"a","Future":"value"}` + "\n")
+
+	c, err := readContainer(data)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if c.Results[0].File != "a" {
+		t.Errorf("Unexpected results %+v", c.Results)
+	}
+}
+
+func TestSignContainer(t *testing.T) {
+	dir := t.TempDir()
+	secret, public := makeMinisignKeys(t, "")
+	_ = os.WriteFile(filepath.Join(dir, "key"), secret, 0600)
+	_ = os.WriteFile(filepath.Join(dir, "key.pub"), public, 0600)
+
+	unsigned := encodeContainer(containerHeader{Compression: "none", Files: 1}, []byte(`{"File":"a","MD5":"5d41402abc4b2a76b9719d911017c592"}`+"\n"))
+	signed, err := signContainer(unsigned, filepath.Join(dir, "key"))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	c, err := readContainer(signed)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if !bytes.Equal(c.Signed, unsigned) {
+		t.Error("Expected signature to cover the whole unsigned container")
+	}
+	if _, err := verifyContainerSignature(c, filepath.Join(dir, "key.pub")); err != nil {
+		t.Errorf("Expected signature to verify got %s", err.Error())
+	}
+
+	tampered := bytes.Replace(signed, []byte("5d41"), []byte("0000"), 1)
+	c, err = readContainer(tampered)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := verifyContainerSignature(c, filepath.Join(dir, "key.pub")); err == nil {
+		t.Error("Expected tampered container to fail verification")
+	}
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Level of detail recorded for each file when --detail-threshold is set
+const (
+	DetailFull    = "full"
+	DetailSampled = "sampled"
+)
+
+// Bytes read at each sample of a file over the detail threshold
+const detailSampleSize = 1024 * 1024
+
+// Parsed --detail-threshold, -1 when every file is hashed in full
+var detailThresholdBytes int64 = -1
+
+// A region of a large file hashed in place of its whole content
+type Sample struct {
+	Offset   int64
+	Size     int64
+	XxHash64 string
+}
+
+// Parses the level of detail policy
+func compileDetail() error {
+	detailThresholdBytes = -1
+	if DetailThreshold == "" {
+		return nil
+	}
+
+	var err error
+	if detailThresholdBytes, err = parseSize(DetailThreshold); err != nil {
+		return err
+	}
+	if DetailSamples < 2 {
+		return fmt.Errorf("--detail-samples must be at least 2 to cover the start and end of files got %d", DetailSamples)
+	}
+	return nil
+}
+
+// Where each sample starts, spread evenly so the first covers the start of the file and
+// the last its end. Files too small for the samples to leave gaps are read whole.
+func sampleOffsets(size int64, samples int) []int64 {
+	if size <= int64(samples)*detailSampleSize {
+		return []int64{0}
+	}
+
+	offsets := make([]int64, samples)
+	for i := range offsets {
+		offsets[i] = int64(i) * (size - detailSampleSize) / int64(samples-1)
+	}
+	return offsets
+}
+
+// Hashes samples of a file over the detail threshold instead of its whole content. Each
+// sample gets its own digest to narrow down where a change is, and the quick hash covers
+// the size and every sample so any difference between them changes it.
+func sampleFile(file io.ReaderAt, size int64) (string, []Sample, error) {
+	quick := xxhash.New()
+	_, _ = fmt.Fprintf(quick, "%d\n", size)
+
+	offsets := sampleOffsets(size, DetailSamples)
+	length := int64(detailSampleSize)
+	if len(offsets) == 1 {
+		length = size
+	}
+
+	samples := []Sample{}
+	buf := make([]byte, length)
+	for _, offset := range offsets {
+		if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return "", nil, err
+		}
+		quick.Write(buf)
+		samples = append(samples, Sample{Offset: offset, Size: length, XxHash64: fmt.Sprintf("%016x", xxhash.Sum64(buf))})
+	}
+
+	return hex.EncodeToString(quick.Sum(nil)), samples, nil
+}
+
+// Builds the sampled result for a file over the detail threshold
+func processSampled(filename string, file *os.File, size int64) (Result, error) {
+	quick, samples, err := sampleFile(limitReaderAt(file), size)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		File:      filename,
+		Bytes:     size,
+		Detail:    DetailSampled,
+		QuickHash: quick,
+		Samples:   samples,
+	}, nil
+}
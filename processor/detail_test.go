@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSampleOffsets(t *testing.T) {
+	offsets := sampleOffsets(10*detailSampleSize, 4)
+	expected := []int64{0, 3 * detailSampleSize, 6 * detailSampleSize, 9 * detailSampleSize}
+	for i := range expected {
+		if offsets[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, offsets)
+			break
+		}
+	}
+
+	if offsets := sampleOffsets(2*detailSampleSize, 4); len(offsets) != 1 || offsets[0] != 0 {
+		t.Errorf("expected a small file to be read whole got %v", offsets)
+	}
+}
+
+func TestSampleFile(t *testing.T) {
+	defer func() { DetailSamples = 16 }()
+	DetailSamples = 3
+
+	content := make([]byte, 8*detailSampleSize)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+
+	quick, samples, err := sampleFile(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 || samples[2].Offset != 7*detailSampleSize || samples[2].Size != detailSampleSize {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	// Changing a sampled byte changes that sample and the quick hash
+	content[len(content)-1]++
+	changed, changedSamples, _ := sampleFile(bytes.NewReader(content), int64(len(content)))
+	if changed == quick || changedSamples[2].XxHash64 == samples[2].XxHash64 || changedSamples[0].XxHash64 != samples[0].XxHash64 {
+		t.Error("expected only the last sample and the quick hash to change")
+	}
+
+	// Changes between samples are not seen which is the trade off for not reading them
+	content[len(content)-1]--
+	content[2*detailSampleSize]++
+	if between, _, _ := sampleFile(bytes.NewReader(content), int64(len(content))); between != quick {
+		t.Error("expected a change outside the samples to be missed")
+	}
+}
+
+func TestWorkerDetail(t *testing.T) {
+	previous := Hash
+	defer func() {
+		Hash = previous
+		DetailThreshold = ""
+		_ = compileDetail()
+	}()
+	Hash = []string{HashNames.MD5}
+	DetailThreshold = "1K"
+	if err := compileDetail(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	large := filepath.Join(dir, "large")
+	_ = os.WriteFile(small, []byte("hello"), 0600)
+	_ = os.WriteFile(large, make([]byte, 4096), 0600)
+
+	input := make(chan string, 2)
+	output := make(chan Result, 2)
+	input <- small
+	input <- large
+	close(input)
+	fileProcessorWorker(0, input, output)
+	close(output)
+
+	for res := range output {
+		switch res.File {
+		case small:
+			if res.Detail != DetailFull || res.MD5 == "" {
+				t.Errorf("expected small to be hashed in full got %+v", res)
+			}
+		case large:
+			if res.Detail != DetailSampled || res.QuickHash == "" || res.MD5 != "" || res.Bytes != 4096 {
+				t.Errorf("expected large to be sampled got %+v", res)
+			}
+		}
+	}
+}
+
+func TestCompileDetail(t *testing.T) {
+	defer func() {
+		DetailThreshold, DetailSamples = "", 16
+		_ = compileDetail()
+	}()
+
+	DetailThreshold, DetailSamples = "1G", 1
+	if err := compileDetail(); err == nil {
+		t.Error("expected a single sample to be rejected")
+	}
+	DetailThreshold, DetailSamples = "big", 16
+	if err := compileDetail(); err == nil {
+		t.Error("expected an invalid size to be rejected")
+	}
+}
+
+func TestChangeSinceQuickHash(t *testing.T) {
+	entry := baselineEntry{Bytes: 10, QuickHash: "aaaa"}
+	if got := changeSince(Result{Bytes: 10, QuickHash: "aaaa"}, entry); got != "unchanged" {
+		t.Errorf("expected unchanged got %s", got)
+	}
+	if got := changeSince(Result{Bytes: 10, QuickHash: "bbbb"}, entry); got != "changed" {
+		t.Errorf("expected changed got %s", got)
+	}
+}
@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DiffReport lists how two directory trees differ by relative path
+type DiffReport struct {
+	A         string
+	B         string
+	OnlyInA   []string
+	OnlyInB   []string
+	Different []string
+	Errors    []string `json:",omitempty"`
+}
+
+// Hashes every file under root keyed by its path relative to root
+func hashTree(root string) (map[string]Result, []string) {
+	fileListQueue := make(chan string, FileListQueueSize)
+	resultQueue := make(chan Result, FileListQueueSize)
+
+	go func() {
+		walkDirectory(context.Background(), root, fileListQueue, resultQueue)
+		close(fileListQueue)
+	}()
+
+	startWorkers(fileListQueue, resultQueue)
+
+	results := map[string]Result{}
+	errors := []string{}
+	for res := range resultQueue {
+		if res.Error != "" {
+			errors = append(errors, fmt.Sprintf("%s: %s", res.File, res.Error))
+			continue
+		}
+		rel, err := filepath.Rel(root, res.File)
+		if err != nil {
+			rel = res.File
+		}
+		results[filepath.ToSlash(rel)] = res
+	}
+
+	return results, errors
+}
+
+// Two files match when they are the same size and every calculated hash agrees
+func sameContent(a Result, b Result) bool {
+	if a.Bytes != b.Bytes {
+		return false
+	}
+
+	var aHashes, bHashes strings.Builder
+	writeTextHashes(&aHashes, a)
+	writeTextHashes(&bHashes, b)
+	return aHashes.String() == bHashes.String()
+}
+
+// Hashes both trees concurrently and compares them by relative path
+func diffTrees(a string, b string) DiffReport {
+	report := DiffReport{A: a, B: b, OnlyInA: []string{}, OnlyInB: []string{}, Different: []string{}}
+
+	var aResults, bResults map[string]Result
+	var aErrors, bErrors []string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		aResults, aErrors = hashTree(a)
+		wg.Done()
+	}()
+	go func() {
+		bResults, bErrors = hashTree(b)
+		wg.Done()
+	}()
+	wg.Wait()
+
+	for path, res := range aResults {
+		other, ok := bResults[path]
+		switch {
+		case !ok:
+			report.OnlyInA = append(report.OnlyInA, path)
+		case !sameContent(res, other):
+			report.Different = append(report.Different, path)
+		}
+	}
+	for path := range bResults {
+		if _, ok := aResults[path]; !ok {
+			report.OnlyInB = append(report.OnlyInB, path)
+		}
+	}
+
+	report.Errors = append(aErrors, bErrors...)
+	sort.Strings(report.OnlyInA)
+	sort.Strings(report.OnlyInB)
+	sort.Strings(report.Different)
+	sort.Strings(report.Errors)
+
+	return report
+}
+
+// Compares the two directories printing the differences, returning the exit code
+// which is 1 when the trees differ like diff
+func processDiff(a string, b string) int {
+	for _, dir := range []string{a, b} {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			printError(err.Error())
+			return 1
+		}
+		if !fi.IsDir() {
+			printError(fmt.Sprintf("--diff requires two directories, %s is not a directory", dir))
+			return 1
+		}
+	}
+
+	report := diffTrees(filepath.Clean(a), filepath.Clean(b))
+
+	if strings.ToLower(Format) == "json" {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+	} else {
+		for _, p := range report.OnlyInA {
+			fmt.Printf("only in %s: %s\n", report.A, p)
+		}
+		for _, p := range report.OnlyInB {
+			fmt.Printf("only in %s: %s\n", report.B, p)
+		}
+		for _, p := range report.Different {
+			fmt.Printf("differs: %s\n", p)
+		}
+	}
+
+	if len(report.Errors) != 0 {
+		return ExitCodeFileError
+	}
+	if len(report.OnlyInA)+len(report.OnlyInB)+len(report.Different) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	Hash = []string{HashNames.SHA256}
+	a := t.TempDir()
+	b := t.TempDir()
+
+	_ = os.MkdirAll(filepath.Join(a, "sub"), 0700)
+	_ = os.MkdirAll(filepath.Join(b, "sub"), 0700)
+	_ = os.WriteFile(filepath.Join(a, "sub", "same"), []byte("same"), 0600)
+	_ = os.WriteFile(filepath.Join(b, "sub", "same"), []byte("same"), 0600)
+	_ = os.WriteFile(filepath.Join(a, "changed"), []byte("one"), 0600)
+	_ = os.WriteFile(filepath.Join(b, "changed"), []byte("two"), 0600)
+	_ = os.WriteFile(filepath.Join(a, "left"), []byte("left"), 0600)
+	_ = os.WriteFile(filepath.Join(b, "right"), []byte("right"), 0600)
+
+	report := diffTrees(a, b)
+
+	if len(report.OnlyInA) != 1 || report.OnlyInA[0] != "left" {
+		t.Errorf("Expected only left in a got %v", report.OnlyInA)
+	}
+	if len(report.OnlyInB) != 1 || report.OnlyInB[0] != "right" {
+		t.Errorf("Expected only right in b got %v", report.OnlyInB)
+	}
+	if len(report.Different) != 1 || report.Different[0] != "changed" {
+		t.Errorf("Expected changed to differ got %v", report.Different)
+	}
+}
@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// A directory recorded by --dir-cache with the signature of its entries and the results
+// of the files directly in it
+type dirCacheEntry struct {
+	Dir       string
+	Signature string
+	Results   []Result
+}
+
+// First line of the state file, the state is only reused by runs selecting the same
+// files and calculating the same hashes
+type dirCacheHeader struct {
+	Options string
+}
+
+// The directories recorded by the last run and those seen by this one
+type dirCacheState struct {
+	path     string
+	mutex    sync.Mutex
+	previous map[string]dirCacheEntry
+	current  map[string]string
+	// Directories whose signature matched so their files are not hashed again
+	unchanged map[string]bool
+	reused    int
+}
+
+// The state of --dir-cache, nil when not in use
+var dirCache *dirCacheState
+
+// Everything deciding which files are hashed and what is recorded for them
+func dirCacheOptions() string {
+	options, _ := json.Marshal([]any{Hash, Exclude, Prune, MinSize, MaxSize, MaxFileSize, NewerThan, OlderThan, FileType,
+		Shard, TextNormalize, KeepOriginal, MTime, DetectType, NoContent, DetailThreshold, PieceLength, PieceHash,
+		AzureBlockSize, ZstdFrames, MaxDepth, OneFileSystem})
+	return string(options)
+}
+
+func validateDirCache() error {
+	if DirCache == "" {
+		return nil
+	}
+	switch {
+	case ListOnly:
+		return errors.New("--dir-cache cannot be combined with --list-only as nothing is hashed")
+	case StandardInput, Partition >= 0, FileInput != "", FromManifest != "":
+		return errors.New("--dir-cache needs directories to walk")
+	}
+	return nil
+}
+
+// Reads the state written by the last run, which is ignored when that run used other options
+func loadDirCache(path string) (*dirCacheState, error) {
+	state := &dirCacheState{
+		path:      path,
+		previous:  map[string]dirCacheEntry{},
+		current:   map[string]string{},
+		unchanged: map[string]bool{},
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 256*1024*1024)
+	if !scanner.Scan() {
+		return state, scanner.Err()
+	}
+	var header dirCacheHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("not a directory cache: %w", err)
+	}
+	if header.Options != dirCacheOptions() {
+		printVerbose("directory cache was written with other options, hashing everything", "path", path)
+		return state, nil
+	}
+
+	for scanner.Scan() {
+		var entry dirCacheEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid directory cache entry: %w", err)
+		}
+		state.previous[entry.Dir] = entry
+	}
+	return state, scanner.Err()
+}
+
+// Signs the names, types, sizes and modification times of everything directly in dir
+// other than hashit's own output. Files added, removed, renamed or rewritten change it
+// without any of them being read.
+func dirSignature(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, e := range entries {
+		// This run's output is rewritten every time
+		if isOutputFile(filepath.Join(dir, e.Name())) {
+			continue
+		}
+		fmt.Fprintf(h, "%s\x00%d", e.Name(), e.Type())
+		if !e.IsDir() {
+			info, err := e.Info()
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "\x00%d\x00%d", info.Size(), info.ModTime().UnixNano())
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Called as the walk enters a directory. When its signature matches the last run the
+// results recorded for its files are sent to output and true is returned so they are
+// not queued to be hashed. Subdirectories are still walked as each has its own signature.
+func (d *dirCacheState) enter(dir string, output chan Result) bool {
+	signature, err := dirSignature(dir)
+	if err != nil {
+		printDebug("unable to sign directory", "dir", dir, "error", err.Error())
+		return false
+	}
+
+	d.mutex.Lock()
+	d.current[dir] = signature
+	entry, ok := d.previous[dir]
+	unchanged := ok && entry.Signature == signature
+	if unchanged {
+		d.unchanged[dir] = true
+		d.reused += len(entry.Results)
+	}
+	d.mutex.Unlock()
+
+	if unchanged {
+		printTrace("directory unchanged, reusing results", "dir", dir, "files", len(entry.Results))
+		for _, res := range entry.Results {
+			output <- res
+		}
+	}
+	return unchanged
+}
+
+// Check if the file is in a directory whose recorded results have been reused
+func (d *dirCacheState) reusing(file string) bool {
+	if d == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.unchanged[filepath.Dir(file)]
+}
+
+// Groups the results by directory and once every result has passed writes the state for
+// the next run. A directory with any file which failed is left out so it is walked again.
+func recordDirCache(input chan Result, d *dirCacheState) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		byDir := map[string][]Result{}
+		failed := map[string]bool{}
+		for res := range input {
+			dir := filepath.Dir(res.File)
+			if res.Error != "" {
+				failed[dir] = true
+			} else {
+				byDir[dir] = append(byDir[dir], res)
+			}
+			output <- res
+		}
+
+		if err := d.write(byDir, failed); err != nil {
+			printError(fmt.Sprintf("unable to write directory cache %s: %s", d.path, err.Error()))
+		}
+		printVerbose("directory cache", "directories", len(d.current), "unchanged", len(d.unchanged), "reused", d.reused)
+		close(output)
+	}()
+	return output
+}
+
+// Replaces the state file so an interrupted write leaves the last one in place
+func (d *dirCacheState) write(byDir map[string][]Result, failed map[string]bool) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	dirs := []string{}
+	for dir := range d.current {
+		if !failed[dir] {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	temp := d.path + ".tmp"
+	file, err := os.Create(temp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+
+	header, _ := json.Marshal(dirCacheHeader{Options: dirCacheOptions()})
+	_, _ = w.Write(append(header, '\n'))
+	for _, dir := range dirs {
+		results := byDir[dir]
+		sort.Slice(results, func(i, j int) bool { return results[i].File < results[j].File })
+		for i := range results {
+			// Each run stamps its own id
+			results[i].ScanID = ""
+		}
+		line, err := json.Marshal(dirCacheEntry{Dir: dir, Signature: d.current[dir], Results: results})
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp, d.path)
+}
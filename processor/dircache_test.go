@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Walks dir with the directory cache at state, hashing nothing but recording a result for
+// every queued file, and returns the files queued and the results reused
+func walkWithDirCache(t *testing.T, dir string, state string) ([]string, []string) {
+	var err error
+	if dirCache, err = loadDirCache(state); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	defer func() { dirCache = nil }()
+
+	files := make(chan string, 100)
+	results := make(chan Result, 100)
+	walkDirectory(context.Background(), dir, files, results)
+	close(files)
+	close(results)
+
+	reused := []string{}
+	input := make(chan Result, 100)
+	for res := range results {
+		reused = append(reused, filepath.Base(res.File))
+		input <- res
+	}
+	queued := []string{}
+	for f := range files {
+		queued = append(queued, filepath.Base(f))
+		input <- Result{File: f, MD5: "digest"}
+	}
+	close(input)
+	for range recordDirCache(input, dirCache) {
+	}
+
+	sort.Strings(queued)
+	sort.Strings(reused)
+	return queued, reused
+}
+
+func TestDirCacheReusesUnchangedDirectories(t *testing.T) {
+	previousHash := Hash
+	defer func() { Hash = previousHash }()
+	Hash = []string{"md5"}
+
+	dir := t.TempDir()
+	state := filepath.Join(t.TempDir(), "state")
+	for _, f := range []string{"a/one", "a/b/two", "c/three"} {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		_ = os.WriteFile(p, []byte(f), 0600)
+	}
+
+	queued, reused := walkWithDirCache(t, dir, state)
+	if len(queued) != 3 || len(reused) != 0 {
+		t.Fatalf("Expected every file hashed on the first run got %v reused %v", queued, reused)
+	}
+
+	// Rewriting a file in place changes its size and modification time
+	changed := filepath.Join(dir, "c", "three")
+	_ = os.WriteFile(changed, []byte("rewritten"), 0600)
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(changed, later, later)
+
+	queued, reused = walkWithDirCache(t, dir, state)
+	if len(queued) != 1 || queued[0] != "three" {
+		t.Errorf("Expected only the changed directory hashed got %v", queued)
+	}
+	if len(reused) != 2 || reused[0] != "one" || reused[1] != "two" {
+		t.Errorf("Expected the unchanged files reused got %v", reused)
+	}
+
+	// Other hashes cannot reuse the recorded results
+	Hash = []string{"sha1"}
+	queued, _ = walkWithDirCache(t, dir, state)
+	if len(queued) != 3 {
+		t.Errorf("Expected every file hashed with other options got %v", queued)
+	}
+}
+
+func TestDirCacheSkipsFailedDirectories(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(t.TempDir(), "state")
+	_ = os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0600)
+
+	d, _ := loadDirCache(state)
+	d.enter(dir, make(chan Result, 1))
+	input := make(chan Result, 1)
+	input <- Result{File: filepath.Join(dir, "file"), Error: "permission denied"}
+	close(input)
+	for range recordDirCache(input, d) {
+	}
+
+	d, err := loadDirCache(state)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, ok := d.previous[dir]; ok {
+		t.Error("Expected a directory with a failed file not to be recorded")
+	}
+}
+
+func TestDirSignature(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0600)
+
+	first, err := dirSignature(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if again, _ := dirSignature(dir); again != first {
+		t.Error("Expected the signature of an unchanged directory to be stable")
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, "added"), []byte{}, 0600)
+	if added, _ := dirSignature(dir); added == first {
+		t.Error("Expected adding a file to change the signature")
+	}
+}
+
+func TestValidateDirCache(t *testing.T) {
+	previousCache, previousList := DirCache, ListOnly
+	defer func() { DirCache, ListOnly = previousCache, previousList }()
+
+	DirCache, ListOnly = "state", true
+	if validateDirCache() == nil {
+		t.Error("Expected --dir-cache with --list-only to be refused")
+	}
+}
@@ -0,0 +1,320 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/boyter/hashit/walker"
+)
+
+// Partition tables are addressed in 512 byte sectors which is what disk images use
+const imageSectorSize = 512
+
+// A partition found in an MBR or GPT partition table
+type imagePartition struct {
+	Number int
+	Offset int64
+	Size   int64
+}
+
+// A regular file found inside a filesystem in a disk image
+type imageFile struct {
+	Path string
+	Size int64
+	open func() (io.Reader, error)
+}
+
+// Lists the partitions numbered the way Linux does. MBR primaries are 1 to 4 by slot
+// with logical partitions in the extended partition from 5, GPT entries by slot.
+func readPartitions(r io.ReaderAt) ([]imagePartition, error) {
+	mbr := make([]byte, imageSectorSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("unable to read partition table: %w", err)
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return nil, errors.New("no partition table found")
+	}
+
+	partitions := []imagePartition{}
+	var extended int64
+	for i := 0; i < 4; i++ {
+		entry := mbr[446+i*16 : 446+(i+1)*16]
+		kind := entry[4]
+		start := int64(binary.LittleEndian.Uint32(entry[8:]))
+		sectors := int64(binary.LittleEndian.Uint32(entry[12:]))
+
+		switch kind {
+		case 0:
+			continue
+		case 0xee:
+			return readGPT(r)
+		case 0x05, 0x0f, 0x85:
+			extended = start
+		}
+		partitions = append(partitions, imagePartition{
+			Number: i + 1,
+			Offset: start * imageSectorSize,
+			Size:   sectors * imageSectorSize,
+		})
+	}
+
+	// Each extended boot record holds one logical partition and a link to the next
+	number := 5
+	ebr := extended
+	for seen := 0; ebr != 0 && seen < 128; seen++ {
+		record := make([]byte, imageSectorSize)
+		if _, err := r.ReadAt(record, ebr*imageSectorSize); err != nil {
+			return nil, fmt.Errorf("unable to read extended partition: %w", err)
+		}
+		if record[510] != 0x55 || record[511] != 0xaa {
+			break
+		}
+
+		if sectors := int64(binary.LittleEndian.Uint32(record[446+12:])); sectors != 0 {
+			partitions = append(partitions, imagePartition{
+				Number: number,
+				Offset: (ebr + int64(binary.LittleEndian.Uint32(record[446+8:]))) * imageSectorSize,
+				Size:   sectors * imageSectorSize,
+			})
+			number++
+		}
+
+		next := int64(binary.LittleEndian.Uint32(record[462+8:]))
+		if next == 0 {
+			break
+		}
+		ebr = extended + next
+	}
+
+	return partitions, nil
+}
+
+func readGPT(r io.ReaderAt) ([]imagePartition, error) {
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, imageSectorSize); err != nil {
+		return nil, fmt.Errorf("unable to read GPT header: %w", err)
+	}
+	if !bytes.Equal(header[:8], []byte("EFI PART")) {
+		return nil, errors.New("protective MBR without a GPT header")
+	}
+
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	count := int(binary.LittleEndian.Uint32(header[80:]))
+	entrySize := int(binary.LittleEndian.Uint32(header[84:]))
+	if entrySize < 128 || count > 1024 {
+		return nil, errors.New("invalid GPT header")
+	}
+
+	entries := make([]byte, count*entrySize)
+	if _, err := r.ReadAt(entries, entriesLBA*imageSectorSize); err != nil {
+		return nil, fmt.Errorf("unable to read GPT entries: %w", err)
+	}
+
+	partitions := []imagePartition{}
+	for i := 0; i < count; i++ {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		if bytes.Equal(entry[:16], make([]byte, 16)) {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(entry[32:]))
+		last := int64(binary.LittleEndian.Uint64(entry[40:]))
+		partitions = append(partitions, imagePartition{
+			Number: i + 1,
+			Offset: first * imageSectorSize,
+			Size:   (last - first + 1) * imageSectorSize,
+		})
+	}
+	return partitions, nil
+}
+
+// Works out which filesystem is in r from its superblock or boot sector and lists its files
+func imageFiles(r *io.SectionReader) ([]imageFile, error) {
+	boot := make([]byte, 2048)
+	if _, err := r.ReadAt(boot, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("unable to read filesystem: %w", err)
+	}
+
+	switch {
+	case bytes.Equal(boot[3:11], []byte("NTFS    ")):
+		return ntfsFiles(r)
+	case binary.LittleEndian.Uint16(boot[1024+56:]) == ext4Magic:
+		return ext4Files(r, r.Size())
+	case boot[510] == 0x55 && boot[511] == 0xaa && isFATBootSector(boot):
+		return fatFiles(r)
+	}
+	return nil, errors.New("unrecognised filesystem, supported filesystems are ext2/3/4, NTFS and FAT")
+}
+
+// Opens the image and the requested partition, 0 meaning the image has no partition table
+func openImagePartition(path string, partition int) (*os.File, *io.SectionReader, error) {
+	file, err := os.Open(longPath(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+
+	if partition == 0 {
+		return file, io.NewSectionReader(file, 0, fi.Size()), nil
+	}
+
+	partitions, err := readPartitions(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	for _, p := range partitions {
+		if p.Number == partition {
+			return file, io.NewSectionReader(file, p.Offset, p.Size), nil
+		}
+	}
+	_ = file.Close()
+	return nil, nil, fmt.Errorf("partition %d not found in %s", partition, path)
+}
+
+// Hashes every regular file in the filesystem inside the image without mounting it
+func processImage(path string, partition int, output chan Result) {
+	defer close(output)
+
+	file, section, err := openImagePartition(path, partition)
+	if err != nil {
+		output <- newErrorResult(path, err)
+		return
+	}
+	defer file.Close()
+
+	files, err := imageFiles(section)
+	if err != nil {
+		output <- newErrorResult(path, err)
+		return
+	}
+
+	fsys, invalid := newImageFS(files)
+	for _, f := range invalid {
+		output <- newErrorResult(f.Path, errors.New("path cannot be placed in the image's directory tree"))
+	}
+
+	w := walker.Walker{
+		MaxDepth: MaxDepth,
+		Error: func(p string, err error) {
+			output <- newErrorResult(p, err)
+		},
+		Filters: []walker.Filter{
+			func(p string, d fs.DirEntry) walker.Action {
+				if p != "." && isExcluded(p) {
+					printVerbose(fmt.Sprintf("excluding: %s", p))
+					return walker.Skip
+				}
+				return walker.Continue
+			},
+		},
+	}
+	_ = w.Walk(context.Background(), fsys, ".", func(p string, d fs.DirEntry) error {
+		f := fsys.files[p]
+		if !inShard(f.Path) || resumeCompleted(f.Path) {
+			return nil
+		}
+		if Debug {
+			printDebug("hashing from image", "file", f.Path, "bytes", f.Size)
+		}
+
+		r, err := hashImageFile(f)
+		if err != nil {
+			output <- newErrorResult(f.Path, err)
+			return nil
+		}
+		output <- r
+		return nil
+	})
+}
+
+func hashImageFile(f imageFile) (Result, error) {
+	reader, err := f.open()
+	if err != nil {
+		return Result{}, err
+	}
+	reader = limitReader(reader)
+
+	var r Result
+	if f.Size > StreamSize {
+		r, err = processStream(f.Path, reader, nil, int(f.Size), nil, TextNormalize)
+	} else {
+		var content []byte
+		if content, err = readAll(reader, f.Size+bytes.MinRead); err == nil {
+			if TextNormalize != "" {
+				content = normalizeNewlines(content, TextNormalize)
+			}
+			r, err = processReadFile(f.Path, &content)
+		}
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	r.File = f.Path
+	r.Bytes = f.Size
+	return r, nil
+}
+
+// A run of bytes in a file stored contiguously in the image, an offset of -1 is a hole
+type imageExtent struct {
+	Offset int64
+	Length int64
+}
+
+// Reads a file from the image following its extents up to size bytes with holes read as zeros
+type extentReader struct {
+	r         io.ReaderAt
+	extents   []imageExtent
+	remaining int64
+	index     int
+	position  int64
+}
+
+func newExtentReader(r io.ReaderAt, extents []imageExtent, size int64) *extentReader {
+	return &extentReader{r: r, extents: extents, remaining: size}
+}
+
+func (e *extentReader) Read(p []byte) (int, error) {
+	for e.index < len(e.extents) && e.position >= e.extents[e.index].Length {
+		e.index++
+		e.position = 0
+	}
+	if e.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if e.index >= len(e.extents) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	extent := e.extents[e.index]
+	n := int64(len(p))
+	if left := extent.Length - e.position; n > left {
+		n = left
+	}
+	if n > e.remaining {
+		n = e.remaining
+	}
+
+	if extent.Offset < 0 {
+		clear(p[:n])
+	} else if read, err := e.r.ReadAt(p[:n], extent.Offset+e.position); int64(read) != n {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return read, err
+	}
+
+	e.position += n
+	e.remaining -= n
+	return int(n), nil
+}
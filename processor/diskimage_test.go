@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func writeMBREntry(sector []byte, slot int, kind byte, start, sectors uint32) {
+	entry := sector[446+slot*16:]
+	entry[4] = kind
+	binary.LittleEndian.PutUint32(entry[8:], start)
+	binary.LittleEndian.PutUint32(entry[12:], sectors)
+	sector[510], sector[511] = 0x55, 0xaa
+}
+
+func TestReadPartitionsMBR(t *testing.T) {
+	img := make([]byte, 200*imageSectorSize)
+	writeMBREntry(img, 0, 0x83, 2, 10)
+	writeMBREntry(img, 2, 0x05, 100, 100)
+
+	// Two logical partitions each described by an EBR relative to the extended partition
+	writeMBREntry(img[100*imageSectorSize:], 0, 0x83, 1, 20)
+	writeMBREntry(img[100*imageSectorSize:], 1, 0x05, 50, 50)
+	writeMBREntry(img[150*imageSectorSize:], 0, 0x07, 1, 30)
+
+	partitions, err := readPartitions(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []imagePartition{
+		{Number: 1, Offset: 2 * imageSectorSize, Size: 10 * imageSectorSize},
+		{Number: 3, Offset: 100 * imageSectorSize, Size: 100 * imageSectorSize},
+		{Number: 5, Offset: 101 * imageSectorSize, Size: 20 * imageSectorSize},
+		{Number: 6, Offset: 151 * imageSectorSize, Size: 30 * imageSectorSize},
+	}
+	if len(partitions) != len(expected) {
+		t.Fatal("expected", expected, "got", partitions)
+	}
+	for i := range expected {
+		if partitions[i] != expected[i] {
+			t.Error("expected", expected[i], "got", partitions[i])
+		}
+	}
+}
+
+func TestReadPartitionsGPT(t *testing.T) {
+	img := make([]byte, 64*imageSectorSize)
+	writeMBREntry(img, 0, 0xee, 1, 63)
+
+	header := img[imageSectorSize:]
+	copy(header, "EFI PART")
+	binary.LittleEndian.PutUint64(header[72:], 2)
+	binary.LittleEndian.PutUint32(header[80:], 4)
+	binary.LittleEndian.PutUint32(header[84:], 128)
+
+	// Slot 1 is empty so the second entry is partition 2 as Linux numbers them
+	entry := img[2*imageSectorSize+128:]
+	entry[0] = 1
+	binary.LittleEndian.PutUint64(entry[32:], 34)
+	binary.LittleEndian.PutUint64(entry[40:], 63)
+
+	partitions, err := readPartitions(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 1 || partitions[0] != (imagePartition{Number: 2, Offset: 34 * imageSectorSize, Size: 30 * imageSectorSize}) {
+		t.Error("unexpected partitions", partitions)
+	}
+}
+
+func TestReadPartitionsMissing(t *testing.T) {
+	if _, err := readPartitions(bytes.NewReader(make([]byte, imageSectorSize))); err == nil {
+		t.Error("expected error without a partition table")
+	}
+}
+
+func TestExtentReader(t *testing.T) {
+	backing := []byte("0123456789abcdef")
+	extents := []imageExtent{{Offset: 10, Length: 4}, {Offset: -1, Length: 3}, {Offset: 2, Length: 6}}
+
+	out, err := io.ReadAll(newExtentReader(bytes.NewReader(backing), extents, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abcd\x00\x00\x00234" {
+		t.Errorf("unexpected content %q", out)
+	}
+
+	// Running out of extents before the size is reached means the file is truncated
+	if _, err := io.ReadAll(newExtentReader(bytes.NewReader(backing), extents, 20)); err != io.ErrUnexpectedEOF {
+		t.Error("expected unexpected EOF got", err)
+	}
+}
+
+func TestProcessImageExt4(t *testing.T) {
+	mkfs, err := exec.LookPath("mkfs.ext4")
+	if err != nil {
+		t.Skip("mkfs.ext4 is not available")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	_ = os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	_ = os.WriteFile(filepath.Join(src, "a.txt"), []byte("hello\n"), 0644)
+	_ = os.WriteFile(filepath.Join(src, "sub", "b.bin"), bytes.Repeat([]byte("hashit"), 100_000), 0644)
+
+	img := filepath.Join(dir, "fs.img")
+	if out, err := exec.Command(mkfs, "-q", "-d", src, img, "8M").CombinedOutput(); err != nil {
+		t.Skip("unable to create ext4 image", string(out))
+	}
+
+	Hash = []string{"md5"}
+	defer func() { Hash = []string{} }()
+
+	output := make(chan Result, 10)
+	processImage(img, 0, output)
+
+	results := map[string]Result{}
+	for r := range output {
+		results[r.File] = r
+	}
+
+	if results["a.txt"].MD5 != "b1946ac92492d2347c6235b4d2611184" {
+		t.Error("unexpected a.txt result", results["a.txt"])
+	}
+	if r := results["sub/b.bin"]; r.Bytes != 600_000 || r.Error != "" {
+		t.Error("unexpected sub/b.bin result", r)
+	}
+	if len(results) != 2 {
+		t.Error("expected two files got", results)
+	}
+}
+
+func TestProcessImageMissingPartition(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "disk.img")
+	_ = os.WriteFile(img, make([]byte, 4096), 0644)
+
+	output := make(chan Result, 1)
+	processImage(img, 1, output)
+
+	r := <-output
+	if r.Error == "" {
+		t.Error("expected an error for an image without a partition table")
+	}
+}
+
+func TestOpenExt4VolumeInvalid(t *testing.T) {
+	superblock := func(logBlockSize uint32, inodes uint32) []byte {
+		image := make([]byte, 8192)
+		sb := image[1024:]
+		binary.LittleEndian.PutUint32(sb[0x00:], inodes)
+		binary.LittleEndian.PutUint32(sb[0x18:], logBlockSize)
+		binary.LittleEndian.PutUint32(sb[0x28:], 1)
+		binary.LittleEndian.PutUint16(sb[0x38:], ext4Magic)
+		return image
+	}
+
+	image := superblock(0, 4)
+	if _, err := openExt4Volume(bytes.NewReader(image), int64(len(image))); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+
+	image = superblock(64, 4)
+	if _, err := openExt4Volume(bytes.NewReader(image), int64(len(image))); err == nil {
+		t.Error("Expected error for a block size shift that overflows")
+	}
+
+	image = superblock(0, 0xffffffff)
+	if _, err := openExt4Volume(bytes.NewReader(image), int64(len(image))); err == nil {
+		t.Error("Expected error for more groups than the image can hold")
+	}
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Check the Encoding option is one that encodeDigest understands. Unlike most options
+// this is case sensitive as hex and HEX differ.
+func validateEncoding(encoding string) error {
+	switch encoding {
+	case "", "hex", "HEX", "base64", "base32":
+		return nil
+	}
+	return fmt.Errorf("invalid encoding %s expected hex, HEX, base64 or base32", encoding)
+}
+
+// Re-encodes a lowercase hex digest, leaving anything which is not hex untouched
+func encodeDigest(digest string, encoding string) string {
+	if encoding == "HEX" {
+		return strings.ToUpper(digest)
+	}
+	raw, err := hex.DecodeString(digest)
+	if err != nil || digest == "" {
+		return digest
+	}
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(raw)
+	case "base32":
+		return base32.StdEncoding.EncodeToString(raw)
+	}
+	return digest
+}
+
+// The digest fields of the result which are hex. Entropy and the similarity digests
+// are text of their own so are not included.
+func hexDigests(res *Result) []*string {
+	return []*string{
+		&res.CRC32, &res.XxHash64, &res.MD4, &res.MD5, &res.SHA1, &res.SHA256, &res.SHA512,
+		&res.Blake2b256, &res.Blake2b512, &res.Blake3, &res.Sha3224, &res.Sha3256, &res.Sha3384,
+		&res.Sha3512, &res.Sha224, &res.Sha384, &res.Sha512256, &res.Ripemd160, &res.Whirlpool,
+		&res.SM3, &res.Streebog256, &res.Streebog512,
+	}
+}
+
+// Rewrites every digest in the result, and in the baseline result it was compared with
+func encodeResult(res *Result, encoding string) {
+	for _, d := range hexDigests(res) {
+		*d = encodeDigest(*d, encoding)
+	}
+	if len(res.Custom) != 0 {
+		custom := make(map[string]string, len(res.Custom))
+		for name, digest := range res.Custom {
+			custom[name] = encodeDigest(digest, encoding)
+		}
+		res.Custom = custom
+	}
+	if res.Original != nil {
+		original := *res.Original
+		encodeResult(&original, encoding)
+		res.Original = &original
+	}
+}
+
+// Renders the digests of every result with Encoding just before they are output.
+// Everything before this such as baselines, known files and resume state keeps
+// working with hex.
+func encodeDigests(input chan Result) chan Result {
+	if Encoding == "" || Encoding == "hex" {
+		return input
+	}
+
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			encodeResult(&res, Encoding)
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
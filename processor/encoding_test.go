@@ -0,0 +1,47 @@
+package processor
+
+import "testing"
+
+func TestEncodeDigest(t *testing.T) {
+	digest := "5d41402abc4b2a76b9719d911017c592"
+	cases := map[string]string{
+		"hex":    digest,
+		"HEX":    "5D41402ABC4B2A76B9719D911017C592",
+		"base64": "XUFAKrxLKna5cZ2REBfFkg==",
+		"base32": "LVAUAKV4JMVHNOLRTWIRAF6FSI======",
+	}
+	for encoding, expected := range cases {
+		if got := encodeDigest(digest, encoding); got != expected {
+			t.Errorf("%s expected %s got %s", encoding, expected, got)
+		}
+	}
+
+	if got := encodeDigest("", "base64"); got != "" {
+		t.Errorf("expected an empty digest to stay empty got %s", got)
+	}
+	if err := validateEncoding("Base64"); err == nil {
+		t.Error("expected encodings to be case sensitive")
+	}
+}
+
+func TestEncodeResult(t *testing.T) {
+	res := Result{
+		File:     "hello",
+		MD5:      "5d41402abc4b2a76b9719d911017c592",
+		Ssdeep:   "3:iKn:b",
+		Custom:   map[string]string{"fnv64a": "a430d84680aabd0b"},
+		Original: &Result{MD5: "7d793037a0760186574b0282f2f435e7"},
+	}
+	original := res.Original
+
+	encodeResult(&res, "base64")
+	if res.MD5 != "XUFAKrxLKna5cZ2REBfFkg==" || res.Custom["fnv64a"] != "pDDYRoCqvQs=" {
+		t.Errorf("expected digests encoded got %+v", res)
+	}
+	if res.Ssdeep != "3:iKn:b" {
+		t.Errorf("expected ssdeep left alone got %s", res.Ssdeep)
+	}
+	if res.Original.MD5 != "fXkwN6B2AYZXSwKC8vQ15w==" || original.MD5 != "7d793037a0760186574b0282f2f435e7" {
+		t.Errorf("expected a re-encoded copy of the original got %+v %+v", res.Original, original)
+	}
+}
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Prefix of --encrypt values naming an age recipient
+const ageScheme = "age:"
+
+// Every age file starts with this line, encrypted manifests are recognised by it
+var ageMagic = []byte("age-encryption.org/")
+
+// The recipients parsed from Encrypt the output is encrypted to
+var encryptRecipients []age.Recipient
+
+// Parses every --encrypt recipient. A recipient is an age1 public key, a file of them such
+// as one written by keys generate --type age, or the name of a key in the keys directory.
+func validateEncrypt() error {
+	encryptRecipients = nil
+	if len(Encrypt) == 0 {
+		return nil
+	}
+	switch {
+	case FileOutput == "" && PerRootOutput == "":
+		return errors.New("--encrypt requires --output or --per-root-output")
+	case Append:
+		return errors.New("--encrypt cannot be combined with --append as the existing manifest cannot be read back")
+	}
+
+	for _, e := range Encrypt {
+		if !strings.HasPrefix(e, ageScheme) {
+			return fmt.Errorf("invalid encryption %s expected age:RECIPIENT", e)
+		}
+		recipients, err := parseAgeRecipients(strings.TrimPrefix(e, ageScheme))
+		if err != nil {
+			return fmt.Errorf("invalid --encrypt %s: %w", e, err)
+		}
+		encryptRecipients = append(encryptRecipients, recipients...)
+	}
+	return nil
+}
+
+func parseAgeRecipients(value string) ([]age.Recipient, error) {
+	if strings.HasPrefix(value, "age1") {
+		r, err := age.ParseX25519Recipient(value)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{r}, nil
+	}
+
+	path := resolveKeyFile(value, keyExtensions["age"][1])
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return age.ParseRecipients(file)
+}
+
+// Wraps the output file so everything written is encrypted to the recipients, closing it
+// finishes the stream without closing w
+func encryptWriter(w io.Writer) (io.WriteCloser, error) {
+	if len(encryptRecipients) == 0 {
+		return nopWriteCloser{w}, nil
+	}
+	return age.Encrypt(w, encryptRecipients...)
+}
+
+// Manifests written with --encrypt can only be read by whoever holds the identity, so
+// rather than failing to parse them the reader is told to decrypt them first
+func checkEncryptedManifest(data []byte) error {
+	if bytes.HasPrefix(data, ageMagic) || bytes.HasPrefix(data, []byte("-----BEGIN AGE ENCRYPTED FILE-----")) {
+		return errors.New("manifest is age encrypted, decrypt it with age --decrypt first")
+	}
+	return nil
+}
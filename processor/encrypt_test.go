@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func resetEncrypt(t *testing.T) {
+	previousEncrypt, previousCompress, previousOutput, previousAppend := Encrypt, Compress, FileOutput, Append
+	t.Cleanup(func() {
+		Encrypt, Compress, FileOutput, Append = previousEncrypt, previousCompress, previousOutput, previousAppend
+		encryptRecipients = nil
+	})
+}
+
+func TestEncryptedOutputRoundTrip(t *testing.T) {
+	resetEncrypt(t)
+	identity, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+
+	content := "d41d8cd98f00b204e9800998ecf8427e  empty\n"
+	for _, c := range []string{"", "gzip"} {
+		output := filepath.Join(t.TempDir(), "manifest")
+		Encrypt = []string{"age:" + identity.Recipient().String(), "age:" + other.Recipient().String()}
+		Compress, FileOutput, Append = c, output, false
+		if err := validateEncrypt(); err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+		if err := writeOutputTo(output, content); err != nil {
+			t.Fatalf("Unexpected error %s", err.Error())
+		}
+
+		data, _ := os.ReadFile(output)
+		if bytes.Contains(data, []byte("empty")) {
+			t.Errorf("Expected %q output to be encrypted", c)
+		}
+		if _, err := readManifest(output); err == nil || !strings.Contains(err.Error(), "age encrypted") {
+			t.Errorf("Expected reading the encrypted manifest to explain it is encrypted got %v", err)
+		}
+
+		// Either recipient can decrypt it
+		for _, id := range []*age.X25519Identity{identity, other} {
+			r, err := age.Decrypt(bytes.NewReader(data), id)
+			if err != nil {
+				t.Fatalf("Unexpected error %s", err.Error())
+			}
+			plain, _ := io.ReadAll(r)
+			out, err := decompressManifest(plain)
+			if err != nil || string(out) != content {
+				t.Errorf("Expected %q round trip to give %q got %q %v", c, content, out, err)
+			}
+		}
+	}
+}
+
+func TestEncryptToKeyName(t *testing.T) {
+	resetEncrypt(t)
+	dir := t.TempDir()
+	t.Setenv("HASHIT_KEYS_DIR", dir)
+	if _, err := generateKey(dir, "audit", "age", ""); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	Encrypt, FileOutput, Append = []string{"age:audit"}, "manifest", false
+	if err := validateEncrypt(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(encryptRecipients) != 1 {
+		t.Errorf("Expected one recipient from the key got %d", len(encryptRecipients))
+	}
+}
+
+func TestValidateEncrypt(t *testing.T) {
+	resetEncrypt(t)
+	identity, _ := age.GenerateX25519Identity()
+	recipient := "age:" + identity.Recipient().String()
+
+	cases := []struct {
+		encrypt []string
+		output  string
+		append  bool
+	}{
+		{[]string{recipient}, "", false},
+		{[]string{recipient}, "manifest", true},
+		{[]string{identity.Recipient().String()}, "manifest", false},
+		{[]string{"age:age1notarecipient"}, "manifest", false},
+		{[]string{"age:missing-key"}, "manifest", false},
+	}
+	for _, c := range cases {
+		Encrypt, FileOutput, Append = c.encrypt, c.output, c.append
+		if err := validateEncrypt(); err == nil {
+			t.Errorf("Expected error for %v output %q append %v", c.encrypt, c.output, c.append)
+		}
+	}
+}
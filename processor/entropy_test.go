@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEntropy(t *testing.T) {
+	random := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+
+	var cases = []struct {
+		input    []byte
+		expected string
+	}{
+		{nil, "0.0000"},
+		{[]byte(strings.Repeat("a", 100)), "0.0000"},
+		{[]byte("abababab"), "1.0000"},
+		{[]byte("abcd"), "2.0000"},
+		{all, "8.0000"},
+		{random, "7.9998"},
+	}
+
+	for _, c := range cases {
+		d := newEntropy()
+		d.Write(c.input)
+		if res := string(d.Sum(nil)); res != c.expected {
+			t.Errorf("Expected %s got %s", c.expected, res)
+		}
+	}
+}
+
+func TestAllLeavesOutAnalysis(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+
+	Hash = []string{"all"}
+	if !hasHash(HashNames.SHA256) || hasHash(HashNames.Entropy) || hasHash(HashNames.Ssdeep) || hasHash(HashNames.TLSH) {
+		t.Error("expected all to select checksums but not entropy or similarity digests")
+	}
+
+	Hash = []string{"all", "tlsh"}
+	if !hasHash(HashNames.TLSH) || hasHash(HashNames.Ssdeep) {
+		t.Error("expected tlsh to be selected when named alongside all")
+	}
+}
@@ -0,0 +1,356 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	ext4Magic           = 0xef53
+	ext4RootInode       = 2
+	ext4Feature64Bit    = 0x80
+	ext4FlagExtents     = 0x80000
+	ext4FlagInlineData  = 0x10000000
+	ext4ExtentMagic     = 0xf30a
+	ext4ModeType        = 0xf000
+	ext4ModeDirectory   = 0x4000
+	ext4ModeRegular     = 0x8000
+	ext4InlineDataBytes = 60
+)
+
+// The parts of an ext2, ext3 or ext4 superblock needed to find inodes and blocks
+type ext4Volume struct {
+	r              io.ReaderAt
+	blockSize      int64
+	inodesPerGroup uint32
+	inodeSize      int64
+	inodeTables    []int64
+}
+
+type ext4Inode struct {
+	mode  uint16
+	size  int64
+	flags uint32
+	block []byte
+}
+
+func openExt4Volume(r io.ReaderAt, size int64) (*ext4Volume, error) {
+	sb := make([]byte, 1024)
+	if _, err := r.ReadAt(sb, 1024); err != nil {
+		return nil, fmt.Errorf("unable to read ext superblock: %w", err)
+	}
+	if binary.LittleEndian.Uint16(sb[0x38:]) != ext4Magic {
+		return nil, errors.New("invalid ext superblock")
+	}
+
+	// Block sizes run from 1KB to 64KB, a larger shift would overflow
+	logBlockSize := binary.LittleEndian.Uint32(sb[0x18:])
+	if logBlockSize > 6 {
+		return nil, fmt.Errorf("invalid ext block size 2^%d", 10+logBlockSize)
+	}
+
+	v := &ext4Volume{
+		r:              r,
+		blockSize:      1024 << logBlockSize,
+		inodesPerGroup: binary.LittleEndian.Uint32(sb[0x28:]),
+		inodeSize:      128,
+	}
+	if binary.LittleEndian.Uint32(sb[0x4c:]) >= 1 {
+		v.inodeSize = int64(binary.LittleEndian.Uint16(sb[0x58:]))
+	}
+
+	inodes := binary.LittleEndian.Uint32(sb[0x00:])
+	if v.inodesPerGroup == 0 || v.blockSize < 1024 || v.blockSize > 65536 || v.inodeSize < 128 {
+		return nil, errors.New("invalid ext geometry")
+	}
+	groups := int64((inodes + v.inodesPerGroup - 1) / v.inodesPerGroup)
+
+	descSize := int64(32)
+	incompat := binary.LittleEndian.Uint32(sb[0x60:])
+	if incompat&ext4Feature64Bit != 0 {
+		if size := int64(binary.LittleEndian.Uint16(sb[0xfe:])); size >= 64 {
+			descSize = size
+		}
+	}
+
+	// The group descriptors follow the block holding the superblock. Every group takes at
+	// least a block so a count the image cannot hold is corrupt and not worth allocating for.
+	firstDataBlock := int64(binary.LittleEndian.Uint32(sb[0x14:]))
+	if groups > size/v.blockSize || (firstDataBlock+1)*v.blockSize+groups*descSize > size {
+		return nil, fmt.Errorf("invalid ext group count %d for a %d byte image", groups, size)
+	}
+	descriptors := make([]byte, groups*descSize)
+	if _, err := r.ReadAt(descriptors, (firstDataBlock+1)*v.blockSize); err != nil {
+		return nil, fmt.Errorf("unable to read ext group descriptors: %w", err)
+	}
+
+	for g := int64(0); g < groups; g++ {
+		d := descriptors[g*descSize:]
+		table := int64(binary.LittleEndian.Uint32(d[0x08:]))
+		if descSize >= 64 {
+			table |= int64(binary.LittleEndian.Uint32(d[0x28:])) << 32
+		}
+		v.inodeTables = append(v.inodeTables, table)
+	}
+	return v, nil
+}
+
+func (v *ext4Volume) inode(number uint32) (ext4Inode, error) {
+	group := int64((number - 1) / v.inodesPerGroup)
+	if number == 0 || group >= int64(len(v.inodeTables)) {
+		return ext4Inode{}, fmt.Errorf("invalid inode %d", number)
+	}
+	index := int64((number - 1) % v.inodesPerGroup)
+
+	b := make([]byte, 128)
+	if _, err := v.r.ReadAt(b, v.inodeTables[group]*v.blockSize+index*v.inodeSize); err != nil {
+		return ext4Inode{}, fmt.Errorf("unable to read inode %d: %w", number, err)
+	}
+
+	size := int64(binary.LittleEndian.Uint32(b[0x04:])) | int64(binary.LittleEndian.Uint32(b[0x6c:]))<<32
+	return ext4Inode{
+		mode:  binary.LittleEndian.Uint16(b[0x00:]),
+		size:  size,
+		flags: binary.LittleEndian.Uint32(b[0x20:]),
+		block: b[0x28 : 0x28+ext4InlineDataBytes],
+	}, nil
+}
+
+// Maps the blocks of an inode to where they are in the image with gaps as holes
+func (v *ext4Volume) extents(in ext4Inode) ([]imageExtent, error) {
+	type mapped struct {
+		logical, physical, length int64
+		hole                      bool
+	}
+	runs := []mapped{}
+
+	if in.flags&ext4FlagExtents != 0 {
+		var walk func(node []byte, depth int) error
+		walk = func(node []byte, depth int) error {
+			if len(node) < 12 || binary.LittleEndian.Uint16(node) != ext4ExtentMagic || depth > 5 {
+				return errors.New("corrupt extent tree")
+			}
+			entries := int(binary.LittleEndian.Uint16(node[2:]))
+			leaf := binary.LittleEndian.Uint16(node[6:]) == 0
+			if 12+entries*12 > len(node) {
+				return errors.New("corrupt extent tree")
+			}
+
+			for i := 0; i < entries; i++ {
+				e := node[12+i*12:]
+				if leaf {
+					length := int64(binary.LittleEndian.Uint16(e[4:]))
+					// Lengths over 32768 mark preallocated blocks which read as zeros
+					hole := length > 32768
+					if hole {
+						length -= 32768
+					}
+					runs = append(runs, mapped{
+						logical:  int64(binary.LittleEndian.Uint32(e)),
+						physical: int64(binary.LittleEndian.Uint16(e[6:]))<<32 | int64(binary.LittleEndian.Uint32(e[8:])),
+						length:   length,
+						hole:     hole,
+					})
+					continue
+				}
+
+				child := make([]byte, v.blockSize)
+				block := int64(binary.LittleEndian.Uint16(e[8:]))<<32 | int64(binary.LittleEndian.Uint32(e[4:]))
+				if _, err := v.r.ReadAt(child, block*v.blockSize); err != nil {
+					return err
+				}
+				if err := walk(child, depth+1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := walk(in.block, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		// ext2 and ext3 use 12 direct blocks then single, double and triple indirect blocks
+		blocks := (in.size + v.blockSize - 1) / v.blockSize
+		perBlock := v.blockSize / 4
+		logical := int64(0)
+
+		var walk func(block uint32, level int) error
+		walk = func(block uint32, level int) error {
+			span := int64(1)
+			for i := 0; i < level; i++ {
+				span *= perBlock
+			}
+			if block == 0 || level == 0 {
+				if block != 0 {
+					runs = append(runs, mapped{logical: logical, physical: int64(block), length: 1})
+				}
+				logical += span
+				return nil
+			}
+
+			pointers := make([]byte, v.blockSize)
+			if _, err := v.r.ReadAt(pointers, int64(block)*v.blockSize); err != nil {
+				return err
+			}
+			for i := int64(0); i < perBlock && logical < blocks; i++ {
+				if err := walk(binary.LittleEndian.Uint32(pointers[i*4:]), level-1); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for i := 0; i < 15 && logical < blocks; i++ {
+			level := 0
+			if i >= 12 {
+				level = i - 11
+			}
+			if err := walk(binary.LittleEndian.Uint32(in.block[i*4:]), level); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].logical < runs[j].logical })
+
+	extents := []imageExtent{}
+	next := int64(0)
+	for _, run := range runs {
+		if run.logical > next {
+			extents = append(extents, imageExtent{Offset: -1, Length: (run.logical - next) * v.blockSize})
+		}
+		offset := run.physical * v.blockSize
+		if run.hole {
+			offset = -1
+		}
+		if last := len(extents) - 1; last >= 0 && offset >= 0 && extents[last].Offset >= 0 && extents[last].Offset+extents[last].Length == offset {
+			extents[last].Length += run.length * v.blockSize
+		} else {
+			extents = append(extents, imageExtent{Offset: offset, Length: run.length * v.blockSize})
+		}
+		next = run.logical + run.length
+	}
+
+	// Anything past the last mapped block is a hole up to the size of the file
+	if end := next * v.blockSize; end < in.size {
+		extents = append(extents, imageExtent{Offset: -1, Length: in.size - end})
+	}
+	return extents, nil
+}
+
+// Opens the content of an inode whether it is stored in blocks or inline in the inode
+func (v *ext4Volume) open(in ext4Inode) (io.Reader, error) {
+	if in.flags&ext4FlagInlineData != 0 {
+		if in.size > ext4InlineDataBytes {
+			return nil, errors.New("inline data stored in extended attributes is not supported")
+		}
+		return bytes.NewReader(in.block[:in.size]), nil
+	}
+
+	extents, err := v.extents(in)
+	if err != nil {
+		return nil, err
+	}
+	return newExtentReader(v.r, extents, in.size), nil
+}
+
+type ext4DirEntry struct {
+	inode uint32
+	name  string
+}
+
+func (v *ext4Volume) readDirectory(in ext4Inode) ([]ext4DirEntry, error) {
+	reader, err := v.open(in)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inline directories start with the parent inode rather than . and .. entries
+	if in.flags&ext4FlagInlineData != 0 && len(data) >= 4 {
+		data = data[4:]
+	}
+
+	entries := []ext4DirEntry{}
+	for offset := 0; offset+8 <= len(data); {
+		inode := binary.LittleEndian.Uint32(data[offset:])
+		recordLength := int(binary.LittleEndian.Uint16(data[offset+4:]))
+		nameLength := int(data[offset+6])
+		if recordLength < 8 || offset+8+nameLength > len(data) {
+			break
+		}
+
+		name := string(data[offset+8 : offset+8+nameLength])
+		if inode != 0 && name != "." && name != ".." {
+			entries = append(entries, ext4DirEntry{inode: inode, name: name})
+		}
+		offset += recordLength
+	}
+	return entries, nil
+}
+
+// Lists every regular file in an ext2, ext3 or ext4 filesystem of size bytes
+func ext4Files(r io.ReaderAt, size int64) ([]imageFile, error) {
+	v, err := openExt4Volume(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []imageFile{}
+	visited := map[uint32]bool{}
+
+	var walk func(number uint32, dir string) error
+	walk = func(number uint32, dir string) error {
+		if visited[number] {
+			return nil
+		}
+		visited[number] = true
+
+		in, err := v.inode(number)
+		if err != nil {
+			return err
+		}
+		entries, err := v.readDirectory(in)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			path := e.name
+			if dir != "" {
+				path = dir + "/" + e.name
+			}
+
+			child, err := v.inode(e.inode)
+			if err != nil {
+				return err
+			}
+
+			switch child.mode & ext4ModeType {
+			case ext4ModeDirectory:
+				if err := walk(e.inode, path); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			case ext4ModeRegular:
+				files = append(files, imageFile{
+					Path: path,
+					Size: child.size,
+					open: func() (io.Reader, error) { return v.open(child) },
+				})
+			}
+		}
+		return nil
+	}
+
+	if err := walk(ext4RootInode, ""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
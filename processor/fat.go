@@ -0,0 +1,349 @@
+package processor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	fatAttrVolume    = 0x08
+	fatAttrDirectory = 0x10
+	fatAttrLongName  = 0x0f
+)
+
+// The layout of a FAT12, FAT16 or FAT32 filesystem read from its boot sector
+type fatVolume struct {
+	r              io.ReaderAt
+	bits           int
+	bytesPerSector int64
+	clusterSize    int64
+	rootOffset     int64
+	rootSize       int64
+	rootCluster    uint32
+	dataOffset     int64
+	clusters       uint32
+	table          []byte
+}
+
+type fatEntry struct {
+	name    string
+	dir     bool
+	cluster uint32
+	size    int64
+}
+
+// Checks the BIOS parameter block looks like FAT rather than some other boot sector
+func isFATBootSector(boot []byte) bool {
+	switch binary.LittleEndian.Uint16(boot[11:]) {
+	case 512, 1024, 2048, 4096:
+	default:
+		return false
+	}
+	perCluster := boot[13]
+	return perCluster != 0 && perCluster&(perCluster-1) == 0 &&
+		binary.LittleEndian.Uint16(boot[14:]) != 0 && boot[16] != 0 && boot[21] >= 0xf0
+}
+
+func openFATVolume(r io.ReaderAt) (*fatVolume, error) {
+	boot := make([]byte, 512)
+	if _, err := r.ReadAt(boot, 0); err != nil {
+		return nil, fmt.Errorf("unable to read FAT boot sector: %w", err)
+	}
+	if !isFATBootSector(boot) {
+		return nil, errors.New("invalid FAT boot sector")
+	}
+
+	bytesPerSector := int64(binary.LittleEndian.Uint16(boot[11:]))
+	perCluster := int64(boot[13])
+	reserved := int64(binary.LittleEndian.Uint16(boot[14:]))
+	fats := int64(boot[16])
+	rootEntries := int64(binary.LittleEndian.Uint16(boot[17:]))
+
+	total := int64(binary.LittleEndian.Uint16(boot[19:]))
+	if total == 0 {
+		total = int64(binary.LittleEndian.Uint32(boot[32:]))
+	}
+	fatSize := int64(binary.LittleEndian.Uint16(boot[22:]))
+	if fatSize == 0 {
+		fatSize = int64(binary.LittleEndian.Uint32(boot[36:]))
+	}
+
+	rootSectors := (rootEntries*32 + bytesPerSector - 1) / bytesPerSector
+	dataSector := reserved + fats*fatSize + rootSectors
+	if total <= dataSector {
+		return nil, errors.New("invalid FAT geometry")
+	}
+
+	v := &fatVolume{
+		r:              r,
+		bytesPerSector: bytesPerSector,
+		clusterSize:    perCluster * bytesPerSector,
+		rootOffset:     (reserved + fats*fatSize) * bytesPerSector,
+		rootSize:       rootSectors * bytesPerSector,
+		dataOffset:     dataSector * bytesPerSector,
+		clusters:       uint32((total - dataSector) / perCluster),
+	}
+
+	// The type is decided only by the number of clusters, never by the label in the boot sector
+	switch {
+	case v.clusters < 4085:
+		v.bits = 12
+	case v.clusters < 65525:
+		v.bits = 16
+	default:
+		v.bits = 32
+		v.rootCluster = binary.LittleEndian.Uint32(boot[44:])
+	}
+
+	v.table = make([]byte, fatSize*bytesPerSector)
+	if _, err := r.ReadAt(v.table, reserved*bytesPerSector); err != nil {
+		return nil, fmt.Errorf("unable to read file allocation table: %w", err)
+	}
+	return v, nil
+}
+
+// Returns the cluster following c in its chain and whether there is one
+func (v *fatVolume) next(c uint32) (uint32, bool) {
+	var n uint32
+	switch v.bits {
+	case 12:
+		offset := int(c + c/2)
+		if offset+1 >= len(v.table) {
+			return 0, false
+		}
+		n = uint32(binary.LittleEndian.Uint16(v.table[offset:]))
+		if c%2 == 1 {
+			n >>= 4
+		}
+		n &= 0xfff
+		if n >= 0xff7 {
+			return 0, false
+		}
+	case 16:
+		if int(c)*2+1 >= len(v.table) {
+			return 0, false
+		}
+		n = uint32(binary.LittleEndian.Uint16(v.table[c*2:]))
+		if n >= 0xfff7 {
+			return 0, false
+		}
+	default:
+		if int(c)*4+3 >= len(v.table) {
+			return 0, false
+		}
+		n = binary.LittleEndian.Uint32(v.table[c*4:]) & 0x0fffffff
+		if n >= 0x0ffffff7 {
+			return 0, false
+		}
+	}
+	return n, n >= 2
+}
+
+// Follows a cluster chain merging neighbouring clusters into a single extent
+func (v *fatVolume) chain(start uint32) ([]imageExtent, error) {
+	extents := []imageExtent{}
+	c := start
+	for count := uint32(0); c >= 2; count++ {
+		if count > v.clusters || c-2 >= v.clusters {
+			return nil, fmt.Errorf("corrupt cluster chain starting at %d", start)
+		}
+
+		offset := v.dataOffset + int64(c-2)*v.clusterSize
+		if last := len(extents) - 1; last >= 0 && extents[last].Offset+extents[last].Length == offset {
+			extents[last].Length += v.clusterSize
+		} else {
+			extents = append(extents, imageExtent{Offset: offset, Length: v.clusterSize})
+		}
+
+		var ok bool
+		if c, ok = v.next(c); !ok {
+			break
+		}
+	}
+	return extents, nil
+}
+
+func (v *fatVolume) readDirectory(cluster uint32) ([]fatEntry, error) {
+	var extents []imageExtent
+	if cluster == 0 && v.bits != 32 {
+		extents = []imageExtent{{Offset: v.rootOffset, Length: v.rootSize}}
+	} else {
+		var err error
+		if extents, err = v.chain(cluster); err != nil {
+			return nil, err
+		}
+	}
+
+	var size int64
+	for _, e := range extents {
+		size += e.Length
+	}
+	data, err := io.ReadAll(newExtentReader(v.r, extents, size))
+	if err != nil {
+		return nil, err
+	}
+	return parseFATDirectory(data), nil
+}
+
+// Checksum of the short name which long name entries carry to show they belong to it
+func fatShortNameChecksum(name []byte) byte {
+	var sum byte
+	for _, b := range name[:11] {
+		sum = (sum>>1 | sum<<7) + b
+	}
+	return sum
+}
+
+func parseFATDirectory(data []byte) []fatEntry {
+	entries := []fatEntry{}
+	var long []uint16
+	var longChecksum byte
+
+	for offset := 0; offset+32 <= len(data); offset += 32 {
+		e := data[offset : offset+32]
+		if e[0] == 0x00 {
+			break
+		}
+		if e[0] == 0xe5 {
+			long = nil
+			continue
+		}
+
+		attr := e[11]
+		if attr&0x3f == fatAttrLongName {
+			// Long name parts are stored last first, each holding 13 UTF-16 characters
+			part := make([]uint16, 0, 13)
+			for _, r := range [][2]int{{1, 11}, {14, 26}, {28, 32}} {
+				for i := r[0]; i < r[1]; i += 2 {
+					part = append(part, binary.LittleEndian.Uint16(e[i:]))
+				}
+			}
+			if e[0]&0x40 != 0 {
+				long = nil
+				longChecksum = e[13]
+			}
+			long = append(part, long...)
+			continue
+		}
+
+		if attr&fatAttrVolume != 0 {
+			long = nil
+			continue
+		}
+
+		name := fatShortName(e)
+		if long != nil && longChecksum == fatShortNameChecksum(e) {
+			for i, c := range long {
+				if c == 0 || c == 0xffff {
+					long = long[:i]
+					break
+				}
+			}
+			name = string(utf16.Decode(long))
+		}
+		long = nil
+
+		if name == "." || name == ".." {
+			continue
+		}
+
+		entries = append(entries, fatEntry{
+			name:    name,
+			dir:     attr&fatAttrDirectory != 0,
+			cluster: uint32(binary.LittleEndian.Uint16(e[20:]))<<16 | uint32(binary.LittleEndian.Uint16(e[26:])),
+			size:    int64(binary.LittleEndian.Uint32(e[28:])),
+		})
+	}
+	return entries
+}
+
+// Builds the 8.3 name applying the lower case flags Windows NT sets for names like readme.txt
+func fatShortName(e []byte) string {
+	base := []byte(strings.TrimRight(string(e[0:8]), " "))
+	if len(base) > 0 && base[0] == 0x05 {
+		base[0] = 0xe5
+	}
+	ext := strings.TrimRight(string(e[8:11]), " ")
+
+	name := string(base)
+	if e[12]&0x08 != 0 {
+		name = strings.ToLower(name)
+	}
+	if e[12]&0x10 != 0 {
+		ext = strings.ToLower(ext)
+	}
+	if ext != "" {
+		name += "." + ext
+	}
+	return name
+}
+
+// Lists every file in a FAT12, FAT16 or FAT32 filesystem
+func fatFiles(r io.ReaderAt) ([]imageFile, error) {
+	v, err := openFATVolume(r)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []imageFile{}
+	visited := map[uint32]bool{}
+
+	var walk func(cluster uint32, dir string) error
+	walk = func(cluster uint32, dir string) error {
+		if cluster != 0 {
+			if visited[cluster] {
+				return nil
+			}
+			visited[cluster] = true
+		}
+
+		entries, err := v.readDirectory(cluster)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			path := e.name
+			if dir != "" {
+				path = dir + "/" + e.name
+			}
+
+			if e.dir {
+				if err := walk(e.cluster, path); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				continue
+			}
+
+			entry := e
+			files = append(files, imageFile{
+				Path: path,
+				Size: entry.size,
+				open: func() (io.Reader, error) {
+					if entry.size == 0 {
+						return newExtentReader(v.r, nil, 0), nil
+					}
+					extents, err := v.chain(entry.cluster)
+					if err != nil {
+						return nil, err
+					}
+					return newExtentReader(v.r, extents, entry.size), nil
+				},
+			})
+		}
+		return nil
+	}
+
+	root := uint32(0)
+	if v.bits == 32 {
+		root = v.rootCluster
+	}
+	if err := walk(root, ""); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,234 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// Image backed by a map of sectors so a FAT32 filesystem does not need 30MB of memory
+type sectorImage map[int64][]byte
+
+func (s sectorImage) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		sector, ok := s[(off+int64(i))/512]
+		if ok {
+			p[i] = sector[(off+int64(i))%512]
+		} else {
+			p[i] = 0
+		}
+	}
+	return len(p), nil
+}
+
+func (s sectorImage) WriteAt(p []byte, off int64) {
+	for i, b := range p {
+		index := (off + int64(i)) / 512
+		if s[index] == nil {
+			s[index] = make([]byte, 512)
+		}
+		s[index][(off+int64(i))%512] = b
+	}
+}
+
+type fatBuilder struct {
+	img        sectorImage
+	bits       int
+	reserved   int64
+	fatSize    int64
+	rootOffset int64
+	dataSector int64
+	table      map[uint32]uint32
+	next       uint32
+}
+
+func newFATBuilder(bits int) *fatBuilder {
+	b := &fatBuilder{img: sectorImage{}, bits: bits, table: map[uint32]uint32{}, next: 2}
+
+	boot := make([]byte, 512)
+	binary.LittleEndian.PutUint16(boot[11:], 512)
+	boot[13] = 1
+	boot[16] = 2
+	boot[21] = 0xf8
+	boot[510], boot[511] = 0x55, 0xaa
+
+	if bits == 12 {
+		b.reserved, b.fatSize = 1, 6
+		binary.LittleEndian.PutUint16(boot[17:], 64)
+		binary.LittleEndian.PutUint16(boot[19:], 2000)
+		binary.LittleEndian.PutUint16(boot[22:], uint16(b.fatSize))
+		b.rootOffset = (b.reserved + 2*b.fatSize) * 512
+		b.dataSector = b.reserved + 2*b.fatSize + 4
+	} else {
+		b.reserved, b.fatSize = 32, 547
+		binary.LittleEndian.PutUint32(boot[32:], 70000)
+		binary.LittleEndian.PutUint32(boot[36:], uint32(b.fatSize))
+		binary.LittleEndian.PutUint32(boot[44:], 2)
+		b.dataSector = b.reserved + 2*b.fatSize
+		b.allocate(1)
+	}
+	binary.LittleEndian.PutUint16(boot[14:], uint16(b.reserved))
+	b.img.WriteAt(boot, 0)
+	return b
+}
+
+// Allocates clusters leaving a gap after each so chains are fragmented
+func (b *fatBuilder) allocate(count int) []uint32 {
+	clusters := []uint32{}
+	for i := 0; i < count; i++ {
+		clusters = append(clusters, b.next)
+		b.next += 2
+	}
+	for i, c := range clusters {
+		if i+1 < len(clusters) {
+			b.table[c] = clusters[i+1]
+		} else {
+			b.table[c] = 0x0fffffff
+		}
+	}
+	return clusters
+}
+
+func (b *fatBuilder) write(data []byte) uint32 {
+	if len(data) == 0 {
+		return 0
+	}
+	clusters := b.allocate((len(data) + 511) / 512)
+	for i, c := range clusters {
+		end := (i + 1) * 512
+		if end > len(data) {
+			end = len(data)
+		}
+		b.img.WriteAt(data[i*512:end], (b.dataSector+int64(c)-2)*512)
+	}
+	return clusters[0]
+}
+
+func fatDirEntry(short string, attr byte, cluster uint32, size int) []byte {
+	e := make([]byte, 32)
+	copy(e, short)
+	e[11] = attr
+	binary.LittleEndian.PutUint16(e[20:], uint16(cluster>>16))
+	binary.LittleEndian.PutUint16(e[26:], uint16(cluster))
+	binary.LittleEndian.PutUint32(e[28:], uint32(size))
+	return e
+}
+
+// Builds the long name entries that go before the short entry, last part first
+func fatLongEntries(name string, short []byte) []byte {
+	units := utf16.Encode([]rune(name))
+	units = append(units, 0)
+	for len(units)%13 != 0 {
+		units = append(units, 0xffff)
+	}
+
+	parts := len(units) / 13
+	out := []byte{}
+	for p := parts; p >= 1; p-- {
+		e := make([]byte, 32)
+		e[0] = byte(p)
+		if p == parts {
+			e[0] |= 0x40
+		}
+		e[11] = fatAttrLongName
+		e[13] = fatShortNameChecksum(short)
+		chunk := units[(p-1)*13 : p*13]
+		positions := []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+		for i, pos := range positions {
+			binary.LittleEndian.PutUint16(e[pos:], chunk[i])
+		}
+		out = append(out, e...)
+	}
+	return out
+}
+
+func (b *fatBuilder) finish(root []byte) sectorImage {
+	if b.bits == 12 {
+		b.img.WriteAt(root, b.rootOffset)
+	} else {
+		b.img.WriteAt(root, b.dataSector*512)
+	}
+
+	table := make([]byte, b.fatSize*512)
+	for c, n := range b.table {
+		if b.bits == 12 {
+			if n == 0x0fffffff {
+				n = 0xfff
+			}
+			offset := c + c/2
+			v := binary.LittleEndian.Uint16(table[offset:])
+			if c%2 == 1 {
+				v = v&0x000f | uint16(n)<<4
+			} else {
+				v = v&0xf000 | uint16(n)
+			}
+			binary.LittleEndian.PutUint16(table[offset:], v)
+		} else {
+			binary.LittleEndian.PutUint32(table[c*4:], n)
+		}
+	}
+	b.img.WriteAt(table, b.reserved*512)
+	return b.img
+}
+
+func TestFatFiles(t *testing.T) {
+	for _, bits := range []int{12, 32} {
+		b := newFATBuilder(bits)
+
+		longContent := bytes.Repeat([]byte("long name content "), 80)
+		readme := []byte("read me\n")
+		note := []byte("a note")
+
+		docs := fatDirEntry(".          ", fatAttrDirectory, 0, 0)
+		docs = append(docs, fatDirEntry("..         ", fatAttrDirectory, 0, 0)...)
+		deleted := fatDirEntry("GONE    TXT", 0, 0, 0)
+		deleted[0] = 0xe5
+		docs = append(docs, deleted...)
+		docs = append(docs, fatDirEntry("NOTE    MD ", 0, b.write(note), len(note))...)
+
+		short := []byte("LONGFI~1TXT")
+		root := fatDirEntry("MY DISK    ", fatAttrVolume, 0, 0)
+		root = append(root, fatLongEntries("Long File Name.txt", short)...)
+		root = append(root, fatDirEntry(string(short), 0, b.write(longContent), len(longContent))...)
+		lower := fatDirEntry("README  TXT", 0, b.write(readme), len(readme))
+		lower[12] = 0x18
+		root = append(root, lower...)
+		root = append(root, fatDirEntry("DOCS       ", fatAttrDirectory, b.write(docs), 0)...)
+		root = append(root, fatDirEntry("EMPTY   TXT", 0, 0, 0)...)
+
+		files, err := fatFiles(b.finish(root))
+		if err != nil {
+			t.Fatal(bits, err)
+		}
+
+		contents := map[string]string{}
+		names := []string{}
+		for _, f := range files {
+			r, err := f.open()
+			if err != nil {
+				t.Fatal(bits, f.Path, err)
+			}
+			content, _ := io.ReadAll(r)
+			contents[f.Path] = string(content)
+			names = append(names, f.Path)
+		}
+		sort.Strings(names)
+
+		if strings.Join(names, ",") != "DOCS/NOTE.MD,EMPTY.TXT,Long File Name.txt,readme.txt" {
+			t.Error(bits, "unexpected files", names)
+		}
+		if contents["Long File Name.txt"] != string(longContent) || contents["readme.txt"] != string(readme) || contents["DOCS/NOTE.MD"] != string(note) || contents["EMPTY.TXT"] != "" {
+			t.Error(bits, "unexpected content")
+		}
+	}
+}
+
+func TestIsFATBootSector(t *testing.T) {
+	if isFATBootSector(make([]byte, 512)) {
+		t.Error("expected empty sector to not be FAT")
+	}
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Descriptors kept back from the limit for the walker, output files, logs and network
+const reservedFiles = 32
+
+// A worker holds its file open while the larger reads and --azure-blocks, --piece-length
+// and --zstd-frames open it again, and the slot is handed back just before it is closed
+const filesPerSlot = 3
+
+// Each worker takes a slot before opening a file so no more files are open at once than
+// the limit allows, nil when there is no limit
+var openFileSlots chan struct{}
+
+// Sizes openFileSlots from --max-open-files or the process limit when it is not set
+func prepareOpenFiles() error {
+	if MaxOpenFiles < 0 {
+		return fmt.Errorf("invalid --max-open-files %d", MaxOpenFiles)
+	}
+
+	limit := MaxOpenFiles
+	if limit == 0 {
+		limit = processFileLimit()
+		if limit == 0 {
+			openFileSlots = nil
+			return nil
+		}
+	}
+
+	slots := (limit - reservedFiles) / filesPerSlot
+	if slots < 1 {
+		if MaxOpenFiles != 0 {
+			return fmt.Errorf("--max-open-files %d is too low, at least %d are needed", MaxOpenFiles, reservedFiles+filesPerSlot)
+		}
+		slots = 1
+	}
+	if slots >= NoThreads {
+		openFileSlots = nil
+		return nil
+	}
+
+	printVerbose("limiting files open at once", "limit", limit, "workers", slots)
+	openFileSlots = make(chan struct{}, slots)
+	return nil
+}
+
+func acquireFileSlot() {
+	if openFileSlots != nil {
+		openFileSlots <- struct{}{}
+	}
+}
+
+func releaseFileSlot() {
+	if openFileSlots != nil {
+		<-openFileSlots
+	}
+}
+
+// Checks if opening failed because the process or system has run out of descriptors
+func isTooManyOpenFiles(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
+
+// How long openFile waits for descriptors to be closed before reporting the error
+var openFileRetryLimit = 30 * time.Second
+
+// Opens a file for reading, waiting and trying again when out of descriptors as they
+// are freed as soon as other files finish rather than failing the file
+func openFile(path string) (*os.File, error) {
+	wait := 10 * time.Millisecond
+	var waited time.Duration
+	for {
+		file, err := openReadOnly(longPath(path))
+		if err == nil || !isTooManyOpenFiles(err) || waited >= openFileRetryLimit {
+			return file, err
+		}
+
+		if Debug {
+			printDebug("out of file descriptors, retrying", "file", path, "wait", wait)
+		}
+		time.Sleep(wait)
+		waited += wait
+		if wait < time.Second {
+			wait *= 2
+		}
+	}
+}
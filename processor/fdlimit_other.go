@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package processor
+
+// Windows has no small per process limit on open handles
+func processFileLimit() int {
+	return 0
+}
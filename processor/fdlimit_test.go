@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPrepareOpenFiles(t *testing.T) {
+	previousThreads := NoThreads
+	defer func() {
+		MaxOpenFiles, NoThreads = 0, previousThreads
+		_ = prepareOpenFiles()
+	}()
+	NoThreads = 64
+
+	MaxOpenFiles = reservedFiles + 4*filesPerSlot
+	if err := prepareOpenFiles(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if openFileSlots == nil || cap(openFileSlots) != 4 {
+		t.Errorf("expected 4 slots got %v", openFileSlots)
+	}
+
+	MaxOpenFiles = 10000
+	if err := prepareOpenFiles(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if openFileSlots != nil {
+		t.Error("expected no limit when every worker fits")
+	}
+
+	for _, invalid := range []int{-1, reservedFiles} {
+		MaxOpenFiles = invalid
+		if prepareOpenFiles() == nil {
+			t.Errorf("expected error for --max-open-files %d", invalid)
+		}
+	}
+}
+
+func TestFileSlotsBlock(t *testing.T) {
+	defer func() { openFileSlots = nil }()
+	openFileSlots = make(chan struct{}, 1)
+
+	acquireFileSlot()
+	acquired := make(chan bool)
+	go func() {
+		acquireFileSlot()
+		acquired <- true
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected the second worker to wait for a slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	releaseFileSlot()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the slot to be handed over once released")
+	}
+	releaseFileSlot()
+}
+
+func TestIsTooManyOpenFiles(t *testing.T) {
+	err := &fs.PathError{Op: "open", Path: "a", Err: syscall.EMFILE}
+	if !isTooManyOpenFiles(fmt.Errorf("wrapped: %w", err)) {
+		t.Error("expected EMFILE to be recognised")
+	}
+	if isTooManyOpenFiles(&fs.PathError{Op: "open", Path: "a", Err: syscall.ENOENT}) {
+		t.Error("expected ENOENT not to be recognised")
+	}
+}
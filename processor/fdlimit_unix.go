@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import "syscall"
+
+// The soft limit on open descriptors, 0 when it cannot be read. Unlimited is far more
+// than any run needs so is capped.
+func processFileLimit() int {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0
+	}
+	if rlimit.Cur > 1<<20 {
+		return 1 << 20
+	}
+	return int(rlimit.Cur)
+}
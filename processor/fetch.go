@@ -0,0 +1,259 @@
+package processor
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A download listed for fetch-verify with the digest it is expected to have
+type fetchEntry struct {
+	URL    string
+	Name   string
+	Digest manifestDigest
+}
+
+// Outcome of fetching and verifying a single download
+type fetchResult struct {
+	URL      string            `json:"url"`
+	Status   string            `json:"status"`
+	Bytes    int64             `json:"bytes"`
+	Expected string            `json:"expected"`
+	Hashes   map[string]string `json:"hashes,omitempty"`
+	File     string            `json:"file,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Works out which hashes an expected digest could be from. It may be prefixed with the
+// hash name as in sha256:ab12..., be a subresource integrity value such as sha384-<base64>,
+// or be bare hex matched by length against the selected hashes as sum files are.
+func parseExpectedDigest(digest string) (manifestDigest, error) {
+	if name, value, found := strings.Cut(digest, ":"); found {
+		name = strings.ToLower(name)
+		for _, info := range HashInfos {
+			if info.Name == name && !info.Analysis {
+				return manifestDigest{Names: []string{name}, Digest: strings.ToLower(value)}, nil
+			}
+		}
+		return manifestDigest{}, fmt.Errorf("unknown hash %s in %s", name, digest)
+	}
+
+	if name, value, found := strings.Cut(digest, "-"); found {
+		switch name {
+		case HashNames.SHA256, HashNames.Sha384, HashNames.SHA512:
+			raw, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return manifestDigest{}, fmt.Errorf("invalid integrity value %s: %w", digest, err)
+			}
+			return manifestDigest{Names: []string{name}, Digest: hex.EncodeToString(raw)}, nil
+		}
+	}
+
+	names := []string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) && !info.Analysis && info.Bits/4 == len(digest) {
+			names = append(names, info.Name)
+		}
+	}
+	if len(names) == 0 {
+		return manifestDigest{}, fmt.Errorf("no selected hash produces a %d character digest, prefix it with the hash name such as sha256:%s", len(digest), digest)
+	}
+	return manifestDigest{Names: names, Digest: strings.ToLower(digest)}, nil
+}
+
+// Reads url,digest[,filename] rows skipping blank lines, # comments and a url header
+func parseFetchList(r io.Reader) ([]fetchEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	entries := []fetchEntry{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "url") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("line %d: expected url,digest[,filename]", line)
+		}
+
+		u, err := url.Parse(record[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("line %d: invalid url %s", line, record[0])
+		}
+		digest, err := parseExpectedDigest(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		name := path.Base(u.Path)
+		if len(record) == 3 && record[2] != "" {
+			name = filepath.Base(record[2])
+		}
+		if name == "." || name == "/" || name == ".." {
+			name = "download"
+		}
+		entries = append(entries, fetchEntry{URL: record[0], Name: name, Digest: digest})
+	}
+	return entries, nil
+}
+
+// Streams the download through the hashes it is expected to match, writing it into keep
+// alongside when set and only leaving it there if it verified
+func fetchVerify(client *http.Client, entry fetchEntry, keep string) fetchResult {
+	result := fetchResult{URL: entry.URL, Expected: entry.Digest.Digest}
+	fail := func(err error) fetchResult {
+		result.Status = "ERROR"
+		result.Error = err.Error()
+		return result
+	}
+
+	resp, err := client.Get(entry.URL)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("unexpected response %s", resp.Status))
+	}
+
+	body := &countingReader{r: resp.Body}
+	var reader io.Reader = body
+	var part *os.File
+	if keep != "" {
+		if part, err = os.CreateTemp(keep, "."+entry.Name+".*.part"); err != nil {
+			return fail(err)
+		}
+		defer func() {
+			// Removes the partial download unless it was renamed into place
+			_ = part.Close()
+			_ = os.Remove(part.Name())
+		}()
+		reader = io.TeeReader(body, part)
+	}
+
+	previous := Hash
+	Hash = entry.Digest.Names
+	res, err := processStream(entry.URL, limitReader(reader), nil, 0, nil, "")
+	hashes := calculatedHashes(res)
+	Hash = previous
+	if err != nil {
+		return fail(err)
+	}
+	result.Bytes = body.n
+
+	result.Hashes = map[string]string{}
+	result.Status = "FAILED"
+	for _, name := range entry.Digest.Names {
+		result.Hashes[name] = hashes[name]
+		if hashes[name] == entry.Digest.Digest {
+			result.Status = "OK"
+		}
+	}
+
+	if part != nil && result.Status == "OK" {
+		if err := part.Close(); err != nil {
+			return fail(err)
+		}
+		file := filepath.Join(keep, entry.Name)
+		if err := os.Rename(part.Name(), file); err != nil {
+			return fail(err)
+		}
+		result.File = file
+	}
+	return result
+}
+
+// FetchVerify downloads every url listed in the from CSV checking each against its
+// expected digest, keeping the verified downloads in keep when set and exiting 1 if
+// any download failed or did not match
+func FetchVerify(from string, keep string, asJSON bool) {
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	var input io.Reader = os.Stdin
+	if from != "-" {
+		file, err := os.Open(from)
+		if err != nil {
+			printError(fmt.Sprintf("failed to read download list: %s, %s", from, err.Error()))
+			os.Exit(1)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	entries, err := parseFetchList(input)
+	if err != nil {
+		printError(fmt.Sprintf("failed to parse download list: %s, %s", from, err.Error()))
+		os.Exit(1)
+	}
+
+	if keep != "" {
+		if err := os.MkdirAll(keep, 0755); err != nil {
+			printError(fmt.Sprintf("unable to create download directory %s: %s", keep, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	results := []fetchResult{}
+	failed := 0
+	for _, entry := range entries {
+		printVerbose("fetching", "url", entry.URL, "hashes", strings.Join(entry.Digest.Names, ","))
+		r := fetchVerify(httpClient, entry, keep)
+		if r.Status != "OK" {
+			failed++
+		}
+		results = append(results, r)
+
+		if !asJSON {
+			printFetchResult(r)
+		}
+	}
+
+	if asJSON {
+		out, _ := json.Marshal(results)
+		fmt.Println(string(out))
+	}
+
+	if failed != 0 {
+		printError(fmt.Sprintf("%d of %d downloads did NOT verify", failed, len(entries)))
+		os.Exit(1)
+	}
+}
+
+// Prints a download's status the way --check prints each file
+func printFetchResult(r fetchResult) {
+	switch r.Status {
+	case "ERROR":
+		fmt.Printf("%s: FAILED %s\n", r.URL, r.Error)
+	case "FAILED":
+		names := make([]string, 0, len(r.Hashes))
+		for name := range r.Hashes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("%s: FAILED expected %s got %s %s\n", r.URL, r.Expected, names[0], r.Hashes[names[0]])
+	default:
+		fmt.Printf("%s: OK\n", r.URL)
+	}
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExpectedDigest(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+
+	cases := []struct {
+		digest string
+		names  string
+		value  string
+	}{
+		{"5D41402ABC4B2A76B9719D911017C592", "md5", "5d41402abc4b2a76b9719d911017c592"},
+		{"sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", "sha1", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"sha384-WeF0h3dEjGnea4ANejO7+5/xtGPkQ1TDVTvNucZm+pASWjx5+QOXvfX2oT3oKGhP", "sha384", "59e1748777448c69de6b800d7a33bbfb9ff1b463e44354c3553bcdb9c666fa90125a3c79f90397bdf5f6a13de828684f"},
+	}
+	for _, c := range cases {
+		d, err := parseExpectedDigest(c.digest)
+		if err != nil || strings.Join(d.Names, ",") != c.names || d.Digest != c.value {
+			t.Errorf("%s expected %s %s got %+v %v", c.digest, c.names, c.value, d, err)
+		}
+	}
+
+	for _, digest := range []string{"abc", "nope:abc", "sha384-!!"} {
+		if _, err := parseExpectedDigest(digest); err == nil {
+			t.Errorf("expected an error for %s", digest)
+		}
+	}
+}
+
+func TestFetchVerify(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	list := "url,digest,filename\n" +
+		server.URL + "/hello.txt,5d41402abc4b2a76b9719d911017c592\n" +
+		"# a comment\n" +
+		server.URL + "/bad,7d793037a0760186574b0282f2f435e7,renamed.txt\n" +
+		server.URL + "/missing,5d41402abc4b2a76b9719d911017c592\n"
+	entries, err := parseFetchList(strings.NewReader(list))
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 entries got %+v %v", entries, err)
+	}
+	if entries[1].Name != "renamed.txt" {
+		t.Errorf("expected the filename column to be used got %s", entries[1].Name)
+	}
+
+	keep := t.TempDir()
+	statuses := []string{}
+	for _, e := range entries {
+		statuses = append(statuses, fetchVerify(server.Client(), e, keep).Status)
+	}
+	if strings.Join(statuses, ",") != "OK,FAILED,ERROR" {
+		t.Errorf("expected OK,FAILED,ERROR got %v", statuses)
+	}
+
+	files, _ := os.ReadDir(keep)
+	if len(files) != 1 || files[0].Name() != "hello.txt" {
+		t.Errorf("expected only the verified download kept got %v", files)
+	}
+	if content, _ := os.ReadFile(filepath.Join(keep, "hello.txt")); string(content) != "hello" {
+		t.Errorf("expected the download content kept got %q", content)
+	}
+}
@@ -1,28 +1,148 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/boyter/hashit/walker"
 )
 
-func walkDirectory(toWalk string, output chan string) {
+// Compiled versions of the Exclude patterns
+var excludeRegex = []*regexp.Regexp{}
 
-	walkErr := filepath.WalkDir(toWalk, func(root string, info os.DirEntry, err error) error {
+// Compiles the user supplied exclude patterns so they are ready for the walker
+func compileExcludes() error {
+	excludeRegex = []*regexp.Regexp{}
+	for _, e := range Exclude {
+		r, err := regexp.Compile(e)
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid exclude pattern %s: %w", e, err)
 		}
+		excludeRegex = append(excludeRegex, r)
+	}
+	return nil
+}
 
-		if !info.IsDir() {
-			output <- root
+// Check if the path matches any of the exclude patterns
+func isExcluded(path string) bool {
+	for _, r := range excludeRegex {
+		if r.MatchString(path) {
+			return true
 		}
+	}
+	return false
+}
 
-		return nil
-	})
+// Check the depth limit and prune patterns before walking anything
+func validateWalkLimits() error {
+	if MaxDepth < 0 {
+		return fmt.Errorf("invalid max depth %d expected 0 or more", MaxDepth)
+	}
+	for _, p := range Prune {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid prune pattern %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Check if the directory name matches any of the prune patterns
+func isPruned(name string) bool {
+	for _, p := range Prune {
+		if matched, _ := filepath.Match(p, name); matched {
+			return true
+		}
+	}
+	return false
+}
 
-	if walkErr != nil {
-		if Verbose {
-			printVerbose(fmt.Sprintf("error walking: %s", toWalk))
+// How many levels below the root being walked the path is, files in the root are at 1
+func walkDepth(toWalk string, path string) int {
+	rel, err := filepath.Rel(toWalk, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// Walks the directory sending every file which passes the filters to output, and
+// anything which could not be read to errorOutput
+func walkDirectory(ctx context.Context, toWalk string, output chan string, errorOutput chan Result) {
+	// Walking /proc or /dev can read files which never end so asking for one is refused
+	// rather than silently hashing nothing
+	if !IncludeVirtualFS && isVirtualFilesystem(toWalk) {
+		errorOutput <- newErrorResult(toWalk, errors.New("is on a virtual filesystem, use --include-virtual-fs to walk it"))
+		return
+	}
+
+	boundary := newFilesystemBoundary(toWalk)
+
+	// The walker works in slash separated paths relative to toWalk
+	osPath := func(p string) string {
+		if p == "." {
+			return toWalk
 		}
+		return filepath.Join(toWalk, filepath.FromSlash(p))
+	}
+
+	w := walker.Walker{
+		MaxDepth: MaxDepth,
+		// Record anything we cannot read such as permission denied or files that vanished
+		// during the walk and keep going rather than aborting everything
+		Error: func(p string, err error) {
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				pathErr.Path = osPath(pathErr.Path)
+			}
+			errorOutput <- newErrorResult(osPath(p), err)
+		},
+		Filters: []walker.Filter{
+			func(p string, info fs.DirEntry) walker.Action {
+				root := osPath(p)
+				if info.IsDir() && p != "." {
+					if isReparsePoint(info) {
+						printVerbose(fmt.Sprintf("skipping reparse point: %s", root))
+						return walker.Skip
+					}
+					if isPruned(info.Name()) {
+						printVerbose(fmt.Sprintf("pruning: %s", root))
+						return walker.Skip
+					}
+					if reason, skip := boundary.skip(root, info); skip {
+						printVerbose(fmt.Sprintf("skipping %s: %s", reason, root))
+						return walker.Skip
+					}
+				}
+
+				if isExcluded(root) {
+					printVerbose(fmt.Sprintf("excluding: %s", root))
+					return walker.Skip
+				}
+
+				if !info.IsDir() && isOutputFile(root) {
+					printVerbose(fmt.Sprintf("skipping output file: %s", root))
+					return walker.Skip
+				}
+
+				if info.IsDir() && dirCache != nil {
+					dirCache.enter(root, errorOutput)
+				}
+				return walker.Continue
+			},
+		},
 	}
+
+	_ = w.Walk(ctx, os.DirFS(toWalk), ".", func(p string, info fs.DirEntry) error {
+		root := osPath(p)
+		if passesFilters(root, info) && inShard(root) && !resumeCompleted(root) && !dirCache.reusing(root) {
+			output <- root
+		}
+		return nil
+	})
 }
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWalkDirectoryMissingProducesErrorRecord(t *testing.T) {
+	output := make(chan string, 10)
+	errorOutput := make(chan Result, 10)
+
+	walkDirectory(context.Background(), "this-path-does-not-exist", output, errorOutput)
+	close(output)
+	close(errorOutput)
+
+	if len(output) != 0 {
+		t.Errorf("Expected no files got %d", len(output))
+	}
+
+	res := <-errorOutput
+	if res.File != "this-path-does-not-exist" || res.Error == "" {
+		t.Errorf("Expected error record for missing path got %+v", res)
+	}
+}
+
+func TestWalkDirectoryMaxDepthAndPrune(t *testing.T) {
+	defer func() { MaxDepth, Prune = 0, []string{} }()
+
+	dir := t.TempDir()
+	for _, f := range []string{"top", "a/one", "a/b/two", ".git/objects/obj", "a/.git/config", "cache.d/x"} {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		_ = os.WriteFile(p, []byte(f), 0600)
+	}
+
+	walk := func() []string {
+		output := make(chan string, 10)
+		errorOutput := make(chan Result, 10)
+		walkDirectory(context.Background(), dir, output, errorOutput)
+		close(output)
+
+		files := []string{}
+		for f := range output {
+			rel, _ := filepath.Rel(dir, f)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	MaxDepth, Prune = 2, []string{}
+	if files := strings.Join(walk(), ","); files != "a/one,cache.d/x,top" {
+		t.Errorf("expected files two levels deep got %s", files)
+	}
+
+	MaxDepth, Prune = 0, []string{".git", "*.d"}
+	if files := strings.Join(walk(), ","); files != "a/b/two,a/one,top" {
+		t.Errorf("expected .git and cache.d pruned got %s", files)
+	}
+
+	MaxDepth = -1
+	if err := validateWalkLimits(); err == nil {
+		t.Error("expected an error for a negative depth")
+	}
+	MaxDepth, Prune = 0, []string{"["}
+	if err := validateWalkLimits(); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
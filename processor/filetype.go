@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Bytes looked at when detecting the type of a file, the same as http.DetectContentType
+const sniffLen = 512
+
+// A signature at a fixed offset identifying a format
+type magicSignature struct {
+	offset      int
+	magic       []byte
+	mime        string
+	description string
+}
+
+// Checked in order before falling back to http.DetectContentType which only knows web
+// formats. Executables, databases and archives are what inventories most want named.
+var magicSignatures = []magicSignature{
+	{0, []byte("\x7fELF"), "application/x-executable", "ELF executable"},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable", "DOS/Windows executable"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, "application/x-mach-binary", "Mach-O executable"},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, "application/x-mach-binary", "Mach-O executable"},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, "application/x-mach-binary", "Mach-O executable"},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, "application/x-mach-binary", "Mach-O executable"},
+	{0, []byte("\x00asm"), "application/wasm", "WebAssembly module"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3", "SQLite database"},
+	{0, []byte("%PDF-"), "application/pdf", "PDF document"},
+	{0, []byte("PK\x03\x04"), "application/zip", "Zip archive"},
+	{0, []byte("PK\x05\x06"), "application/zip", "Zip archive"},
+	{0, []byte("\x1f\x8b"), "application/gzip", "gzip compressed data"},
+	{0, []byte("BZh"), "application/x-bzip2", "bzip2 compressed data"},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz", "XZ compressed data"},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, "application/zstd", "Zstandard compressed data"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed", "7-Zip archive"},
+	{0, []byte("Rar!\x1a\x07"), "application/vnd.rar", "RAR archive"},
+	{0, []byte("!<arch>\n"), "application/x-archive", "ar archive"},
+	{257, []byte("ustar"), "application/x-tar", "POSIX tar archive"},
+	{0, []byte("\x89PNG\r\n\x1a\n"), "image/png", "PNG image"},
+	{0, []byte{0xff, 0xd8, 0xff}, "image/jpeg", "JPEG image"},
+	{0, []byte("GIF87a"), "image/gif", "GIF image"},
+	{0, []byte("GIF89a"), "image/gif", "GIF image"},
+	{0, []byte("HASHIT-MANIFEST"), "application/x-hashit-manifest", "hashit manifest"},
+}
+
+// Works out the MIME type of content from its first bytes along with a description of
+// the format when it has a known signature
+func detectType(head []byte) (string, string) {
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	if len(head) == 0 {
+		return "application/x-empty", "empty"
+	}
+
+	for _, s := range magicSignatures {
+		if len(head) >= s.offset+len(s.magic) && bytes.Equal(head[s.offset:s.offset+len(s.magic)], s.magic) {
+			return s.mime, s.description
+		}
+	}
+	return http.DetectContentType(head), ""
+}
+
+// Keeps a copy of the first bytes read through it so the type can be detected
+// without reading the start of the file a second time
+type sniffReader struct {
+	r    io.Reader
+	head []byte
+}
+
+func (s *sniffReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if remaining := sniffLen - len(s.head); remaining > 0 && n > 0 {
+		s.head = append(s.head, p[:min(n, remaining)]...)
+	}
+	return n, err
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar")
+
+	cases := []struct {
+		name        string
+		content     []byte
+		mime        string
+		description string
+	}{
+		{"empty", nil, "application/x-empty", "empty"},
+		{"elf", []byte("\x7fELF\x02\x01\x01"), "application/x-executable", "ELF executable"},
+		{"sqlite", []byte("SQLite format 3\x00rest"), "application/vnd.sqlite3", "SQLite database"},
+		{"tar", tar, "application/x-tar", "POSIX tar archive"},
+		{"png", []byte("\x89PNG\r\n\x1a\nIHDR"), "image/png", "PNG image"},
+		{"text", []byte("hello world\n"), "text/plain; charset=utf-8", ""},
+		{"html", []byte("<!DOCTYPE html><html></html>"), "text/html; charset=utf-8", ""},
+	}
+
+	for _, c := range cases {
+		mime, description := detectType(c.content)
+		if mime != c.mime || description != c.description {
+			t.Errorf("%s expected %q %q got %q %q", c.name, c.mime, c.description, mime, description)
+		}
+	}
+}
+
+func TestSniffReaderKeepsHead(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 200)
+	sniff := &sniffReader{r: bytes.NewReader(content)}
+
+	buf := make([]byte, 100)
+	var read []byte
+	for {
+		n, err := sniff.Read(buf)
+		read = append(read, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if !bytes.Equal(read, content) {
+		t.Error("expected the content to pass through unchanged")
+	}
+	if !bytes.Equal(sniff.head, content[:sniffLen]) {
+		t.Errorf("expected the first %d bytes kept got %d", sniffLen, len(sniff.head))
+	}
+}
+
+func TestDetectTypeStreamedFile(t *testing.T) {
+	DetectType = true
+	defer func() { DetectType = false }()
+
+	path := filepath.Join(t.TempDir(), "archive.gz")
+	_ = os.WriteFile(path, append([]byte{0x1f, 0x8b, 0x08}, make([]byte, 4096)...), 0600)
+
+	r, err := processScanner(path, 4099, nil, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ContentType != "application/gzip" || r.Magic != "gzip compressed data" {
+		t.Errorf("expected gzip got %q %q", r.ContentType, r.Magic)
+	}
+}
@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsed versions of the size, age and type filters
+var (
+	minSizeBytes int64 = -1
+	maxSizeBytes int64 = -1
+	newerThan    time.Time
+	olderThan    time.Time
+)
+
+// Parses sizes such as 512, 10K, 1.5MB or 2GiB where suffixes are powers of 1024
+func parseSize(value string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "IB"), "B")
+
+	multiplier := int64(1)
+	if v != "" {
+		switch v[len(v)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			v = v[:len(v)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %s", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Parses either a duration before now such as 24h or 7d, or a date as 2006-01-02 or RFC3339
+func parseAge(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid age %s", value)
+		}
+		return now.Add(-time.Duration(n * float64(24*time.Hour))), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid age %s expected a duration like 24h or 7d or a date", value)
+	}
+	return now.Add(-d), nil
+}
+
+// Parses the filter options so the walker can apply them cheaply
+func compileFilters() error {
+	var err error
+	minSizeBytes, maxSizeBytes = -1, -1
+	newerThan, olderThan = time.Time{}, time.Time{}
+
+	if MinSize != "" {
+		if minSizeBytes, err = parseSize(MinSize); err != nil {
+			return err
+		}
+	}
+	if MaxSize != "" {
+		if maxSizeBytes, err = parseSize(MaxSize); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	if NewerThan != "" {
+		if newerThan, err = parseAge(NewerThan, now); err != nil {
+			return err
+		}
+	}
+	if OlderThan != "" {
+		if olderThan, err = parseAge(OlderThan, now); err != nil {
+			return err
+		}
+	}
+
+	switch FileType {
+	case "", "f", "l":
+	default:
+		return fmt.Errorf("invalid type %s expected f or l", FileType)
+	}
+
+	return nil
+}
+
+// Checks a walked file against the size, age and type filters. Symlinks are judged
+// on the size and modification time of their target.
+func passesFilters(path string, entry fs.DirEntry) bool {
+	isLink := entry.Type()&fs.ModeSymlink != 0
+	if (FileType == "f" && !entry.Type().IsRegular()) || (FileType == "l" && !isLink) {
+		return false
+	}
+
+	if minSizeBytes < 0 && maxSizeBytes < 0 && newerThan.IsZero() && olderThan.IsZero() {
+		return true
+	}
+
+	var fi os.FileInfo
+	var err error
+	if isLink {
+		fi, err = os.Stat(path)
+	} else {
+		fi, err = entry.Info()
+	}
+	if err != nil {
+		// Let the worker report why the file cannot be read
+		return true
+	}
+
+	switch {
+	case minSizeBytes >= 0 && fi.Size() < minSizeBytes:
+		return false
+	case maxSizeBytes >= 0 && fi.Size() > maxSizeBytes:
+		return false
+	case !newerThan.IsZero() && !fi.ModTime().After(newerThan):
+		return false
+	case !olderThan.IsZero() && !fi.ModTime().Before(olderThan):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{"512": 512, "10K": 10240, "1.5MB": 1572864, "2GiB": 2147483648, "1t": 1 << 40}
+	for input, expected := range cases {
+		if got, err := parseSize(input); err != nil || got != expected {
+			t.Errorf("Expected %s to be %d got %d %v", input, expected, got, err)
+		}
+	}
+
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("Expected error for invalid size")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	if got, _ := parseAge("24h", now); !got.Equal(now.Add(-24 * time.Hour)) {
+		t.Errorf("Unexpected 24h %s", got)
+	}
+	if got, _ := parseAge("7d", now); !got.Equal(now.Add(-7 * 24 * time.Hour)) {
+		t.Errorf("Unexpected 7d %s", got)
+	}
+	if got, _ := parseAge("2024-01-01T00:00:00Z", now); got.Year() != 2024 || got.Month() != 1 {
+		t.Errorf("Unexpected date %s", got)
+	}
+	if _, err := parseAge("yesterday", now); err == nil {
+		t.Error("Expected error for invalid age")
+	}
+}
+
+func TestWalkDirectoryFilters(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "small"), []byte("x"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "large"), make([]byte, 2048), 0600)
+	old := filepath.Join(dir, "old")
+	_ = os.WriteFile(old, make([]byte, 2048), 0600)
+	_ = os.Chtimes(old, time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+
+	MinSize, NewerThan = "1K", "24h"
+	defer func() {
+		MinSize, NewerThan = "", ""
+		_ = compileFilters()
+	}()
+	if err := compileFilters(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	output := make(chan string, 10)
+	walkDirectory(context.Background(), dir, output, make(chan Result, 10))
+	close(output)
+
+	found := []string{}
+	for f := range output {
+		found = append(found, filepath.Base(f))
+	}
+	if len(found) != 1 || found[0] != "large" {
+		t.Errorf("Expected only large got %v", found)
+	}
+}
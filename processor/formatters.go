@@ -1,9 +1,10 @@
 package processor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 	"time"
 )
@@ -13,29 +14,29 @@ func getFormattedTime() string {
 	return time.Now().UTC().Format(time.RFC3339)
 }
 
-// Prints a message to stdout if flag to enable warning output is set
-func printVerbose(msg string) {
+// Logs a message with optional key value pairs if flag to enable verbose output is set
+func printVerbose(msg string, args ...any) {
 	if Verbose {
-		fmt.Println(fmt.Sprintf("VERBOSE %s: %s", getFormattedTime(), msg))
+		logger.Info(msg, args...)
 	}
 }
 
-// Prints a message to stdout if flag to enable debug output is set
-func printDebug(msg string) {
+// Logs a message with optional key value pairs if flag to enable debug output is set
+func printDebug(msg string, args ...any) {
 	if Debug {
-		fmt.Println(fmt.Sprintf("DEBUG %s: %s", getFormattedTime(), msg))
+		logger.Debug(msg, args...)
 	}
 }
 
-// Used when explicitly for os.exit output when crashing out
-func printError(msg string) {
-	_, _ = fmt.Fprintln(os.Stderr, fmt.Sprintf("ERROR %s: %s", getFormattedTime(), msg))
+// Used when explicitly for os.exit output when crashing out and for files which could not be processed
+func printError(msg string, args ...any) {
+	logger.Error(msg, args...)
 }
 
-// Prints a message to stdout if flag to enable trace output is set
-func printTrace(msg string) {
+// Logs a message with optional key value pairs if flag to enable trace output is set
+func printTrace(msg string, args ...any) {
 	if Trace {
-		fmt.Println(fmt.Sprintf("TRACE %s: %s", getFormattedTime(), msg))
+		logger.Log(context.Background(), LevelTrace, msg, args...)
 	}
 }
 
@@ -51,7 +52,15 @@ func makeTimestampNano() int64 {
 }
 
 func fileSummarize(input chan Result) (string, bool) {
+	if NoContent {
+		input = recordStructure(input)
+	}
+
 	switch {
+	case ListOnly:
+		return toList(input), true
+	case (Match || MatchNegative) && strings.ToLower(Format) == "text":
+		return toFileNames(input), true
 	case strings.ToLower(Format) == "json":
 		return toJSON(input), true
 	case strings.ToLower(Format) == "hashdeep":
@@ -60,18 +69,87 @@ func fileSummarize(input chan Result) (string, bool) {
 		return toSum(input), true
 	case strings.ToLower(Format) == "hashonly":
 		return toHashOnly(input)
+	case strings.ToLower(Format) == "hashit":
+		return toContainer(input), true
+	case strings.ToLower(Format) == "template":
+		return toTemplate(input)
+	case strings.ToLower(Format) == "sri":
+		return toSRI(input), true
+	case strings.ToLower(Format) == "sri-json":
+		return toSRIJSON(input), true
+	case strings.ToLower(Format) == "html":
+		return toHTML(input), true
 	}
 
 	return toText(input)
 }
 
+// Check if the format writes out results as they arrive rather than all at the end
+func formatStreams() bool {
+	switch strings.ToLower(Format) {
+	case "json", "hashdeep", "hashit", "sri-json", "html":
+		return false
+	}
+	return !NoStream
+}
+
+// Where results are written as they are formatted when streaming to an output file,
+// which also prints to stdout when teeing
+var outputWriter io.Writer
+
+// First error writing to outputWriter, reported once the run completes
+var outputWriteErr error
+
+// Writes anything in the builder to outputWriter so formats which are not shown as
+// results arrive still do not build up in memory when writing to a file
+func flushOutput(str *strings.Builder) {
+	if outputWriter == nil {
+		return
+	}
+
+	if _, err := io.WriteString(outputWriter, str.String()); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	str.Reset()
+}
+
+// Prints anything new in the builder to stdout as results arrive. When streaming to
+// an output file everything goes to outputWriter instead.
+func streamOutput(str *strings.Builder, printed *int) {
+	if outputWriter != nil {
+		flushOutput(str)
+		*printed = 0
+		return
+	}
+
+	if NoStream || PerRootOutput != "" || (FileOutput != "" && !TeeOutput) {
+		return
+	}
+
+	out := str.String()
+	fmt.Print(out[*printed:])
+
+	if FileOutput == "" {
+		str.Reset()
+		*printed = 0
+	} else {
+		*printed = len(out)
+	}
+}
+
 // Mimics how md5sum sha1sum etc... work
 func toSum(input chan Result) string {
 	var str strings.Builder
+	printed := 0
 
 	first := true
 
 	for res := range input {
+		// Errors have already been reported on stderr the same way md5sum does
+		if res.Error != "" {
+			continue
+		}
+
 		if !first {
 			str.WriteString("\n")
 		} else {
@@ -79,62 +157,98 @@ func toSum(input chan Result) string {
 		}
 
 		if hasHash(HashNames.CRC32) {
-			str.WriteString(res.CRC32 + "  " + res.File + "\n")
+			writeSumLine(&str, res.CRC32, res.File)
 		}
 		if hasHash(HashNames.XxHash64) {
-			str.WriteString(res.XxHash64 + "  " + res.File + "\n")
+			writeSumLine(&str, res.XxHash64, res.File)
 		}
 		if hasHash(HashNames.MD4) {
-			str.WriteString(res.MD4 + "  " + res.File + "\n")
+			writeSumLine(&str, res.MD4, res.File)
 		}
 		if hasHash(HashNames.MD5) {
-			str.WriteString(res.MD5 + "  " + res.File + "\n")
+			writeSumLine(&str, res.MD5, res.File)
 		}
 		if hasHash(HashNames.SHA1) {
-			str.WriteString(res.SHA1 + "  " + res.File + "\n")
+			writeSumLine(&str, res.SHA1, res.File)
 		}
 		if hasHash(HashNames.SHA256) {
-			str.WriteString(res.SHA256 + "  " + res.File + "\n")
+			writeSumLine(&str, res.SHA256, res.File)
 		}
 		if hasHash(HashNames.SHA512) {
-			str.WriteString(res.SHA512 + "  " + res.File + "\n")
+			writeSumLine(&str, res.SHA512, res.File)
 		}
 		if hasHash(HashNames.Blake2b256) {
-			str.WriteString(res.Blake2b256 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Blake2b256, res.File)
 		}
 		if hasHash(HashNames.Blake2b512) {
-			str.WriteString(res.Blake2b512 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Blake2b512, res.File)
 		}
 		if hasHash(HashNames.Blake3) {
-			str.WriteString(res.Blake3 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Blake3, res.File)
 		}
 		if hasHash(HashNames.Sha3224) {
-			str.WriteString(res.Sha3224 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Sha3224, res.File)
 		}
 		if hasHash(HashNames.Sha3256) {
-			str.WriteString(res.Sha3256 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Sha3256, res.File)
 		}
 		if hasHash(HashNames.Sha3384) {
-			str.WriteString(res.Sha3384 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Sha3384, res.File)
 		}
 		if hasHash(HashNames.Sha3512) {
-			str.WriteString(res.Sha3512 + "  " + res.File + "\n")
+			writeSumLine(&str, res.Sha3512, res.File)
 		}
-
-		if !NoStream && FileOutput == "" {
-			fmt.Print(str.String())
-			str.Reset()
+		if hasHash(HashNames.Sha224) {
+			writeSumLine(&str, res.Sha224, res.File)
+		}
+		if hasHash(HashNames.Sha384) {
+			writeSumLine(&str, res.Sha384, res.File)
+		}
+		if hasHash(HashNames.Sha512256) {
+			writeSumLine(&str, res.Sha512256, res.File)
+		}
+		if hasHash(HashNames.Ripemd160) {
+			writeSumLine(&str, res.Ripemd160, res.File)
 		}
+		if hasHash(HashNames.Whirlpool) {
+			writeSumLine(&str, res.Whirlpool, res.File)
+		}
+		if hasHash(HashNames.SM3) {
+			writeSumLine(&str, res.SM3, res.File)
+		}
+		if hasHash(HashNames.Streebog256) {
+			writeSumLine(&str, res.Streebog256, res.File)
+		}
+		if hasHash(HashNames.Streebog512) {
+			writeSumLine(&str, res.Streebog512, res.File)
+		}
+		for _, info := range selectedCustomHashes() {
+			writeSumLine(&str, res.Custom[info.Name], res.File)
+		}
+
+		streamOutput(&str, &printed)
 	}
 
 	return str.String()
 }
 
+// Writes a line of the sum format leaving out digests a merged manifest did not record
+func writeSumLine(str *strings.Builder, digest string, file string) {
+	if digest != "" {
+		str.WriteString(digest + "  " + file + "\n")
+	}
+}
+
 func toHashOnly(input chan Result) (string, bool) {
 	var str strings.Builder
+	printed := 0
 	valid := true
 
 	for res := range input {
+		if res.Error != "" {
+			continue
+		}
+
 		if hasHash(HashNames.CRC32) {
 			str.WriteString(res.CRC32 + "\n")
 		}
@@ -177,11 +291,44 @@ func toHashOnly(input chan Result) (string, bool) {
 		if hasHash(HashNames.Sha3512) {
 			str.WriteString(res.Sha3512 + "\n")
 		}
-
-		if !NoStream && FileOutput == "" {
-			fmt.Print(str.String())
-			str.Reset()
+		if hasHash(HashNames.Sha224) {
+			str.WriteString(res.Sha224 + "\n")
+		}
+		if hasHash(HashNames.Sha384) {
+			str.WriteString(res.Sha384 + "\n")
+		}
+		if hasHash(HashNames.Sha512256) {
+			str.WriteString(res.Sha512256 + "\n")
+		}
+		if hasHash(HashNames.Ripemd160) {
+			str.WriteString(res.Ripemd160 + "\n")
+		}
+		if hasHash(HashNames.Whirlpool) {
+			str.WriteString(res.Whirlpool + "\n")
+		}
+		if hasHash(HashNames.SM3) {
+			str.WriteString(res.SM3 + "\n")
+		}
+		if hasHash(HashNames.Streebog256) {
+			str.WriteString(res.Streebog256 + "\n")
 		}
+		if hasHash(HashNames.Streebog512) {
+			str.WriteString(res.Streebog512 + "\n")
+		}
+		if hasHash(HashNames.Entropy) {
+			str.WriteString(res.Entropy + "\n")
+		}
+		if hasHash(HashNames.Ssdeep) {
+			str.WriteString(res.Ssdeep + "\n")
+		}
+		if hasHash(HashNames.TLSH) {
+			str.WriteString(res.TLSH + "\n")
+		}
+		for _, info := range selectedCustomHashes() {
+			str.WriteString(res.Custom[info.Name] + "\n")
+		}
+
+		streamOutput(&str, &printed)
 	}
 
 	return str.String(), valid
@@ -189,6 +336,7 @@ func toHashOnly(input chan Result) (string, bool) {
 
 func toText(input chan Result) (string, bool) {
 	var str strings.Builder
+	printed := 0
 	valid := true
 	first := true
 
@@ -199,78 +347,226 @@ func toText(input chan Result) (string, bool) {
 			first = false
 		}
 
-		str.WriteString(fmt.Sprintf("%s (%d bytes)\n", res.File, res.Bytes))
+		if res.Error != "" {
+			str.WriteString(fmt.Sprintf("%s (error)\n", res.File))
+			str.WriteString("      ERROR " + res.Error + "\n")
 
-		if hasHash(HashNames.CRC32) {
-			str.WriteString("      CRC32 " + res.CRC32 + "\n")
+			streamOutput(&str, &printed)
+			continue
 		}
-		if hasHash(HashNames.XxHash64) {
-			str.WriteString("   xxHash64 " + res.XxHash64 + "\n")
+
+		switch {
+		case res.Normalized != "":
+			str.WriteString(fmt.Sprintf("%s (%d bytes, normalized %s)\n", res.File, res.Bytes, res.Normalized))
+		case res.PhysicalBytes != 0:
+			str.WriteString(fmt.Sprintf("%s (%d bytes, %d physical)\n", res.File, res.Bytes, res.PhysicalBytes))
+		default:
+			str.WriteString(fmt.Sprintf("%s (%d bytes)\n", res.File, res.Bytes))
 		}
-		if hasHash(HashNames.MD4) {
-			str.WriteString("        MD4 " + res.MD4 + "\n")
+
+		writeTextHashes(&str, res)
+
+		if MTime && res.MTime != nil {
+			str.WriteString("      MTime " + res.MTime.Format(time.RFC3339Nano) + "\n")
 		}
-		if hasHash(HashNames.MD5) {
-			str.WriteString("        MD5 " + res.MD5 + "\n")
+		if MTime && res.BirthTime != nil {
+			str.WriteString("  BirthTime " + res.BirthTime.Format(time.RFC3339Nano) + "\n")
 		}
-		if hasHash(HashNames.SHA1) {
-			str.WriteString("       SHA1 " + res.SHA1 + "\n")
+
+		if res.AzureBlocks != nil {
+			str.WriteString("  Azure-MD5 " + res.AzureContentMD5 + "\n")
+			for _, b := range res.AzureBlocks {
+				str.WriteString(fmt.Sprintf("      block %s offset=%d size=%d md5=%s\n", b.ID, b.Offset, b.Size, b.MD5))
+			}
 		}
-		if hasHash(HashNames.SHA256) {
-			str.WriteString("     SHA256 " + res.SHA256 + "\n")
+
+		if res.Xattr != "" {
+			str.WriteString("      Xattr " + res.Xattr + "\n")
 		}
-		if hasHash(HashNames.SHA512) {
-			str.WriteString("     SHA512 " + res.SHA512 + "\n")
+
+		if res.LastSeen != nil {
+			str.WriteString("     Change " + res.Change + " since " + res.LastSeen.Format(time.RFC3339) + "\n")
+		} else if res.Change != "" {
+			str.WriteString("     Change " + res.Change + "\n")
 		}
-		if hasHash(HashNames.Blake2b256) {
-			str.WriteString("Blake2b-256 " + res.Blake2b256 + "\n")
+
+		if res.Pieces != nil {
+			for i, p := range res.Pieces {
+				str.WriteString(fmt.Sprintf("      piece %d %s\n", i, p))
+			}
 		}
-		if hasHash(HashNames.Blake2b512) {
-			str.WriteString("Blake2b-512 " + res.Blake2b512 + "\n")
+
+		if res.Detail != "" {
+			str.WriteString("     Detail " + res.Detail + "\n")
 		}
-		if hasHash(HashNames.Blake3) {
-			str.WriteString("     Blake3 " + res.Blake3 + "\n")
+		if res.QuickHash != "" {
+			str.WriteString("  QuickHash " + res.QuickHash + "\n")
 		}
-		if hasHash(HashNames.Sha3224) {
-			str.WriteString("   SHA3-224 " + res.Sha3224 + "\n")
+		for _, s := range res.Samples {
+			str.WriteString(fmt.Sprintf("     sample offset=%d size=%d xxh64=%s\n", s.Offset, s.Size, s.XxHash64))
 		}
-		if hasHash(HashNames.Sha3256) {
-			str.WriteString("   SHA3-256 " + res.Sha3256 + "\n")
-		}
-		if hasHash(HashNames.Sha3384) {
-			str.WriteString("   SHA3-384 " + res.Sha3384 + "\n")
+
+		for i, f := range res.ZstdFrames {
+			str.WriteString(fmt.Sprintf("      frame %d offset=%d size=%d decompressed=%d sha256=%s\n", i, f.Offset, f.Size, f.DecompressedSize, f.SHA256))
 		}
-		if hasHash(HashNames.Sha3512) {
-			str.WriteString("   SHA3-512 " + res.Sha3512 + "\n")
+
+		if res.Original != nil {
+			str.WriteString("   original\n")
+			writeTextHashes(&str, *res.Original)
 		}
 
-		if !NoStream && FileOutput == "" {
-			fmt.Print(str.String())
-			str.Reset()
+		streamOutput(&str, &printed)
+	}
+
+	if NoContent {
+		if !first {
+			str.WriteString("\n")
 		}
+		str.WriteString("structure sha256 " + structureSHA256 + "\n")
+		streamOutput(&str, &printed)
 	}
 
 	return str.String(), valid
 }
 
+// Writes out the aligned hash lines used by the text formatter
+func writeTextHashes(str *strings.Builder, res Result) {
+	if hasHash(HashNames.CRC32) {
+		str.WriteString("      CRC32 " + res.CRC32 + "\n")
+	}
+	if hasHash(HashNames.XxHash64) {
+		str.WriteString("   xxHash64 " + res.XxHash64 + "\n")
+	}
+	if hasHash(HashNames.MD4) {
+		str.WriteString("        MD4 " + res.MD4 + "\n")
+	}
+	if hasHash(HashNames.MD5) {
+		str.WriteString("        MD5 " + res.MD5 + "\n")
+	}
+	if hasHash(HashNames.SHA1) {
+		str.WriteString("       SHA1 " + res.SHA1 + "\n")
+	}
+	if hasHash(HashNames.SHA256) {
+		str.WriteString("     SHA256 " + res.SHA256 + "\n")
+	}
+	if hasHash(HashNames.SHA512) {
+		str.WriteString("     SHA512 " + res.SHA512 + "\n")
+	}
+	if hasHash(HashNames.Blake2b256) {
+		str.WriteString("Blake2b-256 " + res.Blake2b256 + "\n")
+	}
+	if hasHash(HashNames.Blake2b512) {
+		str.WriteString("Blake2b-512 " + res.Blake2b512 + "\n")
+	}
+	if hasHash(HashNames.Blake3) {
+		str.WriteString("     Blake3 " + res.Blake3 + "\n")
+	}
+	if hasHash(HashNames.Sha3224) {
+		str.WriteString("   SHA3-224 " + res.Sha3224 + "\n")
+	}
+	if hasHash(HashNames.Sha3256) {
+		str.WriteString("   SHA3-256 " + res.Sha3256 + "\n")
+	}
+	if hasHash(HashNames.Sha3384) {
+		str.WriteString("   SHA3-384 " + res.Sha3384 + "\n")
+	}
+	if hasHash(HashNames.Sha3512) {
+		str.WriteString("   SHA3-512 " + res.Sha3512 + "\n")
+	}
+	if hasHash(HashNames.Sha224) {
+		str.WriteString("     SHA224 " + res.Sha224 + "\n")
+	}
+	if hasHash(HashNames.Sha384) {
+		str.WriteString("     SHA384 " + res.Sha384 + "\n")
+	}
+	if hasHash(HashNames.Sha512256) {
+		str.WriteString(" SHA512/256 " + res.Sha512256 + "\n")
+	}
+	if hasHash(HashNames.Ripemd160) {
+		str.WriteString(" RIPEMD-160 " + res.Ripemd160 + "\n")
+	}
+	if hasHash(HashNames.Whirlpool) {
+		str.WriteString("  Whirlpool " + res.Whirlpool + "\n")
+	}
+	if hasHash(HashNames.SM3) {
+		str.WriteString("        SM3 " + res.SM3 + "\n")
+	}
+	if hasHash(HashNames.Streebog256) {
+		str.WriteString("Streebog256 " + res.Streebog256 + "\n")
+	}
+	if hasHash(HashNames.Streebog512) {
+		str.WriteString("Streebog512 " + res.Streebog512 + "\n")
+	}
+	if hasHash(HashNames.Entropy) {
+		str.WriteString("    Entropy " + res.Entropy + "\n")
+	}
+	if hasHash(HashNames.Ssdeep) {
+		str.WriteString("     ssdeep " + res.Ssdeep + "\n")
+	}
+	if hasHash(HashNames.TLSH) {
+		str.WriteString("       TLSH " + res.TLSH + "\n")
+	}
+	for _, info := range selectedCustomHashes() {
+		str.WriteString(fmt.Sprintf("%11s %s\n", info.Display, res.Custom[info.Name]))
+	}
+}
+
+// Writes each record as it arrives so large runs can be streamed to a file. The
+// structure digest and statistics are only known at the end so follow the files.
 func toJSON(input chan Result) string {
-	results := []Result{}
+	var str strings.Builder
+
+	wrapped := NoContent || Stats || Provenance
+	if wrapped {
+		str.WriteString("{")
+		if Provenance {
+			provenance, _ := json.Marshal(scanProvenance())
+			str.WriteString(`"Provenance":`)
+			str.Write(provenance)
+			str.WriteString(",")
+		}
+		str.WriteString(`"Files":[`)
+	} else {
+		str.WriteString("[")
+	}
+
+	first := true
 	for res := range input {
-		results = append(results, res)
+		if len(labels) != 0 {
+			res.Labels = labels
+		}
+		if !first {
+			str.WriteString(",")
+		}
+		first = false
+
+		record, _ := json.Marshal(res)
+		str.Write(record)
+		flushOutput(&str)
 	}
+	str.WriteString("]")
 
-	jsonString, _ := json.Marshal(results)
-	return string(jsonString)
+	if wrapped {
+		if NoContent {
+			digest, _ := json.Marshal(structureSHA256)
+			str.WriteString(`,"StructureSHA256":`)
+			str.Write(digest)
+		}
+		if Stats {
+			stats, _ := json.Marshal(collectStats())
+			str.WriteString(`,"Stats":`)
+			str.Write(stats)
+		}
+		str.WriteString("}")
+	}
+
+	return str.String()
 }
 
 func toHashDeep(input chan Result) string {
 	var str strings.Builder
 
-	pwd, err := os.Getwd()
-	if err != nil {
-		printError(fmt.Sprintf("unable to determine working directory: %s", err.Error()))
-		pwd = ""
-	}
+	pwd, _, command := runContext()
 
 	str.WriteString("%%%% HASHDEEP-1.0\n")
 	if !contains(Hash, "sha256") && !contains(Hash, "all") {
@@ -282,27 +578,61 @@ func toHashDeep(input chan Result) string {
 	if MTime {
 		str.WriteString(",mtime")
 	}
+	if DetectType {
+		str.WriteString(",type")
+	}
+	if Provenance {
+		str.WriteString(",hashed")
+	}
 	str.WriteString("\n")
 
 	str.WriteString(fmt.Sprintf("## Invoked from: %s\n", pwd))
-	str.WriteString(fmt.Sprintf("## $ %s\n", strings.Join(os.Args, " ")))
+	str.WriteString(fmt.Sprintf("## $ %s\n", strings.Join(command, " ")))
+	if scanID != "" {
+		str.WriteString(fmt.Sprintf("## Scan: %s\n", scanID))
+	}
+	if Provenance {
+		str.WriteString(hashdeepProvenance())
+	}
 	str.WriteString("##\n")
 
 	if !contains(Hash, "sha256") && !contains(Hash, "all") {
 		for res := range input {
+			if res.Error != "" {
+				str.WriteString(fmt.Sprintf("## error: %s: %s\n", res.File, res.Error))
+				continue
+			}
 			str.WriteString(fmt.Sprintf("%d,%s,%s", res.Bytes, res.MD5, res.File))
 			if MTime {
-				str.WriteString(fmt.Sprintf(",%s", res.MTime.Format("2006-01-02 15:04:05")))
+				str.WriteString(fmt.Sprintf(",%s", res.MTime.Format(hashdeepTimeLayout)))
+			}
+			if DetectType {
+				str.WriteString(fmt.Sprintf(",%s", res.ContentType))
+			}
+			if Provenance {
+				str.WriteString(fmt.Sprintf(",%s", hashdeepHashed(res)))
 			}
 			str.WriteString("\n")
+			flushOutput(&str)
 		}
 	} else {
 		for res := range input {
+			if res.Error != "" {
+				str.WriteString(fmt.Sprintf("## error: %s: %s\n", res.File, res.Error))
+				continue
+			}
 			str.WriteString(fmt.Sprintf("%d,%s,%s,%s", res.Bytes, res.MD5, res.SHA256, res.File))
 			if MTime {
-				str.WriteString(fmt.Sprintf(",%s", res.MTime.Format("2006-01-02 15:04:05")))
+				str.WriteString(fmt.Sprintf(",%s", res.MTime.Format(hashdeepTimeLayout)))
+			}
+			if DetectType {
+				str.WriteString(fmt.Sprintf(",%s", res.ContentType))
+			}
+			if Provenance {
+				str.WriteString(fmt.Sprintf(",%s", hashdeepHashed(res)))
 			}
 			str.WriteString("\n")
+			flushOutput(&str)
 		}
 	}
 
@@ -310,20 +640,24 @@ func toHashDeep(input chan Result) string {
 }
 
 func printHashes() {
-	fmt.Println(fmt.Sprintf("      CRC32 (%s)", HashNames.CRC32))
-	fmt.Println(fmt.Sprintf("   xxHash64 (%s)", HashNames.XxHash64))
-	fmt.Println(fmt.Sprintf("        MD4 (%s)", HashNames.MD4))
-	fmt.Println(fmt.Sprintf("        MD5 (%s)", HashNames.MD5))
-	fmt.Println(fmt.Sprintf("       SHA1 (%s)", HashNames.SHA1))
-	fmt.Println(fmt.Sprintf("     SHA256 (%s)", HashNames.SHA256))
-	fmt.Println(fmt.Sprintf("     SHA512 (%s)", HashNames.SHA512))
-	fmt.Println(fmt.Sprintf("Blake2b-256 (%s)", HashNames.Blake2b256))
-	fmt.Println(fmt.Sprintf("Blake2b-512 (%s)", HashNames.Blake2b512))
-	fmt.Println(fmt.Sprintf("     Blake3 (%s)", HashNames.Blake3))
-	fmt.Println(fmt.Sprintf("   SHA3-224 (%s)", HashNames.Sha3224))
-	fmt.Println(fmt.Sprintf("   SHA3-256 (%s)", HashNames.Sha3256))
-	fmt.Println(fmt.Sprintf("   SHA3-384 (%s)", HashNames.Sha3384))
-	fmt.Println(fmt.Sprintf("   SHA3-512 (%s)", HashNames.Sha3512))
+	for _, h := range HashInfos {
+		fmt.Println(fmt.Sprintf("%11s (%s)", h.Display, h.Name))
+	}
+}
+
+// Prints the supported hashes as JSON so scripts can discover what is available
+func printHashesJSON() {
+	jsonString, _ := json.MarshalIndent(HashInfos, "", "  ")
+	fmt.Println(string(jsonString))
+}
+
+// ListHashes prints all of the supported hashes either as text or JSON
+func ListHashes(asJSON bool) {
+	if asJSON {
+		printHashesJSON()
+		return
+	}
+	printHashes()
 }
 
 func contains(list []string, v string) bool {
@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FuzzCorruption is a copy of a file with a single bit flipped that was not detected
+type FuzzCorruption struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Format string `json:"format"`
+}
+
+// FuzzReport summarises how many corruptions each format detected
+type FuzzReport struct {
+	Files       int              `json:"files"`
+	Corruptions int              `json:"corruptions"`
+	Hashes      []string         `json:"hashes"`
+	Detected    map[string]int   `json:"detected"`
+	Undetected  []FuzzCorruption `json:"undetected"`
+}
+
+// Offsets to corrupt in a file of size bytes, the first, middle and last byte so
+// block and stream boundaries are exercised
+func fuzzOffsets(size int64) []int64 {
+	offsets := []int64{0}
+	if size > 2 {
+		offsets = append(offsets, size/2)
+	}
+	if size > 1 {
+		offsets = append(offsets, size-1)
+	}
+	return offsets
+}
+
+// Writes a copy of src to dst with the lowest bit of the byte at offset flipped
+func writeCorruptCopy(src string, dst string, offset int64) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	content[offset] ^= 0x01
+	return os.WriteFile(dst, content, 0600)
+}
+
+// Hashes the files through the normal workers keyed by file name
+func hashFiles(files []string) map[string]Result {
+	fileListQueue := make(chan string, len(files))
+	resultQueue := make(chan Result, len(files))
+	for _, f := range files {
+		fileListQueue <- f
+	}
+	close(fileListQueue)
+
+	startWorkers(fileListQueue, resultQueue)
+
+	results := map[string]Result{}
+	for res := range resultQueue {
+		results[res.File] = res
+	}
+	return results
+}
+
+// Renders a single result using the named format
+func renderResult(res Result, format string) string {
+	previous := Format
+	Format = format
+	defer func() { Format = previous }()
+
+	input := make(chan Result, 1)
+	input <- res
+	close(input)
+
+	out, _ := fileSummarize(input)
+	return out
+}
+
+// Formats able to show the selected hashes, the sri formats need sha256, sha384 or sha512
+func fuzzFormats() []string {
+	formats := []string{}
+	for _, f := range Formats {
+		if strings.HasPrefix(f, "sri") && len(sriHashes()) == 0 {
+			continue
+		}
+		formats = append(formats, f)
+	}
+	return formats
+}
+
+func fuzzTest(dir string, maxFiles int) (FuzzReport, error) {
+	report := FuzzReport{Detected: map[string]int{}, Undetected: []FuzzCorruption{}}
+	for _, f := range append([]string{"hashes"}, fuzzFormats()...) {
+		report.Detected[f] = 0
+	}
+
+	files := []string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || len(files) >= maxFiles {
+			return nil
+		}
+		if d.Type().IsRegular() && !isExcluded(p) {
+			if fi, err := d.Info(); err == nil && fi.Size() > 0 {
+				files = append(files, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	tmp, err := os.MkdirTemp("", "hashit-fuzztest")
+	if err != nil {
+		return report, err
+	}
+	defer os.RemoveAll(tmp)
+
+	type corruption struct {
+		original string
+		copy     string
+		offset   int64
+	}
+	corruptions := []corruption{}
+	copies := []string{}
+	for i, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return report, err
+		}
+		for j, offset := range fuzzOffsets(fi.Size()) {
+			dst := filepath.Join(tmp, fmt.Sprintf("%d-%d", i, j))
+			if err := writeCorruptCopy(f, dst, offset); err != nil {
+				return report, err
+			}
+			corruptions = append(corruptions, corruption{original: f, copy: dst, offset: offset})
+			copies = append(copies, dst)
+		}
+	}
+
+	originals := hashFiles(files)
+	corrupted := hashFiles(copies)
+
+	report.Files = len(files)
+	report.Corruptions = len(corruptions)
+	for name := range calculatedHashes(Result{}) {
+		report.Hashes = append(report.Hashes, name)
+	}
+	sort.Strings(report.Hashes)
+
+	for _, c := range corruptions {
+		original := originals[c.original]
+		copied := corrupted[c.copy]
+		if original.Error != "" || copied.Error != "" {
+			return report, fmt.Errorf("unable to hash %s", c.original)
+		}
+		// Output should only differ because of the content
+		copied.File = original.File
+		copied.MTime = original.MTime
+		copied.BirthTime = original.BirthTime
+
+		// Every hash on its own has to notice the change
+		detected := true
+		copiedHashes := calculatedHashes(copied)
+		for name, value := range calculatedHashes(original) {
+			if copiedHashes[name] == value {
+				detected = false
+				report.Undetected = append(report.Undetected, FuzzCorruption{File: c.original, Offset: c.offset, Format: "hashes:" + name})
+			}
+		}
+		if detected {
+			report.Detected["hashes"]++
+		}
+
+		for _, format := range fuzzFormats() {
+			if renderResult(original, format) != renderResult(copied, format) {
+				report.Detected[format]++
+			} else {
+				report.Undetected = append(report.Undetected, FuzzCorruption{File: c.original, Offset: c.offset, Format: format})
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// FuzzTest flips bits in copies of up to maxFiles files under dir and confirms every
+// output format detects each corruption, exiting 1 if any went unnoticed
+func FuzzTest(dir string, maxFiles int, asJSON bool) {
+	Hash = formatHashInput()
+	// Results are compared rather than printed
+	NoStream = true
+
+	report, err := fuzzTest(dir, maxFiles)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if asJSON {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("checked %d files with %d corruptions using %s\n", report.Files, report.Corruptions, strings.Join(report.Hashes, ","))
+		for _, f := range append([]string{"hashes"}, fuzzFormats()...) {
+			fmt.Printf("%11s %d/%d detected\n", f, report.Detected[f], report.Corruptions)
+		}
+		for _, u := range report.Undetected {
+			fmt.Printf("undetected: %s offset %d in %s\n", u.File, u.Offset, u.Format)
+		}
+	}
+
+	if len(report.Undetected) != 0 || report.Corruptions == 0 {
+		if report.Corruptions == 0 {
+			printError(fmt.Sprintf("no files to corrupt found in %s", dir))
+		}
+		os.Exit(1)
+	}
+}
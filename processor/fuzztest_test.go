@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFuzzOffsets(t *testing.T) {
+	if got := fuzzOffsets(1); len(got) != 1 {
+		t.Errorf("Expected one offset for a single byte file got %v", got)
+	}
+	if got := fuzzOffsets(10); len(got) != 3 || got[1] != 5 || got[2] != 9 {
+		t.Errorf("Expected first middle and last offsets got %v", got)
+	}
+}
+
+func TestFuzzTestDetectsCorruption(t *testing.T) {
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	NoStream = true
+	defer func() { NoStream = false }()
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a"), []byte("hello world"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "b"), []byte("x"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "empty"), []byte{}, 0600)
+
+	report, err := fuzzTest(dir, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if report.Files != 2 || report.Corruptions != 4 {
+		t.Errorf("Expected 2 files and 4 corruptions got %+v", report)
+	}
+	if len(report.Undetected) != 0 {
+		t.Errorf("Expected every corruption detected got %+v", report.Undetected)
+	}
+	for _, f := range Formats {
+		if report.Detected[f] != 4 {
+			t.Errorf("Expected %s to detect 4 got %d", f, report.Detected[f])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A file gen-testdata writes, holes are left at the start of sparse files where the
+// filesystem supports it
+type testdataFile struct {
+	path    string
+	content []byte
+	sparse  bool
+}
+
+// The tree of edge cases written by gen-testdata with paths relative to its root. The
+// same seed always gives the same contents.
+func testdataFiles(seed int64) []testdataFile {
+	random := rand.New(rand.NewSource(seed))
+	randomBytes := func(n int) []byte {
+		b := make([]byte, n)
+		_, _ = random.Read(b)
+		return b
+	}
+
+	files := []testdataFile{
+		{path: "empty", content: []byte{}},
+		{path: "hello.txt", content: []byte("hello world\n")},
+		{path: "crlf.txt", content: []byte("line one\r\nline two\r\n")},
+		{path: "no-newline.txt", content: []byte("no trailing newline")},
+		{path: ".hidden", content: []byte("hidden\n")},
+		{path: "with space.txt", content: []byte("space\n")},
+		{path: "unicode/café-composed.txt", content: []byte("composed\n")},
+		{path: "unicode/café-decomposed.txt", content: []byte("decomposed\n")},
+		{path: "unicode/日本語.txt", content: []byte("japanese\n")},
+		{path: "unicode/emoji-😀.txt", content: []byte("emoji\n")},
+		{path: "zeros-4096", content: make([]byte, 4096)},
+	}
+
+	// Sizes either side of the block sizes hashes work in and the size above which
+	// files are streamed rather than read whole
+	for _, size := range []int{1, 63, 64, 65, 4095, 4097, int(StreamSize) - 1, int(StreamSize) + 1} {
+		files = append(files, testdataFile{path: fmt.Sprintf("sizes/random-%d", size), content: randomBytes(size)})
+	}
+
+	// Longer than the 260 characters Windows allows without long path support with every
+	// name within the 255 most filesystems allow
+	long := ""
+	for i := 0; i < 5; i++ {
+		long = filepath.Join(long, strings.Repeat(string(rune('a'+i)), 60))
+	}
+	files = append(files, testdataFile{path: filepath.Join(long, "long-path.txt"), content: []byte("long path\n")})
+
+	sparse := make([]byte, 4<<20)
+	copy(sparse[1<<20:], randomBytes(4096))
+	copy(sparse[len(sparse)-4096:], randomBytes(4096))
+	files = append(files, testdataFile{path: "sparse.bin", content: sparse, sparse: true})
+
+	for i := range files {
+		files[i].path = filepath.FromSlash(files[i].path)
+	}
+	return files
+}
+
+// Writes the file only seeking over the zeros of sparse files so they become holes
+func writeTestdataFile(path string, f testdataFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if !f.sparse {
+		return os.WriteFile(longPath(path), f.content, 0644)
+	}
+
+	file, err := os.Create(longPath(path))
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(int64(len(f.content))); err != nil {
+		_ = file.Close()
+		return err
+	}
+	const block = 4096
+	for offset := 0; offset < len(f.content); offset += block {
+		chunk := f.content[offset:min(offset+block, len(f.content))]
+		for _, b := range chunk {
+			if b != 0 {
+				if _, err := file.WriteAt(chunk, int64(offset)); err != nil {
+					_ = file.Close()
+					return err
+				}
+				break
+			}
+		}
+	}
+	return file.Close()
+}
+
+// Creates the edge case tree in dir returning results whose digests are calculated from
+// the contents in memory rather than by reading the files back
+func genTestdata(dir string, seed int64) ([]Result, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) != 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	files := testdataFiles(seed)
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	results := []Result{}
+	for _, f := range files {
+		path := filepath.Join(dir, f.path)
+		if err := writeTestdataFile(path, f); err != nil {
+			return nil, fmt.Errorf("unable to write %s: %w", path, err)
+		}
+
+		res, err := processReadFile(path, &f.content)
+		if err != nil {
+			return nil, err
+		}
+		res.File = path
+		res.Bytes = int64(len(f.content))
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// GenTestdata writes a tree of files with known contents covering edge cases such as
+// empty files, unicode names, long paths and sparse files into dir, then prints a
+// manifest of it in the selected format to check verification pipelines against
+func GenTestdata(dir string, seed int64) {
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	results, err := genTestdata(dir, seed)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	printVerbose("wrote test data", "dir", dir, "files", len(results))
+
+	input := make(chan Result, len(results))
+	for _, res := range results {
+		input <- res
+	}
+	close(input)
+
+	result, _ := fileSummarize(encodeDigests(input))
+	fmt.Print(result)
+}
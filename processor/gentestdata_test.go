@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenTestdataVerifies(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{"md5", "sha256"}
+
+	dir := filepath.Join(t.TempDir(), "testdata")
+	results, err := genTestdata(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(testdataFiles(1)) {
+		t.Errorf("expected a result for every file got %d", len(results))
+	}
+
+	status, err := checkManifest(manifestFromResults(results))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, s := range status {
+		if s != "OK" {
+			t.Errorf("expected %s to verify got %s", file, s)
+		}
+	}
+}
+
+func TestGenTestdataSeed(t *testing.T) {
+	a, b, c := testdataFiles(1), testdataFiles(1), testdataFiles(2)
+	same, different := true, false
+	for i := range a {
+		same = same && bytes.Equal(a[i].content, b[i].content)
+		different = different || !bytes.Equal(a[i].content, c[i].content)
+	}
+	if !same || !different {
+		t.Errorf("expected the seed alone to decide the contents got same %t different %t", same, different)
+	}
+}
+
+func TestGenTestdataRefusesNonEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "existing"), []byte("keep"), 0600)
+	if _, err := genTestdata(dir, 1); err == nil {
+		t.Error("expected a directory with files in it to be refused")
+	}
+}
@@ -0,0 +1,321 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// A command run for results with each argument rendered as a template. Commands are run
+// directly rather than through a shell so paths cannot inject anything.
+type hook struct {
+	flag string
+	args []*template.Template
+}
+
+// What hook arguments are rendered with, everything --template has along with the status
+type hookContext struct {
+	templateContext
+	// OK or the FAILED status for --check, otherwise FAILED when --verify-xattr found a
+	// mismatch, the --baseline change, ERROR for files which could not be read or OK
+	Status string
+	// The manifest being checked and the digests it lists for the file keyed by hash
+	Manifest string
+	Expected map[string]string
+}
+
+// A rendered command waiting for a free slot
+type hookJob struct {
+	flag string
+	file string
+	args []string
+	env  []string
+}
+
+// Runs the hooks ExecConcurrency at a time, nil when no hooks are set
+type hookRunner struct {
+	perFile    *hook
+	onMismatch *hook
+	timeout    time.Duration
+	jobs       chan hookJob
+	start      sync.Once
+	wg         sync.WaitGroup
+	failed     int64
+	index      int64
+}
+
+var hooks *hookRunner
+
+// Splits a command line into arguments the way a shell would for plain words and quotes,
+// without expanding anything. Template actions are kept whole so {{index .Hashes "md5"}}
+// needs no quoting.
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+	var current strings.Builder
+	inArg := false
+	var quote byte
+	escaped := false
+
+	for i := 0; i < len(command); i++ {
+		if !escaped && strings.HasPrefix(command[i:], "{{") {
+			end := strings.Index(command[i:], "}}")
+			if end < 0 {
+				return nil, errors.New("unterminated template action")
+			}
+			current.WriteString(command[i : i+end+2])
+			inArg = true
+			i += end + 1
+			continue
+		}
+
+		r := command[i]
+		switch {
+		case escaped:
+			current.WriteByte(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteByte(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no command")
+	}
+	return args, nil
+}
+
+func compileHook(flag string, command string) (*hook, error) {
+	if command == "" {
+		return nil, nil
+	}
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+	}
+
+	h := &hook{flag: flag}
+	for i, a := range args {
+		t, err := template.New(fmt.Sprintf("%s-%d", flag, i)).Funcs(templateFuncs).Option("missingkey=zero").Parse(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+		h.args = append(h.args, t)
+	}
+	return h, nil
+}
+
+// Compiles the hooks so mistakes are reported before any files are hashed
+func prepareHooks() error {
+	hooks = nil
+	if ExecPerFile == "" && ExecOnMismatch == "" {
+		return nil
+	}
+	if ExecConcurrency < 1 {
+		return fmt.Errorf("invalid exec concurrency %d expected at least 1", ExecConcurrency)
+	}
+
+	r := &hookRunner{}
+	var err error
+	if ExecTimeout != "" {
+		if r.timeout, err = time.ParseDuration(ExecTimeout); err != nil || r.timeout < 0 {
+			return fmt.Errorf("invalid exec timeout %s expected a duration such as 30s", ExecTimeout)
+		}
+	}
+	if r.perFile, err = compileHook("exec-per-file", ExecPerFile); err != nil {
+		return err
+	}
+	if r.onMismatch, err = compileHook("exec-on-mismatch", ExecOnMismatch); err != nil {
+		return err
+	}
+	hooks = r
+	return nil
+}
+
+// The status hooks see for a result of a normal scan
+func resultStatus(res Result) string {
+	switch {
+	case res.Error != "":
+		return "ERROR"
+	case res.Xattr == "FAILED":
+		return "FAILED"
+	case res.Change != "":
+		return res.Change
+	}
+	return "OK"
+}
+
+// Statuses verification reports which are not failures
+func isMismatch(status string) bool {
+	switch status {
+	case "OK", "DELETED", "new", "unchanged", "unknown", "ERROR":
+		return false
+	}
+	return true
+}
+
+// Runs the hooks which apply to a result. manifest and expected are only set by --check.
+func (r *hookRunner) fire(res Result, status string, manifest string, expected map[string]string) {
+	if r == nil {
+		return
+	}
+
+	pwd, host, _ := runContext()
+	ctx := hookContext{
+		templateContext: templateContext{
+			Result:    res,
+			Path:      res.File,
+			Dir:       filepath.Dir(res.File),
+			Name:      filepath.Base(res.File),
+			Hashes:    calculatedHashes(res),
+			Index:     int(atomic.AddInt64(&r.index, 1)),
+			Host:      host,
+			Directory: pwd,
+			Version:   Version,
+			Started:   scanStarted,
+		},
+		Status:   status,
+		Manifest: manifest,
+		Expected: expected,
+	}
+
+	if r.perFile != nil {
+		r.submit(r.perFile, ctx)
+	}
+	if r.onMismatch != nil && isMismatch(status) {
+		r.submit(r.onMismatch, ctx)
+	}
+}
+
+func (r *hookRunner) submit(h *hook, ctx hookContext) {
+	r.start.Do(func() {
+		r.jobs = make(chan hookJob, ExecConcurrency)
+		for i := 0; i < ExecConcurrency; i++ {
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				for job := range r.jobs {
+					r.run(job)
+				}
+			}()
+		}
+	})
+
+	job := hookJob{flag: h.flag, file: ctx.Path}
+	for _, t := range h.args {
+		var arg strings.Builder
+		if err := t.Execute(&arg, ctx); err != nil {
+			printError(fmt.Sprintf("unable to render --%s for %s: %s", h.flag, ctx.Path, err.Error()))
+			atomic.AddInt64(&r.failed, 1)
+			return
+		}
+		job.args = append(job.args, arg.String())
+	}
+	job.env = []string{"HASHIT_FILE=" + ctx.Path, "HASHIT_STATUS=" + ctx.Status}
+	if ctx.Manifest != "" {
+		job.env = append(job.env, "HASHIT_MANIFEST="+ctx.Manifest)
+	}
+	r.jobs <- job
+}
+
+// Runs a command with its output sent to stderr so it stays out of the results
+func (r *hookRunner) run(job hookJob) {
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, job.args[0], job.args[1:]...)
+	cmd.Env = append(os.Environ(), job.env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	printDebug("running hook", "hook", job.flag, "file", job.file, "command", job.args)
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		atomic.AddInt64(&r.failed, 1)
+		printError(fmt.Sprintf("--%s for %s failed: %s", job.flag, job.file, err.Error()))
+	}
+}
+
+// Waits for every hook to finish returning how many failed
+func (r *hookRunner) wait() int64 {
+	if r == nil {
+		return 0
+	}
+	if r.jobs != nil {
+		close(r.jobs)
+	}
+	r.wg.Wait()
+	return atomic.LoadInt64(&r.failed)
+}
+
+// Runs the hooks for each result of a scan as it passes
+func hookResults(input chan Result) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			hooks.fire(res, resultStatus(res), "", nil)
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
+
+// Waits for the hooks reporting when any failed, which fails the run
+func finishHooks() bool {
+	failed := hooks.wait()
+	if failed != 0 {
+		printError(fmt.Sprintf("%d hook commands failed", failed))
+	}
+	return failed == 0
+}
+
+// The digests a manifest lists for a file keyed by each hash they could be from
+func expectedDigests(e manifestEntry) map[string]string {
+	expected := map[string]string{}
+	for _, d := range e.Digests {
+		for _, name := range d.Names {
+			expected[name] = d.Digest
+		}
+	}
+	return expected
+}
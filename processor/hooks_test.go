@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	cases := []struct {
+		command  string
+		expected []string
+	}{
+		{"quarantine {{.Path}}", []string{"quarantine", "{{.Path}}"}},
+		{`notify "file {{.Path}}" 'it'"'"'s'`, []string{"notify", "file {{.Path}}", "it's"}},
+		{`echo {{index .Hashes "md5"}} done`, []string{"echo", `{{index .Hashes "md5"}}`, "done"}},
+		{`echo a\ b café`, []string{"echo", "a b", "café"}},
+		{`echo ""`, []string{"echo", ""}},
+	}
+	for _, c := range cases {
+		got, err := splitCommand(c.command)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.command, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %q got %q", c.command, c.expected, got)
+		}
+	}
+
+	for _, bad := range []string{"", "   ", `echo "open`, "echo {{.Path", `echo \`} {
+		if _, err := splitCommand(bad); err == nil {
+			t.Errorf("Expected error splitting %q", bad)
+		}
+	}
+}
+
+func TestPrepareHooks(t *testing.T) {
+	previousFile, previousMismatch, previousConcurrency, previousTimeout := ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout
+	defer func() {
+		ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout = previousFile, previousMismatch, previousConcurrency, previousTimeout
+		hooks = nil
+	}()
+
+	ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout = "", "", 4, ""
+	if err := prepareHooks(); err != nil || hooks != nil {
+		t.Errorf("Expected no hooks without commands got %v %v", hooks, err)
+	}
+
+	ExecPerFile = "echo {{.Path}}"
+	if err := prepareHooks(); err != nil || hooks == nil || hooks.perFile == nil {
+		t.Errorf("Expected the per file hook to compile got %v", err)
+	}
+
+	for _, c := range []struct {
+		command     string
+		concurrency int
+		timeout     string
+	}{
+		{"echo {{.Missing", 4, ""},
+		{"echo {{.Path}}", 0, ""},
+		{"echo {{.Path}}", 4, "soon"},
+	} {
+		ExecOnMismatch, ExecConcurrency, ExecTimeout = c.command, c.concurrency, c.timeout
+		if err := prepareHooks(); err == nil {
+			t.Errorf("Expected error for %q concurrency %d timeout %q", c.command, c.concurrency, c.timeout)
+		}
+	}
+}
+
+func TestHookStatus(t *testing.T) {
+	cases := []struct {
+		res      Result
+		status   string
+		mismatch bool
+	}{
+		{Result{File: "a"}, "OK", false},
+		{Result{File: "a", Error: "permission denied"}, "ERROR", false},
+		{Result{File: "a", Xattr: "FAILED"}, "FAILED", true},
+		{Result{File: "a", Change: "changed"}, "changed", true},
+		{Result{File: "a", Change: "new"}, "new", false},
+	}
+	for _, c := range cases {
+		status := resultStatus(c.res)
+		if status != c.status || isMismatch(status) != c.mismatch {
+			t.Errorf("Expected %s mismatch %v for %+v got %s", c.status, c.mismatch, c.res, status)
+		}
+	}
+
+	for _, s := range []string{"FAILED", "FAILED open or read", "FAILED missing"} {
+		if !isMismatch(s) {
+			t.Errorf("Expected %s to be a mismatch", s)
+		}
+	}
+	if isMismatch("DELETED") {
+		t.Error("Expected DELETED not to be a mismatch")
+	}
+}
@@ -0,0 +1,65 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHooksRun(t *testing.T) {
+	previousFile, previousMismatch, previousConcurrency, previousTimeout := ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout
+	defer func() {
+		ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout = previousFile, previousMismatch, previousConcurrency, previousTimeout
+		hooks = nil
+	}()
+
+	dir := t.TempDir()
+	ExecPerFile = `sh -c 'echo "$1 $HASHIT_STATUS" > "$2"' hook {{.Name}} ` + filepath.Join(dir, "{{.Name}}.seen")
+	ExecOnMismatch = `sh -c 'echo "$1" > "$2"' hook {{index .Expected "md5"}} ` + filepath.Join(dir, "{{.Name}}.mismatch")
+	ExecConcurrency, ExecTimeout = 2, "10s"
+	if err := prepareHooks(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	hooks.fire(Result{File: "/data/good"}, "OK", "manifest", map[string]string{"md5": "aaaa"})
+	hooks.fire(Result{File: "/data/bad"}, "FAILED", "manifest", map[string]string{"md5": "bbbb"})
+	if !finishHooks() {
+		t.Fatal("Expected every hook to succeed")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "bad.mismatch,bad.seen,good.seen" {
+		t.Fatalf("Expected both files seen and one mismatch got %v", names)
+	}
+	seen, _ := os.ReadFile(filepath.Join(dir, "bad.seen"))
+	mismatch, _ := os.ReadFile(filepath.Join(dir, "bad.mismatch"))
+	if string(seen) != "bad FAILED\n" || string(mismatch) != "bbbb\n" {
+		t.Errorf("Unexpected hook output %q %q", seen, mismatch)
+	}
+}
+
+func TestHooksFailures(t *testing.T) {
+	previousFile, previousMismatch, previousConcurrency, previousTimeout := ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout
+	defer func() {
+		ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout = previousFile, previousMismatch, previousConcurrency, previousTimeout
+		hooks = nil
+	}()
+
+	ExecPerFile, ExecOnMismatch, ExecConcurrency, ExecTimeout = "false", "sleep 5", 1, "50ms"
+	if err := prepareHooks(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	hooks.fire(Result{File: "a"}, "FAILED", "", nil)
+	if failed := hooks.wait(); failed != 2 {
+		t.Errorf("Expected the failing and timed out hooks counted got %d", failed)
+	}
+}
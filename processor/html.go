@@ -0,0 +1,299 @@
+package processor
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A bar in one of the summary charts
+type htmlBar struct {
+	Label   string
+	Value   int64
+	Display string
+	Percent float64
+	Class   string
+}
+
+// A row of the results table, Class highlights files which failed or changed
+type htmlRow struct {
+	File    string
+	Bytes   int64
+	MTime   string
+	Digests []string
+	Status  string
+	Class   string
+}
+
+type htmlReport struct {
+	Title     string
+	Host      string
+	Directory string
+	Command   string
+	Version   string
+	Generated string
+	Files     int
+	Failed    int
+	Bytes     string
+	Hashes    []string
+	MTime     bool
+	Rows      []htmlRow
+	Statuses  []htmlBar
+	Types     []htmlBar
+}
+
+var htmlReportTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 1.5em; }
+.meta span { margin-right: 1.5em; }
+.summary { display: flex; flex-wrap: wrap; gap: 2em; margin-bottom: 2em; }
+.card { border: 1px solid #ddd; border-radius: 4px; padding: 1em; min-width: 20em; }
+.card h2 { font-size: 1em; margin: 0 0 0.8em 0; }
+.total { font-size: 2em; font-weight: bold; }
+.bar { display: flex; align-items: center; margin: 0.3em 0; }
+.bar .label { width: 8em; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar .track { flex: 1; background: #eee; height: 1em; margin: 0 0.5em; min-width: 8em; }
+.bar .fill { background: #4a7ebb; height: 100%; }
+.bar .fill.failed { background: #c0392b; }
+.bar .fill.changed { background: #e67e22; }
+.bar .fill.ok { background: #27ae60; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.5em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; position: sticky; top: 0; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.digest { font-family: monospace; word-break: break-all; }
+td.number { text-align: right; }
+tr.failed { background: #fdecea; }
+tr.changed { background: #fff4e5; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">
+<span>Generated {{.Generated}}</span>
+<span>Host {{.Host}}</span>
+<span>Directory {{.Directory}}</span>
+<span>hashit {{.Version}}</span>
+</div>
+<div class="meta"><code>{{.Command}}</code></div>
+<div class="summary">
+<div class="card">
+<h2>Files</h2>
+<div class="total">{{.Files}}</div>
+<div>{{.Bytes}} hashed{{if .Failed}}, <strong>{{.Failed}} failed</strong>{{end}}</div>
+</div>
+<div class="card">
+<h2>Status</h2>
+{{range .Statuses}}<div class="bar"><span class="label">{{.Label}}</span><span class="track"><span class="fill {{.Class}}" style="display:block;width:{{printf "%.1f" .Percent}}%"></span></span><span>{{.Display}}</span></div>
+{{end}}</div>
+<div class="card">
+<h2>Bytes by type</h2>
+{{range .Types}}<div class="bar"><span class="label">{{.Label}}</span><span class="track"><span class="fill" style="display:block;width:{{printf "%.1f" .Percent}}%"></span></span><span>{{.Display}}</span></div>
+{{end}}</div>
+</div>
+<table id="results">
+<thead>
+<tr><th>File</th><th data-type="number">Bytes</th>{{if .MTime}}<th>MTime</th>{{end}}{{range .Hashes}}<th>{{.}}</th>{{end}}<th>Status</th></tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr class="{{.Class}}"><td>{{.File}}</td><td class="number">{{.Bytes}}</td>{{if $.MTime}}<td>{{.MTime}}</td>{{end}}{{range .Digests}}<td class="digest">{{.}}</td>{{end}}<td>{{.Status}}</td></tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#results th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#results tbody");
+    var ascending = !th.classList.contains("asc");
+    document.querySelectorAll("#results th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(ascending ? "asc" : "desc");
+    var numeric = th.dataset.type === "number";
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var c = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+      return ascending ? c : -c;
+    });
+    rows.forEach(function (r) { tbody.appendChild(r); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// Turns a count of bytes into a readable size such as 1.5 MiB
+func formatBytes(b int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	size := float64(b)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", b)
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+// Scales the values so the largest bar fills the chart
+func scaleBars(bars []htmlBar) []htmlBar {
+	var max int64
+	for _, b := range bars {
+		if b.Value > max {
+			max = b.Value
+		}
+	}
+	for i := range bars {
+		if max > 0 {
+			bars[i].Percent = float64(bars[i].Value) / float64(max) * 100
+		}
+	}
+	return bars
+}
+
+// The status shown for a result along with the row highlight
+func htmlStatus(res Result) (string, string) {
+	switch {
+	case res.Error != "":
+		return "ERROR " + res.Error, "failed"
+	case res.Xattr == "FAILED":
+		return "xattr FAILED", "failed"
+	case res.Change == "changed":
+		return "changed", "changed"
+	case res.Change != "":
+		return res.Change, ""
+	}
+	return "OK", ""
+}
+
+// Groups files by extension for the bytes by type chart, keeping the largest few
+func htmlTypeBars(bytesByType map[string]int64) []htmlBar {
+	bars := []htmlBar{}
+	for t, b := range bytesByType {
+		bars = append(bars, htmlBar{Label: t, Value: b, Display: formatBytes(b)})
+	}
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Value != bars[j].Value {
+			return bars[i].Value > bars[j].Value
+		}
+		return bars[i].Label < bars[j].Label
+	})
+
+	if len(bars) > 10 {
+		other := htmlBar{Label: "other"}
+		for _, b := range bars[9:] {
+			other.Value += b.Value
+		}
+		other.Display = formatBytes(other.Value)
+		bars = append(bars[:9], other)
+	}
+	return scaleBars(bars)
+}
+
+// A standalone page with summary charts and a sortable table of every result, meant to
+// be attached to tickets and read without any other tools
+func toHTML(input chan Result) string {
+	names := []string{}
+	displays := []string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) {
+			names = append(names, info.Name)
+			displays = append(displays, info.Display)
+		}
+	}
+	for _, info := range selectedCustomHashes() {
+		names = append(names, info.Name)
+		displays = append(displays, info.Display)
+	}
+
+	report := htmlReport{
+		Title:     "hashit report",
+		Version:   Version,
+		Generated: getFormattedTime(),
+		Hashes:    displays,
+		MTime:     MTime,
+	}
+	var command []string
+	report.Directory, report.Host, command = runContext()
+	report.Command = strings.Join(command, " ")
+
+	statuses := map[string]int64{}
+	bytesByType := map[string]int64{}
+	var total int64
+	for res := range input {
+		status, class := htmlStatus(res)
+		row := htmlRow{File: res.File, Bytes: res.Bytes, Status: status, Class: class}
+
+		if res.Error == "" {
+			hashes := calculatedHashes(res)
+			for _, name := range names {
+				row.Digests = append(row.Digests, hashes[name])
+			}
+			if res.MTime != nil {
+				row.MTime = res.MTime.Format(time.RFC3339Nano)
+			}
+
+			fileType := res.ContentType
+			if fileType == "" {
+				fileType = strings.ToLower(filepath.Ext(res.File))
+			}
+			if fileType == "" {
+				fileType = "none"
+			}
+			bytesByType[fileType] += res.Bytes
+			total += res.Bytes
+		} else {
+			row.Digests = make([]string, len(names))
+			report.Failed++
+		}
+
+		switch {
+		case class == "failed":
+			statuses["failed"]++
+		case res.Change != "":
+			statuses[res.Change]++
+		default:
+			statuses["ok"]++
+		}
+
+		report.Rows = append(report.Rows, row)
+	}
+
+	report.Files = len(report.Rows)
+	report.Bytes = formatBytes(total)
+	for _, s := range []string{"ok", "new", "unchanged", "changed", "unknown", "failed"} {
+		if statuses[s] == 0 {
+			continue
+		}
+		class := ""
+		switch s {
+		case "ok", "unchanged":
+			class = "ok"
+		case "changed":
+			class = "changed"
+		case "failed":
+			class = "failed"
+		}
+		report.Statuses = append(report.Statuses, htmlBar{Label: s, Value: statuses[s], Display: strconv.FormatInt(statuses[s], 10), Class: class})
+	}
+	report.Statuses = scaleBars(report.Statuses)
+	report.Types = htmlTypeBars(bytesByType)
+
+	var str strings.Builder
+	if err := htmlReportTemplate.Execute(&str, report); err != nil {
+		printError("unable to render html report: " + err.Error())
+	}
+	return str.String()
+}
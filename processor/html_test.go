@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 * 1024 * 1024: "5.0 MiB"}
+	for b, expected := range cases {
+		if got := formatBytes(b); got != expected {
+			t.Errorf("Expected %s for %d got %s", expected, b, got)
+		}
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	input := make(chan Result, 3)
+	input <- Result{File: "<script>.js", MD5: "5d41402abc4b2a76b9719d911017c592", Bytes: 5}
+	input <- Result{File: "changed.txt", MD5: "7d793037a0760186574b0282f2f435e7", Bytes: 5, Change: "changed"}
+	input <- Result{File: "missing", Error: "no such file"}
+	close(input)
+
+	out := toHTML(input)
+	if strings.Contains(out, "<script>.js") || !strings.Contains(out, "&lt;script&gt;.js") {
+		t.Error("Expected file names to be escaped")
+	}
+	if !strings.Contains(out, `<tr class="failed"><td>missing</td>`) {
+		t.Error("Expected missing file to be highlighted as failed")
+	}
+	if !strings.Contains(out, `<tr class="changed"><td>changed.txt</td>`) {
+		t.Error("Expected changed file to be highlighted")
+	}
+	if !strings.Contains(out, "5d41402abc4b2a76b9719d911017c592") || !strings.Contains(out, "<th>MD5</th>") {
+		t.Error("Expected md5 column")
+	}
+	if !strings.Contains(out, `<div class="total">3</div>`) {
+		t.Error("Expected total of 3 files")
+	}
+}
+
+func TestHTMLTypeBars(t *testing.T) {
+	bytesByType := map[string]int64{}
+	for i, ext := range []string{".a", ".b", ".c", ".d", ".e", ".f", ".g", ".h", ".i", ".j", ".k", ".l"} {
+		bytesByType[ext] = int64(100 - i)
+	}
+
+	bars := htmlTypeBars(bytesByType)
+	if len(bars) != 10 || bars[0].Label != ".a" {
+		t.Errorf("Unexpected bars %+v", bars)
+	}
+	if bars[9].Label != "other" || bars[9].Value != 91+90+89 || bars[9].Percent != 100 {
+		t.Errorf("Expected remaining types grouped as other got %+v", bars[9])
+	}
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// The files listed from the filesystem in a disk image presented as an fs.FS, so an
+// image is walked by the same walker as a directory on disk
+type imageFS struct {
+	files map[string]imageFile
+	dirs  map[string][]fs.DirEntry
+}
+
+// Builds the tree from the files found. Paths which cannot be placed in it, such as one
+// naming both a file and a directory in a damaged image, are returned to be reported.
+func newImageFS(files []imageFile) (*imageFS, []imageFile) {
+	fsys := &imageFS{files: map[string]imageFile{}, dirs: map[string][]fs.DirEntry{".": nil}}
+	invalid := []imageFile{}
+
+	isDir := map[string]bool{".": true}
+	for _, f := range files {
+		if !fs.ValidPath(f.Path) || f.Path == "." {
+			continue
+		}
+		for dir := path.Dir(f.Path); !isDir[dir]; dir = path.Dir(dir) {
+			isDir[dir] = true
+		}
+	}
+
+	for _, f := range files {
+		if !fs.ValidPath(f.Path) || isDir[f.Path] {
+			invalid = append(invalid, f)
+			continue
+		}
+		fsys.files[f.Path] = f
+		fsys.add(f.Path, imageFileInfo{name: path.Base(f.Path), size: f.Size})
+	}
+	for dir := range isDir {
+		if dir != "." {
+			fsys.add(dir, imageFileInfo{name: path.Base(dir), dir: true})
+		}
+	}
+	for _, entries := range fsys.dirs {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	}
+	return fsys, invalid
+}
+
+func (fsys *imageFS) add(name string, info imageFileInfo) {
+	parent := path.Dir(name)
+	fsys.dirs[parent] = append(fsys.dirs[parent], fs.FileInfoToDirEntry(info))
+	if info.dir && fsys.dirs[name] == nil {
+		fsys.dirs[name] = []fs.DirEntry{}
+	}
+}
+
+func (fsys *imageFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := fsys.files[name]; ok {
+		return imageFileInfo{name: path.Base(name), size: f.Size}, nil
+	}
+	if _, ok := fsys.dirs[name]; ok {
+		return imageFileInfo{name: path.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (fsys *imageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := fsys.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]fs.DirEntry{}, entries...), nil
+}
+
+// Opens a file for reading, its contents are only located in the image on the first read
+func (fsys *imageFS) Open(name string) (fs.File, error) {
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	return &imageOpenFile{info: info, file: fsys.files[name], entries: fsys.dirs[name]}, nil
+}
+
+type imageOpenFile struct {
+	info    fs.FileInfo
+	file    imageFile
+	reader  io.Reader
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (f *imageOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *imageOpenFile) Read(p []byte) (int, error) {
+	if f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+	if f.reader == nil {
+		r, err := f.file.open()
+		if err != nil {
+			return 0, err
+		}
+		f.reader = r
+	}
+	return f.reader.Read(p)
+}
+
+// Lists a directory n entries at a time as fs.ReadDirFile describes
+func (f *imageOpenFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+	rest := f.entries[f.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	f.offset += len(rest)
+	return append([]fs.DirEntry{}, rest...), nil
+}
+
+func (f *imageOpenFile) Close() error { return nil }
+
+// Images are hashed for their contents so only names, sizes and types are kept
+type imageFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i imageFileInfo) Name() string       { return i.name }
+func (i imageFileInfo) Size() int64        { return i.size }
+func (i imageFileInfo) ModTime() time.Time { return time.Time{} }
+func (i imageFileInfo) IsDir() bool        { return i.dir }
+func (i imageFileInfo) Sys() any           { return nil }
+
+func (i imageFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
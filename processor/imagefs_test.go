@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/boyter/hashit/walker"
+)
+
+func testImageFile(path string, content string) imageFile {
+	return imageFile{
+		Path: path,
+		Size: int64(len(content)),
+		open: func() (io.Reader, error) { return bytes.NewReader([]byte(content)), nil },
+	}
+}
+
+func TestImageFS(t *testing.T) {
+	fsys, invalid := newImageFS([]imageFile{
+		testImageFile("b.txt", "bee"),
+		testImageFile("docs/a.txt", "hello"),
+		testImageFile("docs/deep/c.txt", ""),
+	})
+	if len(invalid) != 0 {
+		t.Fatalf("Expected every path placed got %v", invalid)
+	}
+	if err := fstest.TestFS(fsys, "b.txt", "docs/a.txt", "docs/deep/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImageFSInvalid(t *testing.T) {
+	_, invalid := newImageFS([]imageFile{
+		testImageFile("a", "file"),
+		testImageFile("a/b", "also a directory"),
+		testImageFile("../escape", ""),
+	})
+	names := []string{}
+	for _, f := range invalid {
+		names = append(names, f.Path)
+	}
+	if strings.Join(names, ",") != "a,../escape" {
+		t.Errorf("Expected a and ../escape reported got %v", names)
+	}
+}
+
+func TestImageFSWalked(t *testing.T) {
+	fsys, _ := newImageFS([]imageFile{
+		testImageFile("z", ""),
+		testImageFile("skip/a", ""),
+		testImageFile("keep/a", ""),
+	})
+
+	found := []string{}
+	w := walker.Walker{Filters: []walker.Filter{func(p string, d fs.DirEntry) walker.Action {
+		if p == "skip" {
+			return walker.Skip
+		}
+		return walker.Continue
+	}}}
+	_ = w.Walk(context.Background(), fsys, ".", func(p string, d fs.DirEntry) error {
+		found = append(found, p)
+		return nil
+	})
+	if strings.Join(found, ",") != "keep/a,z" {
+		t.Errorf("Expected keep/a,z got %v", found)
+	}
+}
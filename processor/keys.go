@@ -0,0 +1,461 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/minio/blake2b-simd"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// Extensions of the key files kept in the key directory for each type of key
+var keyExtensions = map[string][2]string{
+	"minisign": {".key", ".pub"},
+	"age":      {".agekey", ".agepub"},
+}
+
+// The scrypt limits minisign -G encrypts secret keys with, lowered by tests
+var (
+	keyScryptOps uint64 = 33554432
+	keyScryptMem uint64 = 1073741824
+)
+
+// DefaultKeyDir is where keys are kept, HASHIT_KEYS_DIR or hashit/keys in the user's
+// config directory
+func DefaultKeyDir() string {
+	if dir := os.Getenv("HASHIT_KEYS_DIR"); dir != "" {
+		return dir
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "keys"
+	}
+	return filepath.Join(config, "hashit", "keys")
+}
+
+// Reads the passphrase from HASHIT_SIGN_PASSWORD or asks for it on the terminal, twice
+// when confirm is set as it is protecting a new key
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if password := os.Getenv(signPasswordEnv); password != "" {
+		return password, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to ask for a passphrase, set %s", signPasswordEnv)
+	}
+
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	password, err := ask(prompt + ": ")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("the passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := ask("Confirm " + strings.ToLower(prompt[:1]) + prompt[1:] + ": ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return password, nil
+}
+
+// The id minisign prints for a key number
+func minisignKeyID(keyNum [8]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyNum[:]))
+}
+
+func minisignKeyEncrypted(data []byte) bool {
+	lines := minisignLines(data)
+	if len(lines) < 2 {
+		return false
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	return err == nil && len(raw) == 158 && bytes.Equal(raw[2:4], minisignKdfScrypt)
+}
+
+// Creates a minisign key pair in the same format as minisign -G, the secret key
+// encrypted with the password unless it is empty
+func generateMinisignKeys(password string) ([]byte, []byte, string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var keyNum [8]byte
+	if _, err := rand.Read(keyNum[:]); err != nil {
+		return nil, nil, "", err
+	}
+
+	checksum := blake2b.New256()
+	checksum.Write(minisignAlgEd)
+	checksum.Write(keyNum[:])
+	checksum.Write(priv)
+	keyNumSk := append(append(append([]byte{}, keyNum[:]...), priv...), checksum.Sum(nil)...)
+
+	kdf := minisignKdfNone
+	salt := make([]byte, 32)
+	limits := make([]byte, 16)
+	comment := "minisign secret key"
+	if password != "" {
+		kdf = minisignKdfScrypt
+		comment = "minisign encrypted secret key"
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, "", err
+		}
+		binary.LittleEndian.PutUint64(limits[0:8], keyScryptOps)
+		binary.LittleEndian.PutUint64(limits[8:16], keyScryptMem)
+		n, r, p := scryptParams(keyScryptOps, keyScryptMem)
+		stream, err := scrypt.Key([]byte(password), salt, n, r, p, len(keyNumSk))
+		if err != nil {
+			return nil, nil, "", err
+		}
+		for i := range keyNumSk {
+			keyNumSk[i] ^= stream[i]
+		}
+	}
+
+	raw := append(append(append(append(append([]byte{}, minisignAlgEd...), kdf...), minisignChecksumAlg...), salt...), limits...)
+	raw = append(raw, keyNumSk...)
+	id := minisignKeyID(keyNum)
+	secret := "untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+	public := "untrusted comment: minisign public key " + id + "\n" +
+		base64.StdEncoding.EncodeToString(append(append(append([]byte{}, minisignAlgEd...), keyNum[:]...), pub...)) + "\n"
+	return []byte(secret), []byte(public), id, nil
+}
+
+// An age recipient has no id of its own so a short digest of it stands in
+func ageKeyID(recipient string) string {
+	sum := sha256.Sum256([]byte(recipient))
+	return strings.ToUpper(hex.EncodeToString(sum[:8]))
+}
+
+// Creates an age identity and its recipient, the identity armored and encrypted with
+// the password unless it is empty which age -d -i accepts either way
+func generateAgeKeys(password string) ([]byte, []byte, string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	recipient := identity.Recipient().String()
+	plain := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n", time.Now().UTC().Format(time.RFC3339), recipient, identity.String())
+
+	secret := []byte(plain)
+	if password != "" {
+		r, err := age.NewScryptRecipient(password)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		var buf bytes.Buffer
+		a := armor.NewWriter(&buf)
+		w, err := age.Encrypt(a, r)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		_, _ = io.WriteString(w, plain)
+		if err := w.Close(); err != nil {
+			return nil, nil, "", err
+		}
+		if err := a.Close(); err != nil {
+			return nil, nil, "", err
+		}
+		secret = buf.Bytes()
+	}
+	return secret, []byte(recipient + "\n"), ageKeyID(recipient), nil
+}
+
+func generateKeys(keyType string, password string) ([]byte, []byte, string, error) {
+	if keyType == "age" {
+		return generateAgeKeys(password)
+	}
+	return generateMinisignKeys(password)
+}
+
+// Writes a new key pair named name into dir refusing to replace an existing key
+func generateKey(dir string, name string, keyType string, password string) (string, error) {
+	ext, ok := keyExtensions[keyType]
+	if !ok {
+		return "", fmt.Errorf("unknown key type %s expected minisign or age", keyType)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid key name %s", name)
+	}
+	if existing, _ := findKeyType(dir, name); existing != "" {
+		return "", fmt.Errorf("key %s already exists, use keys rotate to replace it", name)
+	}
+
+	secret, public, id, err := generateKeys(keyType, password)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := writeNewFile(filepath.Join(dir, name+ext[0]), secret, 0600); err != nil {
+		return "", err
+	}
+	return id, writeNewFile(filepath.Join(dir, name+ext[1]), public, 0644)
+}
+
+// Creates the file failing if it already exists so keys are never overwritten
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// The type of the active key named name, empty when there is none
+func findKeyType(dir string, name string) (string, error) {
+	for keyType, ext := range keyExtensions {
+		if _, err := os.Stat(filepath.Join(dir, name+ext[1])); err == nil {
+			return keyType, nil
+		}
+	}
+	return "", fmt.Errorf("no key named %s in %s", name, dir)
+}
+
+// Reads the id of a public key file
+func publicKeyID(keyType string, data []byte) (string, error) {
+	if keyType == "age" {
+		recipient := strings.TrimSpace(string(data))
+		if _, err := age.ParseX25519Recipient(recipient); err != nil {
+			return "", err
+		}
+		return ageKeyID(recipient), nil
+	}
+	key, err := parseMinisignPublicKey(data)
+	if err != nil {
+		return "", err
+	}
+	return minisignKeyID(key.KeyNum), nil
+}
+
+// Replaces the key named name with a new one of the same type, keeping the old files
+// as name.<id>.retired. A new minisign public key is signed with the old secret key so
+// anyone trusting the old key can check the new one with minisign -V.
+func rotateKey(dir string, name string, oldPassword string, newPassword string) (string, string, error) {
+	keyType, err := findKeyType(dir, name)
+	if err != nil {
+		return "", "", err
+	}
+	ext := keyExtensions[keyType]
+	secretPath, publicPath := filepath.Join(dir, name+ext[0]), filepath.Join(dir, name+ext[1])
+
+	oldPublic, err := os.ReadFile(publicPath)
+	if err != nil {
+		return "", "", err
+	}
+	oldID, err := publicKeyID(keyType, oldPublic)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read %s: %w", publicPath, err)
+	}
+
+	var oldSecret minisignSecretKey
+	if keyType == "minisign" {
+		data, err := os.ReadFile(secretPath)
+		if err != nil {
+			return "", "", err
+		}
+		if oldSecret, err = parseMinisignSecretKey(data, oldPassword); err != nil {
+			return "", "", fmt.Errorf("unable to read %s: %w", secretPath, err)
+		}
+	}
+
+	secret, public, newID, err := generateKeys(keyType, newPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	retired := filepath.Join(dir, name+"."+oldID+".retired")
+	if err := os.Rename(secretPath, retired+ext[0]); err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(publicPath, retired+ext[1]); err != nil {
+		return "", "", err
+	}
+	if err := writeNewFile(secretPath, secret, 0600); err != nil {
+		return "", "", err
+	}
+	if err := writeNewFile(publicPath, public, 0644); err != nil {
+		return "", "", err
+	}
+
+	if keyType == "minisign" {
+		comment := fmt.Sprintf("timestamp:%d\tfile:%s\trotated from %s to %s", time.Now().Unix(), filepath.Base(publicPath), oldID, newID)
+		if err := os.WriteFile(publicPath+signatureExtension, minisignSign(oldSecret, public, comment), 0644); err != nil {
+			return "", "", err
+		}
+	}
+	return oldID, newID, nil
+}
+
+// A key found in the key directory
+type keyListing struct {
+	Name      string
+	Type      string
+	ID        string
+	Encrypted bool
+	Created   time.Time
+	Retired   bool
+}
+
+func listKeys(dir string) ([]keyListing, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []keyListing{}
+	for _, e := range entries {
+		for keyType, ext := range keyExtensions {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ext[1]) {
+				continue
+			}
+			base := strings.TrimSuffix(e.Name(), ext[1])
+			public, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			id, err := publicKeyID(keyType, public)
+			if err != nil {
+				printVerbose("skipping unreadable public key", "file", e.Name(), "error", err.Error())
+				continue
+			}
+
+			k := keyListing{Name: base, Type: keyType, ID: id}
+			if strings.HasSuffix(base, ".retired") {
+				k.Retired = true
+				k.Name = strings.TrimSuffix(strings.TrimSuffix(base, ".retired"), "."+id)
+			}
+			if info, err := e.Info(); err == nil {
+				k.Created = info.ModTime()
+			}
+			if secret, err := os.ReadFile(filepath.Join(dir, base+ext[0])); err == nil {
+				k.Encrypted = minisignKeyEncrypted(secret) || bytes.HasPrefix(secret, []byte(armor.Header))
+			}
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		if keys[i].Retired != keys[j].Retired {
+			return !keys[i].Retired
+		}
+		return keys[i].Created.After(keys[j].Created)
+	})
+	return keys, nil
+}
+
+// KeysGenerate creates a minisign or age key pair named name in dir, asking for the
+// passphrase that encrypts it unless unencrypted
+func KeysGenerate(dir string, name string, keyType string, unencrypted bool) {
+	password := ""
+	if !unencrypted {
+		var err error
+		if password, err = readPassphrase("Passphrase for the new key", true); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	id, err := generateKey(dir, name, keyType, password)
+	if err != nil {
+		printError(fmt.Sprintf("unable to generate key %s: %s", name, err.Error()))
+		os.Exit(1)
+	}
+	ext := keyExtensions[keyType]
+	fmt.Printf("%s key %s written to %s\n", keyType, id, filepath.Join(dir, name+ext[0]))
+	fmt.Printf("public key written to %s\n", filepath.Join(dir, name+ext[1]))
+}
+
+// KeysRotate replaces the key named name in dir with a new one retiring the old
+func KeysRotate(dir string, name string, unencrypted bool) {
+	keyType, err := findKeyType(dir, name)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	oldPassword := ""
+	if keyType == "minisign" {
+		data, err := os.ReadFile(filepath.Join(dir, name+keyExtensions[keyType][0]))
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		if minisignKeyEncrypted(data) {
+			if oldPassword, err = readPassphrase("Passphrase for the current key", false); err != nil {
+				printError(err.Error())
+				os.Exit(1)
+			}
+		}
+	}
+	newPassword := ""
+	if !unencrypted {
+		if newPassword, err = readPassphrase("Passphrase for the new key", true); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	oldID, newID, err := rotateKey(dir, name, oldPassword, newPassword)
+	if err != nil {
+		printError(fmt.Sprintf("unable to rotate key %s: %s", name, err.Error()))
+		os.Exit(1)
+	}
+	fmt.Printf("key %s rotated from %s to %s\n", name, oldID, newID)
+}
+
+// KeysList prints the keys in dir, active keys first
+func KeysList(dir string) {
+	keys, err := listKeys(dir)
+	if err != nil {
+		printError(fmt.Sprintf("unable to list keys in %s: %s", dir, err.Error()))
+		os.Exit(1)
+	}
+
+	for _, k := range keys {
+		status := "active"
+		if k.Retired {
+			status = "retired"
+		}
+		encrypted := "unencrypted"
+		if k.Encrypted {
+			encrypted = "encrypted"
+		}
+		fmt.Printf("%-16s %-8s %-16s %-11s %-7s %s\n", k.Name, k.Type, k.ID, encrypted, status, k.Created.Format("2006-01-02"))
+	}
+}
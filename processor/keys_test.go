@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func lowerKeyScrypt(t *testing.T) {
+	previousOps, previousMem := keyScryptOps, keyScryptMem
+	t.Cleanup(func() { keyScryptOps, keyScryptMem = previousOps, previousMem })
+	keyScryptOps, keyScryptMem = 32768, 1<<24
+}
+
+func TestGenerateMinisignKey(t *testing.T) {
+	lowerKeyScrypt(t)
+	dir := t.TempDir()
+
+	id, err := generateKey(dir, "release", "minisign", "hunter2")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	secret, _ := os.ReadFile(filepath.Join(dir, "release.key"))
+	public, _ := os.ReadFile(filepath.Join(dir, "release.pub"))
+	if !minisignKeyEncrypted(secret) || !strings.Contains(string(public), id) {
+		t.Errorf("Expected an encrypted key with id %s in the public key", id)
+	}
+
+	sk, err := parseMinisignSecretKey(secret, "hunter2")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	pk, _ := parseMinisignPublicKey(public)
+	sig := minisignSign(sk, []byte("content"), "comment")
+	if _, err := minisignVerify(pk, []byte("content"), sig); err != nil {
+		t.Errorf("Expected the generated keys to sign and verify got %s", err.Error())
+	}
+
+	if _, err := generateKey(dir, "release", "age", ""); err == nil {
+		t.Error("Expected an existing key not to be replaced")
+	}
+	if _, err := generateKey(dir, "../escape", "minisign", ""); err == nil {
+		t.Error("Expected a name with a path to be refused")
+	}
+}
+
+func TestGenerateAgeKey(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := generateKey(dir, "backup", "age", "hunter2"); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	secret, _ := os.ReadFile(filepath.Join(dir, "backup.agekey"))
+	public, _ := os.ReadFile(filepath.Join(dir, "backup.agepub"))
+	if !strings.HasPrefix(string(secret), armor.Header) {
+		t.Fatalf("Expected the identity to be armored and encrypted got %s", secret)
+	}
+
+	scrypt, _ := age.NewScryptIdentity("hunter2")
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(string(secret))), scrypt)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	identities, err := age.ParseIdentities(r)
+	if err != nil || len(identities) != 1 {
+		t.Fatalf("Expected one identity got %v %v", identities, err)
+	}
+	if identities[0].(*age.X25519Identity).Recipient().String() != strings.TrimSpace(string(public)) {
+		t.Error("Expected the recipient to belong to the identity")
+	}
+}
+
+func TestRotateAndListKeys(t *testing.T) {
+	lowerKeyScrypt(t)
+	dir := t.TempDir()
+
+	oldID, err := generateKey(dir, "release", "minisign", "")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := generateKey(dir, "backup", "age", ""); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	rotatedFrom, newID, err := rotateKey(dir, "release", "", "hunter2")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if rotatedFrom != oldID || newID == oldID {
+		t.Errorf("Unexpected rotation from %s to %s", rotatedFrom, newID)
+	}
+
+	// Anyone trusting the old key can check the new public key
+	comment, err := verifyFileSignature(filepath.Join(dir, "release.pub"), filepath.Join(dir, "release."+oldID+".retired.pub"))
+	if err != nil || !strings.Contains(comment, newID) {
+		t.Errorf("Expected the new key to be signed by the old got %q %v", comment, err)
+	}
+
+	keys, err := listKeys(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected three keys got %+v", keys)
+	}
+	if keys[0].Name != "backup" || keys[0].Type != "age" || keys[0].Encrypted {
+		t.Errorf("Unexpected age key %+v", keys[0])
+	}
+	if keys[1].Name != "release" || keys[1].ID != newID || keys[1].Retired || !keys[1].Encrypted {
+		t.Errorf("Unexpected active key %+v", keys[1])
+	}
+	if keys[2].Name != "release" || keys[2].ID != oldID || !keys[2].Retired {
+		t.Errorf("Unexpected retired key %+v", keys[2])
+	}
+}
+
+func TestReadSecretKeyByName(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HASHIT_KEYS_DIR", dir)
+	t.Setenv(signPasswordEnv, "")
+	if _, err := generateKey(dir, "release", "minisign", ""); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if _, err := readSecretKey("release"); err != nil {
+		t.Errorf("Expected the key to be found by name got %s", err.Error())
+	}
+	if _, err := readPublicKey("release"); err != nil {
+		t.Errorf("Expected the public key to be found by name got %s", err.Error())
+	}
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Digests from the known files keyed by hash name then digest, recording the file each
+// digest was listed against, along with any known filters which are checked on disk
+type knownHashes struct {
+	digests map[string]map[string]string
+	filters []*knownFilter
+}
+
+// Check the matching flags are used together the way hashdeep allows
+func validateKnown() error {
+	switch {
+	case Match && MatchNegative:
+		return errors.New("--match and --match-negative cannot be used together")
+	case (Match || MatchNegative) && len(Known) == 0:
+		return errors.New("--match and --match-negative require known files supplied with --known")
+	case len(Known) != 0 && !Match && !MatchNegative:
+		return errors.New("--known requires --match or --match-negative")
+	}
+	return nil
+}
+
+// Reads every known file, which may be in any format --check accepts including hashdeep,
+// keeping only the digests of hashes that are being calculated. Filters written by
+// build-filter are opened rather than read.
+func loadKnown(paths []string) (knownHashes, error) {
+	known := knownHashes{digests: map[string]map[string]string{}}
+	filtered := false
+	for _, path := range paths {
+		if isKnownFilter(path) {
+			filter, err := openKnownFilter(path)
+			if err != nil {
+				return known, err
+			}
+			for name := range filter.sections {
+				filtered = filtered || hasHash(name)
+			}
+			known.filters = append(known.filters, filter)
+			continue
+		}
+
+		data, err := readManifest(path)
+		if err != nil {
+			return known, err
+		}
+		entries, err := parseManifest(data)
+		if err != nil {
+			return known, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, e := range entries {
+			for _, d := range e.Digests {
+				for _, name := range d.Names {
+					if !hasHash(name) {
+						continue
+					}
+					if known.digests[name] == nil {
+						known.digests[name] = map[string]string{}
+					}
+					known.digests[name][d.Digest] = e.File
+				}
+			}
+		}
+	}
+
+	if len(known.digests) == 0 && !filtered {
+		return known, fmt.Errorf("known files contain none of the selected hashes %s", strings.Join(Hash, ","))
+	}
+	return known, nil
+}
+
+// Reports the known file entry the result matches, if any of its hashes are listed.
+// A filter only says a digest is probably known so reports the filter itself.
+func (k knownHashes) match(res Result) (string, bool) {
+	hashes := calculatedHashes(res)
+	for name, digest := range hashes {
+		if file, ok := k.digests[name][strings.ToLower(digest)]; ok {
+			return file, true
+		}
+	}
+	for _, f := range k.filters {
+		for name, digest := range hashes {
+			if f.contains(name, strings.ToLower(digest)) {
+				return f.path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Passes on only the results which match a known file, or with negative only those which
+// do not, like hashdeep -m and -x. Files which could not be hashed have already been
+// reported so are dropped.
+func filterKnown(input chan Result, known knownHashes, negative bool) chan Result {
+	output := make(chan Result, FileListQueueSize)
+
+	go func() {
+		for res := range input {
+			if res.Error != "" {
+				continue
+			}
+			file, matched := known.match(res)
+			if matched != negative {
+				if matched {
+					printDebug("known file matched", "file", res.File, "known", file)
+				}
+				output <- res
+			}
+		}
+		close(output)
+	}()
+
+	return output
+}
+
+// Lists the file names alone which is what hashdeep prints when matching
+func toFileNames(input chan Result) string {
+	var str strings.Builder
+	printed := 0
+	for res := range input {
+		str.WriteString(res.File + "\n")
+		streamOutput(&str, &printed)
+	}
+	return str.String()
+}
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterKnown(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	dir := t.TempDir()
+	knownFile := filepath.Join(dir, "known.txt")
+	_ = os.WriteFile(knownFile, []byte("%%%% HASHDEEP-1.0\n%%%% size,md5,sha256,filename\n5,5d41402abc4b2a76b9719d911017c592,,/elsewhere/hello\n"), 0600)
+
+	known, err := loadKnown([]string{knownFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []Result{
+		{File: "hello", MD5: "5d41402abc4b2a76b9719d911017c592"},
+		{File: "other", MD5: "7d793037a0760186574b0282f2f435e7"},
+		{File: "broken", Error: "permission denied"},
+	}
+	filter := func(negative bool) []string {
+		input := make(chan Result, len(results))
+		for _, r := range results {
+			input <- r
+		}
+		close(input)
+
+		files := []string{}
+		for r := range filterKnown(input, known, negative) {
+			files = append(files, r.File)
+		}
+		return files
+	}
+
+	if files := filter(false); len(files) != 1 || files[0] != "hello" {
+		t.Errorf("expected only hello to match got %v", files)
+	}
+	if files := filter(true); len(files) != 1 || files[0] != "other" {
+		t.Errorf("expected only other to not match got %v", files)
+	}
+
+	Hash = []string{HashNames.SHA1}
+	if _, err := loadKnown([]string{knownFile}); err == nil {
+		t.Error("expected an error when the known files share no hashes with those selected")
+	}
+}
+
+func TestValidateKnown(t *testing.T) {
+	defer func() { Known, Match, MatchNegative = []string{}, false, false }()
+
+	cases := []struct {
+		known         []string
+		match, negate bool
+		valid         bool
+	}{
+		{nil, false, false, true},
+		{[]string{"known.txt"}, true, false, true},
+		{[]string{"known.txt"}, false, true, true},
+		{[]string{"known.txt"}, true, true, false},
+		{[]string{"known.txt"}, false, false, false},
+		{nil, true, false, false},
+	}
+	for _, c := range cases {
+		Known, Match, MatchNegative = c.known, c.match, c.negate
+		if err := validateKnown(); (err == nil) != c.valid {
+			t.Errorf("%+v expected valid %v got %v", c, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Known filters are blocked bloom filters, one section per hash, where every bit for a
+// digest falls in the same 64 byte block so checking a digest is a single read from the
+// file rather than needing the set in memory. With 24 bits per digest and 10 probes fewer
+// than one digest in a thousand that was never added is reported as known.
+const (
+	knownFilterVersion   = 1
+	knownFilterBlockSize = 64
+	knownFilterBits      = 24
+	knownFilterProbes    = 10
+	knownFilterNameSize  = 32
+	knownFilterHeader    = 24
+	knownFilterEntry     = knownFilterNameSize + 24
+)
+
+var knownFilterMagic = []byte("HASHITBF")
+
+// Where a hash's blocks are in the filter file
+type knownFilterSection struct {
+	Offset uint64
+	Blocks uint64
+	Count  uint64
+}
+
+// An open filter file checked with a read per digest
+type knownFilter struct {
+	path     string
+	file     *os.File
+	sections map[string]knownFilterSection
+}
+
+func isKnownFilter(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(knownFilterMagic))
+	_, err = io.ReadFull(file, magic)
+	return err == nil && bytes.Equal(magic, knownFilterMagic)
+}
+
+// The block a digest belongs in and the bits it sets within the block
+func knownFilterProbe(raw []byte, blocks uint64) (uint64, [knownFilterProbes]uint16) {
+	h := xxhash.Sum64(raw)
+	block, _ := bits.Mul64(h, blocks)
+
+	// Derive the probes from a remix of the hash so they are independent of the block
+	m := h ^ (h >> 33)
+	m *= 0xff51afd7ed558ccd
+	m ^= m >> 33
+	h1, h2 := uint32(m), uint32(m>>32)|1
+
+	var probes [knownFilterProbes]uint16
+	for i := range probes {
+		probes[i] = uint16((h1 + uint32(i)*h2) % (knownFilterBlockSize * 8))
+	}
+	return block, probes
+}
+
+func knownFilterBlocks(count uint64) uint64 {
+	blocks := (count*knownFilterBits + knownFilterBlockSize*8 - 1) / (knownFilterBlockSize * 8)
+	if blocks == 0 {
+		blocks = 1
+	}
+	return blocks
+}
+
+// Pulls the digests of the selected hashes out of a line of any text hash list, sum,
+// hashdeep and NSRL style CSV files alike, routed by their length
+func knownFilterDigests(line string, lengths map[int][]string, found func(name string, raw []byte)) {
+	if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") {
+		return
+	}
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == '"' || r == '*' || unicode.IsSpace(r)
+	})
+	for _, f := range fields {
+		names, ok := lengths[len(f)]
+		if !ok {
+			continue
+		}
+		raw, err := hex.DecodeString(f)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			found(name, raw)
+		}
+	}
+}
+
+// Reads every source line by line calling found for each digest
+func scanKnownFilterSources(sources []string, lengths map[int][]string, found func(name string, raw []byte)) error {
+	for _, source := range sources {
+		stream, err := openManifestStream(source)
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			knownFilterDigests(scanner.Text(), lengths, found)
+		}
+		err = scanner.Err()
+		_ = stream.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// Builds a filter of the selected hashes' digests found in the sources. The sources are
+// read twice, once to size the filter and once to fill it, so only the filter itself is
+// held in memory.
+func buildKnownFilter(output string, sources []string) (map[string]knownFilterSection, error) {
+	lengths := map[int][]string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) && !info.Analysis {
+			lengths[info.Bits/4] = append(lengths[info.Bits/4], info.Name)
+		}
+	}
+	if len(lengths) == 0 {
+		return nil, errors.New("no hashes selected to build the filter for")
+	}
+
+	counts := map[string]uint64{}
+	if err := scanKnownFilterSources(sources, lengths, func(name string, _ []byte) { counts[name]++ }); err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("sources contain no digests of the selected hashes %s", strings.Join(Hash, ","))
+	}
+
+	names := []string{}
+	for _, info := range HashInfos {
+		if counts[info.Name] != 0 {
+			names = append(names, info.Name)
+		}
+	}
+
+	sections := map[string]knownFilterSection{}
+	data := map[string][]byte{}
+	offset := uint64(knownFilterHeader + knownFilterEntry*len(names))
+	offset = (offset + knownFilterBlockSize - 1) / knownFilterBlockSize * knownFilterBlockSize
+	for _, name := range names {
+		s := knownFilterSection{Offset: offset, Blocks: knownFilterBlocks(counts[name]), Count: counts[name]}
+		sections[name] = s
+		data[name] = make([]byte, s.Blocks*knownFilterBlockSize)
+		offset += s.Blocks * knownFilterBlockSize
+	}
+
+	err := scanKnownFilterSources(sources, lengths, func(name string, raw []byte) {
+		block, probes := knownFilterProbe(raw, sections[name].Blocks)
+		b := data[name][block*knownFilterBlockSize : (block+1)*knownFilterBlockSize]
+		for _, p := range probes {
+			b[p/8] |= 1 << (p % 8)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sections, writeKnownFilter(output, names, sections, data)
+}
+
+func writeKnownFilter(output string, names []string, sections map[string]knownFilterSection, data map[string][]byte) error {
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+
+	header := make([]byte, knownFilterHeader)
+	copy(header, knownFilterMagic)
+	binary.LittleEndian.PutUint32(header[8:], knownFilterVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(names)))
+	binary.LittleEndian.PutUint32(header[16:], knownFilterProbes)
+	_, _ = w.Write(header)
+
+	written := uint64(knownFilterHeader)
+	for _, name := range names {
+		s := sections[name]
+		entry := make([]byte, knownFilterEntry)
+		copy(entry, name)
+		binary.LittleEndian.PutUint64(entry[knownFilterNameSize:], s.Offset)
+		binary.LittleEndian.PutUint64(entry[knownFilterNameSize+8:], s.Blocks)
+		binary.LittleEndian.PutUint64(entry[knownFilterNameSize+16:], s.Count)
+		_, _ = w.Write(entry)
+		written += knownFilterEntry
+	}
+	for _, name := range names {
+		_, _ = w.Write(make([]byte, sections[name].Offset-written))
+		_, _ = w.Write(data[name])
+		written = sections[name].Offset + uint64(len(data[name]))
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Opens a filter reading only its section table
+func openKnownFilter(path string) (*knownFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	fail := func(reason string) (*knownFilter, error) {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s is not a valid known filter: %s", path, reason)
+	}
+
+	header := make([]byte, knownFilterHeader)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header[:8], knownFilterMagic) {
+		return fail("missing header")
+	}
+	if v := binary.LittleEndian.Uint32(header[8:]); v != knownFilterVersion {
+		return fail(fmt.Sprintf("unsupported version %d", v))
+	}
+	if k := binary.LittleEndian.Uint32(header[16:]); k != knownFilterProbes {
+		return fail(fmt.Sprintf("unsupported probe count %d", k))
+	}
+
+	filter := &knownFilter{path: path, file: file, sections: map[string]knownFilterSection{}}
+	count := binary.LittleEndian.Uint32(header[12:])
+	entry := make([]byte, knownFilterEntry)
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(file, entry); err != nil {
+			return fail("truncated section table")
+		}
+		name := string(bytes.TrimRight(entry[:knownFilterNameSize], "\x00"))
+		s := knownFilterSection{
+			Offset: binary.LittleEndian.Uint64(entry[knownFilterNameSize:]),
+			Blocks: binary.LittleEndian.Uint64(entry[knownFilterNameSize+8:]),
+			Count:  binary.LittleEndian.Uint64(entry[knownFilterNameSize+16:]),
+		}
+		if s.Blocks == 0 || s.Offset+s.Blocks*knownFilterBlockSize > uint64(info.Size()) {
+			return fail("section " + name + " is truncated")
+		}
+		filter.sections[name] = s
+	}
+	return filter, nil
+}
+
+// Reports whether the digest is probably in the filter, an error reading the filter is
+// treated as not known
+func (f *knownFilter) contains(name string, digest string) bool {
+	s, ok := f.sections[name]
+	if !ok || digest == "" {
+		return false
+	}
+	raw, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	block, probes := knownFilterProbe(raw, s.Blocks)
+	b := make([]byte, knownFilterBlockSize)
+	if _, err := f.file.ReadAt(b, int64(s.Offset+block*knownFilterBlockSize)); err != nil {
+		printError(fmt.Sprintf("unable to read known filter %s: %s", f.path, err.Error()))
+		return false
+	}
+	for _, p := range probes {
+		if b[p/8]&(1<<(p%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildKnownFilter writes a filter of the digests of the selected hashes found in the
+// sources which --known accepts in place of a manifest
+func BuildKnownFilter(output string, sources []string) {
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	sections, err := buildKnownFilter(output, sources)
+	if err != nil {
+		printError(fmt.Sprintf("unable to build known filter %s: %s", output, err.Error()))
+		os.Exit(1)
+	}
+
+	for _, info := range HashInfos {
+		if s, ok := sections[info.Name]; ok {
+			fmt.Printf("%s: %d digests in %s\n", info.Name, s.Count, formatBytes(int64(s.Blocks*knownFilterBlockSize)))
+		}
+	}
+	fmt.Printf("filter written to %s\n", output)
+}
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKnownFilterDigests(t *testing.T) {
+	lengths := map[int][]string{32: {HashNames.MD5}, 40: {HashNames.SHA1}}
+	found := map[string]int{}
+	count := func(name string, _ []byte) { found[name]++ }
+
+	knownFilterDigests(`"0000000000000000000000000000000000000000","5D41402ABC4B2A76B9719D911017C592","00000000","file.txt",5`, lengths, count)
+	knownFilterDigests("5d41402abc4b2a76b9719d911017c592  file.txt", lengths, count)
+	knownFilterDigests("%%%% size,md5,filename", lengths, count)
+	knownFilterDigests("zz41402abc4b2a76b9719d911017c592", lengths, count)
+	if found[HashNames.MD5] != 2 || found[HashNames.SHA1] != 1 {
+		t.Errorf("Unexpected digests found %v", found)
+	}
+}
+
+func TestKnownFilterRoundTrip(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.SHA1, HashNames.MD5}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "NSRLFile.txt")
+	var str strings.Builder
+	str.WriteString(`"SHA-1","MD5","CRC32","FileName"` + "\n")
+	for i := 0; i < 5000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("known %d", i)))
+		str.WriteString(fmt.Sprintf("\"%X\",\"5D41402ABC4B2A76B9719D911017C592\",\"00000000\",\"file%d\"\n", sum, i))
+	}
+	_ = os.WriteFile(source, []byte(str.String()), 0600)
+
+	output := filepath.Join(dir, "known.filter")
+	sections, err := buildKnownFilter(output, []string{source})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if sections[HashNames.SHA1].Count != 5000 || sections[HashNames.MD5].Count != 5000 {
+		t.Errorf("Unexpected sections %+v", sections)
+	}
+
+	if !isKnownFilter(output) || isKnownFilter(source) {
+		t.Fatal("Expected only the filter to be detected as one")
+	}
+	filter, err := openKnownFilter(output)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	defer filter.file.Close()
+
+	for i := 0; i < 5000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("known %d", i)))
+		if !filter.contains(HashNames.SHA1, hex.EncodeToString(sum[:])) {
+			t.Fatalf("Expected digest %d to be in the filter", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 100000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("unknown %d", i)))
+		if filter.contains(HashNames.SHA1, hex.EncodeToString(sum[:])) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 100 {
+		t.Errorf("Expected fewer than one in a thousand false positives got %d in 100000", falsePositives)
+	}
+	if filter.contains(HashNames.SHA256, "5d41402abc4b2a76b9719d911017c592") {
+		t.Error("Expected hashes without a section never to match")
+	}
+}
+
+func TestFilterKnownWithFilter(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "known.md5")
+	_ = os.WriteFile(source, []byte("5d41402abc4b2a76b9719d911017c592\n"), 0600)
+	output := filepath.Join(dir, "known.filter")
+	if _, err := buildKnownFilter(output, []string{source}); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	known, err := loadKnown([]string{output})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	input := make(chan Result, 2)
+	input <- Result{File: "hello", MD5: "5d41402abc4b2a76b9719d911017c592"}
+	input <- Result{File: "other", MD5: "7d793037a0760186574b0282f2f435e7"}
+	close(input)
+
+	files := []string{}
+	for r := range filterKnown(input, known, false) {
+		files = append(files, r.File)
+	}
+	if len(files) != 1 || files[0] != "hello" {
+		t.Errorf("Expected only hello to match got %v", files)
+	}
+
+	Hash = []string{HashNames.SHA1}
+	if _, err := loadKnown([]string{output}); err == nil {
+		t.Error("Expected an error when the filter has none of the selected hashes")
+	}
+}
+
+func TestOpenKnownFilterTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "truncated.filter")
+	_ = os.WriteFile(path, append([]byte("HASHITBF"), 1, 0, 0, 0), 0600)
+	if _, err := openKnownFilter(path); err == nil {
+		t.Error("Expected error for a truncated filter")
+	}
+}
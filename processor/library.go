@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// HashFile hashes a single file with the current options for use as a library. Problems
+// reading the file are returned as an error rather than an error record.
+func HashFile(path string) (Result, error) {
+	if err := prepareOptions(); err != nil {
+		return Result{}, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Result{}, err
+	}
+	if !fi.Mode().IsRegular() {
+		return Result{}, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	res := hashFiles([]string{path})[path]
+	if res.Error != "" {
+		return Result{}, errors.New(res.Error)
+	}
+	return res, nil
+}
+
+// HashBytes hashes content already in memory with the current options, name is
+// recorded as the File of the result
+func HashBytes(name string, content []byte) (Result, error) {
+	if err := prepareOptions(); err != nil {
+		return Result{}, err
+	}
+
+	res, err := processStream(name, bytes.NewReader(content), nil, len(content), nil, TextNormalize)
+	if err != nil {
+		return Result{}, err
+	}
+	res.File = name
+	res.Bytes = int64(len(content))
+	return res, nil
+}
+
+// Audit rehashes every file listed in a manifest produced with the hashit, json, hashdeep
+// or sum formats returning OK, FAILED or FAILED open or read for each of them
+func Audit(manifest []byte) (map[string]string, error) {
+	if err := prepareOptions(); err != nil {
+		return nil, err
+	}
+
+	manifest, err := decompressManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return checkManifest(entries)
+}
+
+// SelectedHashes returns the digests of the hashes set with Hash keyed by hash name
+func SelectedHashes(res Result) map[string]string {
+	return calculatedHashes(res)
+}
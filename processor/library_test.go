@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	res, err := HashFile(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if res.MD5 != "5d41402abc4b2a76b9719d911017c592" || res.Bytes != 5 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+
+	if _, err := HashFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := HashFile(dir); err == nil {
+		t.Error("Expected error for directory")
+	}
+}
+
+func TestHashBytes(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.SHA256}
+
+	res, err := HashBytes("upload", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	hashes := SelectedHashes(res)
+	if len(hashes) != 1 || hashes[HashNames.SHA256] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected hashes %v", hashes)
+	}
+	if res.File != "upload" || res.Bytes != 5 {
+		t.Errorf("Unexpected result %+v", res)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	bad := filepath.Join(dir, "bad")
+	_ = os.WriteFile(good, []byte("hello"), 0600)
+	_ = os.WriteFile(bad, []byte("world"), 0600)
+
+	manifest := "5d41402abc4b2a76b9719d911017c592  " + good + "\n" +
+		"5d41402abc4b2a76b9719d911017c592  " + bad + "\n"
+
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5}
+
+	status, err := Audit([]byte(manifest))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if status[good] != "OK" || status[bad] != "FAILED" {
+		t.Errorf("Unexpected status %v", status)
+	}
+
+	if _, err := Audit([]byte("")); err == nil {
+		t.Error("Expected error for empty manifest")
+	}
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Parsed versions of the safety limits
+var (
+	maxFileSizeBytes int64 = -1
+	timeoutPerFile   time.Duration
+)
+
+// Parses the limits which stop a scan hanging on a single file
+func compileLimits() error {
+	var err error
+	maxFileSizeBytes, timeoutPerFile = -1, 0
+
+	if MaxFileSize != "" {
+		if maxFileSizeBytes, err = parseSize(MaxFileSize); err != nil {
+			return err
+		}
+	}
+	if TimeoutPerFile != "" {
+		if timeoutPerFile, err = time.ParseDuration(TimeoutPerFile); err != nil || timeoutPerFile <= 0 {
+			return fmt.Errorf("invalid timeout %s expected a duration like 30s or 5m", TimeoutPerFile)
+		}
+	}
+	return nil
+}
+
+// Records a file which was deliberately not read. The reason is logged as a warning and
+// unlike an error it does not change the exit code.
+func newSkippedResult(file string, reason string) Result {
+	logger.Warn(fmt.Sprintf("skipping %s: %s", file, reason))
+	return Result{
+		File:  file,
+		Error: "skipped " + reason,
+	}
+}
+
+// Describes files which never finish reading or block on open such as named pipes,
+// sockets and device nodes, empty for anything else
+func specialFileKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return ""
+}
+
+// Checks a file before it is opened as opening a named pipe blocks until something
+// writes to it. Returns why the file should be skipped.
+func exceedsLimits(path string) (string, bool) {
+	fi, err := os.Stat(longPath(path))
+	if err != nil {
+		// Let opening the file report the error
+		return "", false
+	}
+	if kind := specialFileKind(fi.Mode()); kind != "" {
+		return kind, true
+	}
+	if maxFileSizeBytes >= 0 && fi.Size() > maxFileSizeBytes {
+		return fmt.Sprintf("%d bytes is larger than --max-file-size %s", fi.Size(), MaxFileSize), true
+	}
+	return "", false
+}
+
+// Hands files one at a time to a worker of its own, abandoning it for a fresh one when a
+// file takes longer than --timeout-per-file such as a read stuck on a hung network share.
+// The stuck read cannot be interrupted so the abandoned worker exits whenever it returns.
+func guardedWorker(worker int, input chan string, output chan Result, process func(int, chan string, chan Result)) {
+	var files chan string
+	var results chan Result
+	start := func() {
+		files = make(chan string)
+		results = make(chan Result, 1)
+		go process(worker, files, results)
+	}
+	start()
+
+	for file := range input {
+		files <- file
+
+		timer := time.NewTimer(timeoutPerFile)
+		select {
+		case r := <-results:
+			timer.Stop()
+			output <- r
+		case <-timer.C:
+			close(files)
+			output <- newErrorResult(file, fmt.Errorf("timed out after %s", timeoutPerFile))
+			start()
+		}
+	}
+	close(files)
+}
@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileLimits(t *testing.T) {
+	defer func() {
+		MaxFileSize, TimeoutPerFile = "", ""
+		_ = compileLimits()
+	}()
+
+	MaxFileSize, TimeoutPerFile = "1K", "30s"
+	if err := compileLimits(); err != nil {
+		t.Fatalf("expected no error got %s", err.Error())
+	}
+	if maxFileSizeBytes != 1024 || timeoutPerFile.String() != "30s" {
+		t.Errorf("expected 1024 and 30s got %d and %s", maxFileSizeBytes, timeoutPerFile)
+	}
+
+	for _, timeout := range []string{"soon", "0s", "-1m"} {
+		MaxFileSize, TimeoutPerFile = "", timeout
+		if err := compileLimits(); err == nil {
+			t.Errorf("expected %s to be rejected", timeout)
+		}
+	}
+}
+
+func TestSpecialFileKind(t *testing.T) {
+	cases := map[fs.FileMode]string{
+		0644:                              "",
+		fs.ModeDir | 0755:                 "",
+		fs.ModeNamedPipe | 0644:           "named pipe",
+		fs.ModeSocket | 0755:              "socket",
+		fs.ModeDevice | 0660:              "device",
+		fs.ModeDevice | fs.ModeCharDevice: "device",
+	}
+	for mode, expected := range cases {
+		if got := specialFileKind(mode); got != expected {
+			t.Errorf("expected %s for %s got %s", expected, mode, got)
+		}
+	}
+}
+
+func TestWorkerSkipsFilesOverMaxFileSize(t *testing.T) {
+	defer func() {
+		MaxFileSize = ""
+		_ = compileLimits()
+	}()
+	MaxFileSize = "4"
+	_ = compileLimits()
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	large := filepath.Join(dir, "large")
+	_ = os.WriteFile(small, []byte("abc"), 0600)
+	_ = os.WriteFile(large, []byte("abcdefgh"), 0600)
+
+	input := make(chan string, 2)
+	output := make(chan Result, 2)
+	input <- small
+	input <- large
+	close(input)
+
+	before := fileErrorCount
+	fileProcessorWorker(0, input, output)
+	close(output)
+
+	for res := range output {
+		switch res.File {
+		case small:
+			if res.Error != "" {
+				t.Errorf("expected small to be hashed got %s", res.Error)
+			}
+		case large:
+			if !strings.HasPrefix(res.Error, "skipped ") {
+				t.Errorf("expected large to be skipped got %+v", res)
+			}
+		}
+	}
+	if fileErrorCount != before {
+		t.Error("expected a skipped file not to count as an error")
+	}
+}
+
+func TestGuardedWorkerTimesOut(t *testing.T) {
+	defer func() {
+		TimeoutPerFile = ""
+		_ = compileLimits()
+	}()
+	TimeoutPerFile = "10ms"
+	_ = compileLimits()
+
+	// Hangs on stuck and answers anything else straight away
+	release := make(chan struct{})
+	defer close(release)
+	process := func(worker int, files chan string, results chan Result) {
+		for f := range files {
+			if f == "stuck" {
+				<-release
+			}
+			results <- Result{File: f}
+		}
+	}
+
+	input := make(chan string, 2)
+	output := make(chan Result, 2)
+	input <- "stuck"
+	input <- "fine"
+	close(input)
+
+	guardedWorker(0, input, output, process)
+
+	res := <-output
+	if res.File != "stuck" || !strings.Contains(res.Error, "timed out") {
+		t.Errorf("expected stuck to time out got %+v", res)
+	}
+	res = <-output
+	if res.File != "fine" || res.Error != "" {
+		t.Errorf("expected fine to be handled by a fresh worker got %+v", res)
+	}
+}
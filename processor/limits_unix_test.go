@@ -0,0 +1,35 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWorkerSkipsNamedPipe(t *testing.T) {
+	pipe := filepath.Join(t.TempDir(), "pipe")
+	if err := syscall.Mkfifo(pipe, 0600); err != nil {
+		t.Skipf("named pipes unavailable %s", err.Error())
+	}
+
+	input := make(chan string, 1)
+	output := make(chan Result, 1)
+	input <- pipe
+	close(input)
+
+	go fileProcessorWorker(0, input, output)
+
+	select {
+	case res := <-output:
+		if res.Error != "skipped named pipe" {
+			t.Errorf("expected the pipe to be skipped got %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the pipe to be skipped rather than opened")
+	}
+	_ = os.Remove(pipe)
+}
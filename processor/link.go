@@ -0,0 +1,56 @@
+package processor
+
+import "sync"
+
+// Identifies a file's content independent of the path used to reach it
+type linkKey struct {
+	device uint64
+	inode  uint64
+}
+
+// The result for an inode shared by every path hard linked to it
+type linkEntry struct {
+	first  string
+	done   chan struct{}
+	result Result
+}
+
+var linkMutex sync.Mutex
+var linkEntries = map[linkKey]*linkEntry{}
+
+// Forgets every inode seen so a new run hashes everything again
+func resetLinks() {
+	linkMutex.Lock()
+	linkEntries = map[linkKey]*linkEntry{}
+	linkMutex.Unlock()
+}
+
+// Returns the entry for the inode and whether the caller is the first path to claim
+// it and so must hash it and call finish
+func claimLink(device uint64, inode uint64, file string) (*linkEntry, bool) {
+	linkMutex.Lock()
+	defer linkMutex.Unlock()
+
+	key := linkKey{device: device, inode: inode}
+	if entry, ok := linkEntries[key]; ok {
+		return entry, false
+	}
+
+	entry := &linkEntry{first: file, done: make(chan struct{})}
+	linkEntries[key] = entry
+	return entry, true
+}
+
+func (l *linkEntry) finish(r Result) {
+	l.result = r
+	close(l.done)
+}
+
+// Waits for the first path to finish then returns its result for this path
+func (l *linkEntry) wait(file string) Result {
+	<-l.done
+	r := l.result
+	r.File = file
+	r.HardLinkOf = l.first
+	return r
+}
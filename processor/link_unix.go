@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the device, inode and hard link count of the file
+func fileIdentity(fi os.FileInfo) (uint64, uint64, uint64, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), uint64(stat.Nlink), true
+}
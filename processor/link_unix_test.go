@@ -0,0 +1,61 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileIdentityHardLink(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	_ = os.WriteFile(a, []byte("hello"), 0600)
+	if err := os.Link(a, b); err != nil {
+		t.Skipf("Hard links unavailable %s", err.Error())
+	}
+
+	aInfo, _ := os.Stat(a)
+	bInfo, _ := os.Stat(b)
+	aDevice, aInode, aLinks, ok := fileIdentity(aInfo)
+	bDevice, bInode, _, _ := fileIdentity(bInfo)
+
+	if !ok || aLinks != 2 {
+		t.Errorf("Expected 2 links got %d", aLinks)
+	}
+	if aDevice != bDevice || aInode != bInode {
+		t.Error("Expected hard links to share device and inode")
+	}
+}
+
+func TestHardLinksHashedOnce(t *testing.T) {
+	Hash = []string{HashNames.MD5}
+	resetLinks()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	_ = os.WriteFile(a, []byte("hello"), 0600)
+	if err := os.Link(a, b); err != nil {
+		t.Skipf("Hard links unavailable %s", err.Error())
+	}
+
+	results := hashFiles([]string{a, b})
+
+	reused := 0
+	for _, r := range results {
+		if r.MD5 != "5d41402abc4b2a76b9719d911017c592" || r.Links != 2 {
+			t.Errorf("Unexpected result %+v", r)
+		}
+		if r.HardLinkOf != "" {
+			reused++
+			if r.HardLinkOf == r.File {
+				t.Error("Expected reused result to point at the other path")
+			}
+		}
+	}
+	if reused != 1 {
+		t.Errorf("Expected exactly one reused result got %d", reused)
+	}
+}
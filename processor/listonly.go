@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Check --list-only is used where there is a walk to list and nothing needs digests
+func validateListOnly() error {
+	if !ListOnly {
+		return nil
+	}
+	switch {
+	case StandardInput, Partition >= 0:
+		return errors.New("--list-only needs files or directories to walk")
+	case Baseline != "", len(Known) != 0, Append:
+		return errors.New("--list-only cannot be combined with --baseline, --known or --append as nothing is hashed")
+	}
+	return nil
+}
+
+// Stands in for the workers with --list-only, passing on the size of each file that
+// would be hashed without opening it
+func listFiles(input chan string, output chan Result) {
+	for path := range input {
+		if reason, skip := exceedsLimits(path); skip {
+			output <- newSkippedResult(path, reason)
+			continue
+		}
+		fi, err := os.Stat(longPath(path))
+		if err != nil {
+			output <- newErrorResult(path, err)
+			continue
+		}
+		output <- Result{File: path, Bytes: fi.Size()}
+	}
+	close(output)
+}
+
+// One line per file of its size and path followed by the totals
+func toList(input chan Result) string {
+	var str strings.Builder
+	printed := 0
+	var files, total int64
+
+	for res := range input {
+		// Errors and skipped files have already been reported on stderr
+		if res.Error != "" {
+			continue
+		}
+		files++
+		total += res.Bytes
+		str.WriteString(fmt.Sprintf("%d  %s\n", res.Bytes, res.File))
+		streamOutput(&str, &printed)
+	}
+
+	str.WriteString(fmt.Sprintf("%d files, %d bytes (%s)\n", files, total, formatBytes(total)))
+	return str.String()
+}
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListFiles(t *testing.T) {
+	defer func() {
+		MaxFileSize = ""
+		_ = compileLimits()
+	}()
+	MaxFileSize = "10"
+	if err := compileLimits(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	large := filepath.Join(dir, "large")
+	_ = os.WriteFile(small, []byte("hello"), 0600)
+	_ = os.WriteFile(large, make([]byte, 100), 0600)
+
+	input := make(chan string, 3)
+	output := make(chan Result, 3)
+	input <- small
+	input <- large
+	input <- filepath.Join(dir, "missing")
+	close(input)
+	listFiles(input, output)
+
+	results := map[string]Result{}
+	for res := range output {
+		results[filepath.Base(res.File)] = res
+	}
+	if r := results["small"]; r.Error != "" || r.Bytes != 5 || r.MD5 != "" {
+		t.Errorf("expected small to be listed with its size and no digests got %+v", r)
+	}
+	if r := results["large"]; !strings.HasPrefix(r.Error, "skipped") {
+		t.Errorf("expected large to be skipped by --max-file-size got %+v", r)
+	}
+	if r := results["missing"]; r.Error == "" {
+		t.Errorf("expected an error for a missing file got %+v", r)
+	}
+}
+
+func TestToList(t *testing.T) {
+	NoStream = true
+	defer func() { NoStream = false }()
+
+	input := make(chan Result, 3)
+	input <- Result{File: "a", Bytes: 1024}
+	input <- Result{File: "b", Bytes: 2048}
+	input <- Result{File: "c", Error: "skipped socket"}
+	close(input)
+
+	expected := "1024  a\n2048  b\n2 files, 3072 bytes (3.0 KiB)\n"
+	if out := toList(input); out != expected {
+		t.Errorf("expected %q got %q", expected, out)
+	}
+}
+
+func TestValidateListOnly(t *testing.T) {
+	defer func() { ListOnly, Baseline = false, "" }()
+
+	ListOnly = true
+	if err := validateListOnly(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+	Baseline = "baseline.json"
+	if validateListOnly() == nil {
+		t.Error("expected --list-only with --baseline to be rejected")
+	}
+}
@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelTrace is below debug for the very chatty per hash timings
+const LevelTrace = slog.Level(-8)
+
+// Logs go to stderr so they never mix with results on stdout
+var logger = slog.New(newTextLogHandler(os.Stderr))
+
+// Opens the log destination and picks the handler for the log format
+func setupLogging() error {
+	var w io.Writer = os.Stderr
+	if LogFile != "" {
+		file, err := os.OpenFile(LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("unable to open log file %s: %w", LogFile, err)
+		}
+		w = file
+	}
+
+	switch strings.ToLower(LogFormat) {
+	case "", "text":
+		logger = slog.New(newTextLogHandler(w))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: LevelTrace,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.LevelKey {
+					a.Value = slog.StringValue(levelName(a.Value.Any().(slog.Level)))
+				}
+				return a
+			},
+		}))
+	default:
+		return fmt.Errorf("invalid log format %s expected text or json", LogFormat)
+	}
+	return nil
+}
+
+// Names match the flags which enable each level
+func levelName(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARN"
+	case level >= slog.LevelInfo:
+		return "VERBOSE"
+	case level >= slog.LevelDebug:
+		return "DEBUG"
+	}
+	return "TRACE"
+}
+
+// Writes records as LEVEL time: message key=value which is easy to read in a terminal
+type textLogHandler struct {
+	mutex *sync.Mutex
+	w     io.Writer
+	attrs []slog.Attr
+}
+
+func newTextLogHandler(w io.Writer) *textLogHandler {
+	return &textLogHandler{mutex: &sync.Mutex{}, w: w}
+}
+
+func (h *textLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+func (h *textLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("%s %s: %s", levelName(r.Level), r.Time.UTC().Format(time.RFC3339), r.Message))
+
+	write := func(a slog.Attr) bool {
+		str.WriteString(fmt.Sprintf(" %s=%v", a.Key, a.Value.Any()))
+		return true
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(write)
+	str.WriteString("\n")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := io.WriteString(h.w, str.String())
+	return err
+}
+
+func (h *textLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textLogHandler{mutex: h.mutex, w: h.w, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+// Groups are not used so attributes are kept flat
+func (h *textLogHandler) WithGroup(name string) slog.Handler {
+	return h
+}
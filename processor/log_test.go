@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetupLoggingInvalidFormat(t *testing.T) {
+	LogFormat = "xml"
+	defer func() { LogFormat = "text" }()
+
+	if err := setupLogging(); err == nil {
+		t.Error("expected error for invalid log format")
+	}
+}
+
+func TestSetupLoggingTextFile(t *testing.T) {
+	LogFile = filepath.Join(t.TempDir(), "hashit.log")
+	Debug = true
+	defer func() {
+		LogFile = ""
+		Debug = false
+		_ = setupLogging()
+	}()
+
+	if err := setupLogging(); err != nil {
+		t.Fatal(err)
+	}
+	printDebug("using read file", "worker", 2, "file", "a.txt", "bytes", 10)
+
+	content, _ := os.ReadFile(LogFile)
+	line := string(content)
+	if !strings.HasPrefix(line, "DEBUG ") || !strings.Contains(line, ": using read file worker=2 file=a.txt bytes=10") {
+		t.Error("unexpected log line", line)
+	}
+}
+
+func TestSetupLoggingJSON(t *testing.T) {
+	LogFile = filepath.Join(t.TempDir(), "hashit.log")
+	LogFormat = "JSON"
+	Trace = true
+	defer func() {
+		LogFile = ""
+		LogFormat = "text"
+		Trace = false
+		_ = setupLogging()
+	}()
+
+	if err := setupLogging(); err != nil {
+		t.Fatal(err)
+	}
+	printTrace("processScanner", "worker", 1, "file", "b.txt")
+	printVerbose("not enabled")
+
+	content, _ := os.ReadFile(LogFile)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 {
+		t.Fatal("expected one line got", len(lines))
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "TRACE" || record["msg"] != "processScanner" || record["file"] != "b.txt" || record["worker"] != float64(1) {
+		t.Error("unexpected record", record)
+	}
+}
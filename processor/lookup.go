@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LookupMatch is a record in a manifest found by a lookup query, one per digest
+type LookupMatch struct {
+	Query    string `json:"query"`
+	Manifest string `json:"manifest"`
+	File     string `json:"file"`
+	// The hash the digest came from, several separated by commas for sum manifests
+	// where the length fits more than one
+	Hash   string `json:"hash"`
+	Digest string `json:"digest"`
+	// digest when the query matched the digest and path when it matched the file
+	By string `json:"by"`
+}
+
+// Reads any manifest as entries, sum digests are matched by length against the selected hashes
+func loadLookupEntries(path string) ([]manifestEntry, error) {
+	data, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if isContainer(data) || bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		results, err := loadManifestResults(path)
+		if err != nil {
+			return nil, err
+		}
+		return manifestFromResults(results), nil
+	}
+	return parseTextManifest(trimmed)
+}
+
+// Checks if any of the names are in both lists
+func namesOverlap(a []string, b []string) bool {
+	for _, x := range a {
+		if contains(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the records matching each query by digest or path. A digest may be bare hex,
+// prefixed with its hash name such as sha256:ab12... or a subresource integrity value.
+func lookup(entries map[string][]manifestEntry, manifests []string, queries []string) []LookupMatch {
+	previous := Hash
+	Hash = []string{"all"}
+	defer func() { Hash = previous }()
+
+	matches := []LookupMatch{}
+	for _, query := range queries {
+		digest, err := parseExpectedDigest(query)
+		isDigest := err == nil
+		path := filepath.Clean(query)
+
+		for _, manifest := range manifests {
+			for _, e := range entries[manifest] {
+				byPath := filepath.Clean(e.File) == path
+				for _, d := range e.Digests {
+					match := LookupMatch{Query: query, Manifest: manifest, File: e.File, Hash: strings.Join(d.Names, ","), Digest: d.Digest}
+					switch {
+					case isDigest && d.Digest == digest.Digest && namesOverlap(d.Names, digest.Names):
+						match.By = "digest"
+					case byPath:
+						match.By = "path"
+					default:
+						continue
+					}
+					matches = append(matches, match)
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// Lookup answers which files in the manifests have a digest, or what digests a path has,
+// for each query. Sum manifests are read using the hashes selected with --hash. It exits
+// 1 when any query matched nothing.
+func Lookup(manifests []string, queries []string, asJSON bool) {
+	if len(manifests) == 0 {
+		printError("lookup requires at least one --manifest")
+		os.Exit(1)
+	}
+
+	Hash = formatHashInput()
+	entries := map[string][]manifestEntry{}
+	for _, m := range manifests {
+		e, err := loadLookupEntries(m)
+		if err != nil {
+			printError(fmt.Sprintf("unable to read manifest %s: %s", m, err.Error()))
+			os.Exit(1)
+		}
+		entries[m] = e
+	}
+
+	matches := lookup(entries, manifests, queries)
+
+	if asJSON {
+		out, _ := json.Marshal(matches)
+		fmt.Println(string(out))
+	} else {
+		for _, m := range matches {
+			fmt.Printf("%s: %s %s:%s\n", m.Manifest, m.File, m.Hash, m.Digest)
+		}
+	}
+
+	found := map[string]bool{}
+	for _, m := range matches {
+		found[m.Query] = true
+	}
+	missing := false
+	for _, q := range queries {
+		if !found[q] {
+			printError(fmt.Sprintf("no record of %s found", q))
+			missing = true
+		}
+	}
+	if missing {
+		os.Exit(1)
+	}
+}
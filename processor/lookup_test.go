@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	entries := map[string][]manifestEntry{
+		"a.json": {
+			{File: "dir/one", Digests: []manifestDigest{
+				{Names: []string{HashNames.MD5}, Digest: "5d41402abc4b2a76b9719d911017c592"},
+				{Names: []string{HashNames.SHA256}, Digest: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+			}},
+		},
+		"b.sum": {
+			{File: "copy", Digests: []manifestDigest{{Names: []string{HashNames.MD5}, Digest: "5d41402abc4b2a76b9719d911017c592"}}},
+			{File: "other", Digests: []manifestDigest{{Names: []string{HashNames.MD5}, Digest: "7d793037a0760186574b0282f2f435e7"}}},
+		},
+	}
+	manifests := []string{"a.json", "b.sum"}
+
+	matches := lookup(entries, manifests, []string{"5D41402ABC4B2A76B9719D911017C592"})
+	if len(matches) != 2 || matches[0].File != "dir/one" || matches[1].File != "copy" || matches[1].By != "digest" {
+		t.Errorf("Expected both copies across manifests got %+v", matches)
+	}
+
+	matches = lookup(entries, manifests, []string{"./dir/one"})
+	if len(matches) != 2 || matches[0].By != "path" || matches[1].Hash != HashNames.SHA256 {
+		t.Errorf("Expected both digests of the path got %+v", matches)
+	}
+
+	// Prefixed with a different hash name the md5 digest should not match
+	if matches := lookup(entries, manifests, []string{"sha1:5d41402abc4b2a76b9719d911017c592"}); len(matches) != 0 {
+		t.Errorf("Expected no matches got %+v", matches)
+	}
+
+	matches = lookup(entries, manifests, []string{"sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="})
+	if len(matches) != 1 || matches[0].File != "dir/one" {
+		t.Errorf("Expected integrity value to match got %+v", matches)
+	}
+}
@@ -0,0 +1,49 @@
+package processor
+
+import "errors"
+
+// Limits used by --low-memory, small enough that a Raspberry Pi class device
+// verifying an SD card stays within a few megabytes per worker
+const (
+	lowMemoryQueueSize  = 16
+	lowMemoryStreamSize = 65_536
+	lowMemoryReadSize   = 65_536
+	lowMemoryQueueBytes = 1 << 20
+)
+
+// Size of each read when streaming a large file through the hashers
+var scannerReadSize = 4_194_304
+
+// Holding every result back until the end is what --low-memory avoids, so an explicit
+// --no-stream is refused rather than quietly overridden
+func validateLowMemory() error {
+	if LowMemory && NoStream {
+		return errors.New("--low-memory cannot be combined with --no-stream as it holds every result until the end")
+	}
+	return nil
+}
+
+// Shrinks queues and buffers and turns off anything that holds results or whole
+// files in memory. Options are only ever lowered so smaller user values are kept.
+func applyLowMemory() {
+	if !LowMemory {
+		return
+	}
+
+	if FileListQueueSize > lowMemoryQueueSize {
+		FileListQueueSize = lowMemoryQueueSize
+	}
+	if queueMemoryBytes > lowMemoryQueueBytes {
+		queueMemoryBytes = lowMemoryQueueBytes
+	}
+	if StreamSize > lowMemoryStreamSize {
+		StreamSize = lowMemoryStreamSize
+	}
+	if scannerReadSize > lowMemoryReadSize {
+		scannerReadSize = lowMemoryReadSize
+	}
+
+	// Parallel BLAKE3 reads a segment per core and the cache keeps every result
+	Blake3ParallelSize = 0
+	CacheSize = 0
+}
@@ -0,0 +1,57 @@
+package processor
+
+import "testing"
+
+func TestApplyLowMemory(t *testing.T) {
+	LowMemory = true
+	CacheSize = 100
+	StreamSize = 10
+	defer func() {
+		LowMemory = false
+		CacheSize = 0
+		FileListQueueSize = 1000
+		StreamSize = 1_000_000
+		Blake3ParallelSize = 128_000_000
+		scannerReadSize = 4_194_304
+	}()
+
+	applyLowMemory()
+
+	if FileListQueueSize != lowMemoryQueueSize || scannerReadSize != lowMemoryReadSize {
+		t.Error("expected queue and read sizes to shrink")
+	}
+	if StreamSize != 10 {
+		t.Error("expected smaller stream size to be kept got", StreamSize)
+	}
+	if Blake3ParallelSize != 0 || CacheSize != 0 {
+		t.Error("expected parallel blake3 and cache to be disabled")
+	}
+}
+
+func TestValidateLowMemory(t *testing.T) {
+	defer func() {
+		LowMemory = false
+		NoStream = false
+	}()
+
+	LowMemory, NoStream = true, false
+	if err := validateLowMemory(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+
+	LowMemory, NoStream = true, true
+	if err := validateLowMemory(); err == nil {
+		t.Error("Expected --no-stream to be refused with --low-memory")
+	}
+	if !NoStream {
+		t.Error("Expected --no-stream not to be changed")
+	}
+}
+
+func TestApplyLowMemoryDisabled(t *testing.T) {
+	applyLowMemory()
+
+	if FileListQueueSize != 1000 || scannerReadSize != 4_194_304 {
+		t.Error("expected sizes to be unchanged")
+	}
+}
@@ -0,0 +1,249 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Points at the field of the result holding the named digest
+func digestField(res *Result, name string) *string {
+	fields := map[string]*string{
+		HashNames.CRC32:       &res.CRC32,
+		HashNames.XxHash64:    &res.XxHash64,
+		HashNames.MD4:         &res.MD4,
+		HashNames.MD5:         &res.MD5,
+		HashNames.SHA1:        &res.SHA1,
+		HashNames.SHA256:      &res.SHA256,
+		HashNames.SHA512:      &res.SHA512,
+		HashNames.Blake2b256:  &res.Blake2b256,
+		HashNames.Blake2b512:  &res.Blake2b512,
+		HashNames.Blake3:      &res.Blake3,
+		HashNames.Sha3224:     &res.Sha3224,
+		HashNames.Sha3256:     &res.Sha3256,
+		HashNames.Sha3384:     &res.Sha3384,
+		HashNames.Sha3512:     &res.Sha3512,
+		HashNames.Sha224:      &res.Sha224,
+		HashNames.Sha384:      &res.Sha384,
+		HashNames.Sha512256:   &res.Sha512256,
+		HashNames.Ripemd160:   &res.Ripemd160,
+		HashNames.Whirlpool:   &res.Whirlpool,
+		HashNames.SM3:         &res.SM3,
+		HashNames.Streebog256: &res.Streebog256,
+		HashNames.Streebog512: &res.Streebog512,
+	}
+	return fields[name]
+}
+
+// Builds results from text manifest entries. Sum lines only give the digest length so
+// it has to match exactly one of the selected hashes to know which field it belongs in.
+func resultsFromEntries(entries []manifestEntry) ([]Result, error) {
+	results := []Result{}
+	for _, e := range entries {
+		res := Result{File: e.File, Bytes: e.Bytes}
+		for _, d := range e.Digests {
+			if len(d.Names) != 1 {
+				return nil, fmt.Errorf("digest %s for %s could be any of %s, select one with --hash", d.Digest, e.File, strings.Join(d.Names, ","))
+			}
+			if field := digestField(&res, d.Names[0]); field != nil {
+				*field = d.Digest
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// Reads the results recorded in a manifest in any of the hashit, json, hashdeep or sum formats
+func loadManifestResults(path string) ([]Result, error) {
+	data, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	data = stripPartialTrailer(data)
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case isContainer(data):
+		c, err := readContainer(data)
+		if err != nil {
+			return nil, err
+		}
+		return c.Results, nil
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		results := []Result{}
+		err := json.Unmarshal(trimmed, &results)
+		return results, err
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		// Written when --stats, --no-content or --provenance wrap the results
+		wrapped := struct{ Files []Result }{}
+		err := json.Unmarshal(trimmed, &wrapped)
+		return wrapped.Files, err
+	case len(trimmed) == 0:
+		return []Result{}, nil
+	}
+
+	entries, err := parseTextManifest(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return resultsFromEntries(entries)
+}
+
+// Combines results keeping the order paths were first seen with later records for a
+// path replacing earlier ones
+func mergeResults(sets ...[]Result) []Result {
+	merged := []Result{}
+	index := map[string]int{}
+	for _, set := range sets {
+		for _, res := range set {
+			if i, ok := index[res.File]; ok {
+				merged[i] = res
+				continue
+			}
+			index[res.File] = len(merged)
+			merged = append(merged, res)
+		}
+	}
+	return merged
+}
+
+// Names of the hashes with a digest in any of the results in the order they are output
+func recordedHashes(results []Result) []string {
+	previous := Hash
+	Hash = []string{"all", HashNames.Entropy, HashNames.Ssdeep, HashNames.TLSH}
+	defer func() { Hash = previous }()
+
+	present := map[string]bool{}
+	for _, res := range results {
+		for name, digest := range calculatedHashes(res) {
+			if digest != "" {
+				present[name] = true
+			}
+		}
+	}
+
+	names := []string{}
+	for _, info := range HashInfos {
+		if present[info.Name] {
+			names = append(names, info.Name)
+		}
+	}
+	for _, info := range selectedCustomHashes() {
+		if present[info.Name] {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// Reads the existing output manifest when appending, a missing file is simply created
+func loadAppend() ([]Result, error) {
+	if !Append {
+		return nil, nil
+	}
+	if FileOutput == "" {
+		return nil, errors.New("--append requires --output")
+	}
+
+	results, err := loadManifestResults(FileOutput)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Result{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s to append to: %w", FileOutput, err)
+	}
+	return results, nil
+}
+
+// Passes results through then follows them with the existing records for paths this
+// run did not produce
+func appendResults(input chan Result, existing []Result) chan Result {
+	output := make(chan Result, FileListQueueSize)
+
+	go func() {
+		seen := map[string]bool{}
+		for res := range input {
+			seen[res.File] = true
+			output <- res
+		}
+		for _, res := range existing {
+			if !seen[res.File] {
+				output <- res
+			}
+		}
+		close(output)
+	}()
+
+	return output
+}
+
+// Merge reads manifests in any supported format and writes them as one in the selected
+// format, later manifests replacing records for paths already seen. The output includes
+// every hash recorded in the manifests.
+func Merge(manifests []string) {
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := prepareDatabaseOutput(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	sets := [][]Result{}
+	for _, m := range manifests {
+		results, err := loadManifestResults(m)
+		if err != nil {
+			printError(fmt.Sprintf("unable to read manifest %s: %s", m, err.Error()))
+			os.Exit(1)
+		}
+		printVerbose("read manifest", "manifest", m, "records", len(results))
+		sets = append(sets, results)
+	}
+
+	merged := mergeResults(sets...)
+	Hash = recordedHashes(merged)
+
+	input := make(chan Result, FileListQueueSize)
+	go func() {
+		for _, res := range merged {
+			input <- res
+		}
+		close(input)
+	}()
+
+	if databaseOutput != "" {
+		writeDatabaseOutput(encodeDigests(anonymizePaths(input)))
+		return
+	}
+
+	var stream *outputStream
+	if FileOutput != "" {
+		var err error
+		if stream, err = openOutputStream(); err != nil {
+			printError(fmt.Sprintf("unable to write output file %s: %s", FileOutput, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	result, valid := fileSummarize(encodeDigests(anonymizePaths(input)))
+	if stream == nil {
+		fmt.Print(result)
+	} else {
+		if err := stream.close(result); err != nil {
+			printError(fmt.Sprintf("unable to write output file %s: %s", FileOutput, err.Error()))
+			os.Exit(1)
+		}
+		if !TeeOutput {
+			fmt.Printf("%d records written to %s\n", len(merged), FileOutput)
+		}
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
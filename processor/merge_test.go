@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadManifestResults(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+
+	dir := t.TempDir()
+	hashdeep := filepath.Join(dir, "a.hashdeep")
+	_ = os.WriteFile(hashdeep, []byte(hashdeepMagic+"\n%%%% size,md5,sha256,filename\n##\n5,5d41402abc4b2a76b9719d911017c592,2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824,a,b\n"), 0600)
+	sum := filepath.Join(dir, "b.sum")
+	_ = os.WriteFile(sum, []byte("7d793037a0760186574b0282f2f435e7  c\n"), 0600)
+	wrapped := filepath.Join(dir, "c.json")
+	_ = os.WriteFile(wrapped, []byte(`{"Files":[{"File":"d","MD5":"x"}],"StructureSHA256":"y"}`), 0600)
+
+	results, err := loadManifestResults(hashdeep)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Unexpected results %v %v", results, err)
+	}
+	if results[0].File != "a,b" || results[0].Bytes != 5 || results[0].SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected hashdeep result %+v", results[0])
+	}
+
+	results, err = loadManifestResults(sum)
+	if err != nil || len(results) != 1 || results[0].MD5 != "7d793037a0760186574b0282f2f435e7" {
+		t.Errorf("Unexpected sum results %v %v", results, err)
+	}
+
+	results, err = loadManifestResults(wrapped)
+	if err != nil || len(results) != 1 || results[0].File != "d" {
+		t.Errorf("Unexpected wrapped json results %v %v", results, err)
+	}
+
+	// Several selected hashes produce 64 character digests so the field is unknown
+	Hash = []string{HashNames.SHA256, HashNames.Blake3}
+	_ = os.WriteFile(sum, []byte("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  c\n"), 0600)
+	if _, err := loadManifestResults(sum); err == nil {
+		t.Error("Expected error for ambiguous sum digest")
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	first := []Result{{File: "a", MD5: "1"}, {File: "b", MD5: "2"}}
+	second := []Result{{File: "b", MD5: "3"}, {File: "c", SHA256: "4"}}
+
+	merged := mergeResults(first, second)
+	if len(merged) != 3 || merged[0].File != "a" || merged[1].MD5 != "3" || merged[2].File != "c" {
+		t.Errorf("Unexpected merge %+v", merged)
+	}
+
+	names := recordedHashes(merged)
+	if len(names) != 2 || names[0] != HashNames.MD5 || names[1] != HashNames.SHA256 {
+		t.Errorf("Expected md5 and sha256 got %v", names)
+	}
+}
+
+func TestAppendResults(t *testing.T) {
+	input := make(chan Result, 2)
+	input <- Result{File: "b", MD5: "new"}
+	input <- Result{File: "c", MD5: "3"}
+	close(input)
+
+	out := []Result{}
+	for res := range appendResults(input, []Result{{File: "a", MD5: "1"}, {File: "b", MD5: "old"}}) {
+		out = append(out, res)
+	}
+
+	if len(out) != 3 || out[0].MD5 != "new" || out[2].File != "a" {
+		t.Errorf("Unexpected appended results %+v", out)
+	}
+}
+
+func TestLoadAppend(t *testing.T) {
+	previousAppend, previousOutput := Append, FileOutput
+	defer func() { Append, FileOutput = previousAppend, previousOutput }()
+
+	Append = true
+	FileOutput = ""
+	if _, err := loadAppend(); err == nil {
+		t.Error("Expected error without an output file")
+	}
+
+	FileOutput = filepath.Join(t.TempDir(), "missing.json")
+	if results, err := loadAppend(); err != nil || results == nil || len(results) != 0 {
+		t.Errorf("Expected no existing results for a missing output got %v %v", results, err)
+	}
+}
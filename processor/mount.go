@@ -0,0 +1,49 @@
+package processor
+
+import "os"
+
+// Tracks the filesystem a walk started on so mount points beneath it can be recognised
+type filesystemBoundary struct {
+	device  uint64
+	known   bool
+	virtual map[uint64]bool
+}
+
+func newFilesystemBoundary(root string) *filesystemBoundary {
+	b := &filesystemBoundary{virtual: map[uint64]bool{}}
+	if fi, err := os.Stat(root); err == nil {
+		b.device, _, _, b.known = fileIdentity(fi)
+	}
+	return b
+}
+
+// Reports why the directory should not be entered if it is on another filesystem which
+// --one-file-system rules out, or a virtual one such as /proc or /sys. Directories on
+// the same device as the root are never statted beyond what the walk already does.
+func (b *filesystemBoundary) skip(path string, info os.DirEntry) (string, bool) {
+	if !b.known || (!OneFileSystem && IncludeVirtualFS) {
+		return "", false
+	}
+	fi, err := info.Info()
+	if err != nil {
+		return "", false
+	}
+	device, _, _, ok := fileIdentity(fi)
+	if !ok || device == b.device {
+		return "", false
+	}
+
+	if OneFileSystem {
+		return "mount point", true
+	}
+
+	virtual, seen := b.virtual[device]
+	if !seen {
+		virtual = isVirtualFilesystem(path)
+		b.virtual[device] = virtual
+	}
+	if virtual {
+		return "virtual filesystem", true
+	}
+	return "", false
+}
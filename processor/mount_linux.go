@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Kernel filesystems whose files describe the running system rather than hold content.
+// Reading them is slow at best and some such as /proc/kcore never finish.
+var virtualFilesystems = map[uint32]bool{
+	unix.PROC_SUPER_MAGIC:      true,
+	unix.SYSFS_MAGIC:           true,
+	unix.DEVPTS_SUPER_MAGIC:    true,
+	unix.CGROUP_SUPER_MAGIC:    true,
+	unix.CGROUP2_SUPER_MAGIC:   true,
+	unix.DEBUGFS_MAGIC:         true,
+	unix.TRACEFS_MAGIC:         true,
+	unix.SECURITYFS_MAGIC:      true,
+	unix.SELINUX_MAGIC:         true,
+	unix.SMACK_MAGIC:           true,
+	unix.PSTOREFS_MAGIC:        true,
+	unix.EFIVARFS_MAGIC:        true,
+	unix.BPF_FS_MAGIC:          true,
+	unix.NSFS_MAGIC:            true,
+	unix.BINFMTFS_MAGIC:        true,
+	unix.AUTOFS_SUPER_MAGIC:    true,
+	unix.BDEVFS_MAGIC:          true,
+	unix.HUGETLBFS_MAGIC:       true,
+	unix.USBDEVICE_SUPER_MAGIC: true,
+	// configfs, mqueue and fusectl have no constant in x/sys
+	0x62656570: true,
+	0x19800202: true,
+	0x65735543: true,
+}
+
+// Device files such as /dev/zero and /dev/random never end. devtmpfs shares its magic
+// with tmpfs which holds real files so it is told apart by the mount table.
+var virtualMountTypes = map[string]bool{
+	"devtmpfs": true,
+}
+
+// Check if the directory is on one of the kernel's virtual filesystems
+func isVirtualFilesystem(path string) bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return false
+	}
+	if virtualFilesystems[uint32(st.Type)] {
+		return true
+	}
+	if uint32(st.Type) == unix.TMPFS_MAGIC {
+		return virtualMountTypes[mountType("/proc/self/mountinfo", path)]
+	}
+	return false
+}
+
+// Finds the filesystem type of the mount holding path from a mountinfo table, the
+// mount point being the fifth field and the type following the - separator
+func mountType(mountinfo string, path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	f, err := os.Open(mountinfo)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	longest := -1
+	fsType := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i, field := range fields {
+			if field == "-" {
+				separator = i
+				break
+			}
+		}
+		if len(fields) < 5 || separator < 0 || separator+1 >= len(fields) {
+			continue
+		}
+
+		point := unescapeMountPoint(fields[4])
+		inside := abs == point || point == "/" || strings.HasPrefix(abs, point+"/")
+		// Later mounts over the same point hide earlier ones
+		if inside && len(point) >= longest {
+			longest = len(point)
+			fsType = fields[separator+1]
+		}
+	}
+	return fsType
+}
+
+// Mount points escape spaces and other whitespace as octal such as \040
+func unescapeMountPoint(point string) string {
+	if !strings.Contains(point, `\`) {
+		return point
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(point); i++ {
+		if point[i] == '\\' && i+3 < len(point) {
+			if c, err := strconv.ParseUint(point[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(point[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMountType(t *testing.T) {
+	mountinfo := filepath.Join(t.TempDir(), "mountinfo")
+	_ = os.WriteFile(mountinfo, []byte(`22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:5 / /dev rw,nosuid shared:2 - devtmpfs devtmpfs rw
+24 23 0:20 / /dev/shm rw,nosuid shared:3 - tmpfs tmpfs rw
+25 22 0:21 / /mnt/my\040disk rw shared:4 - tmpfs tmpfs rw
+`), 0600)
+
+	cases := map[string]string{
+		"/":              "ext4",
+		"/home/user":     "ext4",
+		"/dev":           "devtmpfs",
+		"/dev/null":      "devtmpfs",
+		"/dev/shm/x":     "tmpfs",
+		"/devices":       "ext4",
+		"/mnt/my disk/a": "tmpfs",
+	}
+	for path, expected := range cases {
+		if got := mountType(mountinfo, path); got != expected {
+			t.Errorf("expected %s to be on %s got %s", path, expected, got)
+		}
+	}
+}
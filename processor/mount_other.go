@@ -0,0 +1,8 @@
+//go:build !linux
+
+package processor
+
+// Virtual filesystems are only recognised on Linux where they are mounted into the tree
+func isVirtualFilesystem(path string) bool {
+	return false
+}
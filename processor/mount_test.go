@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemBoundarySameDevice(t *testing.T) {
+	defer func() { OneFileSystem = false }()
+	OneFileSystem = true
+
+	dir := t.TempDir()
+	_ = os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	entries, _ := os.ReadDir(dir)
+
+	b := newFilesystemBoundary(dir)
+	if reason, skip := b.skip(filepath.Join(dir, "sub"), entries[0]); skip {
+		t.Errorf("expected a directory on the same filesystem to be walked got %s", reason)
+	}
+}
+
+func TestIsVirtualFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("virtual filesystems are only recognised on linux")
+	}
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("/proc is not mounted")
+	}
+
+	if !isVirtualFilesystem("/proc") {
+		t.Error("expected /proc to be a virtual filesystem")
+	}
+	if isVirtualFilesystem(t.TempDir()) {
+		t.Error("expected a temporary directory not to be a virtual filesystem")
+	}
+}
+
+func TestWalkDirectoryRefusesVirtualFilesystem(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("virtual filesystems are only recognised on linux")
+	}
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("/proc is not mounted")
+	}
+
+	output := make(chan string, 10)
+	errorOutput := make(chan Result, 10)
+	walkDirectory(context.Background(), "/proc", output, errorOutput)
+	close(output)
+	close(errorOutput)
+
+	if len(output) != 0 {
+		t.Errorf("expected no files from /proc got %d", len(output))
+	}
+	res := <-errorOutput
+	if res.File != "/proc" || !strings.Contains(res.Error, "--include-virtual-fs") {
+		t.Errorf("expected /proc to be refused got %+v", res)
+	}
+}
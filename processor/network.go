@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// The client every network feature makes its requests with, fetch-verify and s3:// and
+// gs:// replicas. It is built from the network options by prepareOptions so everything
+// goes through the same proxy and trusts the same certificates.
+var httpClient = http.DefaultClient
+
+// Parses a timeout option where empty keeps the default
+func parseNetworkTimeout(flag string, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --%s %s expected a duration such as 30s", flag, value)
+	}
+	return d, nil
+}
+
+// Which proxy requests go through. Without --proxy the standard HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables apply and direct turns proxying off entirely.
+func networkProxy() (func(*http.Request) (*url.URL, error), error) {
+	switch Proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case "direct":
+		return nil, nil
+	}
+	u, err := url.Parse(Proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid --proxy %s expected a url such as http://proxy:3128", Proxy)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid --proxy %s only http, https and socks5 proxies are supported", Proxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// The certificates servers are checked against and the certificate presented to them
+func networkTLS() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(CACert) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			// Some platforms have no system pool to add to
+			pool = x509.NewCertPool()
+		}
+		for _, path := range CACert {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --ca-cert: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("invalid --ca-cert %s no PEM certificates found", path)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	switch {
+	case ClientCert == "" && ClientKey == "":
+	case ClientCert == "" || ClientKey == "":
+		return nil, errors.New("--client-cert and --client-key must be set together")
+	default:
+		cert, err := tls.LoadX509KeyPair(ClientCert, ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --client-cert: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Builds httpClient from the network options
+func prepareNetwork() error {
+	if IPv4 && IPv6 {
+		return errors.New("--ipv4 and --ipv6 cannot be combined")
+	}
+	if MaxConnsPerHost < 0 || MaxIdleConns < 0 {
+		return errors.New("connection limits cannot be negative")
+	}
+
+	connect, err := parseNetworkTimeout("connect-timeout", ConnectTimeout, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	response, err := parseNetworkTimeout("response-timeout", ResponseTimeout, 0)
+	if err != nil {
+		return err
+	}
+	proxy, err := networkProxy()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := networkTLS()
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}
+	family := ""
+	switch {
+	case IPv4:
+		family = "4"
+	case IPv6:
+		family = "6"
+	}
+
+	httpClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy: proxy,
+			DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				// tcp becomes tcp4 or tcp6 so only addresses of that family are tried
+				if family != "" && strings.HasPrefix(network, "tcp") {
+					network = "tcp" + family
+				}
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   connect,
+			ResponseHeaderTimeout: response,
+			ExpectContinueTimeout: time.Second,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          MaxIdleConns,
+			MaxIdleConnsPerHost:   MaxIdleConns,
+			MaxConnsPerHost:       MaxConnsPerHost,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package processor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func resetNetwork(t *testing.T) {
+	proxy, caCert, clientCert, clientKey := Proxy, CACert, ClientCert, ClientKey
+	connect, response, perHost, idle, ipv4, ipv6 := ConnectTimeout, ResponseTimeout, MaxConnsPerHost, MaxIdleConns, IPv4, IPv6
+	t.Cleanup(func() {
+		Proxy, CACert, ClientCert, ClientKey = proxy, caCert, clientCert, clientKey
+		ConnectTimeout, ResponseTimeout, MaxConnsPerHost, MaxIdleConns, IPv4, IPv6 = connect, response, perHost, idle, ipv4, ipv6
+		httpClient = http.DefaultClient
+	})
+	Proxy, CACert, ClientCert, ClientKey = "", []string{}, "", ""
+	ConnectTimeout, ResponseTimeout, MaxConnsPerHost, MaxIdleConns, IPv4, IPv6 = "30s", "", 0, 100, false, false
+}
+
+func writePEM(t *testing.T, path string, kind string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+}
+
+func TestPrepareNetworkInvalid(t *testing.T) {
+	resetNetwork(t)
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	_ = os.WriteFile(notPEM, []byte("nothing here"), 0600)
+
+	cases := []func(){
+		func() { IPv4, IPv6 = true, true },
+		func() { MaxConnsPerHost = -1 },
+		func() { ConnectTimeout = "soon" },
+		func() { ResponseTimeout = "-1s" },
+		func() { Proxy = "proxy:3128" },
+		func() { Proxy = "ftp://proxy:21" },
+		func() { CACert = []string{notPEM} },
+		func() { CACert = []string{filepath.Join(dir, "missing.pem")} },
+		func() { ClientCert = notPEM },
+		func() { ClientCert, ClientKey = notPEM, notPEM },
+	}
+	for i, c := range cases {
+		resetNetwork(t)
+		c()
+		if err := prepareNetwork(); err == nil {
+			t.Errorf("Expected error for case %d", i)
+		}
+	}
+}
+
+func TestNetworkCACert(t *testing.T) {
+	resetNetwork(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	// The rejected handshake is expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := httpClient.Get(server.URL); err == nil {
+		t.Fatal("Expected the test certificate to be untrusted")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, ca, "CERTIFICATE", server.Certificate().Raw)
+	CACert = []string{ca}
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("Unexpected body %s", body)
+	}
+}
+
+func TestNetworkClientCert(t *testing.T) {
+	resetNetwork(t)
+	dir := t.TempDir()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hashit"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	ClientCert, ClientKey = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, ClientCert, "CERTIFICATE", der)
+	writePEM(t, ClientKey, "EC PRIVATE KEY", keyDER)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	CACert = []string{filepath.Join(dir, "ca.pem")}
+	writePEM(t, CACert[0], "CERTIFICATE", server.Certificate().Raw)
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hashit" {
+		t.Errorf("Expected the client certificate presented got %s", body)
+	}
+}
+
+func TestNetworkProxy(t *testing.T) {
+	resetNetwork(t)
+	requested := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	Proxy = proxy.URL
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	resp, err := httpClient.Get("http://downloads.example/file.tar")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	_ = resp.Body.Close()
+	if requested != "http://downloads.example/file.tar" {
+		t.Errorf("Expected the request sent through the proxy got %s", requested)
+	}
+}
+
+func TestNetworkAddressFamily(t *testing.T) {
+	resetNetwork(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	IPv6 = true
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := httpClient.Get(server.URL); err == nil {
+		t.Error("Expected an IPv4 server to be unreachable with --ipv6")
+	}
+
+	IPv4, IPv6 = true, false
+	if err := prepareNetwork(); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	_ = resp.Body.Close()
+}
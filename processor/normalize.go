@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	NormalizeLF   = "lf"
+	NormalizeCRLF = "crlf"
+)
+
+// Rewrites line endings as data passes through so files can be hashed based on
+// their logical content. Holds state between chunks so a CRLF split across
+// two reads is still handled correctly.
+type newlineNormalizer struct {
+	mode      string
+	pendingCR bool // lf mode, CR at end of the previous chunk not yet written
+	lastCR    bool // crlf mode, previous byte written was a CR
+}
+
+func newNewlineNormalizer(mode string) *newlineNormalizer {
+	return &newlineNormalizer{mode: mode}
+}
+
+// Transform returns a new slice containing the normalized chunk, the input is not modified
+func (n *newlineNormalizer) Transform(chunk []byte) []byte {
+	out := make([]byte, 0, len(chunk)+len(chunk)/8+1)
+
+	switch n.mode {
+	case NormalizeLF:
+		for _, b := range chunk {
+			if n.pendingCR {
+				n.pendingCR = false
+				if b != '\n' {
+					out = append(out, '\r')
+				}
+			}
+
+			if b == '\r' {
+				n.pendingCR = true
+				continue
+			}
+			out = append(out, b)
+		}
+	case NormalizeCRLF:
+		for _, b := range chunk {
+			if b == '\n' && !n.lastCR {
+				out = append(out, '\r')
+			}
+			out = append(out, b)
+			n.lastCR = b == '\r'
+		}
+	default:
+		out = append(out, chunk...)
+	}
+
+	return out
+}
+
+// Flush returns anything held back waiting on the next chunk
+func (n *newlineNormalizer) Flush() []byte {
+	if n.pendingCR {
+		n.pendingCR = false
+		return []byte{'\r'}
+	}
+	return nil
+}
+
+// Normalizes an entire buffer in one pass
+func normalizeNewlines(content []byte, mode string) []byte {
+	n := newNewlineNormalizer(mode)
+	out := n.Transform(content)
+	return append(out, n.Flush()...)
+}
+
+// Wraps a reader normalizing line endings as it is read, used where we
+// cannot get at the chunks directly such as stdin
+type normalizingReader struct {
+	r          io.Reader
+	normalizer *newlineNormalizer
+	pending    []byte
+	scratch    []byte
+	eof        bool
+}
+
+func newNormalizingReader(r io.Reader, mode string) *normalizingReader {
+	return &normalizingReader{
+		r:          r,
+		normalizer: newNewlineNormalizer(mode),
+		scratch:    make([]byte, 32*1024),
+	}
+}
+
+func (n *normalizingReader) Read(p []byte) (int, error) {
+	for len(n.pending) == 0 {
+		if n.eof {
+			return 0, io.EOF
+		}
+
+		c, err := n.r.Read(n.scratch)
+		n.pending = n.normalizer.Transform(n.scratch[:c])
+		if err == io.EOF {
+			n.eof = true
+			n.pending = append(n.pending, n.normalizer.Flush()...)
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	c := copy(p, n.pending)
+	n.pending = n.pending[c:]
+	return c, nil
+}
+
+// Check that the normalize mode supplied is one we know how to handle
+func validateTextNormalize(mode string) error {
+	switch mode {
+	case "", NormalizeLF, NormalizeCRLF:
+		return nil
+	}
+	return fmt.Errorf("unknown text normalize mode %s expected one of [%s, %s]", mode, NormalizeLF, NormalizeCRLF)
+}
@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNormalizeNewlines(t *testing.T) {
+	var cases = []struct {
+		mode     string
+		input    string
+		expected string
+	}{
+		{NormalizeLF, "a\r\nb\r\n", "a\nb\n"},
+		{NormalizeLF, "a\rb\n", "a\rb\n"},
+		{NormalizeLF, "a\r", "a\r"},
+		{NormalizeCRLF, "a\nb\n", "a\r\nb\r\n"},
+		{NormalizeCRLF, "a\r\nb\n", "a\r\nb\r\n"},
+	}
+
+	for _, c := range cases {
+		res := string(normalizeNewlines([]byte(c.input), c.mode))
+		if res != c.expected {
+			t.Errorf("Expected %q got %q for %s", c.expected, res, c.mode)
+		}
+	}
+}
+
+func TestNormalizeNewlinesSplitChunks(t *testing.T) {
+	n := newNewlineNormalizer(NormalizeLF)
+	out := n.Transform([]byte("a\r"))
+	out = append(out, n.Transform([]byte("\nb"))...)
+	out = append(out, n.Flush()...)
+
+	if string(out) != "a\nb" {
+		t.Errorf("Expected %q got %q", "a\nb", string(out))
+	}
+}
+
+func TestNormalizingReader(t *testing.T) {
+	r := newNormalizingReader(bytes.NewReader([]byte("a\r\nb\r\n")), NormalizeLF)
+	out, err := io.ReadAll(r)
+
+	if err != nil || string(out) != "a\nb\n" {
+		t.Errorf("Expected %q got %q %v", "a\nb\n", string(out), err)
+	}
+}
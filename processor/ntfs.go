@@ -0,0 +1,343 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf16"
+)
+
+const (
+	ntfsRootRecord      = 5
+	ntfsExtendRecord    = 11
+	ntfsFirstUserRecord = 16
+	ntfsAttrFileName    = 0x30
+	ntfsAttrData        = 0x80
+	ntfsAttrEnd         = 0xffffffff
+	ntfsRecordInUse     = 0x01
+	ntfsRecordDirectory = 0x02
+	ntfsFlagCompressed  = 0x0001
+	ntfsFlagEncrypted   = 0x4000
+	ntfsNamespaceDOS    = 2
+	ntfsReferenceMask   = 0xffffffffffff
+)
+
+// Part of the unnamed $DATA attribute, large or fragmented files can be split over
+// several records each holding the runs from a starting cluster
+type ntfsFragment struct {
+	startVCN int64
+	runs     []imageExtent
+}
+
+// What is needed from each MFT record to build paths and read content
+type ntfsRecord struct {
+	inUse     bool
+	dir       bool
+	base      uint64
+	parent    uint64
+	name      string
+	named     bool
+	resident  []byte
+	hasData   bool
+	size      int64
+	flags     uint16
+	fragments []ntfsFragment
+}
+
+type ntfsVolume struct {
+	r           io.ReaderAt
+	clusterSize int64
+	recordSize  int64
+	mftOffset   int64
+}
+
+func openNTFSVolume(r io.ReaderAt) (*ntfsVolume, error) {
+	boot := make([]byte, 512)
+	if _, err := r.ReadAt(boot, 0); err != nil {
+		return nil, fmt.Errorf("unable to read NTFS boot sector: %w", err)
+	}
+	if !bytes.Equal(boot[3:11], []byte("NTFS    ")) {
+		return nil, errors.New("invalid NTFS boot sector")
+	}
+
+	bytesPerSector := int64(binary.LittleEndian.Uint16(boot[11:]))
+	perCluster := int64(boot[13])
+	if perCluster > 0x80 {
+		perCluster = 1 << (256 - perCluster)
+	}
+
+	v := &ntfsVolume{r: r, clusterSize: bytesPerSector * perCluster}
+	if perRecord := int8(boot[0x40]); perRecord < 0 {
+		v.recordSize = 1 << -perRecord
+	} else {
+		v.recordSize = int64(perRecord) * v.clusterSize
+	}
+	if v.clusterSize == 0 || v.recordSize < 512 || v.recordSize > 65536 {
+		return nil, errors.New("invalid NTFS geometry")
+	}
+	v.mftOffset = int64(binary.LittleEndian.Uint64(boot[0x30:])) * v.clusterSize
+	return v, nil
+}
+
+// Replaces the last two bytes of each 512 byte stride with the saved originals.
+// NTFS writes a sequence number there so torn writes can be detected.
+func ntfsApplyFixups(record []byte) error {
+	offset := int(binary.LittleEndian.Uint16(record[4:]))
+	count := int(binary.LittleEndian.Uint16(record[6:]))
+	if count < 2 || offset+count*2 > len(record) {
+		return errors.New("invalid update sequence")
+	}
+
+	stride := len(record) / (count - 1)
+	for i := 1; i < count; i++ {
+		end := i*stride - 2
+		if !bytes.Equal(record[end:end+2], record[offset:offset+2]) {
+			return errors.New("update sequence mismatch, record is torn")
+		}
+		copy(record[end:end+2], record[offset+i*2:offset+i*2+2])
+	}
+	return nil
+}
+
+// Decodes a run list into extents, runs without an offset are sparse holes
+func (v *ntfsVolume) decodeRuns(data []byte) ([]imageExtent, error) {
+	extents := []imageExtent{}
+	lcn := int64(0)
+	for i := 0; i < len(data) && data[i] != 0; {
+		lengthSize := int(data[i] & 0x0f)
+		offsetSize := int(data[i] >> 4)
+		i++
+		if lengthSize == 0 || lengthSize > 8 || offsetSize > 8 || i+lengthSize+offsetSize > len(data) {
+			return nil, errors.New("corrupt run list")
+		}
+
+		var length int64
+		for j := lengthSize - 1; j >= 0; j-- {
+			length = length<<8 | int64(data[i+j])
+		}
+		i += lengthSize
+
+		if offsetSize == 0 {
+			extents = append(extents, imageExtent{Offset: -1, Length: length * v.clusterSize})
+			continue
+		}
+
+		// Offsets are signed and relative to the previous run
+		delta := int64(int8(data[i+offsetSize-1]))
+		for j := offsetSize - 2; j >= 0; j-- {
+			delta = delta<<8 | int64(data[i+j])
+		}
+		i += offsetSize
+
+		lcn += delta
+		extents = append(extents, imageExtent{Offset: lcn * v.clusterSize, Length: length * v.clusterSize})
+	}
+	return extents, nil
+}
+
+func (v *ntfsVolume) parseRecord(record []byte) (ntfsRecord, error) {
+	if !bytes.Equal(record[:4], []byte("FILE")) {
+		return ntfsRecord{}, nil
+	}
+	if err := ntfsApplyFixups(record); err != nil {
+		return ntfsRecord{}, err
+	}
+
+	flags := binary.LittleEndian.Uint16(record[0x16:])
+	rec := ntfsRecord{
+		inUse: flags&ntfsRecordInUse != 0,
+		dir:   flags&ntfsRecordDirectory != 0,
+		base:  binary.LittleEndian.Uint64(record[0x20:]) & ntfsReferenceMask,
+	}
+
+	for offset := int(binary.LittleEndian.Uint16(record[0x14:])); offset+16 <= len(record); {
+		kind := binary.LittleEndian.Uint32(record[offset:])
+		length := int(binary.LittleEndian.Uint32(record[offset+4:]))
+		if kind == ntfsAttrEnd || length < 16 || offset+length > len(record) {
+			break
+		}
+		attr := record[offset : offset+length]
+		offset += length
+
+		nonResident := attr[8] != 0
+		unnamed := attr[9] == 0
+
+		if !nonResident {
+			valueLength := int(binary.LittleEndian.Uint32(attr[16:]))
+			valueOffset := int(binary.LittleEndian.Uint16(attr[20:]))
+			if valueOffset+valueLength > len(attr) {
+				return ntfsRecord{}, errors.New("corrupt resident attribute")
+			}
+			value := attr[valueOffset : valueOffset+valueLength]
+
+			switch {
+			case kind == ntfsAttrFileName && len(value) >= 66:
+				nameLength := int(value[64])
+				namespace := value[65]
+				if 66+nameLength*2 > len(value) {
+					continue
+				}
+				// The short DOS name is only used when there is nothing better
+				if rec.named && namespace == ntfsNamespaceDOS {
+					continue
+				}
+				units := make([]uint16, nameLength)
+				for i := range units {
+					units[i] = binary.LittleEndian.Uint16(value[66+i*2:])
+				}
+				rec.parent = binary.LittleEndian.Uint64(value) & ntfsReferenceMask
+				rec.name = string(utf16.Decode(units))
+				rec.named = true
+			case kind == ntfsAttrData && unnamed:
+				rec.hasData = true
+				rec.resident = append([]byte{}, value...)
+				rec.size = int64(len(value))
+			}
+			continue
+		}
+
+		if kind != ntfsAttrData || !unnamed || len(attr) < 64 {
+			continue
+		}
+		startVCN := int64(binary.LittleEndian.Uint64(attr[16:]))
+		runOffset := int(binary.LittleEndian.Uint16(attr[32:]))
+		if runOffset > len(attr) {
+			return ntfsRecord{}, errors.New("corrupt run list offset")
+		}
+		runs, err := v.decodeRuns(attr[runOffset:])
+		if err != nil {
+			return ntfsRecord{}, err
+		}
+
+		rec.hasData = true
+		rec.fragments = append(rec.fragments, ntfsFragment{startVCN: startVCN, runs: runs})
+		if startVCN == 0 {
+			rec.size = int64(binary.LittleEndian.Uint64(attr[48:]))
+			rec.flags = binary.LittleEndian.Uint16(attr[12:])
+		}
+	}
+	return rec, nil
+}
+
+// Joins the data fragments in cluster order so the content can be read front to back
+func (rec ntfsRecord) extents() []imageExtent {
+	sort.Slice(rec.fragments, func(i, j int) bool {
+		return rec.fragments[i].startVCN < rec.fragments[j].startVCN
+	})
+	extents := []imageExtent{}
+	for _, f := range rec.fragments {
+		extents = append(extents, f.runs...)
+	}
+	return extents
+}
+
+// Lists every file in an NTFS filesystem by reading the whole MFT rather than walking
+// directory indexes, skipping the filesystem metadata files
+func ntfsFiles(r io.ReaderAt) ([]imageFile, error) {
+	v, err := openNTFSVolume(r)
+	if err != nil {
+		return nil, err
+	}
+
+	first := make([]byte, v.recordSize)
+	if _, err := r.ReadAt(first, v.mftOffset); err != nil {
+		return nil, fmt.Errorf("unable to read MFT: %w", err)
+	}
+	mft, err := v.parseRecord(first)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read MFT: %w", err)
+	}
+	if !mft.hasData || mft.resident != nil {
+		return nil, errors.New("unable to read MFT: record 0 has no data runs")
+	}
+
+	// The MFT describes itself in record 0 so read every record through its own runs
+	records := map[uint64]*ntfsRecord{}
+	reader := newExtentReader(r, mft.extents(), mft.size)
+	buf := make([]byte, v.recordSize)
+	for number := uint64(0); number < uint64(mft.size/v.recordSize); number++ {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, fmt.Errorf("unable to read MFT record %d: %w", number, err)
+		}
+		rec, err := v.parseRecord(buf)
+		if err != nil {
+			if Verbose {
+				printVerbose(fmt.Sprintf("skipping MFT record %d: %s", number, err.Error()))
+			}
+			continue
+		}
+		if !rec.inUse {
+			continue
+		}
+
+		if rec.base != 0 {
+			// Extension records carry more of the base record's attributes
+			if base, ok := records[rec.base]; ok {
+				base.fragments = append(base.fragments, rec.fragments...)
+			} else {
+				records[rec.base] = &ntfsRecord{base: rec.base, fragments: rec.fragments}
+			}
+			continue
+		}
+
+		if existing, ok := records[number]; ok && existing.base != 0 {
+			rec.fragments = append(rec.fragments, existing.fragments...)
+		}
+		records[number] = &rec
+	}
+
+	var pathOf func(number uint64, depth int) (string, bool)
+	pathOf = func(number uint64, depth int) (string, bool) {
+		rec, ok := records[number]
+		if !ok || !rec.named || depth > 256 || number == ntfsExtendRecord {
+			return "", false
+		}
+		if rec.parent == ntfsRootRecord {
+			return rec.name, true
+		}
+		parent, ok := pathOf(rec.parent, depth+1)
+		if !ok {
+			return "", false
+		}
+		return parent + "/" + rec.name, true
+	}
+
+	numbers := make([]uint64, 0, len(records))
+	for number := range records {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	files := []imageFile{}
+	for _, number := range numbers {
+		rec := records[number]
+		if number < ntfsFirstUserRecord || rec.dir || !rec.named || rec.base != 0 {
+			continue
+		}
+		path, ok := pathOf(number, 0)
+		if !ok {
+			continue
+		}
+
+		files = append(files, imageFile{
+			Path: path,
+			Size: rec.size,
+			open: func() (io.Reader, error) {
+				switch {
+				case rec.flags&ntfsFlagCompressed != 0:
+					return nil, errors.New("compressed NTFS files are not supported")
+				case rec.flags&ntfsFlagEncrypted != 0:
+					return nil, errors.New("encrypted NTFS files cannot be read")
+				case rec.resident != nil || !rec.hasData:
+					return bytes.NewReader(rec.resident), nil
+				}
+				return newExtentReader(v.r, rec.extents(), rec.size), nil
+			},
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
@@ -0,0 +1,163 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const testNTFSRecords = 24
+
+func ntfsResidentAttr(kind uint32, value []byte) []byte {
+	length := (24 + len(value) + 7) &^ 7
+	a := make([]byte, length)
+	binary.LittleEndian.PutUint32(a[0:], kind)
+	binary.LittleEndian.PutUint32(a[4:], uint32(length))
+	binary.LittleEndian.PutUint32(a[16:], uint32(len(value)))
+	binary.LittleEndian.PutUint16(a[20:], 24)
+	copy(a[24:], value)
+	return a
+}
+
+func ntfsFileNameAttr(parent uint64, name string, namespace byte) []byte {
+	units := utf16.Encode([]rune(name))
+	value := make([]byte, 66+len(units)*2)
+	binary.LittleEndian.PutUint64(value, parent|1<<48)
+	value[64] = byte(len(units))
+	value[65] = namespace
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(value[66+i*2:], u)
+	}
+	return ntfsResidentAttr(ntfsAttrFileName, value)
+}
+
+func ntfsDataAttr(startVCN int64, runs []byte, size int64, flags uint16) []byte {
+	length := (64 + len(runs) + 1 + 7) &^ 7
+	a := make([]byte, length)
+	binary.LittleEndian.PutUint32(a[0:], ntfsAttrData)
+	binary.LittleEndian.PutUint32(a[4:], uint32(length))
+	a[8] = 1
+	binary.LittleEndian.PutUint16(a[12:], flags)
+	binary.LittleEndian.PutUint64(a[16:], uint64(startVCN))
+	binary.LittleEndian.PutUint16(a[32:], 64)
+	binary.LittleEndian.PutUint64(a[48:], uint64(size))
+	copy(a[64:], runs)
+	return a
+}
+
+// Builds a 1024 byte MFT record with the update sequence applied as NTFS writes it
+func ntfsTestRecord(flags uint16, base uint64, attrs ...[]byte) []byte {
+	r := make([]byte, 1024)
+	copy(r, "FILE")
+	binary.LittleEndian.PutUint16(r[4:], 0x30)
+	binary.LittleEndian.PutUint16(r[6:], 3)
+	binary.LittleEndian.PutUint16(r[0x14:], 0x38)
+	binary.LittleEndian.PutUint16(r[0x16:], flags)
+	binary.LittleEndian.PutUint64(r[0x20:], base)
+
+	offset := 0x38
+	for _, a := range attrs {
+		copy(r[offset:], a)
+		offset += len(a)
+	}
+	binary.LittleEndian.PutUint32(r[offset:], ntfsAttrEnd)
+
+	binary.LittleEndian.PutUint16(r[0x30:], 7)
+	for i, end := range []int{510, 1022} {
+		copy(r[0x32+i*2:], r[end:end+2])
+		binary.LittleEndian.PutUint16(r[end:], 7)
+	}
+	return r
+}
+
+func buildNTFSImage() []byte {
+	img := make([]byte, 100*512)
+	copy(img[3:], "NTFS    ")
+	binary.LittleEndian.PutUint16(img[11:], 512)
+	img[13] = 1
+	binary.LittleEndian.PutUint64(img[0x30:], 16)
+	img[0x40] = 0xf6
+
+	inUse := uint16(ntfsRecordInUse)
+	dir := uint16(ntfsRecordInUse | ntfsRecordDirectory)
+	records := map[int][]byte{
+		0: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(5, "$MFT", 3),
+			ntfsDataAttr(0, []byte{0x11, 48, 16}, testNTFSRecords*1024, 0)),
+		5:  ntfsTestRecord(dir, 0, ntfsFileNameAttr(5, ".", 3)),
+		11: ntfsTestRecord(dir, 0, ntfsFileNameAttr(5, "$Extend", 3)),
+		16: ntfsTestRecord(dir, 0, ntfsFileNameAttr(5, "docs", 1)),
+		17: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(16, "A.TXT", 2), ntfsFileNameAttr(16, "a.txt", 1),
+			ntfsResidentAttr(ntfsAttrData, []byte("hello"))),
+		// Two clusters at 64, a sparse cluster, then one cluster six further on at 70
+		18: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(5, "big.bin", 3),
+			ntfsDataAttr(0, []byte{0x11, 2, 64, 0x01, 1, 0x11, 1, 6}, 4*512-100, 0)),
+		19: ntfsTestRecord(0, 0, ntfsFileNameAttr(5, "deleted.txt", 3), ntfsResidentAttr(ntfsAttrData, []byte("gone"))),
+		// The second cluster of frag.bin is described by an extension record
+		20: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(5, "frag.bin", 3),
+			ntfsDataAttr(0, []byte{0x11, 1, 80}, 1024, 0)),
+		21: ntfsTestRecord(inUse, 20, ntfsDataAttr(1, []byte{0x11, 1, 90}, 0, 0)),
+		22: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(5, "packed.bin", 3),
+			ntfsDataAttr(0, []byte{0x11, 1, 95}, 512, ntfsFlagCompressed)),
+		23: ntfsTestRecord(inUse, 0, ntfsFileNameAttr(11, "$UsnJrnl", 3), ntfsResidentAttr(ntfsAttrData, nil)),
+	}
+	for number, r := range records {
+		copy(img[16*512+number*1024:], r)
+	}
+
+	copy(img[64*512:], bytes.Repeat([]byte("A"), 1024))
+	copy(img[70*512:], bytes.Repeat([]byte("B"), 512))
+	copy(img[80*512:], bytes.Repeat([]byte("C"), 512))
+	copy(img[90*512:], bytes.Repeat([]byte("D"), 512))
+	return img
+}
+
+func TestNTFSFiles(t *testing.T) {
+	files, err := ntfsFiles(bytes.NewReader(buildNTFSImage()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	contents := map[string]string{}
+	for _, f := range files {
+		names = append(names, f.Path)
+		r, err := f.open()
+		if err != nil {
+			contents[f.Path] = "error: " + err.Error()
+			continue
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(f.Path, err)
+		}
+		contents[f.Path] = string(content)
+	}
+
+	if strings.Join(names, ",") != "big.bin,docs/a.txt,frag.bin,packed.bin" {
+		t.Error("unexpected files", names)
+	}
+	if contents["docs/a.txt"] != "hello" {
+		t.Errorf("unexpected resident content %q", contents["docs/a.txt"])
+	}
+	big := strings.Repeat("A", 1024) + strings.Repeat("\x00", 512) + strings.Repeat("B", 412)
+	if contents["big.bin"] != big {
+		t.Error("unexpected sparse content")
+	}
+	if contents["frag.bin"] != strings.Repeat("C", 512)+strings.Repeat("D", 512) {
+		t.Error("unexpected fragmented content")
+	}
+	if !strings.HasPrefix(contents["packed.bin"], "error: compressed") {
+		t.Error("expected compressed file to fail got", contents["packed.bin"])
+	}
+}
+
+func TestNTFSApplyFixupsTorn(t *testing.T) {
+	r := ntfsTestRecord(ntfsRecordInUse, 0)
+	r[510] = 0
+	if err := ntfsApplyFixups(r); err == nil {
+		t.Error("expected torn record to fail")
+	}
+}
@@ -0,0 +1,480 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// One version of an object, or a delete marker, in a versioned bucket
+type objectVersion struct {
+	Key          string
+	VersionID    string
+	Latest       bool
+	DeleteMarker bool
+}
+
+// A bucket --check can audit a manifest against as a --root replica
+type objectStore interface {
+	listVersions(prefix string) ([]objectVersion, error)
+	open(v objectVersion) (io.ReadCloser, error)
+}
+
+func isObjectRoot(root string) bool {
+	return strings.Contains(root, "://")
+}
+
+// Returns the store and key prefix for s3:// and gs:// replicas, nil for directories
+func parseObjectRoot(root string) (objectStore, string, error) {
+	if !isObjectRoot(root) {
+		return nil, "", nil
+	}
+	scheme, rest, _ := strings.Cut(root, "://")
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, "", fmt.Errorf("invalid replica %s missing the bucket", root)
+	}
+
+	switch scheme {
+	case "s3":
+		return newS3Store(bucket), prefix, nil
+	case "gs":
+		return newGCSStore(bucket), prefix, nil
+	}
+	return nil, "", fmt.Errorf("unsupported replica %s, only s3:// and gs:// buckets can be audited", root)
+}
+
+// Joins a manifest path onto a bucket location using forward slashes whatever the platform
+func joinObjectPath(root string, rest string) string {
+	rest = strings.TrimLeft(path.Clean("/"+filepath.ToSlash(rest)), "/")
+	return strings.TrimRight(root, "/") + "/" + rest
+}
+
+// The key of a location such as s3://bucket/key
+func objectKey(location string) string {
+	_, rest, _ := strings.Cut(location, "://")
+	_, key, _ := strings.Cut(rest, "/")
+	return key
+}
+
+func validateObjectAudit() error {
+	if ObjectVersion == "" {
+		return errors.New("--object-version must be latest, all or a version id")
+	}
+	switch DeleteMarkers {
+	case "missing", "deleted":
+		return nil
+	}
+	return fmt.Errorf("unknown --delete-markers %s expected missing or deleted", DeleteMarkers)
+}
+
+// Checks every entry against the versions selected by ObjectVersion in the bucket the
+// replica names, listing the versions under its prefix once
+func checkObjectReplica(entries []manifestEntry, root checkRoot, store objectStore, prefix string) (replicaStats, error) {
+	stats := replicaStats{Replica: root.New, Statuses: map[string]string{}}
+	listed, err := store.listVersions(prefix)
+	if err != nil {
+		return stats, fmt.Errorf("unable to list %s: %w", root.New, err)
+	}
+	versions := map[string][]objectVersion{}
+	for _, v := range listed {
+		versions[v.Key] = append(versions[v.Key], v)
+	}
+
+	for _, e := range entries {
+		location, ok := root.rewrite(e.File)
+		if !ok {
+			stats.Outside++
+			stats.Statuses[e.File] = "FAILED not under " + root.Old
+			continue
+		}
+
+		status := auditObject(store, e, versions[objectKey(location)])
+		switch {
+		case status == "OK" || strings.HasPrefix(status, "OK "):
+			stats.OK++
+		case status == "DELETED":
+			stats.Deleted++
+		case status == "FAILED missing":
+			stats.Missing++
+		default:
+			stats.Failed++
+		}
+		stats.Statuses[location] = status
+	}
+	return stats, nil
+}
+
+// Hashes the versions of an object selected by ObjectVersion. With all the entry is OK
+// when any stored version matches so content kept only in an older version is reported
+// as recoverable. A delete marker hiding the object is missing unless DeleteMarkers says
+// deletions were intentional.
+func auditObject(store objectStore, e manifestEntry, versions []objectVersion) string {
+	candidates := []objectVersion{}
+	deleted := false
+	for _, v := range versions {
+		selected := false
+		switch ObjectVersion {
+		case "all":
+			selected = true
+		case "latest":
+			selected = v.Latest
+		default:
+			selected = v.VersionID == ObjectVersion
+		}
+		switch {
+		case !selected:
+		case v.DeleteMarker:
+			deleted = deleted || v.Latest || ObjectVersion != "all"
+		default:
+			candidates = append(candidates, v)
+		}
+	}
+
+	if len(candidates) == 0 {
+		if deleted && DeleteMarkers == "deleted" {
+			return "DELETED"
+		}
+		return "FAILED missing"
+	}
+
+	for _, v := range candidates {
+		matched, err := objectMatches(store, v, e)
+		if err != nil {
+			printVerbose("unable to read object version", "key", v.Key, "version", v.VersionID, "error", err.Error())
+			return "FAILED open or read"
+		}
+		if matched && ObjectVersion == "all" {
+			return "OK version " + v.VersionID
+		}
+		if matched {
+			return "OK"
+		}
+	}
+	return "FAILED"
+}
+
+// Streams an object version through the hashes the entry lists
+func objectMatches(store objectStore, v objectVersion, e manifestEntry) (bool, error) {
+	body, err := store.open(v)
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	names := []string{}
+	for _, d := range e.Digests {
+		names = append(names, d.Names...)
+	}
+	previous := Hash
+	Hash = names
+	res, err := processStream(v.Key, limitReader(body), nil, 0, nil, "")
+	Hash = previous
+	if err != nil {
+		return false, err
+	}
+	return digestsMatch(calculatedHashes(res), e.Digests), nil
+}
+
+// Fails on anything other than a 200 so error pages are never hashed as content
+func objectResponse(req *http.Request) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// S3 or a compatible store addressed path style so buckets with dots in the name work.
+// Credentials and the endpoint come from the standard AWS environment variables, requests
+// are unsigned when there are no credentials which works for public buckets.
+type s3Store struct {
+	endpoint  string
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+}
+
+func newS3Store(bucket string) *s3Store {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	return &s3Store{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		bucket:    bucket,
+		region:    region,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Percent encodes everything but the unreserved characters as signature version 4 requires
+func awsEscape(s string, keepSlash bool) string {
+	var str strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			str.WriteByte(b)
+		case b == '/' && keepSlash:
+			str.WriteByte(b)
+		default:
+			str.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return str.String()
+}
+
+// Query parameters sorted and encoded the same way for the request and its signature
+func awsQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Signs a GET with signature version 4, the body is always empty
+func (s *s3Store) sign(req *http.Request, now time.Time) {
+	const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", emptyPayload)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, emptyPayload, amzDate}
+	if s.token != "" {
+		req.Header.Set("x-amz-security-token", s.token)
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, s.token)
+	}
+	canonicalHeaders := ""
+	for i, h := range headers {
+		canonicalHeaders += h + ":" + values[i] + "\n"
+	}
+	signed := strings.Join(headers, ";")
+
+	canonical := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders, signed, emptyPayload}, "\n")
+	digest := sha256.Sum256([]byte(canonical))
+	scope := amzDate[:8] + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func (s *s3Store) get(key string, query url.Values) (*http.Response, error) {
+	target := s.endpoint + "/" + awsEscape(s.bucket, false)
+	if key != "" {
+		target += "/" + awsEscape(key, true)
+	}
+	if len(query) != 0 {
+		target += "?" + awsQuery(query)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.accessKey != "" {
+		s.sign(req, time.Now())
+	}
+	return objectResponse(req)
+}
+
+func (s *s3Store) listVersions(prefix string) ([]objectVersion, error) {
+	versions := []objectVersion{}
+	query := url.Values{"versions": {""}, "prefix": {prefix}}
+	for {
+		resp, err := s.get("", query)
+		if err != nil {
+			return nil, err
+		}
+
+		type entry struct {
+			Key       string
+			VersionId string
+			IsLatest  bool
+		}
+		listing := struct {
+			IsTruncated         bool
+			NextKeyMarker       string
+			NextVersionIdMarker string
+			Version             []entry
+			DeleteMarker        []entry
+		}{}
+		err = xml.NewDecoder(resp.Body).Decode(&listing)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range listing.Version {
+			versions = append(versions, objectVersion{Key: v.Key, VersionID: v.VersionId, Latest: v.IsLatest})
+		}
+		for _, v := range listing.DeleteMarker {
+			versions = append(versions, objectVersion{Key: v.Key, VersionID: v.VersionId, Latest: v.IsLatest, DeleteMarker: true})
+		}
+
+		if !listing.IsTruncated {
+			return versions, nil
+		}
+		query.Set("key-marker", listing.NextKeyMarker)
+		query.Set("version-id-marker", listing.NextVersionIdMarker)
+	}
+}
+
+func (s *s3Store) open(v objectVersion) (io.ReadCloser, error) {
+	query := url.Values{}
+	if v.VersionID != "" && v.VersionID != "null" {
+		query.Set("versionId", v.VersionID)
+	}
+	resp, err := s.get(v.Key, query)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Google Cloud Storage through the JSON API. STORAGE_EMULATOR_HOST points it elsewhere
+// and GOOGLE_OAUTH_ACCESS_TOKEN, such as from gcloud auth print-access-token, is sent
+// as the bearer token when set.
+type gcsStore struct {
+	endpoint string
+	bucket   string
+	token    string
+}
+
+func newGCSStore(bucket string) *gcsStore {
+	endpoint := os.Getenv("STORAGE_EMULATOR_HOST")
+	switch {
+	case endpoint == "":
+		endpoint = "https://storage.googleapis.com"
+	case !strings.Contains(endpoint, "://"):
+		endpoint = "http://" + endpoint
+	}
+	return &gcsStore{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		bucket:   bucket,
+		token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+}
+
+func (g *gcsStore) get(target string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	return objectResponse(req)
+}
+
+// GCS has no delete markers, an object whose versions are all noncurrent was deleted so
+// one is added as its latest version
+func (g *gcsStore) listVersions(prefix string) ([]objectVersion, error) {
+	versions := []objectVersion{}
+	live := map[string]bool{}
+	query := url.Values{"versions": {"true"}, "prefix": {prefix}}
+	for {
+		resp, err := g.get(g.endpoint + "/storage/v1/b/" + url.PathEscape(g.bucket) + "/o?" + query.Encode())
+		if err != nil {
+			return nil, err
+		}
+
+		listing := struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items         []struct {
+				Name        string `json:"name"`
+				Generation  string `json:"generation"`
+				TimeDeleted string `json:"timeDeleted"`
+			} `json:"items"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&listing)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range listing.Items {
+			current := item.TimeDeleted == ""
+			live[item.Name] = live[item.Name] || current
+			versions = append(versions, objectVersion{Key: item.Name, VersionID: item.Generation, Latest: current})
+		}
+
+		if listing.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", listing.NextPageToken)
+	}
+
+	for _, v := range versions {
+		if !live[v.Key] {
+			live[v.Key] = true
+			versions = append(versions, objectVersion{Key: v.Key, Latest: true, DeleteMarker: true})
+		}
+	}
+	return versions, nil
+}
+
+func (g *gcsStore) open(v objectVersion) (io.ReadCloser, error) {
+	query := url.Values{"alt": {"media"}}
+	if v.VersionID != "" {
+		query.Set("generation", v.VersionID)
+	}
+	resp, err := g.get(g.endpoint + "/storage/v1/b/" + url.PathEscape(g.bucket) + "/o/" + url.PathEscape(v.Key) + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Check the options controlling how files are opened
+func validateOpenMode() error {
+	switch ShareMode {
+	case "read", "write", "delete":
+	default:
+		return fmt.Errorf("unknown --share-mode %s expected read, write or delete", ShareMode)
+	}
+	switch Locked {
+	case "ignore", "skip", "wait":
+	default:
+		return fmt.Errorf("unknown --locked %s expected ignore, skip or wait", Locked)
+	}
+	return nil
+}
+
+// How long a file held under an exclusive lock is waited for with --locked wait
+var lockWaitLimit = time.Minute
+
+// Reports why the file should be skipped when another process holds an exclusive
+// advisory lock on it. The shared lock taken to find out is released straight away so
+// applications are never kept waiting on hashit.
+func lockedReason(file *os.File) (string, bool) {
+	if Locked == "ignore" {
+		return "", false
+	}
+
+	wait := 10 * time.Millisecond
+	var waited time.Duration
+	for {
+		free, err := probeSharedLock(file)
+		if err != nil || free {
+			// Filesystems without lock support cannot be held locked either
+			return "", false
+		}
+		if Locked != "wait" || waited >= lockWaitLimit {
+			return "locked by another process", true
+		}
+
+		if Debug {
+			printDebug("file is locked, waiting", "file", file.Name(), "wait", wait)
+		}
+		time.Sleep(wait)
+		waited += wait
+		if wait < time.Second {
+			wait *= 2
+		}
+	}
+}
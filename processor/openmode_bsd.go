@@ -0,0 +1,6 @@
+//go:build darwin || freebsd
+
+package processor
+
+// Reading never updates the access time with O_NOATIME as there is no such flag
+const noAtimeFlag = 0
@@ -0,0 +1,5 @@
+package processor
+
+import "syscall"
+
+const noAtimeFlag = syscall.O_NOATIME
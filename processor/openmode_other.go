@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || windows)
+
+package processor
+
+import "os"
+
+func openReadOnly(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDONLY, 0644)
+}
+
+// There are no locks to find so every file is free to read
+func probeSharedLock(file *os.File) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,21 @@
+package processor
+
+import "testing"
+
+func TestValidateOpenMode(t *testing.T) {
+	previousShare, previousLocked := ShareMode, Locked
+	defer func() { ShareMode, Locked = previousShare, previousLocked }()
+
+	ShareMode, Locked = "delete", "wait"
+	if err := validateOpenMode(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+	ShareMode = "exclusive"
+	if validateOpenMode() == nil {
+		t.Error("Expected error for unknown share mode")
+	}
+	ShareMode, Locked = "read", "block"
+	if validateOpenMode() == nil {
+		t.Error("Expected error for unknown locked mode")
+	}
+}
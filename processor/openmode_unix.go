@@ -0,0 +1,34 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Opens read only without updating the access time when --no-atime is set. Only the
+// owner of a file may ask for that so anything else is opened normally.
+func openReadOnly(path string) (*os.File, error) {
+	if NoAtime && noAtimeFlag != 0 {
+		file, err := os.OpenFile(path, os.O_RDONLY|noAtimeFlag, 0644)
+		if !errors.Is(err, syscall.EPERM) {
+			return file, err
+		}
+	}
+	return os.OpenFile(path, os.O_RDONLY, 0644)
+}
+
+// Takes and drops a shared flock, false when an exclusive one is held elsewhere
+func probeSharedLock(file *os.File) (bool, error) {
+	fd := int(file.Fd())
+	err := syscall.Flock(fd, syscall.LOCK_SH|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, syscall.Flock(fd, syscall.LOCK_UN)
+}
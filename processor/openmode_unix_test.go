@@ -0,0 +1,75 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLockedReason(t *testing.T) {
+	previousLocked, previousWait := Locked, lockWaitLimit
+	defer func() { Locked, lockWaitLimit = previousLocked, previousWait }()
+
+	path := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(path, []byte("hello"), 0600)
+	holder, _ := os.Open(path)
+	defer holder.Close()
+	if err := syscall.Flock(int(holder.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Skipf("unable to lock: %s", err)
+	}
+
+	file, err := openReadOnly(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	defer file.Close()
+
+	Locked = "ignore"
+	if _, locked := lockedReason(file); locked {
+		t.Error("Expected locks to be ignored")
+	}
+	Locked = "skip"
+	if _, locked := lockedReason(file); !locked {
+		t.Error("Expected the exclusively locked file to be skipped")
+	}
+
+	Locked, lockWaitLimit = "wait", 5*time.Second
+	fd := int(holder.Fd())
+	released := make(chan struct{})
+	go func() {
+		defer close(released)
+		time.Sleep(50 * time.Millisecond)
+		_ = syscall.Flock(fd, syscall.LOCK_UN)
+	}()
+	if _, locked := lockedReason(file); locked {
+		t.Error("Expected the file to be read once the lock was released")
+	}
+	<-released
+
+	// The probe must not leave a lock behind to block writers
+	if err := syscall.Flock(fd, syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Errorf("Expected no lock to be left behind got %s", err)
+	}
+}
+
+func TestOpenReadOnlyNoAtime(t *testing.T) {
+	previous := NoAtime
+	defer func() { NoAtime = previous }()
+	NoAtime = true
+
+	path := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(path, []byte("hello"), 0600)
+	file, err := openReadOnly(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	defer file.Close()
+	buf := make([]byte, 5)
+	if n, _ := file.Read(buf); n != 5 || string(buf) != "hello" {
+		t.Errorf("Expected to read the file got %q", buf[:n])
+	}
+}
@@ -0,0 +1,46 @@
+package processor
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Opens read only letting other applications share the file as --share-mode allows,
+// read stops them writing while it is hashed and delete lets them remove or rename it
+func openReadOnly(path string) (*os.File, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	share := uint32(windows.FILE_SHARE_READ)
+	switch ShareMode {
+	case "write":
+		share |= windows.FILE_SHARE_WRITE
+	case "delete":
+		share |= windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE
+	}
+
+	handle, err := windows.CreateFile(name, windows.GENERIC_READ, share, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+// Takes and drops a shared lock on the whole file, false when another process has an
+// exclusive lock on any of it
+func probeSharedLock(file *os.File) (bool, error) {
+	handle := windows.Handle(file.Fd())
+	overlapped := &windows.Overlapped{}
+	err := windows.LockFileEx(handle, windows.LOCKFILE_FAIL_IMMEDIATELY, 0, ^uint32(0), ^uint32(0), overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, windows.UnlockFileEx(handle, 0, ^uint32(0), ^uint32(0), overlapped)
+}
@@ -0,0 +1,166 @@
+package processor
+
+import (
+	"container/heap"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Check the Order option is one that orderFiles understands
+func validateOrder(order string) error {
+	switch order {
+	case "", "largest-first", "smallest-first", "size", "mtime", "name":
+		return nil
+	}
+	return fmt.Errorf("invalid order %s expected size, smallest-first, largest-first, mtime or name", order)
+}
+
+// A queued file with the metadata it is sorted by
+type orderedFile struct {
+	path  string
+	size  int64
+	mtime time.Time
+	err   error
+}
+
+// Reports if a should be hashed before b. Files which cannot be stated go last so the
+// worker reports the error.
+func orderedBefore(a orderedFile, b orderedFile, order string) bool {
+	if (a.err == nil) != (b.err == nil) {
+		return a.err == nil
+	}
+	switch order {
+	case "largest-first":
+		return a.size > b.size
+	case "smallest-first", "size":
+		return a.size < b.size
+	case "mtime":
+		return a.mtime.After(b.mtime)
+	case "name":
+		return a.path < b.path
+	}
+	return false
+}
+
+// Files waiting for a worker, ties keep the order they were found in
+type fileQueue struct {
+	files []orderedFile
+	seq   []int
+	next  int
+	order string
+}
+
+func (q *fileQueue) Len() int { return len(q.files) }
+
+func (q *fileQueue) Less(i, j int) bool {
+	if orderedBefore(q.files[i], q.files[j], q.order) {
+		return true
+	}
+	if orderedBefore(q.files[j], q.files[i], q.order) {
+		return false
+	}
+	return q.seq[i] < q.seq[j]
+}
+
+func (q *fileQueue) Swap(i, j int) {
+	q.files[i], q.files[j] = q.files[j], q.files[i]
+	q.seq[i], q.seq[j] = q.seq[j], q.seq[i]
+}
+
+func (q *fileQueue) Push(x any) {
+	q.files = append(q.files, x.(orderedFile))
+	q.seq = append(q.seq, q.next)
+	q.next++
+}
+
+func (q *fileQueue) Pop() any {
+	n := len(q.files) - 1
+	f := q.files[n]
+	q.files, q.seq = q.files[:n], q.seq[:n]
+	return f
+}
+
+func statOrderedFile(path string) orderedFile {
+	f := orderedFile{path: path}
+	if fi, err := os.Stat(path); err == nil {
+		f.size = fi.Size()
+		f.mtime = fi.ModTime()
+	} else {
+		f.err = err
+	}
+	return f
+}
+
+// Hands files to the workers through a priority queue so each one picks up the best
+// file found so far by Order, smallest first, most recently modified first or by name.
+// Hashing starts while the walk is still running and small files finishing first gives
+// useful output early on. largest-first is the exception and waits for the walk to
+// finish, as a huge file found late would otherwise be left running alone at the end.
+func orderFiles(input chan string) chan string {
+	if Order == "" {
+		return input
+	}
+	collect := Order == "largest-first"
+
+	// Unbuffered so the choice is made as a worker becomes free rather than as found
+	output := make(chan string)
+	go func() {
+		queue := &fileQueue{order: Order}
+		open := true
+
+		for open || queue.Len() != 0 {
+			if collect && open {
+				path, ok := <-input
+				if ok {
+					heap.Push(queue, statOrderedFile(path))
+				}
+				open = ok
+				continue
+			}
+
+			// Take in everything already found before choosing
+			for drained := false; open && !drained; {
+				select {
+				case path, ok := <-input:
+					if !ok {
+						open = false
+						break
+					}
+					heap.Push(queue, statOrderedFile(path))
+				default:
+					drained = true
+				}
+			}
+
+			if queue.Len() == 0 {
+				if path, ok := <-input; ok {
+					heap.Push(queue, statOrderedFile(path))
+				} else {
+					open = false
+				}
+				continue
+			}
+
+			if !open {
+				output <- heap.Pop(queue).(orderedFile).path
+				continue
+			}
+			select {
+			case output <- queue.files[0].path:
+				heap.Pop(queue)
+			case path, ok := <-input:
+				if !ok {
+					open = false
+					continue
+				}
+				heap.Push(queue, statOrderedFile(path))
+			}
+		}
+
+		printDebug("ordered queue", "order", Order, "files", queue.next)
+		close(output)
+	}()
+
+	return output
+}
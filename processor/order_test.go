@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]int{"small": 1, "large": 100, "medium": 10}
+	for name, size := range files {
+		p := filepath.Join(dir, name)
+		_ = os.WriteFile(p, make([]byte, size), 0600)
+		_ = os.Chtimes(p, now, now.Add(-time.Duration(size)*time.Hour))
+	}
+
+	cases := map[string]string{
+		"":               "small,large,missing,medium",
+		"largest-first":  "large,medium,small,missing",
+		"smallest-first": "small,medium,large,missing",
+		"mtime":          "small,medium,large,missing",
+	}
+	defer func() { Order = "" }()
+	for order, expected := range cases {
+		Order = order
+		input := make(chan string, 4)
+		for _, name := range []string{"small", "large", "missing", "medium"} {
+			input <- filepath.Join(dir, name)
+		}
+		close(input)
+
+		names := []string{}
+		for p := range orderFiles(input) {
+			names = append(names, filepath.Base(p))
+		}
+		if strings.Join(names, ",") != expected {
+			t.Errorf("%s expected %s got %s", order, expected, strings.Join(names, ","))
+		}
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	if err := validateOrder("largest-first"); err != nil {
+		t.Error(err)
+	}
+	if err := validateOrder("random"); err == nil {
+		t.Error("Expected unknown order to fail")
+	}
+}
+
+func TestOrderFilesByName(t *testing.T) {
+	defer func() { Order = "" }()
+	Order = "name"
+
+	input := make(chan string, 3)
+	for _, name := range []string{"b", "c", "a"} {
+		input <- name
+	}
+	close(input)
+
+	names := []string{}
+	for p := range orderFiles(input) {
+		names = append(names, p)
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("expected a,b,c got %s", strings.Join(names, ","))
+	}
+}
+
+func TestOrderFilesWhileWalking(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"large": 100, "small": 1, "medium": 10} {
+		_ = os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600)
+	}
+
+	defer func() { Order = "" }()
+	Order = "size"
+
+	// The first file is handed on before the walk has finished then the rest smallest first
+	input := make(chan string)
+	output := orderFiles(input)
+	input <- filepath.Join(dir, "large")
+	if got := filepath.Base(<-output); got != "large" {
+		t.Errorf("expected large while it was the only file got %s", got)
+	}
+	input <- filepath.Join(dir, "medium")
+	input <- filepath.Join(dir, "small")
+	close(input)
+
+	names := []string{}
+	for p := range output {
+		names = append(names, filepath.Base(p))
+	}
+	if strings.Join(names, ",") != "small,medium" {
+		t.Errorf("expected small,medium got %s", strings.Join(names, ","))
+	}
+}
+
+func TestOrderFilesLargestFirstWaitsForWalk(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{"large": 100, "small": 1, "medium": 10} {
+		_ = os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0600)
+	}
+
+	defer func() { Order = "" }()
+	Order = "largest-first"
+
+	// Nothing is handed on until the walk finishes so the large file found last still goes first
+	input := make(chan string)
+	output := orderFiles(input)
+	input <- filepath.Join(dir, "small")
+	select {
+	case p := <-output:
+		t.Fatalf("expected nothing before the walk finished got %s", filepath.Base(p))
+	case input <- filepath.Join(dir, "medium"):
+	}
+	input <- filepath.Join(dir, "large")
+	close(input)
+
+	names := []string{}
+	for p := range output {
+		names = append(names, filepath.Base(p))
+	}
+	if strings.Join(names, ",") != "large,medium,small" {
+		t.Errorf("expected large,medium,small got %s", strings.Join(names, ","))
+	}
+}
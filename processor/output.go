@@ -0,0 +1,238 @@
+package processor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Parses an octal file mode such as 0644
+func parseOutputMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid output mode %s expected octal permissions such as 0644", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// Resolves user[:group] where each can be a name or numeric id. Missing group means
+// leave the group unchanged which os.Chown represents with -1
+func parseOutputOwner(owner string) (int, int, error) {
+	name, group, _ := strings.Cut(owner, ":")
+
+	uid := -1
+	if name != "" {
+		id, err := strconv.Atoi(name)
+		if err != nil {
+			u, lookupErr := user.Lookup(name)
+			if lookupErr != nil {
+				return 0, 0, fmt.Errorf("unknown output owner %s: %w", name, lookupErr)
+			}
+			id, _ = strconv.Atoi(u.Uid)
+		}
+		uid = id
+	}
+
+	gid := -1
+	if group != "" {
+		id, err := strconv.Atoi(group)
+		if err != nil {
+			g, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return 0, 0, fmt.Errorf("unknown output group %s: %w", group, lookupErr)
+			}
+			id, _ = strconv.Atoi(g.Gid)
+		}
+		gid = id
+	}
+
+	return uid, gid, nil
+}
+
+// Absolute paths of every file this run writes which the walker leaves out so a manifest
+// saved inside a scanned directory never includes a hash of its own partial self
+var outputPaths = map[string]bool{}
+
+// Working directory relative paths are resolved against when checking for output files
+var outputDir = ""
+
+// Records the output file, per root manifests, signatures and resume state for this run
+func excludeOutputs(roots []string) {
+	outputPaths = map[string]bool{}
+	outputDir, _ = os.Getwd()
+
+	paths := []string{Resume, PartialOutput}
+	if DirCache != "" {
+		paths = append(paths, DirCache, DirCache+".tmp")
+	}
+	if FileOutput != "" {
+		paths = append(paths, FileOutput, FileOutput+signatureExtension)
+	}
+	if databaseOutput != "" {
+		paths = append(paths, databaseOutput, databaseOutput+"-journal", databaseOutput+"-wal")
+	}
+	if PerRootOutput != "" {
+		for _, o := range newRootOutputs(roots, PerRootOutput) {
+			paths = append(paths, o.file, o.file+signatureExtension)
+		}
+	}
+
+	for _, p := range paths {
+		if p != "" {
+			outputPaths[absoluteOutputPath(p)] = true
+		}
+	}
+}
+
+func absoluteOutputPath(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(outputDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Check if the path is one of the files this run is writing
+func isOutputFile(path string) bool {
+	if len(outputPaths) == 0 {
+		return false
+	}
+	return outputPaths[absoluteOutputPath(path)]
+}
+
+// Writes the results to the output file
+func writeOutputFile(result string) error {
+	return writeOutputTo(FileOutput, result)
+}
+
+// Writes the results to path applying the requested permissions, ownership, compression
+// and encryption
+func writeOutputTo(path string, result string) error {
+	f, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Compressing happens before encrypting as encrypted output does not compress
+	e, err := encryptWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w, err := compressWriter(e)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := io.WriteString(w, result); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := e.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Creates or truncates path so results can be written into it applying the requested
+// permissions and ownership. The mode is set explicitly after opening so it is not
+// affected by the umask.
+func createOutputFile(path string) (*os.File, error) {
+	mode, err := parseOutputMode(OutputMode)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if OutputOwner != "" {
+		uid, gid, err := parseOutputOwner(OutputOwner)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		if err := f.Chown(uid, gid); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("unable to set owner of %s, this usually requires running privileged: %w", path, err)
+		}
+	}
+
+	return f, nil
+}
+
+// The output file results are streamed into as they are formatted
+type outputStream struct {
+	file       *os.File
+	encryptor  io.WriteCloser
+	compressor io.WriteCloser
+	buffered   *bufio.Writer
+}
+
+// Opens FileOutput and points outputWriter at it so results are written as they are
+// formatted rather than held in memory, also printing them to stdout when teeing
+func openOutputStream() (*outputStream, error) {
+	f, err := createOutputFile(FileOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptor, err := encryptWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	compressor, err := compressWriter(encryptor)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	o := &outputStream{file: f, encryptor: encryptor, compressor: compressor, buffered: bufio.NewWriter(compressor)}
+	outputWriter = o.buffered
+	// Teeing writes the plain results to stdout
+	if TeeOutput {
+		outputWriter = io.MultiWriter(o.buffered, os.Stdout)
+	}
+	outputWriteErr = nil
+	return o, nil
+}
+
+// Writes whatever the formatter returned at the end then closes the file
+func (o *outputStream) close(rest string) error {
+	defer func() { outputWriter = nil }()
+
+	if _, err := io.WriteString(outputWriter, rest); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	if err := o.buffered.Flush(); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	if err := o.compressor.Close(); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	if err := o.encryptor.Close(); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	if err := o.file.Close(); err != nil && outputWriteErr == nil {
+		outputWriteErr = err
+	}
+	return outputWriteErr
+}
@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputMode(t *testing.T) {
+	mode, err := parseOutputMode("0644")
+	if err != nil || mode != 0644 {
+		t.Errorf("Expected 0644 got %o %v", mode, err)
+	}
+
+	if _, err := parseOutputMode("0999"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestParseOutputOwnerNumeric(t *testing.T) {
+	uid, gid, err := parseOutputOwner("1000")
+	if err != nil || uid != 1000 || gid != -1 {
+		t.Errorf("Expected 1000 -1 got %d %d %v", uid, gid, err)
+	}
+
+	uid, gid, err = parseOutputOwner(":50")
+	if err != nil || uid != -1 || gid != 50 {
+		t.Errorf("Expected -1 50 got %d %d %v", uid, gid, err)
+	}
+}
+
+func TestWalkDirectorySkipsOutputFiles(t *testing.T) {
+	previousOutput, previousRoot := FileOutput, PerRootOutput
+	defer func() {
+		FileOutput, PerRootOutput = previousOutput, previousRoot
+		outputPaths = map[string]bool{}
+	}()
+
+	dir := t.TempDir()
+	for _, f := range []string{"a", "out.txt", "a.txt", "manifests/root.txt"} {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, f)), 0755)
+		_ = os.WriteFile(filepath.Join(dir, f), []byte(f), 0600)
+	}
+	FileOutput = filepath.Join(dir, "out.txt")
+	PerRootOutput = filepath.Join(dir, "manifests")
+	excludeOutputs([]string{"/data/root"})
+
+	output := make(chan string, 10)
+	walkDirectory(context.Background(), dir, output, make(chan Result, 10))
+	close(output)
+
+	files := []string{}
+	for f := range output {
+		files = append(files, filepath.Base(f))
+	}
+	sort.Strings(files)
+	if strings.Join(files, ",") != "a,a.txt" {
+		t.Errorf("expected the output files to be skipped got %v", files)
+	}
+}
+
+func TestOutputStreamWritesAsResultsArrive(t *testing.T) {
+	previousOutput, previousMode, previousHash, previousFormat := FileOutput, OutputMode, Hash, Format
+	defer func() {
+		FileOutput, OutputMode, Hash, Format = previousOutput, previousMode, previousHash, previousFormat
+	}()
+
+	FileOutput = filepath.Join(t.TempDir(), "out.json")
+	Format = "json"
+	OutputMode = "0640"
+	Hash = []string{HashNames.MD5}
+
+	stream, err := openOutputStream()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	input := make(chan Result)
+	done := make(chan string)
+	go func() {
+		rest, _ := fileSummarize(input)
+		done <- rest
+	}()
+
+	input <- Result{File: "a", MD5: "5d41402abc4b2a76b9719d911017c592"}
+	input <- Result{File: "b", MD5: "7d793037a0760186574b0282f2f435e7"}
+	close(input)
+	rest := <-done
+
+	if rest != "]" {
+		t.Errorf("Expected only the closing bracket to be left over got %q", rest)
+	}
+	if err := stream.close(rest); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if outputWriter != nil {
+		t.Error("Expected output writer to be cleared")
+	}
+
+	content, _ := os.ReadFile(FileOutput)
+	results := []Result{}
+	if err := json.Unmarshal(content, &results); err != nil || len(results) != 2 {
+		t.Errorf("Expected valid json with 2 results got %s %v", content, err)
+	}
+
+	fi, _ := os.Stat(FileOutput)
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("Expected 0640 got %o", fi.Mode().Perm())
+	}
+}
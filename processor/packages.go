@@ -0,0 +1,221 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Package managers whose databases can be verified
+const (
+	PackagesAuto = "auto"
+	PackagesDpkg = "dpkg"
+	PackagesRpm  = "rpm"
+)
+
+// A file installed by a package with its packaged digest
+type packageFile struct {
+	Package string
+	Entry   manifestEntry
+}
+
+// PackageDrift is an installed file which no longer matches its package
+type PackageDrift struct {
+	Package string
+	File    string
+	Status  string
+}
+
+// PackageReport is the consolidated result of verifying installed packages
+type PackageReport struct {
+	Manager  string
+	Packages int
+	Files    int
+	Drift    []PackageDrift
+}
+
+// Digest names for the lengths package managers use
+func packageDigestNames(digest string) []string {
+	switch len(digest) {
+	case 32:
+		return []string{HashNames.MD5}
+	case 40:
+		return []string{HashNames.SHA1}
+	case 64:
+		return []string{HashNames.SHA256}
+	case 128:
+		return []string{HashNames.SHA512}
+	}
+	return nil
+}
+
+// Reads the md5sums dpkg keeps for each installed package. Conffiles are not listed
+// there as they are expected to change.
+func dpkgFiles(root string) ([]packageFile, error) {
+	sums, err := filepath.Glob(filepath.Join(root, "var", "lib", "dpkg", "info", "*.md5sums"))
+	if err != nil {
+		return nil, err
+	}
+	if len(sums) == 0 {
+		return nil, errors.New("no dpkg md5sums found")
+	}
+
+	files := []packageFile{}
+	for _, s := range sums {
+		pkg := strings.TrimSuffix(filepath.Base(s), ".md5sums")
+		pkg, _, _ = strings.Cut(pkg, ":")
+
+		data, err := os.ReadFile(s)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			digest, path, found := strings.Cut(scanner.Text(), "  ")
+			if !found {
+				continue
+			}
+			files = append(files, packageFile{
+				Package: pkg,
+				Entry: manifestEntry{
+					File:    filepath.Join(root, path),
+					Digests: []manifestDigest{{Names: packageDigestNames(digest), Digest: digest}},
+				},
+			})
+		}
+	}
+	return files, nil
+}
+
+// Query format listing every file of every package one per line as
+// package, digest, octal mode, flags and path separated by tabs
+const rpmQueryFormat = "[%{=NAME}\\t%{FILEDIGESTS}\\t%{FILEMODES:octal}\\t%{FILEFLAGS:fflags}\\t%{FILENAMES}\\n]"
+
+func parseRpmQuery(root string, output []byte) []packageFile {
+	files := []packageFile{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		pkg, digest, mode, flags, path := fields[0], fields[1], fields[2], fields[3], fields[4]
+		names := packageDigestNames(digest)
+		// Only regular files have content digests and config files are expected to change
+		if !strings.HasPrefix(mode, "100") || strings.Contains(flags, "c") || names == nil {
+			continue
+		}
+
+		files = append(files, packageFile{
+			Package: pkg,
+			Entry: manifestEntry{
+				File:    filepath.Join(root, path),
+				Digests: []manifestDigest{{Names: names, Digest: digest}},
+			},
+		})
+	}
+	return files
+}
+
+func rpmFiles(root string) ([]packageFile, error) {
+	output, err := exec.Command("rpm", "--root", root, "-qa", "--queryformat", rpmQueryFormat).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to query rpm: %w", err)
+	}
+	return parseRpmQuery(root, output), nil
+}
+
+// Picks the package manager present under root
+func detectPackageManager(root string) string {
+	if _, err := os.Stat(filepath.Join(root, "var", "lib", "dpkg", "info")); err == nil {
+		return PackagesDpkg
+	}
+	return PackagesRpm
+}
+
+// Verifies every file installed by the package manager under root against its packaged digest
+func verifyPackages(manager string, root string) (PackageReport, error) {
+	if manager == PackagesAuto {
+		manager = detectPackageManager(root)
+	}
+	report := PackageReport{Manager: manager, Drift: []PackageDrift{}}
+
+	var files []packageFile
+	var err error
+	switch manager {
+	case PackagesDpkg:
+		files, err = dpkgFiles(root)
+	case PackagesRpm:
+		files, err = rpmFiles(root)
+	default:
+		err = fmt.Errorf("unknown package manager %s expected auto, dpkg or rpm", manager)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	entries := []manifestEntry{}
+	packages := map[string]bool{}
+	for _, f := range files {
+		entries = append(entries, f.Entry)
+		packages[f.Package] = true
+	}
+	report.Packages = len(packages)
+	report.Files = len(entries)
+
+	status, err := checkManifest(entries)
+	if err != nil {
+		return report, err
+	}
+
+	for _, f := range files {
+		s := status[f.Entry.File]
+		if s == "OK" {
+			continue
+		}
+		if _, err := os.Lstat(f.Entry.File); errors.Is(err, os.ErrNotExist) {
+			s = "MISSING"
+		}
+		report.Drift = append(report.Drift, PackageDrift{Package: f.Package, File: f.Entry.File, Status: s})
+	}
+	sort.Slice(report.Drift, func(i, j int) bool {
+		if report.Drift[i].Package != report.Drift[j].Package {
+			return report.Drift[i].Package < report.Drift[j].Package
+		}
+		return report.Drift[i].File < report.Drift[j].File
+	})
+
+	return report, nil
+}
+
+// Verifies installed packages printing a drift report, returning the exit code
+func processPackages(manager string, root string) int {
+	report, err := verifyPackages(strings.ToLower(manager), root)
+	if err != nil {
+		printError(err.Error())
+		return 1
+	}
+
+	if strings.ToLower(Format) == "json" {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+	} else {
+		for _, d := range report.Drift {
+			fmt.Printf("%s: %s: %s\n", d.Package, d.File, d.Status)
+		}
+		fmt.Printf("checked %d files from %d %s packages, %d drifted\n", report.Files, report.Packages, report.Manager, len(report.Drift))
+	}
+
+	if len(report.Drift) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyPackagesDpkg(t *testing.T) {
+	root := t.TempDir()
+	info := filepath.Join(root, "var", "lib", "dpkg", "info")
+	_ = os.MkdirAll(info, 0700)
+	_ = os.MkdirAll(filepath.Join(root, "usr", "bin"), 0700)
+	_ = os.WriteFile(filepath.Join(root, "usr", "bin", "good"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(root, "usr", "bin", "bad"), []byte("changed"), 0600)
+	_ = os.WriteFile(filepath.Join(info, "tool:amd64.md5sums"), []byte(
+		"5d41402abc4b2a76b9719d911017c592  usr/bin/good\n"+
+			"5d41402abc4b2a76b9719d911017c592  usr/bin/bad\n"+
+			"5d41402abc4b2a76b9719d911017c592  usr/bin/gone\n"), 0600)
+
+	report, err := verifyPackages(PackagesAuto, root)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if report.Manager != PackagesDpkg || report.Packages != 1 || report.Files != 3 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if len(report.Drift) != 2 || report.Drift[0].Status != "FAILED" || report.Drift[1].Status != "MISSING" || report.Drift[0].Package != "tool" {
+		t.Errorf("Unexpected drift %+v", report.Drift)
+	}
+}
+
+func TestParseRpmQuery(t *testing.T) {
+	output := "bash\t2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\t100755\t\t/usr/bin/bash\n" +
+		"bash\t\t40755\t\t/usr/share/doc/bash\n" +
+		"bash\t5d41402abc4b2a76b9719d911017c592\t100644\tc\t/etc/bashrc\n" +
+		"bash\t5d41402abc4b2a76b9719d911017c592\t100644\td\t/usr/share/doc/bash/a file\n"
+
+	files := parseRpmQuery("/", []byte(output))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files got %+v", files)
+	}
+	if files[0].Entry.Digests[0].Names[0] != HashNames.SHA256 || files[1].Entry.File != "/usr/share/doc/bash/a file" {
+		t.Errorf("Unexpected files %+v", files)
+	}
+}
@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+)
+
+// Ends a manifest written because the run aborted so it is never mistaken for a complete
+// one. It goes on its own line after the results so it is easy to find and strip.
+const partialTrailer = "## hashit PARTIAL manifest"
+
+// The results a run has produced so far, kept so they can be written out if it aborts
+type partialState struct {
+	path    string
+	mutex   sync.Mutex
+	results []Result
+	once    sync.Once
+}
+
+// The state of the partial output, nil when disabled
+var partial *partialState
+
+// Only kept when asked for with --partial-output as holding every result for the whole
+// run undoes streaming output for scans of millions of files
+func preparePartial() {
+	partial = nil
+	if PartialOutput != "" {
+		partial = &partialState{path: PartialOutput}
+	}
+}
+
+// Keeps every result as it passes on its way to be formatted
+func keepPartial(input chan Result, p *partialState) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			p.mutex.Lock()
+			p.results = append(p.results, res)
+			p.mutex.Unlock()
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
+
+// Encodes the results so far as JSON followed by the trailer. The formatters are left
+// alone as the run may abort while they are still writing the output.
+func (p *partialState) render(reason string) (string, error) {
+	p.mutex.Lock()
+	results := append([]Result{}, p.results...)
+	p.mutex.Unlock()
+
+	out, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n" + fmt.Sprintf("%s: aborted after %d files: %s\n", partialTrailer, len(results), reason), nil
+}
+
+// Writes the results so far. When the disk the output was going to is full the partial
+// output would fail the same way, so it is tried again in the temporary directory.
+func (p *partialState) write(reason string) {
+	p.mutex.Lock()
+	empty := len(p.results) == 0
+	p.mutex.Unlock()
+	if empty {
+		return
+	}
+
+	content, err := p.render(reason)
+	if err != nil {
+		printError(fmt.Sprintf("unable to write partial output %s: %s", p.path, err.Error()))
+		return
+	}
+	path := p.path
+	if err = writeOutputTo(path, content); err != nil {
+		printError(fmt.Sprintf("unable to write partial output %s: %s", path, err.Error()))
+		path = filepath.Join(os.TempDir(), filepath.Base(p.path))
+		err = writeOutputTo(path, content)
+	}
+	if err != nil {
+		printError(fmt.Sprintf("unable to write partial output %s: %s", path, err.Error()))
+		return
+	}
+	printError(fmt.Sprintf("partial results written to %s", path))
+}
+
+// Reports the error that ended the run, saving whatever results it produced, then exits 1.
+// Only the first abort writes anything should several goroutines fail together.
+func abortRun(reason string) {
+	printError(reason)
+	if partial != nil {
+		partial.once.Do(func() { partial.write(reason) })
+	}
+	os.Exit(1)
+}
+
+// Deferred by workers so a panic hashing one file still leaves the results of the others.
+// Without a partial output to write the panic carries on as normal.
+func abortOnPanic() {
+	if e := recover(); e != nil {
+		if partial == nil {
+			panic(e)
+		}
+		abortRun(fmt.Sprintf("worker panicked: %v\n%s", e, debug.Stack()))
+	}
+}
+
+// Removes the trailer from a partial manifest so it can be read like any other, warning
+// that it does not list everything
+func stripPartialTrailer(data []byte) []byte {
+	i := bytes.LastIndex(data, []byte(partialTrailer))
+	if i < 0 || (i > 0 && data[i-1] != '\n') {
+		return data
+	}
+	logger.Warn(fmt.Sprintf("manifest is partial, it does not list every file: %s", bytes.TrimSpace(data[i:])))
+	return data[:i]
+}
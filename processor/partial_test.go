@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreparePartial(t *testing.T) {
+	previousOutput, previousPartial := FileOutput, PartialOutput
+	defer func() {
+		FileOutput, PartialOutput = previousOutput, previousPartial
+		partial = nil
+	}()
+
+	// Results are only held when asked for so streaming keeps memory bounded
+	FileOutput, PartialOutput = "sums.txt", ""
+	preparePartial()
+	if partial != nil {
+		t.Error("Expected no partial output unless asked for")
+	}
+
+	PartialOutput = "/mnt/other/sums.partial"
+	preparePartial()
+	if partial == nil || partial.path != "/mnt/other/sums.partial" {
+		t.Errorf("Expected the partial output to be kept got %+v", partial)
+	}
+}
+
+func TestPartialRoundTrip(t *testing.T) {
+	input := make(chan Result, 2)
+	input <- Result{File: "a", MD5: "0cc175b9c0f1b6a831c399e269772661"}
+	input <- Result{File: "b", MD5: "92eb5ffee6ae2fec3ad71c777531578f"}
+	close(input)
+
+	p := &partialState{}
+	for range keepPartial(input, p) {
+	}
+	if len(p.results) != 2 {
+		t.Fatalf("Expected both results kept got %d", len(p.results))
+	}
+
+	out, err := p.render("no space left on device")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if !strings.HasSuffix(out, "\n"+partialTrailer+": aborted after 2 files: no space left on device\n") {
+		t.Errorf("Expected the trailer at the end got %s", out)
+	}
+
+	entries, err := parseManifest([]byte(out))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(entries) != 2 || entries[1].File != "b" || entries[1].Digests[0].Digest != "92eb5ffee6ae2fec3ad71c777531578f" {
+		t.Errorf("Expected both files read back got %+v", entries)
+	}
+}
+
+func TestPartialWriteFallsBackToTemp(t *testing.T) {
+	temp := t.TempDir()
+	// os.TempDir reads TMPDIR everywhere other than Windows which uses TMP then TEMP
+	for _, env := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(env, temp)
+	}
+
+	p := &partialState{path: filepath.Join(t.TempDir(), "missing", "sums.txt.partial")}
+	p.write("ignored as there is nothing to save")
+	if _, err := os.Stat(filepath.Join(temp, "sums.txt.partial")); err == nil {
+		t.Error("Expected nothing written without results")
+	}
+
+	p.results = []Result{{File: "a", MD5: "0cc175b9c0f1b6a831c399e269772661"}}
+	p.write("disk full")
+	data, err := os.ReadFile(filepath.Join(temp, "sums.txt.partial"))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if !strings.Contains(string(data), `"File":"a"`) || !strings.Contains(string(data), "disk full") {
+		t.Errorf("Unexpected partial output %s", data)
+	}
+}
+
+func TestStripPartialTrailer(t *testing.T) {
+	complete := []byte("0cc175b9c0f1b6a831c399e269772661  a\n")
+	if got := stripPartialTrailer(complete); string(got) != string(complete) {
+		t.Errorf("Expected a complete manifest unchanged got %s", got)
+	}
+
+	// Only a trailer on its own line counts
+	named := []byte("0cc175b9c0f1b6a831c399e269772661  x" + partialTrailer + "\n")
+	if got := stripPartialTrailer(named); string(got) != string(named) {
+		t.Errorf("Expected a file named like the trailer unchanged got %s", got)
+	}
+
+	partialData := append(append([]byte{}, complete...), []byte(partialTrailer+": aborted after 1 files: disk full\n")...)
+	if got := stripPartialTrailer(partialData); string(got) != string(complete) {
+		t.Errorf("Expected the trailer removed got %s", got)
+	}
+}
+
+func TestAbortOnPanicWithoutPartial(t *testing.T) {
+	previous := partial
+	partial = nil
+	defer func() { partial = previous }()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected the panic to carry on without a partial output")
+		}
+	}()
+	func() {
+		defer abortOnPanic()
+		panic("boom")
+	}()
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package processor
+
+import "io/fs"
+
+func longPath(path string) string {
+	return path
+}
+
+func isReparsePoint(entry fs.DirEntry) bool {
+	return false
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	short := `C:\short`
+	if got := longPath(short); got != short {
+		t.Errorf("Expected short path unchanged got %s", got)
+	}
+
+	long := `C:\` + strings.Repeat(`a\`, 150) + "file"
+	if got := longPath(long); got != `\\?\`+long {
+		t.Errorf("Expected prefixed path got %s", got)
+	}
+
+	unc := `\\server\share\` + strings.Repeat(`a\`, 150) + "file"
+	if got := longPath(unc); got != `\\?\UNC\`+unc[2:] {
+		t.Errorf("Expected UNC prefixed path got %s", got)
+	}
+}
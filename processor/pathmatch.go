@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Folds a path so names which differ only in Unicode form or case compare equal, as
+// macOS writes decomposed names and Windows and macOS ignore case by default
+func comparablePath(path string) string {
+	if IgnorePathCase {
+		path = cases.Fold().String(path)
+	}
+	if NormalizePaths || IgnorePathCase {
+		path = norm.NFC.String(path)
+	}
+	return path
+}
+
+// Finds the file on disk a manifest path refers to when they differ in Unicode form or
+// case, listing each directory at most once
+type pathResolver struct {
+	listings map[string]map[string][]string
+}
+
+func newPathResolver() *pathResolver {
+	return &pathResolver{listings: map[string]map[string][]string{}}
+}
+
+// Names in the directory keyed by their comparable form
+func (p *pathResolver) listing(dir string) map[string][]string {
+	if names, ok := p.listings[dir]; ok {
+		return names
+	}
+
+	names := map[string][]string{}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			key := comparablePath(e.Name())
+			names[key] = append(names[key], e.Name())
+		}
+	}
+	p.listings[dir] = names
+	return names
+}
+
+// Returns the path as it exists on disk, or unchanged when it exists as written, the
+// options are off or nothing matches so opening it reports it missing. Several names
+// matching such as README and readme on a case sensitive filesystem is an error.
+func (p *pathResolver) resolve(path string) (string, error) {
+	if !NormalizePaths && !IgnorePathCase {
+		return path, nil
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return path, nil
+	}
+
+	clean := filepath.Clean(path)
+	volume := filepath.VolumeName(clean)
+	rest := clean[len(volume):]
+	current := volume
+	if strings.HasPrefix(rest, string(filepath.Separator)) {
+		current += string(filepath.Separator)
+	}
+
+	for _, part := range strings.Split(rest, string(filepath.Separator)) {
+		if part == "" {
+			continue
+		}
+		next := filepath.Join(current, part)
+		if _, err := os.Lstat(next); err == nil {
+			current = next
+			continue
+		}
+
+		dir := current
+		if dir == "" {
+			dir = "."
+		}
+		matches := p.listing(dir)[comparablePath(part)]
+		switch len(matches) {
+		case 0:
+			return path, nil
+		case 1:
+			current = filepath.Join(current, matches[0])
+		default:
+			return path, fmt.Errorf("%s matches %s", path, strings.Join(matches, " and "))
+		}
+	}
+	return current, nil
+}
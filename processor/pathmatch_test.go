@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// café written composed and decomposed as macOS does
+const (
+	composedName   = "café.txt"
+	decomposedName = "café.txt"
+)
+
+func TestComparablePath(t *testing.T) {
+	defer func() { NormalizePaths, IgnorePathCase = false, false }()
+
+	if comparablePath(composedName) == comparablePath(decomposedName) {
+		t.Error("expected the forms to differ with normalisation off")
+	}
+
+	NormalizePaths = true
+	if comparablePath(composedName) != comparablePath(decomposedName) {
+		t.Error("expected the forms to match with normalisation on")
+	}
+	if comparablePath("README") == comparablePath("readme") {
+		t.Error("expected case to matter without --ignore-path-case")
+	}
+
+	NormalizePaths, IgnorePathCase = false, true
+	if comparablePath("Docs/README") != comparablePath("docs/readme") {
+		t.Error("expected case to be ignored")
+	}
+	if comparablePath("CAFÉ.TXT") != comparablePath(decomposedName) {
+		t.Error("expected ignoring case to also normalise")
+	}
+}
+
+func TestPathResolverUnicode(t *testing.T) {
+	defer func() { NormalizePaths = false }()
+
+	dir := t.TempDir()
+	_ = os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	onDisk := filepath.Join(dir, "sub", composedName)
+	_ = os.WriteFile(onDisk, []byte("hello"), 0600)
+	listed := filepath.Join(dir, "sub", decomposedName)
+	if _, err := os.Stat(listed); err == nil {
+		t.Skip("filesystem already normalises names")
+	}
+
+	if got, _ := newPathResolver().resolve(listed); got != listed {
+		t.Errorf("expected the path unchanged with normalisation off got %s", got)
+	}
+
+	NormalizePaths = true
+	got, err := newPathResolver().resolve(listed)
+	if err != nil || got != onDisk {
+		t.Errorf("expected %s got %s %v", onDisk, got, err)
+	}
+
+	missing := filepath.Join(dir, "missing", decomposedName)
+	if got, _ := newPathResolver().resolve(missing); got != missing {
+		t.Errorf("expected a missing path unchanged got %s", got)
+	}
+}
+
+func TestPathResolverCase(t *testing.T) {
+	defer func() { IgnorePathCase = false }()
+	IgnorePathCase = true
+
+	dir := t.TempDir()
+	_ = os.Mkdir(filepath.Join(dir, "Docs"), 0755)
+	onDisk := filepath.Join(dir, "Docs", "README.md")
+	_ = os.WriteFile(onDisk, []byte("hello"), 0600)
+
+	got, err := newPathResolver().resolve(filepath.Join(dir, "docs", "readme.md"))
+	if err != nil || !strings.EqualFold(got, onDisk) {
+		t.Errorf("expected %s got %s %v", onDisk, got, err)
+	}
+
+	// Two names only differing in case cannot both exist on a case insensitive filesystem
+	_ = os.WriteFile(filepath.Join(dir, "Docs", "readme.MD"), []byte("other"), 0600)
+	entries, _ := os.ReadDir(filepath.Join(dir, "Docs"))
+	if len(entries) != 2 {
+		t.Skip("filesystem ignores case")
+	}
+	if _, err := newPathResolver().resolve(filepath.Join(dir, "docs", "Readme.md")); err == nil {
+		t.Error("expected several matching names to be an error")
+	}
+}
+
+func TestCheckManifestNormalizedPaths(t *testing.T) {
+	defer func() { NormalizePaths = false }()
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, composedName), []byte("hello"), 0600)
+	listed := filepath.Join(dir, decomposedName)
+	if _, err := os.Stat(listed); err == nil {
+		t.Skip("filesystem already normalises names")
+	}
+
+	entries := []manifestEntry{{File: listed, Digests: []manifestDigest{{Names: []string{"md5"}, Digest: "5d41402abc4b2a76b9719d911017c592"}}}}
+
+	status, _ := checkManifest(entries)
+	if status[listed] == "OK" {
+		t.Error("expected the decomposed name not to be found without --normalize-paths")
+	}
+
+	NormalizePaths = true
+	status, _ = checkManifest(entries)
+	if status[listed] != "OK" {
+		t.Errorf("expected OK got %s", status[listed])
+	}
+}
+
+func TestAnnotateChangesIgnorePathCase(t *testing.T) {
+	defer func() { IgnorePathCase = false }()
+	IgnorePathCase = true
+
+	baseline := map[string]baselineEntry{"Docs/README.md": {Bytes: 5}}
+	input := make(chan Result, 1)
+	input <- Result{File: "docs/readme.md", Bytes: 5}
+	close(input)
+
+	res := <-annotateChanges(input, baseline, time.Now())
+	if res.Change != "unchanged" {
+		t.Errorf("expected unchanged got %s", res.Change)
+	}
+}
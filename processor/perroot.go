@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A root supplied on the command line and the manifest its results are written to
+type rootOutput struct {
+	root    string
+	file    string
+	results []Result
+}
+
+// Check --per-root-output is only used where the results can be traced back to a root
+func validatePerRootOutput() error {
+	if PerRootOutput == "" {
+		return nil
+	}
+	switch {
+	case FileOutput != "":
+		return errors.New("--per-root-output cannot be combined with --output")
+	case StandardInput, FileInput != "", Partition >= 0:
+		return errors.New("--per-root-output needs files or directories to be supplied as arguments")
+	}
+	return nil
+}
+
+// File extension used for each format when writing one manifest per root
+func formatExtension(format string) string {
+	switch strings.ToLower(format) {
+	case "json", "sum", "hashdeep", "hashit", "html":
+		return strings.ToLower(format)
+	case "sri-json":
+		return "json"
+	}
+	return "txt"
+}
+
+// Names each root's manifest after the last element of the root, numbering any which
+// would otherwise collide such as two roots both called data
+func newRootOutputs(roots []string, dir string) []*rootOutput {
+	outputs := []*rootOutput{}
+	used := map[string]bool{}
+	for _, r := range roots {
+		root := filepath.Clean(r)
+
+		name := root
+		if abs, err := filepath.Abs(root); err == nil {
+			name = abs
+		}
+		name = strings.Map(func(c rune) rune {
+			if c == '/' || c == '\\' || c == ':' {
+				return '_'
+			}
+			return c
+		}, filepath.Base(name))
+		if strings.Trim(name, "_.") == "" {
+			name = "root"
+		}
+
+		file := name
+		for i := 2; used[file]; i++ {
+			file = fmt.Sprintf("%s-%d", name, i)
+		}
+		used[file] = true
+
+		outputs = append(outputs, &rootOutput{
+			root: root,
+			file: filepath.Join(dir, file+"."+formatExtension(Format)),
+		})
+	}
+	return outputs
+}
+
+// Finds the root a result was found under preferring the most specific when roots are
+// nested. Relative paths fall under a root of . when nothing else claims them.
+func matchRoot(outputs []*rootOutput, file string) *rootOutput {
+	var match *rootOutput
+	for _, o := range outputs {
+		inside := file == o.root || strings.HasPrefix(file, strings.TrimSuffix(o.root, string(filepath.Separator))+string(filepath.Separator))
+		if o.root == "." {
+			inside = !filepath.IsAbs(file) && file != ".." && !strings.HasPrefix(file, ".."+string(filepath.Separator))
+		}
+		if inside && (match == nil || match.root == "." || len(o.root) > len(match.root)) {
+			match = o
+		}
+	}
+	if match == nil {
+		match = outputs[0]
+	}
+	return match
+}
+
+// Splits the results by the root they were found under then writes each root's
+// manifest into PerRootOutput in the selected format
+func writeRootOutputs(input chan Result, roots []string) bool {
+	if err := os.MkdirAll(PerRootOutput, 0755); err != nil {
+		printError(fmt.Sprintf("unable to create output directory %s: %s", PerRootOutput, err.Error()))
+		os.Exit(1)
+	}
+
+	outputs := newRootOutputs(roots, PerRootOutput)
+	for res := range input {
+		o := matchRoot(outputs, res.File)
+		o.results = append(o.results, res)
+	}
+
+	valid := true
+	for _, o := range outputs {
+		queue := make(chan Result, len(o.results))
+		for _, res := range o.results {
+			queue <- res
+		}
+		close(queue)
+
+		result, ok := fileSummarize(queue)
+		valid = valid && ok
+
+		embedSignature := SignKey != "" && strings.ToLower(Format) == "hashit"
+		if embedSignature {
+			signed, err := signContainer([]byte(result), SignKey)
+			if err != nil {
+				printError(fmt.Sprintf("unable to sign output: %s", err.Error()))
+				os.Exit(1)
+			}
+			result = string(signed)
+		}
+
+		if err := writeOutputTo(o.file, result); err != nil {
+			printError(fmt.Sprintf("unable to write output file %s: %s", o.file, err.Error()))
+			os.Exit(1)
+		}
+
+		if SignKey != "" && !embedSignature {
+			if err := signFile(o.file, SignKey); err != nil {
+				printError(fmt.Sprintf("unable to sign output file %s: %s", o.file, err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("results for %s written to %s\n", o.root, o.file)
+	}
+	return valid
+}
@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRootOutputs(t *testing.T) {
+	previous := Format
+	defer func() { Format = previous }()
+	Format = "hashdeep"
+
+	outputs := newRootOutputs([]string{"/mnt/a/data", "/mnt/b/data/", "/", "logs"}, "out")
+	expected := []string{"data.hashdeep", "data-2.hashdeep", "root.hashdeep", "logs.hashdeep"}
+	for i, o := range outputs {
+		if o.file != filepath.Join("out", expected[i]) {
+			t.Errorf("expected %s got %s", expected[i], o.file)
+		}
+	}
+}
+
+func TestMatchRoot(t *testing.T) {
+	outputs := newRootOutputs([]string{".", "/mnt/data", "/mnt/data/nested", "/mnt/database"}, "out")
+
+	cases := map[string]string{
+		"README.md":                    ".",
+		"processor/file.go":            ".",
+		"/mnt/data/a.txt":              "/mnt/data",
+		"/mnt/data":                    "/mnt/data",
+		"/mnt/data/nested/b.txt":       "/mnt/data/nested",
+		"/mnt/database/c.txt":          "/mnt/database",
+		"/elsewhere/unknown":           ".",
+		filepath.Join("..", "sibling"): ".",
+	}
+	for file, root := range cases {
+		if o := matchRoot(outputs, filepath.FromSlash(file)); o.root != filepath.FromSlash(root) {
+			t.Errorf("%s expected root %s got %s", file, root, o.root)
+		}
+	}
+}
+
+func TestFormatExtension(t *testing.T) {
+	cases := map[string]string{"text": "txt", "JSON": "json", "sum": "sum", "hashonly": "txt", "hashit": "hashit"}
+	for format, ext := range cases {
+		if res := formatExtension(format); res != ext {
+			t.Errorf("%s expected %s got %s", format, ext, res)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Minimal XML property list decoder. Dictionaries become map[string]interface{}, arrays
+// []interface{}, data []byte, integers int64, reals float64 and booleans bool.
+func plistDecode(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return nil, errors.New("binary property lists are not supported")
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return plistValue(decoder, start)
+		}
+	}
+}
+
+func plistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if key, err = plistText(decoder); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := plistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				v, err := plistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, v)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := plistText(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string", "date":
+		return text, nil
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	}
+	return nil, fmt.Errorf("unsupported plist element %s", start.Name.Local)
+}
+
+// Reads character data up to the end of the current element
+func plistText(decoder *xml.Decoder) (string, error) {
+	var str strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			str.Write(t)
+		case xml.EndElement:
+			return str.String(), nil
+		}
+	}
+}
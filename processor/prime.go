@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"fmt"
+)
+
+// Sits between the queue and the workers asking the kernel to read each file into the
+// page cache before a worker picks it up, so on rotational disks and network storage the
+// reads for the next files are in flight while the current ones are hashed. The output
+// holds PrimeDepth paths which is how far ahead of the workers priming runs.
+func primeQueue(input chan string) chan string {
+	if !PrimeCache {
+		return input
+	}
+
+	output := make(chan string, PrimeDepth)
+	go func() {
+		for path := range input {
+			if err := primeFile(path); err != nil && Debug {
+				printDebug("unable to prime cache", "file", path, "error", err.Error())
+			}
+			output <- path
+		}
+		close(output)
+	}()
+	return output
+}
+
+func validatePrimeCache() error {
+	if PrimeCache && PrimeDepth < 1 {
+		return fmt.Errorf("invalid prime depth %d expected at least 1", PrimeDepth)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build linux || freebsd
+
+package processor
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Starts readahead of the whole file returning without waiting for it to finish
+func primeFile(path string) error {
+	file, err := openReadOnly(longPath(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_WILLNEED)
+}
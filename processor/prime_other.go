@@ -0,0 +1,18 @@
+//go:build !(linux || freebsd)
+
+package processor
+
+import (
+	"io"
+)
+
+// Without fadvise the file is read through once so it is cached when the worker reads it
+func primeFile(path string) error {
+	file, err := openReadOnly(longPath(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(io.Discard, file)
+	return err
+}
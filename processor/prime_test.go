@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrimeQueuePassesThroughInOrder(t *testing.T) {
+	previousPrime, previousDepth := PrimeCache, PrimeDepth
+	defer func() { PrimeCache, PrimeDepth = previousPrime, previousDepth }()
+	PrimeCache, PrimeDepth = true, 2
+
+	dir := t.TempDir()
+	paths := []string{}
+	for _, name := range []string{"a", "b", "c"} {
+		p := filepath.Join(dir, name)
+		_ = os.WriteFile(p, []byte(name), 0600)
+		paths = append(paths, p)
+	}
+	// Files which cannot be primed still reach the workers to be reported
+	paths = append(paths, filepath.Join(dir, "missing"))
+
+	input := make(chan string, len(paths))
+	for _, p := range paths {
+		input <- p
+	}
+	close(input)
+
+	got := []string{}
+	for p := range primeQueue(input) {
+		got = append(got, p)
+	}
+	if len(got) != len(paths) {
+		t.Fatalf("Expected %d paths got %v", len(paths), got)
+	}
+	for i := range paths {
+		if got[i] != paths[i] {
+			t.Errorf("Expected %s at %d got %s", paths[i], i, got[i])
+		}
+	}
+}
+
+func TestPrimeFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(p, []byte("content"), 0600)
+	if err := primeFile(p); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+	if err := primeFile(p + ".missing"); err == nil {
+		t.Error("Expected error priming a missing file")
+	}
+}
+
+func TestValidatePrimeCache(t *testing.T) {
+	previousPrime, previousDepth := PrimeCache, PrimeDepth
+	defer func() { PrimeCache, PrimeDepth = previousPrime, previousDepth }()
+
+	PrimeCache, PrimeDepth = true, 0
+	if validatePrimeCache() == nil {
+		t.Error("Expected error for a prime depth of 0")
+	}
+	PrimeCache = false
+	if validatePrimeCache() != nil {
+		t.Error("Expected the depth to be ignored without --prime-cache")
+	}
+}
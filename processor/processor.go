@@ -2,13 +2,14 @@ package processor
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gosuri/uiprogress"
 )
@@ -28,6 +29,13 @@ var Trace = false
 // MTime enable mtime calculation and output
 var MTime = false
 
+// Provenance adds when the scan started, the host, version and command to json and hashdeep
+// output along with when each file was hashed
+var Provenance = false
+
+// DetectType sniffs the start of each file and records its MIME type and format
+var DetectType = false
+
 // Progress uses ui bar to display the progress of files
 var Progress = false
 
@@ -40,27 +48,89 @@ var NoStream = false
 // If data is being piped in using stdin
 var StandardInput = false
 
+// ForceStdin hashes stdin without checking whether anything is piped to it
+var ForceStdin = false
+
+// NoStdin never hashes stdin, scanning the current directory when nothing else is supplied
+var NoStdin = false
+
 // Should the application print all hashes it knows about
 var Hashes = false
 
 // List of hashes that we want to process
 var Hash = []string{}
 
+// SelfVerify is a digest or manifest the running executable must match before anything is hashed
+var SelfVerify = ""
+
 // Format sets the output format of the formatter
 var Format = ""
 
+// Encoding renders digests as hex, HEX for uppercase hex, base64 or base32
+var Encoding = "hex"
+
+// ExecPerFile is a command run for every result with each argument rendered as a template like Template
+var ExecPerFile = ""
+
+// ExecOnMismatch is a command run for every file failing --check, --verify-xattr or changed since --baseline
+var ExecOnMismatch = ""
+
+// ExecConcurrency is how many ExecPerFile and ExecOnMismatch commands run at once
+var ExecConcurrency = 4
+
+// ExecTimeout is how long a hook command may run before it is killed and counted as failed
+var ExecTimeout = ""
+
+// Template is the text/template each result is rendered with when Format is template
+var Template = ""
+
 // FileOutput sets the file that output should be written to
 var FileOutput = ""
 
+// PartialOutput is where the results produced so far are written as JSON should the run
+// abort, empty to not keep them
+var PartialOutput = ""
+
+// Label is a list of key=value pairs attached to every record in structured output
+var Label = []string{}
+
+// Parsed version of Label
+var labels = map[string]string{}
+
+// TeeOutput streams results to stdout as well as writing them to FileOutput
+var TeeOutput = false
+
+// OutputMode is the octal permissions applied to the output file
+var OutputMode = "0600"
+
+// OutputOwner is the user[:group] the output file is changed to, usually requires running privileged
+var OutputOwner = ""
+
 // AuditFile sets the file that we want to audit against similar to hashdeep
 var AuditFile = ""
 
 // DirFilePaths is not set via flags but by arguments following the flags for file or directory to process
 var DirFilePaths = []string{}
 
+// Append adds the results of this run to the existing FileOutput manifest, replacing
+// any records for the same paths, rather than overwriting it
+var Append = false
+
+// PerRootOutput is a directory to write one manifest per supplied root into rather than a single output
+var PerRootOutput = ""
+
 // FileListQueueSize is the queue of files found and ready to be processed
 var FileListQueueSize = 1000
 
+// QueueMemory caps the memory used by paths queued ahead of the workers as the queue grows, 0 keeps it fixed
+var QueueMemory = "64M"
+
+// PrimeCache reads files into the page cache ahead of the workers, helping rotational and network storage
+var PrimeCache = false
+
+// PrimeDepth is how many files ahead of the workers PrimeCache reads
+var PrimeDepth = 8
+
 // Number of bytes in a size to enable memory maps or streaming
 var StreamSize int64 = 1_000_000
 
@@ -70,40 +140,350 @@ var FileAudit = false
 // FileInput indicates we have a file passed in which consists of a
 var FileInput = ""
 
+// FromManifest hashes exactly the paths listed in this manifest rather than walking
+var FromManifest = ""
+
 var NoThreads = runtime.NumCPU()
 
+// Blake3ParallelSize is the file size in bytes from which BLAKE3 splits a single file across --threads workers, 0 disables
+var Blake3ParallelSize int64 = 128_000_000
+
+// Blake3SegmentSize is the size of each piece of a file read and hashed by a core when BLAKE3 runs in parallel
+var Blake3SegmentSize int64 = 8_388_608
+
+// AzureBlockSize enables calculation of Azure block blob per block MD5s using blocks of this many bytes, 0 disables
+var AzureBlockSize int64 = 0
+
+// DetailThreshold hashes files over this size such as 1G by sampling them rather than in
+// full, empty hashes every file in full
+var DetailThreshold = ""
+
+// DetailSamples is how many regions of a file over DetailThreshold are hashed
+var DetailSamples = 16
+
+// ZstdFrames digests every frame of .zst files in the seekable format so corruption can be
+// narrowed down to a frame
+var ZstdFrames = false
+
+// PieceLength enables per file piece hashes using pieces of this many bytes, 0 disables
+var PieceLength int64 = 0
+
+// PieceHash is the algorithm used for piece hashes, sha1 or sha256
+var PieceHash = "sha1"
+
+// TorrentFile is a .torrent metainfo file to verify the supplied directory against
+var TorrentFile = ""
+
+// OnResult is called by Run with every result including error records
+var OnResult func(Result)
+
+// OnProgress is called by Run after each file is processed
+var OnProgress func(ProgressEvent)
+
+// NoLinkDedupe hashes every hard linked path rather than reusing the result of the first path to the inode
+var NoLinkDedupe = false
+
+// StoreXattr writes calculated hashes into extended attributes, or alternate data streams on Windows
+var StoreXattr = false
+
+// VerifyXattr compares calculated hashes against those previously written by StoreXattr
+var VerifyXattr = false
+
+// Compress writes the output file compressed with gzip or zstd, manifests read back by
+// --check and the other commands taking manifests are decompressed automatically
+var Compress = ""
+
+// Encrypt is age:RECIPIENT recipients the output file is encrypted to, each one can decrypt it
+var Encrypt = []string{}
+
+// SignKey is a minisign secret key used to write a detached signature next to the output file
+var SignKey = ""
+
+// Check is a manifest in the hashit, json or sum format to verify files against
+var Check = ""
+
+// CheckRoot rewrites Check manifest paths onto a replica as old=path,new=path, each one
+// is a replica the manifest is verified against
+var CheckRoot = []string{}
+
+// ObjectVersion selects which versions of objects in s3:// and gs:// CheckRoot replicas
+// are audited, latest, all or a specific version id
+var ObjectVersion = "latest"
+
+// DeleteMarkers says whether an object hidden by a delete marker is missing or was
+// deleted intentionally and so not a failure
+var DeleteMarkers = "missing"
+
+// Proxy is the url of the proxy network requests go through, direct for none. Empty uses
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment.
+var Proxy = ""
+
+// CACert is PEM bundles of certificate authorities trusted alongside the system ones
+var CACert = []string{}
+
+// ClientCert and ClientKey are a PEM certificate and key presented to servers which ask for one
+var ClientCert = ""
+var ClientKey = ""
+
+// ConnectTimeout limits connecting and the TLS handshake, ResponseTimeout how long to wait
+// for a server to start responding, empty for no limit
+var ConnectTimeout = "30s"
+var ResponseTimeout = ""
+
+// MaxConnsPerHost limits the connections open to each host, 0 for no limit
+var MaxConnsPerHost = 0
+
+// MaxIdleConns is how many connections are kept open to be reused
+var MaxIdleConns = 100
+
+// IPv4 and IPv6 only connect to addresses of that family
+var IPv4 = false
+var IPv6 = false
+
+// VerifyKey is a minisign public key the Check manifest signature must verify with before it is trusted
+var VerifyKey = ""
+
+// AnonymizePaths replaces paths in the output with a keyed digest so manifests can be
+// shared without revealing file names, hmac is the only method
+var AnonymizePaths = ""
+
+// NormalizePaths compares paths from a manifest or baseline in Unicode NFC form so names
+// written decomposed on macOS match those on other systems
+var NormalizePaths = false
+
+// IgnorePathCase compares paths from a manifest or baseline without regard to case
+var IgnorePathCase = false
+
+// CodeSign checks macOS binaries and application bundles against their code signature seal
+var CodeSign = false
+
+// VerifyPackages checks installed files against the dpkg or rpm database, auto picks whichever is present
+var VerifyPackages = ""
+
+// VSS scans a volume shadow copy of each path's volume so locked files can be read, Windows only
+var VSS = false
+
+// Diff compares the two supplied directories reporting files only in one and content differences
+var Diff = false
+
+// NoContent records paths, sizes and mtimes with a digest of the structure without reading any file contents
+var NoContent = false
+
+// ListOnly walks and filters as normal but prints the files and total bytes which would be hashed instead of hashing them
+var ListOnly = false
+
+// NoSparse disables detection of holes in sparse files which are otherwise hashed as zeros without being read
+var NoSparse = false
+
+// CacheSize is the number of results kept in memory so unchanged inputs seen again are not rehashed, 0 disables
+var CacheSize = 0
+
+// Exclude is a list of regular expressions, files or directories matching any of them are skipped
+var Exclude = []string{}
+
+// MaxDepth limits how many directories deep the walker goes with 1 being only the files
+// directly inside each root, 0 means no limit
+var MaxDepth = 0
+
+// OneFileSystem stops the walker descending into directories on other filesystems
+var OneFileSystem = false
+
+// IncludeVirtualFS walks into virtual filesystems such as /proc and /sys which are skipped by default
+var IncludeVirtualFS = false
+
+// Prune lists directory names, which may be glob patterns, that the walker never enters
+var Prune = []string{}
+
+// MinSize skips walked files smaller than this size such as 1M, empty disables
+var MinSize = ""
+
+// MaxSize skips walked files larger than this size such as 1G, empty disables
+var MaxSize = ""
+
+// MaxFileSize refuses to read any file larger than this size such as 10G, including files
+// named directly, writing a skipped record instead. Empty disables
+var MaxFileSize = ""
+
+// TimeoutPerFile gives up on a file which takes longer than this duration such as 5m to
+// hash, empty disables
+var TimeoutPerFile = ""
+
+// ScanID identifies the run in every record, header, database row and json log line,
+// a random UUID is used when empty
+var ScanID = ""
+
+// MaxOpenFiles caps how many files are open at once, by default just under the process
+// limit where the platform has one. 0 uses the default
+var MaxOpenFiles = 0
+
+// NoAtime opens files with O_NOATIME on Linux so hashing does not update access times
+var NoAtime = false
+
+// ShareMode is what other applications may do to a file while it is open on Windows,
+// read, write or delete which also allows renaming it
+var ShareMode = "write"
+
+// Locked is what happens to files another process holds an exclusive advisory lock on,
+// ignore, skip or wait for the lock to be released
+var Locked = "ignore"
+
+// NewerThan only hashes walked files modified after this duration ago or date
+var NewerThan = ""
+
+// OlderThan only hashes walked files modified before this duration ago or date
+var OlderThan = ""
+
+// FileType limits walked files to regular files with f or symlinks with l, empty allows both
+var FileType = ""
+
+// LowMemory shrinks queues and buffers and disables caching so hashit fits on small devices
+var LowMemory = false
+
+// Stats reports bytes hashed, wall time, per algorithm time and per worker throughput after a run
+var Stats = false
+
+// DirCache records a signature of every walked directory in this state file and reuses the results of directories unchanged since
+var DirCache = ""
+
+// Resume records completed results in this state file and skips them when the scan is run again
+var Resume = ""
+
+// Baseline is a manifest from a previous run, each result is marked new, unchanged or changed since it
+var Baseline = ""
+
+// Known lists manifests of known files which results are matched against like hashdeep -k
+var Known = []string{}
+
+// Match only outputs files whose hashes appear in the known files like hashdeep -m
+var Match = false
+
+// MatchNegative only outputs files whose hashes do not appear in the known files like hashdeep -x
+var MatchNegative = false
+
+// Order picks the next file for a worker from those found so far, size or smallest-first,
+// largest-first, mtime for newest first or name
+var Order = ""
+
+// LimitRate caps the total read throughput across all workers such as 50M for 50 MiB per second
+var LimitRate = ""
+
+// Partition treats the argument as a raw disk image and hashes the files in this partition, 0 for an image without a partition table and -1 to disable
+var Partition = -1
+
+// LogFormat is text for readable log lines or json for one object per line
+var LogFormat = "text"
+
+// LogFile appends log output to this file instead of stderr
+var LogFile = ""
+
+// Shard such as 3/8 hashes only the files whose path hashes into that shard so hosts can split a tree
+var Shard = ""
+
+// TextNormalize converts line endings to lf or crlf before hashing so text files compare logically
+var TextNormalize = ""
+
+// KeepOriginal also records the hashes of the original bytes when TextNormalize is set
+var KeepOriginal = false
+
+// ExitCodeFileError is returned when the run completed but one or more files could not be processed
+const ExitCodeFileError = 2
+
+// Count of files which produced an error record rather than a result
+var fileErrorCount int64
+
 // String mapping for hash names
 var HashNames = Result{
-	CRC32:      "crc32",
-	XxHash64:   "xxhash64",
-	MD4:        "md4",
-	MD5:        "md5",
-	SHA1:       "sha1",
-	SHA256:     "sha256",
-	SHA512:     "sha512",
-	Blake2b256: "blake2b256",
-	Blake2b512: "blake2b512",
-	Blake3:     "blake3",
-	Sha3224:    "sha3224",
-	Sha3256:    "sha3256",
-	Sha3384:    "sha3384",
-	Sha3512:    "sha3512",
+	CRC32:       "crc32",
+	XxHash64:    "xxhash64",
+	MD4:         "md4",
+	MD5:         "md5",
+	SHA1:        "sha1",
+	SHA256:      "sha256",
+	SHA512:      "sha512",
+	Blake2b256:  "blake2b256",
+	Blake2b512:  "blake2b512",
+	Blake3:      "blake3",
+	Sha3224:     "sha3224",
+	Sha3256:     "sha3256",
+	Sha3384:     "sha3384",
+	Sha3512:     "sha3512",
+	Sha224:      "sha224",
+	Sha384:      "sha384",
+	Sha512256:   "sha512256",
+	Ripemd160:   "ripemd160",
+	Whirlpool:   "whirlpool",
+	SM3:         "sm3",
+	Streebog256: "streebog256",
+	Streebog512: "streebog512",
+	Entropy:     "entropy",
+	Ssdeep:      "ssdeep",
+	TLSH:        "tlsh",
+}
+
+// HashInfos describes every supported hash in the order they are output
+var HashInfos = []HashInfo{
+	{Name: HashNames.CRC32, Display: "CRC32", Bits: 32, Cryptographic: false},
+	{Name: HashNames.XxHash64, Display: "xxHash64", Bits: 64, Cryptographic: false},
+	{Name: HashNames.MD4, Display: "MD4", Bits: 128, Cryptographic: true},
+	{Name: HashNames.MD5, Display: "MD5", Bits: 128, Cryptographic: true},
+	{Name: HashNames.SHA1, Display: "SHA1", Bits: 160, Cryptographic: true},
+	{Name: HashNames.SHA256, Display: "SHA256", Bits: 256, Cryptographic: true},
+	{Name: HashNames.SHA512, Display: "SHA512", Bits: 512, Cryptographic: true},
+	{Name: HashNames.Blake2b256, Display: "Blake2b-256", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Blake2b512, Display: "Blake2b-512", Bits: 512, Cryptographic: true},
+	{Name: HashNames.Blake3, Display: "Blake3", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Sha3224, Display: "SHA3-224", Bits: 224, Cryptographic: true},
+	{Name: HashNames.Sha3256, Display: "SHA3-256", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Sha3384, Display: "SHA3-384", Bits: 384, Cryptographic: true},
+	{Name: HashNames.Sha3512, Display: "SHA3-512", Bits: 512, Cryptographic: true},
+	{Name: HashNames.Sha224, Display: "SHA224", Bits: 224, Cryptographic: true},
+	{Name: HashNames.Sha384, Display: "SHA384", Bits: 384, Cryptographic: true},
+	{Name: HashNames.Sha512256, Display: "SHA512/256", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Ripemd160, Display: "RIPEMD-160", Bits: 160, Cryptographic: true},
+	{Name: HashNames.Whirlpool, Display: "Whirlpool", Bits: 512, Cryptographic: true},
+	{Name: HashNames.SM3, Display: "SM3", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Streebog256, Display: "Streebog256", Bits: 256, Cryptographic: true},
+	{Name: HashNames.Streebog512, Display: "Streebog512", Bits: 512, Cryptographic: true},
+	{Name: HashNames.Entropy, Display: "Entropy", Analysis: true},
+	{Name: HashNames.Ssdeep, Display: "ssdeep", Analysis: true},
+	{Name: HashNames.TLSH, Display: "TLSH", Analysis: true},
 }
 
+// Formats lists the built in output formats, template is also accepted along with --template
+var Formats = []string{"text", "json", "sum", "hashdeep", "hashonly", "hashit", "sri", "sri-json", "html"}
+
 // Process is the main entry point of the command line it sets everything up and starts running
 func Process() {
 	// Display the supported hashes then bail out
 	if Hashes {
-		printHashes()
+		ListHashes(strings.ToLower(Format) == "json")
 		return
 	}
+	scanStarted = time.Now()
 
-	// Check if we are accepting data from stdin
-	if len(DirFilePaths) == 0 {
-		stat, _ := os.Stdin.Stat()
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			StandardInput = true
+	// Package verification defaults to the running system rather than the current directory
+	if VerifyPackages != "" {
+		root := "/"
+		if len(DirFilePaths) != 0 {
+			root = DirFilePaths[0]
+		}
+		if err := prepareOptions(); err != nil {
+			printError(err.Error())
+			os.Exit(1)
 		}
+		os.Exit(processPackages(VerifyPackages, root))
+	}
+
+	if FromManifest != "" && (len(DirFilePaths) != 0 || FileInput != "") {
+		printError("--from-manifest cannot be combined with --input or files and directories")
+		os.Exit(1)
+	}
+
+	// Check if we are accepting data from stdin
+	var err error
+	if StandardInput, err = detectStandardInput(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
 	}
 
 	// If nothing was supplied as an argument to run against assume run against everything in the
@@ -118,19 +498,187 @@ func Process() {
 		Recursive = true
 	}
 
-	// Clean up hashes by setting all input to lowercase
-	Hash = formatHashInput()
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	// Without content there is nothing to hash so only metadata is output
+	if NoContent {
+		switch strings.ToLower(Format) {
+		case "text", "json":
+		default:
+			printError("--no-content only supports the text and json formats")
+			os.Exit(1)
+		}
+		Hash = []string{}
+		MTime = true
+	}
+
+	if err := prepareDatabaseOutput(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if err := validateCompress(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if err := validateEncrypt(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if err := validateSRI(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if _, err := parseOutputMode(OutputMode); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if err := validatePerRootOutput(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := validateKnown(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if err := validateListOnly(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	// Kept before snapshots replace them as results are mapped back to these paths
+	roots := DirFilePaths
+	excludeOutputs(roots)
+
+	// Containers embed their signature so only the other formats need a file to sign
+	if SignKey != "" && FileOutput == "" && PerRootOutput == "" && strings.ToLower(Format) != "hashit" {
+		printError("--sign requires --output so the signature can be written alongside it")
+		os.Exit(1)
+	}
+
+	// Verifying against a torrent replaces normal hashing entirely
+	if TorrentFile != "" {
+		os.Exit(processTorrent(TorrentFile, DirFilePaths[0]))
+	}
+
+	if CodeSign {
+		os.Exit(processCodeSign(DirFilePaths))
+	}
+
+	if Check != "" {
+		os.Exit(processCheck(Check))
+	}
+
+	if Partition >= 0 && len(DirFilePaths) != 1 {
+		printError("--partition requires exactly one disk image")
+		os.Exit(1)
+	}
+
+	if Diff {
+		if len(DirFilePaths) != 2 {
+			printError("--diff requires exactly two directories")
+			os.Exit(1)
+		}
+		os.Exit(processDiff(DirFilePaths[0], DirFilePaths[1]))
+	}
+
+	// Scan a point in time snapshot so files locked by running programs can be read
+	var shadows []shadowMapping
+	if VSS && !StandardInput && FileInput == "" && FromManifest == "" {
+		paths, mappings, err := snapshotPaths(DirFilePaths)
+		if err != nil {
+			releaseSnapshots(mappings)
+			printError(err.Error())
+			os.Exit(1)
+		}
+		DirFilePaths = paths
+		shadows = mappings
+	}
+	if !StandardInput && FileInput == "" && FromManifest == "" {
+		paths, mappings, err := shadowRootPaths(DirFilePaths)
+		if err != nil {
+			releaseSnapshots(shadows)
+			printError(err.Error())
+			os.Exit(1)
+		}
+		DirFilePaths = paths
+		shadows = append(shadows, mappings...)
+	}
+
+	// Pick up where an interrupted run left off skipping anything it completed
+	var resumeState *os.File
+	if Resume != "" {
+		var err error
+		if resumeResults, resumeOrder, err = loadResume(Resume); err != nil {
+			printError(fmt.Sprintf("unable to read resume state %s: %s", Resume, err.Error()))
+			os.Exit(1)
+		}
+		if resumeState, err = openResumeState(Resume); err != nil {
+			printError(fmt.Sprintf("unable to open resume state %s: %s", Resume, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Directories unchanged since the last run have their results reused rather than hashed
+	if err := validateDirCache(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	if DirCache != "" {
+		var err error
+		if dirCache, err = loadDirCache(DirCache); err != nil {
+			printError(fmt.Sprintf("unable to read directory cache %s: %s", DirCache, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Compare against an earlier manifest so the output doubles as a change report
+	var baseline map[string]baselineEntry
+	var baselineSeen time.Time
+	if Baseline != "" {
+		var err error
+		if baseline, baselineSeen, err = loadBaseline(Baseline); err != nil {
+			printError(fmt.Sprintf("unable to read baseline %s: %s", Baseline, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	var known knownHashes
+	if len(Known) != 0 {
+		var err error
+		if known, err = loadKnown(Known); err != nil {
+			printError(fmt.Sprintf("unable to read known files: %s", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Read before the output file is replaced by this run's results
+	existing, err := loadAppend()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
 
 	// Results ready to be printed
 	fileSummaryQueue := make(chan Result, FileListQueueSize)
 
 	if StandardInput {
 		go processStandardInput(fileSummaryQueue)
+	} else if Partition >= 0 {
+		go processImage(DirFilePaths[0], Partition, fileSummaryQueue)
 	} else {
 		// Files ready to be read from disk
 		fileListQueue := make(chan string, FileListQueueSize)
 
-		if FileInput == "" {
+		if FromManifest != "" {
+			go queueManifest(FromManifest, fileListQueue, fileSummaryQueue)
+		} else if FileInput == "" {
 			// Spawn routine to start finding files on disk
 			go func() {
 				// Check if the paths or files added exist and inform the user if they don't
@@ -138,16 +686,15 @@ func Process() {
 					fp := filepath.Clean(f)
 					fi, err := os.Stat(fp)
 
-					// If there is an error which is usually does not exist then exit non zero
+					// If there is an error which is usually does not exist then record it and carry on
 					if err != nil {
-						printError(fmt.Sprintf("file or directory issue: %s %s", fp, err.Error()))
-						os.Exit(1)
+						fileSummaryQueue <- newErrorResult(fp, err)
 					} else {
 						if fi.IsDir() {
 							if Recursive {
-								walkDirectory(fp, fileListQueue)
+								walkDirectory(context.Background(), fp, fileListQueue, fileSummaryQueue)
 							}
-						} else {
+						} else if inShard(fp) && !resumeCompleted(fp) {
 							fileListQueue <- fp
 						}
 					}
@@ -170,7 +717,9 @@ func Process() {
 				// Read the file line by line
 				for scanner.Scan() {
 					line := scanner.Text()
-					fileListQueue <- line
+					if inShard(line) && !resumeCompleted(line) {
+						fileListQueue <- line
+					}
 				}
 				close(fileListQueue)
 
@@ -186,22 +735,74 @@ func Process() {
 			uiprogress.Start() // start rendering of progress bars
 		}
 
-		var wg sync.WaitGroup
-		for i := 0; i < NoThreads; i++ {
-			wg.Add(1)
-			go func() {
-				fileProcessorWorker(fileListQueue, fileSummaryQueue)
-				wg.Done()
-			}()
+		if ListOnly {
+			go listFiles(fileListQueue, fileSummaryQueue)
+		} else {
+			startWorkers(primeQueue(orderFiles(adaptiveQueue(fileListQueue))), fileSummaryQueue)
 		}
+	}
+
+	summaryQueue := tagScanID(fileSummaryQueue)
+	if shadows != nil {
+		summaryQueue = unsnapshotPaths(summaryQueue, shadows)
+	}
+	if resumeState != nil {
+		summaryQueue = journalResults(summaryQueue, resumeState)
+	}
+	if dirCache != nil {
+		summaryQueue = recordDirCache(summaryQueue, dirCache)
+	}
+	if baseline != nil {
+		summaryQueue = annotateChanges(summaryQueue, baseline, baselineSeen)
+	}
+	if len(Known) != 0 {
+		summaryQueue = filterKnown(summaryQueue, known, MatchNegative)
+	}
+	if hooks != nil {
+		summaryQueue = hookResults(summaryQueue)
+	}
+	if existing != nil {
+		summaryQueue = appendResults(summaryQueue, existing)
+	}
+	summaryQueue = encodeDigests(anonymizePaths(summaryQueue))
+	if partial != nil {
+		summaryQueue = keepPartial(summaryQueue, partial)
+	}
 
-		go func() {
-			wg.Wait()
-			close(fileSummaryQueue)
-		}()
+	// An embedded signature covers the whole container so it has to be built in memory,
+	// and holding results back from stdout with --no-stream needs them kept until the end
+	embedSignature := SignKey != "" && strings.ToLower(Format) == "hashit" && PerRootOutput == ""
+	var stream *outputStream
+	if FileOutput != "" && !embedSignature && !(TeeOutput && NoStream) {
+		var err error
+		if stream, err = openOutputStream(); err != nil {
+			printError(fmt.Sprintf("unable to write output file %s: %s", FileOutput, err.Error()))
+			os.Exit(1)
+		}
 	}
 
-	result, valid := fileSummarize(fileSummaryQueue)
+	var result string
+	valid := true
+	switch {
+	case databaseOutput != "":
+		writeDatabaseOutput(summaryQueue)
+	case PerRootOutput != "":
+		valid = writeRootOutputs(summaryQueue, roots)
+	default:
+		result, valid = fileSummarize(summaryQueue)
+	}
+	releaseSnapshots(shadows)
+	// Every result has passed so only the hooks still running are waited on
+	hooksOK := finishHooks()
+
+	if embedSignature {
+		signed, err := signContainer([]byte(result), SignKey)
+		if err != nil {
+			printError(fmt.Sprintf("unable to sign output: %s", err.Error()))
+			os.Exit(1)
+		}
+		result = string(signed)
+	}
 
 	if FileOutput == "" {
 		fmt.Print(result)
@@ -209,9 +810,205 @@ func Process() {
 			os.Exit(1)
 		}
 	} else {
-		_ = ioutil.WriteFile(FileOutput, []byte(result), 0600)
-		fmt.Println("results written to " + FileOutput)
+		var err error
+		if stream != nil {
+			err = stream.close(result)
+		} else {
+			// Formats which do not stream still need to be shown when teeing
+			if TeeOutput && !formatStreams() {
+				fmt.Print(result)
+			}
+			err = writeOutputFile(result)
+		}
+		if err != nil {
+			abortRun(fmt.Sprintf("unable to write output file %s: %s", FileOutput, err.Error()))
+		}
+
+		if SignKey != "" && !embedSignature {
+			if err := signFile(FileOutput, SignKey); err != nil {
+				printError(fmt.Sprintf("unable to sign output file %s: %s", FileOutput, err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		if !TeeOutput {
+			fmt.Println("results written to " + FileOutput)
+		}
+	}
+
+	// JSON output already carries the statistics alongside the results
+	if Stats && strings.ToLower(Format) != "json" {
+		fmt.Fprint(os.Stderr, formatStats(collectStats()))
+	}
+
+	if Verbose && resultCache != nil {
+		stats := resultCache.Stats()
+		printVerbose("cache", "size", stats.Size, "capacity", stats.Capacity, "hits", stats.Hits, "misses", stats.Misses, "evictions", stats.Evictions)
+	}
+
+	if !hooksOK {
+		os.Exit(1)
+	}
+
+	if atomic.LoadInt64(&fileErrorCount) != 0 {
+		os.Exit(ExitCodeFileError)
+	}
+
+	if atomic.LoadInt64(&xattrFailCount) != 0 {
+		os.Exit(1)
+	}
+}
+
+// Normalises and validates the options shared by the command line and library entry points
+func prepareOptions() error {
+	if err := setupLogging(); err != nil {
+		return err
+	}
+
+	if err := prepareScanID(); err != nil {
+		return err
+	}
+
+	if err := selfVerify(); err != nil {
+		return err
+	}
+
+	var err error
+	if queueMemoryBytes, err = parseQueueMemory(QueueMemory); err != nil {
+		return err
+	}
+
+	if err := validateLowMemory(); err != nil {
+		return err
+	}
+	applyLowMemory()
+
+	if err := validatePrimeCache(); err != nil {
+		return err
+	}
+
+	// Clean up hashes by setting all input to lowercase
+	Hash = formatHashInput()
+
+	if PieceLength > 0 {
+		if _, err := pieceHashFunc(PieceHash); err != nil {
+			return err
+		}
+	}
+
+	if err := compileExcludes(); err != nil {
+		return err
+	}
+
+	if err := validateWalkLimits(); err != nil {
+		return err
+	}
+
+	if err := compileFilters(); err != nil {
+		return err
+	}
+
+	if err := compileLimits(); err != nil {
+		return err
+	}
+
+	if err := prepareOpenFiles(); err != nil {
+		return err
+	}
+
+	if err := validateOpenMode(); err != nil {
+		return err
+	}
+
+	if err := prepareAnonymize(); err != nil {
+		return err
+	}
+
+	if err := compileDetail(); err != nil {
+		return err
+	}
+
+	if shardIndex, shardTotal, err = parseShard(Shard); err != nil {
+		return err
+	}
+
+	TextNormalize = strings.ToLower(TextNormalize)
+	if err := validateTextNormalize(TextNormalize); err != nil {
+		return err
+	}
+
+	if err := validateEncoding(Encoding); err != nil {
+		return err
+	}
+
+	Order = strings.ToLower(Order)
+	if err := validateOrder(Order); err != nil {
+		return err
 	}
+
+	if err := parseLabels(); err != nil {
+		return err
+	}
+
+	if err := parseOutputTemplate(); err != nil {
+		return err
+	}
+
+	if err := prepareHooks(); err != nil {
+		return err
+	}
+
+	if err := prepareNetwork(); err != nil {
+		return err
+	}
+
+	preparePartial()
+
+	if CacheSize > 0 {
+		resultCache = newLruCache(CacheSize)
+	}
+
+	if readLimiter, err = parseLimitRate(LimitRate); err != nil {
+		return err
+	}
+
+	resetLinks()
+	resetStats()
+
+	return nil
+}
+
+// Creates a result recording that the file could not be processed so it
+// shows up in the output rather than silently going missing
+func newErrorResult(file string, err error) Result {
+	atomic.AddInt64(&fileErrorCount, 1)
+	printError(fmt.Sprintf("unable to process %s: %s", file, err.Error()))
+	return Result{
+		File:  file,
+		Error: anonymizeError(err),
+	}
+}
+
+// Splits the key=value labels supplied so they can be attached to each record
+func parseLabels() error {
+	labels = map[string]string{}
+	for _, l := range Label {
+		key, value, found := strings.Cut(l, "=")
+		if !found || key == "" {
+			return fmt.Errorf("invalid label %s expected key=value", l)
+		}
+		labels[key] = value
+	}
+	return nil
+}
+
+// Entropy and similarity digests have to be asked for by name as they are not checksums
+func isAnalysis(hash string) bool {
+	switch hash {
+	case HashNames.Entropy, HashNames.Ssdeep, HashNames.TLSH:
+		return true
+	}
+	return false
 }
 
 // ToLower all of the input hashes so we can match them easily
@@ -226,7 +1023,7 @@ func formatHashInput() []string {
 // Check if a hash was supplied to the input so we know if we should calculate it
 func hasHash(hash string) bool {
 	for _, x := range Hash {
-		if x == "all" {
+		if x == "all" && !isAnalysis(hash) {
 			return true
 		}
 
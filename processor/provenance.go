@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Describes the run that produced a json or hashdeep manifest when --provenance is set
+type ScanProvenance struct {
+	ScanID    string
+	Started   time.Time
+	Host      string
+	Directory string
+	Version   string
+	Command   []string
+}
+
+// When Process started, each result records when it was hashed relative to this
+var scanStarted time.Time
+
+func scanProvenance() ScanProvenance {
+	started := scanStarted
+	if started.IsZero() {
+		started = time.Now()
+	}
+	pwd, host, command := runContext()
+	return ScanProvenance{
+		ScanID:    scanID,
+		Started:   started.UTC(),
+		Host:      host,
+		Directory: pwd,
+		Version:   Version,
+		Command:   command,
+	}
+}
+
+// Records when a result finished hashing
+func stampHashed(r *Result) {
+	if !Provenance || r.Error != "" {
+		return
+	}
+	hashed := time.Now().UTC()
+	r.HashedAt = &hashed
+}
+
+// Comment lines written after the hashdeep invocation header
+func hashdeepProvenance() string {
+	p := scanProvenance()
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("## Started: %s\n", p.Started.Format(time.RFC3339Nano)))
+	str.WriteString(fmt.Sprintf("## Host: %s\n", p.Host))
+	str.WriteString(fmt.Sprintf("## Version: hashit %s\n", p.Version))
+	return str.String()
+}
+
+// The hashed column of a hashdeep line, empty for results read back from a manifest
+func hashdeepHashed(res Result) string {
+	if res.HashedAt == nil {
+		return ""
+	}
+	return res.HashedAt.Format(hashdeepTimeLayout)
+}
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkerProvenanceStampsHashed(t *testing.T) {
+	previous := Hash
+	defer func() { Hash, Provenance = previous, false }()
+	Hash = []string{HashNames.MD5}
+	Provenance = true
+
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	before := time.Now()
+	input := make(chan string, 2)
+	output := make(chan Result, 2)
+	input <- file
+	input <- filepath.Join(t.TempDir(), "missing")
+	close(input)
+	fileProcessorWorker(0, input, output)
+	close(output)
+
+	for res := range output {
+		if res.Error != "" {
+			if res.HashedAt != nil {
+				t.Errorf("expected no hashed time for an error got %s", res.HashedAt)
+			}
+			continue
+		}
+		if res.HashedAt == nil || res.HashedAt.Before(before) {
+			t.Errorf("expected a hashed time after %s got %v", before, res.HashedAt)
+		}
+	}
+}
+
+func TestToJSONProvenance(t *testing.T) {
+	previousHash, previousStarted := Hash, scanStarted
+	defer func() { Hash, Provenance, scanStarted = previousHash, false, previousStarted }()
+	Hash = []string{HashNames.MD5}
+	Provenance = true
+	scanStarted = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	hashed := time.Date(2024, 1, 31, 12, 0, 1, 0, time.UTC)
+	input := make(chan Result, 1)
+	input <- Result{File: "a", MD5: "5d41402abc4b2a76b9719d911017c592", HashedAt: &hashed}
+	close(input)
+
+	out := toJSON(input)
+	decoded := struct {
+		Provenance ScanProvenance
+		Files      []Result
+	}{}
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("expected valid json got %s: %s", err, out)
+	}
+	if !decoded.Provenance.Started.Equal(scanStarted) || decoded.Provenance.Version != Version {
+		t.Errorf("unexpected provenance %+v", decoded.Provenance)
+	}
+	if len(decoded.Files) != 1 || decoded.Files[0].HashedAt == nil || !decoded.Files[0].HashedAt.Equal(hashed) {
+		t.Errorf("expected the hashed time to be kept got %+v", decoded.Files)
+	}
+
+	entries, err := parseManifest([]byte(out))
+	if err != nil || len(entries) != 1 || entries[0].File != "a" {
+		t.Errorf("expected the wrapped manifest to be checkable got %v %v", entries, err)
+	}
+}
+
+func TestToHashDeepProvenance(t *testing.T) {
+	previousHash, previousStarted := Hash, scanStarted
+	defer func() { Hash, Provenance, scanStarted = previousHash, false, previousStarted }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	Provenance = true
+	scanStarted = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	hashed := time.Date(2024, 1, 31, 12, 0, 1, 500000000, time.UTC)
+	input := make(chan Result, 1)
+	input <- Result{File: "a", Bytes: 5, MD5: "5d41402abc4b2a76b9719d911017c592", SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", HashedAt: &hashed}
+	close(input)
+
+	out := toHashDeep(input)
+	for _, expected := range []string{
+		"%%%% size,md5,sha256,filename,hashed\n",
+		"## Started: 2024-01-31T12:00:00Z\n",
+		"## Version: hashit " + Version + "\n",
+		",a,2024-01-31 12:00:01.5\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in %s", expected, out)
+		}
+	}
+
+	entries, err := parseManifest([]byte(out))
+	if err != nil || len(entries) != 1 || entries[0].File != "a" || len(entries[0].Digests) != 2 {
+		t.Errorf("expected the hashed column to be ignored when reading got %v %v", entries, err)
+	}
+}
@@ -0,0 +1,293 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Bytes charged for each queued path on top of its length, roughly the string header
+// and the slot holding it
+const queueEntryOverhead = 32
+
+// How often the queue compares the walker and workers and resizes itself
+const queueTuneInterval = 100 * time.Millisecond
+
+// Quiet intervals before an oversized queue gives memory back
+const queueShrinkAfter = 20
+
+// Cap on the memory held by queued paths parsed from QueueMemory, zero keeps a fixed queue
+var queueMemoryBytes int64
+
+var queueMutex sync.Mutex
+var queueMetrics queueStats
+
+type queueStats struct {
+	initialLimit   int
+	limit          int
+	maxLimit       int
+	maxDepth       int
+	depthTotal     int64
+	samples        int64
+	grows          int
+	shrinks        int
+	walkerStalls   int64
+	workerStalls   int64
+	budgetStalls   int64
+	maxQueuedBytes int64
+}
+
+func parseQueueMemory(value string) (int64, error) {
+	size, err := parseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid queue memory %s expected a size such as 64M or 0 to disable", value)
+	}
+	return size, nil
+}
+
+func resetQueueStats() {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	queueMetrics = queueStats{}
+}
+
+// Sits between the walker and the workers holding as many paths as the workers need
+// to stay busy. The queue starts at FileListQueueSize and doubles whenever the workers
+// run dry soon after the walker was held back by a full queue, which is what a tree
+// alternating between huge directories and deep slow ones looks like. It never holds
+// more than QueueMemory worth of paths and halves again once it has sat mostly empty.
+func adaptiveQueue(input chan string) chan string {
+	if queueMemoryBytes <= 0 {
+		return input
+	}
+
+	output := make(chan string)
+	go func() {
+		q := newPathQueue(FileListQueueSize, queueMemoryBytes)
+		ticker := time.NewTicker(queueTuneInterval)
+		defer ticker.Stop()
+
+		in := input
+		for in != nil || q.len() != 0 {
+			var out chan string
+			var next string
+			if q.len() != 0 {
+				out = output
+				next = q.peek()
+			}
+
+			var recv chan string
+			if in != nil {
+				if q.accepting() {
+					recv = in
+				} else if len(in) == cap(in) {
+					q.noteFull()
+				}
+			}
+
+			select {
+			case path, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				q.push(path)
+			case out <- next:
+				q.pop()
+				if q.len() == 0 && in != nil {
+					q.workerStalls++
+				}
+			case <-ticker.C:
+				q.tune()
+			}
+		}
+
+		q.publish()
+		close(output)
+	}()
+
+	return output
+}
+
+// FIFO of paths bounded by both a count which is tuned and a fixed memory budget
+type pathQueue struct {
+	paths  []string
+	head   int
+	bytes  int64
+	budget int64
+	stats  queueStats
+
+	// Stalls seen since the last tune
+	walkerStalls int64
+	workerStalls int64
+	blocked      bool
+	quiet        int
+}
+
+func newPathQueue(limit int, budget int64) *pathQueue {
+	if limit < 1 {
+		limit = 1
+	}
+	q := &pathQueue{budget: budget}
+	q.stats.initialLimit = limit
+	q.stats.limit = limit
+	q.stats.maxLimit = limit
+	return q
+}
+
+func pathCost(path string) int64 {
+	return int64(len(path) + queueEntryOverhead)
+}
+
+func (q *pathQueue) len() int {
+	return len(q.paths) - q.head
+}
+
+func (q *pathQueue) peek() string {
+	return q.paths[q.head]
+}
+
+// Something is always accepted into an empty queue so one huge path cannot wedge it
+func (q *pathQueue) accepting() bool {
+	if q.len() == 0 {
+		return true
+	}
+	return q.len() < q.stats.limit && q.bytes < q.budget
+}
+
+// Records that the walker is waiting on a full queue, counted once until it is let in
+func (q *pathQueue) noteFull() {
+	if q.blocked {
+		return
+	}
+	q.blocked = true
+	q.walkerStalls++
+	if q.bytes >= q.budget {
+		q.stats.budgetStalls++
+	}
+}
+
+func (q *pathQueue) push(path string) {
+	q.blocked = false
+	q.paths = append(q.paths, path)
+	q.bytes += pathCost(path)
+	if q.bytes > q.stats.maxQueuedBytes {
+		q.stats.maxQueuedBytes = q.bytes
+	}
+	if q.len() > q.stats.maxDepth {
+		q.stats.maxDepth = q.len()
+	}
+}
+
+func (q *pathQueue) pop() {
+	q.bytes -= pathCost(q.paths[q.head])
+	q.paths[q.head] = ""
+	q.head++
+
+	// Move what is left to the front once most of the slice has been consumed
+	if q.head > 1024 && q.head*2 > len(q.paths) {
+		q.paths = append(q.paths[:0], q.paths[q.head:]...)
+		q.head = 0
+	}
+}
+
+// Largest count the memory budget allows given the paths seen so far
+func (q *pathQueue) budgetLimit() int {
+	cost := int64(queueEntryOverhead * 4)
+	if q.len() != 0 {
+		cost = q.bytes / int64(q.len())
+	}
+	limit := int(q.budget / cost)
+	if limit < q.stats.initialLimit {
+		limit = q.stats.initialLimit
+	}
+	return limit
+}
+
+// Called every interval to sample the depth and grow or shrink the limit
+func (q *pathQueue) tune() {
+	q.stats.depthTotal += int64(q.len())
+	q.stats.samples++
+
+	walkerStalled := q.walkerStalls != 0
+	workersStalled := q.workerStalls != 0
+	q.stats.walkerStalls += q.walkerStalls
+	q.stats.workerStalls += q.workerStalls
+	q.walkerStalls, q.workerStalls = 0, 0
+
+	switch {
+	case walkerStalled && workersStalled:
+		// Both sides waited on each other so a deeper queue would have smoothed it out
+		limit := q.stats.limit * 2
+		if budget := q.budgetLimit(); limit > budget {
+			limit = budget
+		}
+		if limit > q.stats.limit {
+			q.resize(limit, "grow")
+		}
+		q.quiet = 0
+	case q.len() < q.stats.limit/4:
+		q.quiet++
+		if q.quiet >= queueShrinkAfter && q.stats.limit > q.stats.initialLimit {
+			limit := q.stats.limit / 2
+			if limit < q.stats.initialLimit {
+				limit = q.stats.initialLimit
+			}
+			q.resize(limit, "shrink")
+			q.quiet = 0
+		}
+	default:
+		q.quiet = 0
+	}
+
+	q.publish()
+}
+
+func (q *pathQueue) resize(limit int, reason string) {
+	if limit > q.stats.limit {
+		q.stats.grows++
+	} else {
+		q.stats.shrinks++
+	}
+	q.stats.limit = limit
+	if limit > q.stats.maxLimit {
+		q.stats.maxLimit = limit
+	}
+	printDebug("queue resized", "reason", reason, "limit", limit, "depth", q.len(), "bytes", q.bytes)
+}
+
+// Makes the latest figures available to collectStats
+func (q *pathQueue) publish() {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	queueMetrics = q.stats
+}
+
+// Summary of the adaptive queue for --stats, nil when it was not used
+func collectQueueStats() *QueueStats {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	m := queueMetrics
+	if m.initialLimit == 0 {
+		return nil
+	}
+
+	average := 0.0
+	if m.samples != 0 {
+		average = float64(m.depthTotal) / float64(m.samples)
+	}
+	return &QueueStats{
+		InitialLimit:   m.initialLimit,
+		FinalLimit:     m.limit,
+		MaxLimit:       m.maxLimit,
+		MaxDepth:       m.maxDepth,
+		AverageDepth:   average,
+		MaxQueuedBytes: m.maxQueuedBytes,
+		Grows:          m.grows,
+		Shrinks:        m.shrinks,
+		WalkerStalls:   m.walkerStalls,
+		WorkerStalls:   m.workerStalls,
+		BudgetStalls:   m.budgetStalls,
+	}
+}
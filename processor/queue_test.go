@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdaptiveQueueKeepsOrder(t *testing.T) {
+	queueMemoryBytes = 1 << 20
+	defer func() { queueMemoryBytes = 0 }()
+	resetQueueStats()
+
+	input := make(chan string, 2)
+	go func() {
+		for i := 0; i < 5000; i++ {
+			input <- fmt.Sprintf("file%d", i)
+		}
+		close(input)
+	}()
+
+	i := 0
+	for path := range adaptiveQueue(input) {
+		if expected := fmt.Sprintf("file%d", i); path != expected {
+			t.Fatalf("expected %s got %s", expected, path)
+		}
+		i++
+	}
+	if i != 5000 {
+		t.Errorf("expected 5000 paths got %d", i)
+	}
+
+	stats := collectQueueStats()
+	if stats == nil || stats.MaxDepth == 0 {
+		t.Errorf("expected queue stats got %+v", stats)
+	}
+}
+
+func TestAdaptiveQueueDisabled(t *testing.T) {
+	queueMemoryBytes = 0
+	input := make(chan string)
+	if adaptiveQueue(input) != input {
+		t.Error("expected the input channel back when the queue memory is 0")
+	}
+}
+
+func TestPathQueueGrowsWhenBothSidesStall(t *testing.T) {
+	q := newPathQueue(4, 1<<20)
+	for i := 0; i < 4; i++ {
+		q.push("a")
+	}
+	if q.accepting() {
+		t.Fatal("expected a full queue to stop accepting")
+	}
+
+	q.noteFull()
+	q.workerStalls++
+	q.tune()
+	if q.stats.limit != 8 || q.stats.grows != 1 {
+		t.Errorf("expected the limit to double to 8 got %d", q.stats.limit)
+	}
+	if !q.accepting() {
+		t.Error("expected the grown queue to accept")
+	}
+
+	// Only the walker waiting means the workers are the bottleneck so nothing changes
+	q.noteFull()
+	q.tune()
+	if q.stats.limit != 8 {
+		t.Errorf("expected the limit to stay 8 got %d", q.stats.limit)
+	}
+}
+
+func TestPathQueueShrinksWhenQuiet(t *testing.T) {
+	q := newPathQueue(4, 1<<20)
+	q.resize(32, "grow")
+	for i := 0; i < queueShrinkAfter; i++ {
+		q.tune()
+	}
+	if q.stats.limit != 16 || q.stats.shrinks != 1 {
+		t.Errorf("expected the limit to halve to 16 got %d", q.stats.limit)
+	}
+
+	for i := 0; i < queueShrinkAfter*4; i++ {
+		q.tune()
+	}
+	if q.stats.limit != 4 {
+		t.Errorf("expected the limit to stop at the initial 4 got %d", q.stats.limit)
+	}
+}
+
+func TestPathQueueMemoryBudget(t *testing.T) {
+	path := "0123456789012345678901234567890"
+	budget := pathCost(path) * 3
+	q := newPathQueue(100, budget)
+	for q.accepting() {
+		q.push(path)
+	}
+	if q.len() != 3 {
+		t.Errorf("expected the budget to hold 3 paths got %d", q.len())
+	}
+
+	// Growing is capped by what the budget can hold
+	q.noteFull()
+	q.workerStalls++
+	q.tune()
+	if q.stats.limit != 100 {
+		t.Errorf("expected the limit to stay at the initial 100 got %d", q.stats.limit)
+	}
+	if q.stats.budgetStalls != 1 {
+		t.Errorf("expected one budget stall got %d", q.stats.budgetStalls)
+	}
+
+	// An empty queue always takes the next path however large
+	q = newPathQueue(1, 1)
+	if !q.accepting() {
+		t.Error("expected an empty queue to accept")
+	}
+}
+
+func TestParseQueueMemory(t *testing.T) {
+	if v, err := parseQueueMemory("64M"); err != nil || v != 64<<20 {
+		t.Errorf("expected 64M got %d %v", v, err)
+	}
+	if _, err := parseQueueMemory("lots"); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Shared by every worker so the limit applies to the total read throughput, nil when unlimited
+var readLimiter *rateLimiter
+
+// Token bucket holding up to one second of reads. Reads are allowed to take the
+// bucket negative and the reader then sleeps until it would have refilled, so reads
+// larger than the bucket still average out to the rate.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	sleep  func(time.Duration)
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+		sleep:  time.Sleep,
+	}
+}
+
+// Takes n bytes from the bucket blocking until they are covered
+func (l *rateLimiter) wait(n int) {
+	if n <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mutex.Unlock()
+
+	if deficit > 0 {
+		l.sleep(time.Duration(deficit / l.rate * float64(time.Second)))
+	}
+}
+
+// Parses --limit-rate such as 50M into bytes per second, empty means unlimited
+func parseLimitRate(value string) (*rateLimiter, error) {
+	if value == "" {
+		return nil, nil
+	}
+	rate, err := parseSize(value)
+	if err != nil || rate <= 0 {
+		return nil, fmt.Errorf("invalid limit rate %s expected bytes per second such as 50M", value)
+	}
+	return newRateLimiter(rate), nil
+}
+
+type limitedReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.limiter.wait(n)
+	return n, err
+}
+
+type limitedReaderAt struct {
+	r       io.ReaderAt
+	limiter *rateLimiter
+}
+
+func (l *limitedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := l.r.ReadAt(p, off)
+	l.limiter.wait(n)
+	return n, err
+}
+
+// Throttles reads from r when --limit-rate is set
+func limitReader(r io.Reader) io.Reader {
+	if readLimiter == nil {
+		return r
+	}
+	return &limitedReader{r: r, limiter: readLimiter}
+}
+
+// Throttles reads from r when --limit-rate is set
+func limitReaderAt(r io.ReaderAt) io.ReaderAt {
+	if readLimiter == nil {
+		return r
+	}
+	return &limitedReaderAt{r: r, limiter: readLimiter}
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseLimitRate(t *testing.T) {
+	if l, err := parseLimitRate(""); l != nil || err != nil {
+		t.Error("expected no limiter for empty rate")
+	}
+	if _, err := parseLimitRate("fast"); err == nil {
+		t.Error("expected error for invalid rate")
+	}
+	if _, err := parseLimitRate("0"); err == nil {
+		t.Error("expected error for zero rate")
+	}
+
+	l, err := parseLimitRate("50M")
+	if err != nil || l.rate != 50*1024*1024 {
+		t.Error("expected 50 MiB per second", err)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	var slept time.Duration
+	l := newRateLimiter(1000)
+	l.sleep = func(d time.Duration) { slept += d }
+
+	// The first second is available straight away
+	l.wait(1000)
+	if slept != 0 {
+		t.Error("expected no sleep within the bucket got", slept)
+	}
+
+	// Going 500 bytes over at 1000 per second needs about half a second
+	l.wait(500)
+	if slept < 400*time.Millisecond || slept > 500*time.Millisecond {
+		t.Error("expected around half a second got", slept)
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	defer func() { readLimiter = nil }()
+
+	r := bytes.NewReader([]byte("hello"))
+	if limitReader(r) != io.Reader(r) {
+		t.Error("expected reader unchanged without a limit")
+	}
+
+	var slept time.Duration
+	readLimiter = newRateLimiter(2)
+	readLimiter.sleep = func(d time.Duration) { slept += d }
+
+	content, err := io.ReadAll(limitReader(r))
+	if err != nil || string(content) != "hello" {
+		t.Error("unexpected content", string(content), err)
+	}
+	if slept < time.Second {
+		t.Error("expected reads over the limit to sleep got", slept)
+	}
+}
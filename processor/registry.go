@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+)
+
+// A hash algorithm added by a library user through RegisterHash
+type registeredHash struct {
+	info    HashInfo
+	newHash func() hash.Hash
+}
+
+var registryMutex sync.Mutex
+var registeredHashes []registeredHash
+
+// RegisterHash adds a custom hash algorithm so it can be selected with --hash, is listed by
+// --hashes and appears in every formatter. The name is matched case insensitively and must
+// not clash with a built in hash. Call it before Process or Run, usually from an init function.
+func RegisterHash(name string, newHash func() hash.Hash) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "all" || strings.ContainsAny(name, ", \t") {
+		return fmt.Errorf("invalid hash name %q", name)
+	}
+	if newHash == nil {
+		return fmt.Errorf("hash %s has no constructor", name)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	for _, info := range HashInfos {
+		if info.Name == name {
+			return fmt.Errorf("hash %s is already registered", name)
+		}
+	}
+
+	info := HashInfo{Name: name, Display: name, Bits: newHash().Size() * 8}
+	registeredHashes = append(registeredHashes, registeredHash{info: info, newHash: newHash})
+	HashInfos = append(HashInfos, info)
+	return nil
+}
+
+// A registered hash selected for this run along with its running state
+type customHasher struct {
+	name string
+	hash hash.Hash
+}
+
+// Creates fresh hashers for every selected registered hash
+func newCustomHashers() []customHasher {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	hashers := []customHasher{}
+	for _, r := range registeredHashes {
+		if hasHash(r.info.Name) {
+			hashers = append(hashers, customHasher{name: r.info.Name, hash: timeHash(r.info.Name, r.newHash())})
+		}
+	}
+	return hashers
+}
+
+// Collects the digests of the hashers, nil when no registered hash was selected
+func sumCustomHashers(hashers []customHasher) map[string]string {
+	if len(hashers) == 0 {
+		return nil
+	}
+	sums := map[string]string{}
+	for _, h := range hashers {
+		sums[h.name] = hex.EncodeToString(h.hash.Sum(nil))
+	}
+	return sums
+}
+
+// Hashes content already in memory with every selected registered hash
+func customHashes(content []byte) map[string]string {
+	hashers := newCustomHashers()
+	for _, h := range hashers {
+		h.hash.Write(content)
+	}
+	return sumCustomHashers(hashers)
+}
+
+// Lists the selected registered hashes in the order they were registered
+func selectedCustomHashes() []HashInfo {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	infos := []HashInfo{}
+	for _, r := range registeredHashes {
+		if hasHash(r.info.Name) {
+			infos = append(infos, r.info)
+		}
+	}
+	return infos
+}
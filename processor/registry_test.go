@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"bytes"
+	"hash"
+	"hash/fnv"
+	"strings"
+	"testing"
+)
+
+func registerTestHash(t *testing.T) {
+	infos := HashInfos
+	registered := registeredHashes
+	t.Cleanup(func() {
+		HashInfos = infos
+		registeredHashes = registered
+		Hash = []string{}
+	})
+
+	if err := RegisterHash("FNV64a", func() hash.Hash { return fnv.New64a() }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterHashInvalid(t *testing.T) {
+	registerTestHash(t)
+
+	for _, name := range []string{"", "all", "md5", "fnv64a", "a,b"} {
+		if err := RegisterHash(name, func() hash.Hash { return fnv.New32() }); err == nil {
+			t.Error("expected error registering", name)
+		}
+	}
+	if err := RegisterHash("nil", nil); err == nil {
+		t.Error("expected error without a constructor")
+	}
+}
+
+func TestRegisterHashListed(t *testing.T) {
+	registerTestHash(t)
+
+	last := HashInfos[len(HashInfos)-1]
+	if last.Name != "fnv64a" || last.Bits != 64 {
+		t.Error("unexpected hash info", last)
+	}
+}
+
+func TestRegisteredHashCalculated(t *testing.T) {
+	registerTestHash(t)
+	Hash = []string{"md5", "fnv64a"}
+
+	content := bytes.Repeat([]byte("hashit"), 100_000)
+	expected := "0863d734b5596765"
+
+	single, _ := processReadFile("test", &content)
+	parallel, _ := processReadFileParallel("test", &content)
+	streamed, _ := processStream("test", bytes.NewReader(content), nil, len(content), nil, "")
+
+	for _, r := range []Result{single, parallel, streamed} {
+		if r.Custom["fnv64a"] != expected || r.MD5 == "" {
+			t.Error("expected custom digest alongside md5 got", r.Custom, r.MD5)
+		}
+	}
+
+	var str strings.Builder
+	writeTextHashes(&str, single)
+	if !strings.Contains(str.String(), "     fnv64a "+expected) {
+		t.Error("expected custom hash in text output got", str.String())
+	}
+	if calculatedHashes(single)["fnv64a"] != expected {
+		t.Error("expected custom hash to be calculated")
+	}
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Lists the paths recorded in a manifest in the order they appear, each only once.
+// Sum lines are read directly as their digests need not match the selected hashes.
+func manifestPaths(path string) ([]string, error) {
+	data, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	trimmed := bytes.TrimSpace(data)
+	if isContainer(data) || bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte(hashdeepMagic)) {
+		results, err := loadManifestResults(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			files = append(files, res.File)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			_, file, found := strings.Cut(line, "  ")
+			if !found {
+				return nil, fmt.Errorf("invalid manifest line: %s", line)
+			}
+			files = append(files, file)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, f := range files {
+		if !seen[f] {
+			seen[f] = true
+			unique = append(unique, f)
+		}
+	}
+	return unique, nil
+}
+
+// Queues the files listed in the manifest for hashing in manifest order rather than
+// walking, recording an error for each that no longer exists
+func queueManifest(manifest string, output chan string, errorOutput chan Result) {
+	files, err := manifestPaths(manifest)
+	if err != nil {
+		printError(fmt.Sprintf("failed to read manifest: %s, %s", manifest, err.Error()))
+		os.Exit(1)
+	}
+
+	for _, f := range files {
+		if !inShard(f) || resumeCompleted(f) {
+			continue
+		}
+
+		if _, err := os.Lstat(f); errors.Is(err, os.ErrNotExist) {
+			errorOutput <- newErrorResult(f, fmt.Errorf("listed in %s but no longer exists", manifest))
+			continue
+		}
+		output <- f
+	}
+	close(output)
+}
@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestPaths(t *testing.T) {
+	dir := t.TempDir()
+	sum := filepath.Join(dir, "a.sum")
+	_ = os.WriteFile(sum, []byte("5d41402abc4b2a76b9719d911017c592  b\naaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d  b\n7d793037a0760186574b0282f2f435e7  a\n"), 0600)
+
+	files, err := manifestPaths(sum)
+	if err != nil || len(files) != 2 || files[0] != "b" || files[1] != "a" {
+		t.Errorf("Expected b then a got %v %v", files, err)
+	}
+
+	json := filepath.Join(dir, "a.json")
+	_ = os.WriteFile(json, []byte(`[{"File":"z"},{"File":"y","Error":"unreadable"}]`), 0600)
+	files, err = manifestPaths(json)
+	if err != nil || len(files) != 2 || files[0] != "z" || files[1] != "y" {
+		t.Errorf("Expected z then y got %v %v", files, err)
+	}
+
+	_ = os.WriteFile(sum, []byte("not a manifest\n"), 0600)
+	if _, err := manifestPaths(sum); err == nil {
+		t.Error("Expected error for invalid manifest")
+	}
+}
+
+func TestQueueManifestFlagsVanishedFiles(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present")
+	vanished := filepath.Join(dir, "vanished")
+	_ = os.WriteFile(present, []byte("hello"), 0600)
+
+	manifest := filepath.Join(dir, "m.sum")
+	_ = os.WriteFile(manifest, []byte("5d41402abc4b2a76b9719d911017c592  "+vanished+"\n5d41402abc4b2a76b9719d911017c592  "+present+"\n"), 0600)
+
+	output := make(chan string, 2)
+	errorOutput := make(chan Result, 2)
+	queueManifest(manifest, output, errorOutput)
+	close(errorOutput)
+
+	queued := []string{}
+	for f := range output {
+		queued = append(queued, f)
+	}
+	if len(queued) != 1 || queued[0] != present {
+		t.Errorf("Expected only the present file queued got %v", queued)
+	}
+
+	errors := []Result{}
+	for res := range errorOutput {
+		errors = append(errors, res)
+	}
+	if len(errors) != 1 || errors[0].File != vanished || errors[0].Error == "" {
+		t.Errorf("Expected an error record for the vanished file got %+v", errors)
+	}
+}
@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// How often completed results are flushed to the resume state file
+var resumeCheckpoint = time.Second
+
+// Results completed by a previous run keyed by path, nil when not resuming
+var resumeResults map[string]Result
+
+// The same results in the order they were completed so output is stable
+var resumeOrder []Result
+
+// Reads the results recorded by an interrupted run. A run killed while writing can
+// leave a partial last line which is ignored so that file is hashed again.
+func loadResume(path string) (map[string]Result, []Result, error) {
+	results := map[string]Result{}
+	order := []Result{}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, order, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var res Result
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil || res.File == "" {
+			if Verbose {
+				printVerbose(fmt.Sprintf("ignoring incomplete resume entry in %s", path))
+			}
+			continue
+		}
+		if _, ok := results[res.File]; !ok {
+			order = append(order, res)
+		}
+		results[res.File] = res
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return results, order, nil
+}
+
+// Opens the state file for appending. Anything partially written by a killed run is
+// ended with a newline first so it cannot run into the next entry.
+func openResumeState(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := file.Stat()
+	if err == nil && fi.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err = file.ReadAt(last, fi.Size()-1); err == nil && last[0] != '\n' {
+			_, err = file.Write([]byte("\n"))
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Check if a previous run already produced a result for this path
+func resumeCompleted(path string) bool {
+	if resumeResults == nil {
+		return false
+	}
+	_, ok := resumeResults[path]
+	return ok
+}
+
+// Replays the results of the interrupted run and then records each new successful
+// result in the state file, checkpointing at least every resumeCheckpoint. Failed
+// files are not recorded so they are tried again on the next run.
+func journalResults(input chan Result, state *os.File) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for _, res := range resumeOrder {
+			output <- res
+		}
+
+		writer := bufio.NewWriter(state)
+		lastFlush := time.Now()
+		for res := range input {
+			if res.Error == "" {
+				line, _ := json.Marshal(res)
+				_, _ = writer.Write(append(line, '\n'))
+
+				if time.Since(lastFlush) >= resumeCheckpoint {
+					if err := writer.Flush(); err != nil {
+						printError(fmt.Sprintf("unable to write resume state %s: %s", state.Name(), err.Error()))
+					}
+					lastFlush = time.Now()
+				}
+			}
+			output <- res
+		}
+
+		if err := writer.Flush(); err != nil {
+			printError(fmt.Sprintf("unable to write resume state %s: %s", state.Name(), err.Error()))
+		}
+		_ = state.Close()
+		close(output)
+	}()
+	return output
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadResumeMissing(t *testing.T) {
+	results, order, err := loadResume(filepath.Join(t.TempDir(), "state"))
+	if err != nil || len(results) != 0 || len(order) != 0 {
+		t.Error("expected empty state", err)
+	}
+}
+
+func TestJournalResultsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	defer func() {
+		resumeResults = nil
+		resumeOrder = nil
+	}()
+
+	state, err := openResumeState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := make(chan Result, 3)
+	input <- Result{File: "a", MD5: "1"}
+	input <- Result{File: "b", Error: "permission denied"}
+	input <- Result{File: "c", MD5: "3"}
+	close(input)
+	for range journalResults(input, state) {
+	}
+
+	// Simulate being killed part way through writing the next entry
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = f.WriteString(`{"File":"d","MD`)
+	_ = f.Close()
+
+	resumeResults, resumeOrder, err = loadResume(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumeOrder) != 2 || resumeOrder[0].File != "a" || resumeOrder[1].MD5 != "3" {
+		t.Error("unexpected resumed results", resumeOrder)
+	}
+	if !resumeCompleted("a") || resumeCompleted("b") || resumeCompleted("d") {
+		t.Error("only successful complete entries should be skipped")
+	}
+
+	state, _ = openResumeState(path)
+	input = make(chan Result, 1)
+	input <- Result{File: "b", MD5: "2"}
+	close(input)
+
+	files := []string{}
+	for res := range journalResults(input, state) {
+		files = append(files, res.File)
+	}
+	if len(files) != 3 || files[0] != "a" || files[1] != "c" || files[2] != "b" {
+		t.Error("expected replayed results followed by new ones got", files)
+	}
+
+	if _, order, _ := loadResume(path); len(order) != 3 {
+		t.Error("expected new result to be recorded after the partial entry got", len(order))
+	}
+}
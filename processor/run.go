@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Run hashes DirFilePaths using the current options for use as a library. Rather than
+// printing, every result is passed to OnResult and progress to OnProgress, both called
+// from a single goroutine. Directories are always walked. When ctx is cancelled no new
+// files are started, files already being hashed finish and ctx.Err() is returned.
+func Run(ctx context.Context) error {
+	if len(DirFilePaths) == 0 {
+		return errors.New("no files or directories supplied")
+	}
+
+	if err := prepareOptions(); err != nil {
+		return err
+	}
+
+	fileListQueue := make(chan string, FileListQueueSize)
+	fileSummaryQueue := make(chan Result, FileListQueueSize)
+
+	// Errors found while walking are merged in with the results once the workers finish
+	walkErrors := make(chan Result, FileListQueueSize)
+	go func() {
+		for _, f := range DirFilePaths {
+			if ctx.Err() != nil {
+				break
+			}
+
+			fp := filepath.Clean(f)
+			fi, err := os.Stat(fp)
+			switch {
+			case err != nil:
+				walkErrors <- newErrorResult(fp, err)
+			case fi.IsDir():
+				walkDirectory(ctx, fp, fileListQueue, walkErrors)
+			default:
+				if inShard(fp) {
+					fileListQueue <- fp
+				}
+			}
+		}
+		close(fileListQueue)
+		close(walkErrors)
+	}()
+
+	// Files queued but not yet started are dropped on cancellation. Draining what is
+	// ordered rather than the walk lets orderFiles finish as the walk stops.
+	ordered := orderFiles(fileListQueue)
+	filtered := make(chan string)
+	go func() {
+		defer close(filtered)
+		for f := range ordered {
+			select {
+			case filtered <- f:
+			case <-ctx.Done():
+				for range ordered {
+				}
+				return
+			}
+		}
+	}()
+
+	startWorkers(primeQueue(filtered), fileSummaryQueue)
+
+	event := ProgressEvent{}
+	report := func(res Result) {
+		event.File = res.File
+		event.FilesDone++
+		event.BytesDone += res.Bytes
+		if res.Error != "" {
+			event.Errors++
+		}
+
+		if OnResult != nil {
+			OnResult(res)
+		}
+		if OnProgress != nil {
+			OnProgress(event)
+		}
+	}
+
+	for fileSummaryQueue != nil || walkErrors != nil {
+		select {
+		case res, ok := <-fileSummaryQueue:
+			if !ok {
+				fileSummaryQueue = nil
+				continue
+			}
+			report(res)
+		case res, ok := <-walkErrors:
+			if !ok {
+				walkErrors = nil
+				continue
+			}
+			report(res)
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunCallbacks(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "b"), []byte("world!"), 0600)
+
+	DirFilePaths = []string{dir, filepath.Join(dir, "missing")}
+	Hash = []string{HashNames.MD5}
+	defer func() {
+		DirFilePaths = []string{}
+		OnResult = nil
+		OnProgress = nil
+	}()
+
+	results := map[string]Result{}
+	OnResult = func(r Result) { results[filepath.Base(r.File)] = r }
+	last := ProgressEvent{}
+	OnProgress = func(e ProgressEvent) { last = e }
+
+	if err := Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if results["a"].MD5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected result for a %+v", results["a"])
+	}
+	if results["missing"].Error == "" {
+		t.Error("Expected error record for missing path")
+	}
+	if last.FilesDone != 3 || last.BytesDone != 11 || last.Errors != 1 {
+		t.Errorf("Unexpected final progress %+v", last)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a"), []byte("hello"), 0600)
+
+	DirFilePaths = []string{dir}
+	defer func() { DirFilePaths = []string{} }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Run(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+}
+
+func TestRunCancelledWithOrder(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 200; i++ {
+		_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d", i)), []byte("hello"), 0600)
+	}
+
+	DirFilePaths = []string{dir}
+	Order = "size"
+	defer func() {
+		DirFilePaths = []string{}
+		Order = ""
+		OnResult = nil
+	}()
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	OnResult = func(r Result) { cancel() }
+	if err := Run(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+
+	// Every goroutine Run started, including the one ordering files, has to finish
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected no goroutines left behind got %d more", n-before)
+	}
+}
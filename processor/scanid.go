@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Identifies this run in every record, header, database row and json log line so the
+// output of one run can be told apart when collected from many hosts
+var scanID = ""
+
+// Supplied ids end up in file names, database rows and log lines so are kept simple
+var scanIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// A random version 4 UUID
+func newScanID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// Uses --scan-id when set so a run can be given a known id, otherwise generates one
+func prepareScanID() error {
+	if ScanID != "" {
+		if !scanIDPattern.MatchString(ScanID) {
+			return fmt.Errorf("invalid --scan-id %s expected up to 128 letters, digits, '.', '_', ':' or '-'", ScanID)
+		}
+		scanID = ScanID
+	} else {
+		id, err := newScanID()
+		if err != nil {
+			return fmt.Errorf("unable to generate a scan id: %w", err)
+		}
+		scanID = id
+	}
+
+	if strings.ToLower(LogFormat) == "json" {
+		logger = logger.With("scan_id", scanID)
+	}
+	return nil
+}
+
+// Stamps the results of this run with its id, records carried over from earlier runs by
+// --append keep theirs so are added after this
+func tagScanID(input chan Result) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			res.ScanID = scanID
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
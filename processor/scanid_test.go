@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewScanID(t *testing.T) {
+	a, err := newScanID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newScanID()
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(a) || a == b {
+		t.Errorf("expected distinct version 4 UUIDs got %s %s", a, b)
+	}
+}
+
+func TestPrepareScanID(t *testing.T) {
+	previous := scanID
+	defer func() { ScanID, scanID = "", previous }()
+
+	ScanID = "host1:2024-01-31"
+	if err := prepareScanID(); err != nil || scanID != ScanID {
+		t.Errorf("expected the supplied id to be used got %s %v", scanID, err)
+	}
+
+	ScanID = "two words"
+	if prepareScanID() == nil {
+		t.Error("expected an id with spaces to be rejected")
+	}
+}
+
+func TestTagScanID(t *testing.T) {
+	previous := scanID
+	defer func() { scanID = previous }()
+	scanID = "abc"
+
+	input := make(chan Result, 1)
+	input <- Result{File: "a", ScanID: "earlier"}
+	close(input)
+	if res := <-tagScanID(input); res.ScanID != "abc" {
+		t.Errorf("expected the record to be stamped got %s", res.ScanID)
+	}
+}
+
+func TestScanIDInOutputs(t *testing.T) {
+	previousHash, previousID := Hash, scanID
+	defer func() { Hash, scanID = previousHash, previousID }()
+	Hash = []string{HashNames.MD5}
+	scanID = "run-42"
+
+	input := make(chan Result, 1)
+	input <- Result{File: "a", MD5: "5d41402abc4b2a76b9719d911017c592", ScanID: scanID}
+	close(input)
+	if out := toHashDeep(input); !strings.Contains(out, "## Scan: run-42\n") {
+		t.Errorf("expected the scan in the hashdeep header got %s", out)
+	}
+
+	path := filepath.Join(t.TempDir(), "scan.db")
+	input = make(chan Result, 1)
+	input <- Result{File: "a", MD5: "5d41402abc4b2a76b9719d911017c592", ScanID: scanID}
+	close(input)
+	if _, err := writeSQLite(path, input); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var scan, file string
+	if err := db.QueryRow(`SELECT scans.scan_id, files.scan_id FROM files JOIN scans ON files.scan = scans.id`).Scan(&scan, &file); err != nil {
+		t.Fatal(err)
+	}
+	if scan != "run-42" || file != "run-42" {
+		t.Errorf("expected the scan id in both tables got %s %s", scan, file)
+	}
+}
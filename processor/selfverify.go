@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The digests the executable is expected to have. Bare digests are matched by length
+// against the selected hashes first and any hash when none of those fit.
+func selfVerifyDigests(expected string, exe string) ([]manifestDigest, error) {
+	digests, err := readSelfVerifyDigests(expected, exe)
+	if err == nil {
+		return digests, nil
+	}
+
+	previous := Hash
+	Hash = []string{"all"}
+	defer func() { Hash = previous }()
+	return readSelfVerifyDigests(expected, exe)
+}
+
+// SelfVerify is either a digest or a manifest listing the executable by name, or listing only it
+func readSelfVerifyDigests(expected string, exe string) ([]manifestDigest, error) {
+	if info, err := os.Stat(expected); err != nil || info.IsDir() {
+		digest, err := parseExpectedDigest(expected)
+		if err != nil {
+			return nil, err
+		}
+		return []manifestDigest{digest}, nil
+	}
+
+	data, err := readManifest(expected)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if strings.EqualFold(filepath.Base(filepath.FromSlash(e.File)), filepath.Base(exe)) {
+			return e.Digests, nil
+		}
+	}
+	if len(entries) == 1 {
+		return entries[0].Digests, nil
+	}
+	return nil, fmt.Errorf("manifest %s does not list %s", expected, filepath.Base(exe))
+}
+
+// Hashes the file read only with just the hashes the digests need
+func verifyExecutable(exe string, digests []manifestDigest) error {
+	file, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	previous := Hash
+	Hash = []string{}
+	for _, d := range digests {
+		Hash = append(Hash, d.Names...)
+	}
+	res, err := processStream(exe, file, nil, 0, nil, "")
+	actual := calculatedHashes(res)
+	Hash = previous
+	if err != nil {
+		return err
+	}
+
+	if !digestsMatch(actual, digests) {
+		d := digests[0]
+		return fmt.Errorf("expected %s %s got %s", d.Names[0], d.Digest, actual[d.Names[0]])
+	}
+	return nil
+}
+
+// Refuses to go any further when the running executable does not match SelfVerify
+func selfVerify() error {
+	if SelfVerify == "" {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to find the running executable to verify: %w", err)
+	}
+
+	digests, err := selfVerifyDigests(SelfVerify, exe)
+	if err != nil {
+		return fmt.Errorf("invalid --self-verify: %w", err)
+	}
+	if len(digests) == 0 {
+		return errors.New("invalid --self-verify: no digests for the executable")
+	}
+	if err := verifyExecutable(exe, digests); err != nil {
+		return fmt.Errorf("refusing to run, %s failed --self-verify: %w", exe, err)
+	}
+
+	printVerbose("self verify ok", "executable", exe)
+	return nil
+}
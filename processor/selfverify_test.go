@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func executableSHA256(t *testing.T) (string, string) {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		t.Skipf("unable to find the test executable: %s", err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Skipf("unable to read the test executable: %s", err)
+	}
+	sum := sha256.Sum256(data)
+	return exe, hex.EncodeToString(sum[:])
+}
+
+func TestSelfVerifyDigest(t *testing.T) {
+	previous, previousHash := SelfVerify, Hash
+	defer func() { SelfVerify, Hash = previous, previousHash }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	_, digest := executableSHA256(t)
+
+	for _, expected := range []string{"sha256:" + digest, digest} {
+		SelfVerify = expected
+		if err := selfVerify(); err != nil {
+			t.Errorf("Unexpected error for %s: %s", expected, err.Error())
+		}
+	}
+
+	SelfVerify = "sha256:" + digest[:63] + "0"
+	if digest[63] == '0' {
+		SelfVerify = "sha256:" + digest[:63] + "1"
+	}
+	if selfVerify() == nil {
+		t.Error("Expected a mismatched digest to refuse to run")
+	}
+
+	SelfVerify = "sha256:"
+	if selfVerify() == nil {
+		t.Error("Expected an empty digest to refuse to run")
+	}
+}
+
+func TestSelfVerifyManifest(t *testing.T) {
+	previous, previousHash := SelfVerify, Hash
+	defer func() { SelfVerify, Hash = previous, previousHash }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	exe, digest := executableSHA256(t)
+
+	dir := t.TempDir()
+	SelfVerify = filepath.Join(dir, "SHA256SUMS")
+	_ = os.WriteFile(SelfVerify, []byte(
+		"0000000000000000000000000000000000000000000000000000000000000000  other\n"+
+			digest+"  dist/"+filepath.Base(exe)+"\n"), 0600)
+	if err := selfVerify(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+
+	_ = os.WriteFile(SelfVerify, []byte(
+		"0000000000000000000000000000000000000000000000000000000000000000  other\n"+
+			"0000000000000000000000000000000000000000000000000000000000000000  another\n"), 0600)
+	if selfVerify() == nil {
+		t.Error("Expected a manifest not listing the executable to refuse to run")
+	}
+}
@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Returned by the server for every hashed upload or path
+type serveResponse struct {
+	File   string            `json:"file"`
+	Bytes  int64             `json:"bytes"`
+	Hashes map[string]string `json:"hashes,omitempty"`
+	Match  *bool             `json:"match,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+type hashServer struct {
+	// Resolved directories local paths must be inside, none disables hashing local paths
+	roots []string
+}
+
+// Serve listens on addr hashing uploaded content and, when roots are supplied, files
+// under them using the hashes selected with --hash. It only returns if the listener fails.
+func Serve(addr string, roots []string) {
+	if err := prepareOptions(); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	handler, err := newServeHandler(roots)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	printVerbose("serving", "address", addr, "roots", strings.Join(roots, ","), "hashes", strings.Join(Hash, ","))
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+}
+
+func newServeHandler(roots []string) (http.Handler, error) {
+	s := &hashServer{}
+	for _, root := range roots {
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root %s: %w", root, err)
+		}
+		s.roots = append(s.roots, resolved)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /hashes", s.handleHashes)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("POST /hash", s.handleUpload)
+	mux.HandleFunc("GET /hash", s.handlePath)
+	return mux, nil
+}
+
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, serveResponse{Error: err.Error()})
+}
+
+// Lists the hashes this server calculates
+func (s *hashServer) handleHashes(w http.ResponseWriter, r *http.Request) {
+	infos := []HashInfo{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) {
+			infos = append(infos, info)
+		}
+	}
+	writeServeJSON(w, http.StatusOK, infos)
+}
+
+// Reports the result cache hits, misses and size, all zero when --cache-size is not set
+func (s *hashServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeServeJSON(w, http.StatusOK, GetCacheStats())
+}
+
+// Works out which of the server's hashes the request wants, all of them by default
+func requestedHashes(r *http.Request) ([]string, error) {
+	selected := []string{}
+	for _, info := range HashInfos {
+		if hasHash(info.Name) {
+			selected = append(selected, info.Name)
+		}
+	}
+
+	query := r.URL.Query().Get("hash")
+	if query == "" {
+		return selected, nil
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(strings.ToLower(query), ",") {
+		if !contains(selected, name) {
+			return nil, fmt.Errorf("hash %s is not enabled on this server, available: %s", name, strings.Join(selected, ","))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Builds the response for a result keeping only the requested hashes and checking
+// them against the expected digest when one was supplied
+func newServeResponse(res Result, names []string, expect string) serveResponse {
+	response := serveResponse{File: res.File, Bytes: res.Bytes, Hashes: map[string]string{}}
+	calculated := calculatedHashes(res)
+	for _, name := range names {
+		response.Hashes[name] = calculated[name]
+	}
+
+	if expect != "" {
+		match := false
+		for _, digest := range response.Hashes {
+			match = match || strings.EqualFold(digest, expect)
+		}
+		response.Match = &match
+	}
+	return response
+}
+
+// Hashes the request body, named by the name parameter so the response can be matched up
+func (s *hashServer) handleUpload(w http.ResponseWriter, r *http.Request) {
+	names, err := requestedHashes(r)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "upload"
+	}
+
+	body := &countingReader{r: r.Body}
+	res, err := processStream(name, limitReader(body), nil, 0, nil, TextNormalize)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res.File = name
+	res.Bytes = body.n
+
+	printVerbose("hashed upload", "name", name, "bytes", body.n)
+	writeServeJSON(w, http.StatusOK, newServeResponse(res, names, r.URL.Query().Get("expect")))
+}
+
+// Hashes a file on the server given by the path parameter which must be under a root
+func (s *hashServer) handlePath(w http.ResponseWriter, r *http.Request) {
+	names, err := requestedHashes(r)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeServeError(w, http.StatusBadRequest, errors.New("path parameter is required"))
+		return
+	}
+	if !s.allowed(path) {
+		writeServeError(w, http.StatusForbidden, fmt.Errorf("%s is not under a served root", path))
+		return
+	}
+
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		writeServeError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	case !fi.Mode().IsRegular():
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("%s is not a regular file", path))
+		return
+	}
+
+	res := hashFiles([]string{path})[path]
+	if res.Error != "" {
+		writeServeError(w, http.StatusInternalServerError, errors.New(res.Error))
+		return
+	}
+
+	printVerbose("hashed path", "path", path, "bytes", res.Bytes)
+	writeServeJSON(w, http.StatusOK, newServeResponse(res, names, r.URL.Query().Get("expect")))
+}
+
+// Check the path resolves to somewhere inside one of the roots so symlinks and .. cannot escape
+func (s *hashServer) allowed(path string) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		// Missing files are reported as such if their parent is allowed
+		if !errors.Is(err, os.ErrNotExist) {
+			return false
+		}
+		parent, parentErr := resolvePath(filepath.Dir(path))
+		if parentErr != nil {
+			return false
+		}
+		resolved = filepath.Join(parent, filepath.Base(path))
+	}
+
+	for _, root := range s.roots {
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func serveRequest(t *testing.T, handler http.Handler, method string, target string, body string) (int, serveResponse) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	response := serveResponse{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response
+}
+
+func TestServeUpload(t *testing.T) {
+	Hash = []string{HashNames.MD5, HashNames.SHA1}
+	defer func() { Hash = []string{} }()
+
+	handler, err := newServeHandler(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, res := serveRequest(t, handler, http.MethodPost, "/hash?name=a.txt&hash=md5&expect=5D41402ABC4B2A76B9719D911017C592", "hello")
+	if code != http.StatusOK || res.File != "a.txt" || res.Bytes != 5 {
+		t.Fatalf("Unexpected response %d %+v", code, res)
+	}
+	if len(res.Hashes) != 1 || res.Hashes["md5"] != "5d41402abc4b2a76b9719d911017c592" || res.Match == nil || !*res.Match {
+		t.Errorf("Unexpected hashes %+v", res)
+	}
+
+	if code, _ := serveRequest(t, handler, http.MethodPost, "/hash?hash=sha256", "hello"); code != http.StatusBadRequest {
+		t.Errorf("Expected a hash the server does not run to be rejected got %d", code)
+	}
+}
+
+func TestServePath(t *testing.T) {
+	Hash = []string{HashNames.MD5}
+	defer func() { Hash = []string{} }()
+
+	root := t.TempDir()
+	outside := t.TempDir()
+	_ = os.WriteFile(filepath.Join(root, "a"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0600)
+	_ = os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "link"))
+
+	handler, err := newServeHandler([]string{root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := func(p string) string { return "/hash?path=" + url.QueryEscape(p) }
+
+	code, res := serveRequest(t, handler, http.MethodGet, path(filepath.Join(root, "a"))+"&expect=0000", "")
+	if code != http.StatusOK || res.Hashes["md5"] != "5d41402abc4b2a76b9719d911017c592" || res.Match == nil || *res.Match {
+		t.Errorf("Unexpected response %d %+v", code, res)
+	}
+
+	cases := map[string]int{
+		filepath.Join(root, "missing"):      http.StatusNotFound,
+		filepath.Join(root, "link"):         http.StatusForbidden,
+		filepath.Join(root, "..", "secret"): http.StatusForbidden,
+		filepath.Join(outside, "secret"):    http.StatusForbidden,
+		root:                                http.StatusBadRequest,
+	}
+	for p, expected := range cases {
+		if code, res := serveRequest(t, handler, http.MethodGet, path(p), ""); code != expected {
+			t.Errorf("%s expected %d got %d %+v", p, expected, code, res)
+		}
+	}
+}
+
+func TestServeWithoutRoots(t *testing.T) {
+	handler, _ := newServeHandler(nil)
+	if code, _ := serveRequest(t, handler, http.MethodGet, "/hash?path="+url.QueryEscape(os.Args[0]), ""); code != http.StatusForbidden {
+		t.Errorf("Expected local paths to be refused without roots got %d", code)
+	}
+}
+
+func TestServeStats(t *testing.T) {
+	defer func() { resultCache = nil }()
+	resultCache = newLruCache(10)
+	resultCache.Put("a", Result{})
+	resultCache.Get("a")
+	resultCache.Get("b")
+
+	handler, _ := newServeHandler(nil)
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	stats := CacheStats{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Size != 1 || stats.Capacity != 10 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Parsed version of Shard, a total of 0 means every file is selected
+var shardIndex, shardTotal uint64
+
+// Parses a shard such as 3/8 meaning the third of eight
+func parseShard(shard string) (uint64, uint64, error) {
+	if shard == "" {
+		return 0, 0, nil
+	}
+
+	index, total, found := strings.Cut(shard, "/")
+	i, err1 := strconv.ParseUint(index, 10, 64)
+	n, err2 := strconv.ParseUint(total, 10, 64)
+	if !found || err1 != nil || err2 != nil || n == 0 || i == 0 || i > n {
+		return 0, 0, fmt.Errorf("invalid shard %s expected N/TOTAL with N from 1 to TOTAL", shard)
+	}
+	return i, n, nil
+}
+
+// Deterministically assigns each path to a shard using FNV-1a so every host running
+// with the same total agrees without coordinating
+func inShard(path string) bool {
+	if shardTotal == 0 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(filepath.ToSlash(path)))
+	return h.Sum64()%shardTotal == shardIndex-1
+}
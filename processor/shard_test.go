@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	if i, n, err := parseShard("3/8"); err != nil || i != 3 || n != 8 {
+		t.Errorf("Expected 3/8 got %d/%d %v", i, n, err)
+	}
+	for _, invalid := range []string{"0/8", "9/8", "3", "a/b", "1/0"} {
+		if _, _, err := parseShard(invalid); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestInShardPartitions(t *testing.T) {
+	defer func() { shardIndex, shardTotal = 0, 0 }()
+
+	counts := map[string]int{}
+	for i := uint64(1); i <= 4; i++ {
+		shardIndex, shardTotal = i, 4
+		for f := 0; f < 1000; f++ {
+			path := fmt.Sprintf("dir/file%d", f)
+			if inShard(path) {
+				counts[path]++
+			}
+		}
+	}
+
+	if len(counts) != 1000 {
+		t.Errorf("Expected every path in a shard got %d", len(counts))
+	}
+	for path, c := range counts {
+		if c != 1 {
+			t.Errorf("Expected %s in exactly one shard got %d", path, c)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/blake2b-simd"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Signatures use the minisign format so they can also be checked with minisign -V
+
+// Extension of the detached signature written next to a signed manifest
+const signatureExtension = ".minisig"
+
+// Environment variable holding the password for encrypted secret keys
+const signPasswordEnv = "HASHIT_SIGN_PASSWORD"
+
+var (
+	minisignAlgEd       = []byte("Ed")
+	minisignAlgPrehash  = []byte("ED")
+	minisignKdfScrypt   = []byte("Sc")
+	minisignKdfNone     = []byte{0, 0}
+	minisignChecksumAlg = []byte("B2")
+)
+
+type minisignSecretKey struct {
+	KeyNum     [8]byte
+	PrivateKey ed25519.PrivateKey
+}
+
+type minisignPublicKey struct {
+	KeyNum    [8]byte
+	PublicKey ed25519.PublicKey
+}
+
+// Returns the base64 payload line of a minisign key or signature file skipping comments
+func minisignLines(data []byte) []string {
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Mirrors libsodium's pickparams for crypto_pwhash_scryptsalsa208sha256
+func scryptParams(opsLimit uint64, memLimit uint64) (int, int, int) {
+	if opsLimit < 32768 {
+		opsLimit = 32768
+	}
+	r := uint64(8)
+	var nLog2, p uint64
+	if opsLimit < memLimit/32 {
+		p = 1
+		maxN := opsLimit / (r * 4)
+		for nLog2 = 1; nLog2 < 63; nLog2++ {
+			if uint64(1)<<nLog2 > maxN/2 {
+				break
+			}
+		}
+	} else {
+		maxN := memLimit / (r * 128)
+		for nLog2 = 1; nLog2 < 63; nLog2++ {
+			if uint64(1)<<nLog2 > maxN/2 {
+				break
+			}
+		}
+		maxRP := (opsLimit / 4) / (uint64(1) << nLog2)
+		if maxRP > 0x3fffffff {
+			maxRP = 0x3fffffff
+		}
+		p = maxRP / r
+	}
+	return 1 << nLog2, int(r), int(p)
+}
+
+func parseMinisignSecretKey(data []byte, password string) (minisignSecretKey, error) {
+	key := minisignSecretKey{}
+	lines := minisignLines(data)
+	if len(lines) < 2 {
+		return key, errors.New("invalid secret key file")
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 158 {
+		return key, errors.New("invalid secret key encoding")
+	}
+
+	sigAlg, kdfAlg, checksumAlg := raw[0:2], raw[2:4], raw[4:6]
+	salt := raw[6:38]
+	opsLimit := binary.LittleEndian.Uint64(raw[38:46])
+	memLimit := binary.LittleEndian.Uint64(raw[46:54])
+	keyNumSk := append([]byte{}, raw[54:158]...)
+
+	if !bytes.Equal(sigAlg, minisignAlgEd) || !bytes.Equal(checksumAlg, minisignChecksumAlg) {
+		return key, errors.New("unsupported secret key algorithm")
+	}
+
+	switch {
+	case bytes.Equal(kdfAlg, minisignKdfScrypt):
+		if password == "" {
+			return key, fmt.Errorf("secret key is encrypted, set %s", signPasswordEnv)
+		}
+		n, r, p := scryptParams(opsLimit, memLimit)
+		stream, err := scrypt.Key([]byte(password), salt, n, r, p, len(keyNumSk))
+		if err != nil {
+			return key, err
+		}
+		for i := range keyNumSk {
+			keyNumSk[i] ^= stream[i]
+		}
+	case !bytes.Equal(kdfAlg, minisignKdfNone):
+		return key, errors.New("unsupported secret key derivation")
+	}
+
+	copy(key.KeyNum[:], keyNumSk[0:8])
+	key.PrivateKey = ed25519.PrivateKey(keyNumSk[8:72])
+
+	checksum := blake2b.New256()
+	checksum.Write(sigAlg)
+	checksum.Write(keyNumSk[0:72])
+	if subtle.ConstantTimeCompare(checksum.Sum(nil), keyNumSk[72:104]) != 1 {
+		return key, errors.New("secret key checksum mismatch, wrong password?")
+	}
+
+	return key, nil
+}
+
+func parseMinisignPublicKey(data []byte) (minisignPublicKey, error) {
+	key := minisignPublicKey{}
+	lines := minisignLines(data)
+	// Accept both a full .pub file and the bare base64 line minisign prints
+	payload := lines
+	if len(lines) >= 2 {
+		payload = lines[1:]
+	}
+	if len(payload) == 0 {
+		return key, errors.New("invalid public key file")
+	}
+	raw, err := base64.StdEncoding.DecodeString(payload[0])
+	if err != nil || len(raw) != 42 || !bytes.Equal(raw[0:2], minisignAlgEd) {
+		return key, errors.New("invalid public key encoding")
+	}
+
+	copy(key.KeyNum[:], raw[2:10])
+	key.PublicKey = ed25519.PublicKey(raw[10:42])
+	return key, nil
+}
+
+// Creates a prehashed minisign signature of content
+func minisignSign(key minisignSecretKey, content []byte, trustedComment string) []byte {
+	digest := blake2b.Sum512(content)
+	signature := ed25519.Sign(key.PrivateKey, digest[:])
+	globalSignature := ed25519.Sign(key.PrivateKey, append(append([]byte{}, signature...), trustedComment...))
+
+	var str bytes.Buffer
+	str.WriteString("untrusted comment: signature from hashit secret key\n")
+	str.WriteString(base64.StdEncoding.EncodeToString(append(append(append([]byte{}, minisignAlgPrehash...), key.KeyNum[:]...), signature...)) + "\n")
+	str.WriteString("trusted comment: " + trustedComment + "\n")
+	str.WriteString(base64.StdEncoding.EncodeToString(globalSignature) + "\n")
+	return str.Bytes()
+}
+
+// Checks a minisign signature of content returning the trusted comment
+func minisignVerify(key minisignPublicKey, content []byte, sigFile []byte) (string, error) {
+	lines := minisignLines(sigFile)
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return "", errors.New("invalid signature file")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 74 {
+		return "", errors.New("invalid signature encoding")
+	}
+	if !bytes.Equal(raw[2:10], key.KeyNum[:]) {
+		return "", errors.New("signature was made with a different key")
+	}
+
+	signature := raw[10:74]
+	message := content
+	switch {
+	case bytes.Equal(raw[0:2], minisignAlgPrehash):
+		digest := blake2b.Sum512(content)
+		message = digest[:]
+	case !bytes.Equal(raw[0:2], minisignAlgEd):
+		return "", errors.New("unsupported signature algorithm")
+	}
+	if !ed25519.Verify(key.PublicKey, message, signature) {
+		return "", errors.New("signature verification failed")
+	}
+
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	globalSignature, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || !ed25519.Verify(key.PublicKey, append(append([]byte{}, signature...), trustedComment...), globalSignature) {
+		return "", errors.New("trusted comment signature verification failed")
+	}
+
+	return trustedComment, nil
+}
+
+// A key given by name rather than path is looked for in the keys directory
+func resolveKeyFile(keyFile string, ext string) string {
+	if _, err := os.Stat(keyFile); err == nil || strings.ContainsAny(keyFile, `/\`) {
+		return keyFile
+	}
+	named := filepath.Join(DefaultKeyDir(), keyFile+ext)
+	if _, err := os.Stat(named); err == nil {
+		return named
+	}
+	return keyFile
+}
+
+// Reads a minisign secret key file decrypting it with the password from the environment,
+// or asking for it when there is a terminal
+func readSecretKey(keyFile string) (minisignSecretKey, error) {
+	keyFile = resolveKeyFile(keyFile, keyExtensions["minisign"][0])
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return minisignSecretKey{}, err
+	}
+
+	password := ""
+	if minisignKeyEncrypted(keyData) {
+		if password, err = readPassphrase("Passphrase for "+keyFile, false); err != nil {
+			return minisignSecretKey{}, err
+		}
+	}
+	return parseMinisignSecretKey(keyData, password)
+}
+
+// Reads a minisign public key file
+func readPublicKey(keyFile string) (minisignPublicKey, error) {
+	keyData, err := os.ReadFile(resolveKeyFile(keyFile, keyExtensions["minisign"][1]))
+	if err != nil {
+		return minisignPublicKey{}, err
+	}
+	return parseMinisignPublicKey(keyData)
+}
+
+// Writes a detached signature for the file alongside it
+func signFile(file string, keyFile string) error {
+	key, err := readSecretKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	trustedComment := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), filepath.Base(file))
+	return os.WriteFile(file+signatureExtension, minisignSign(key, content, trustedComment), 0644)
+}
+
+// Verifies the detached signature next to file returning the trusted comment
+func verifyFileSignature(file string, keyFile string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return verifyDetached(file, content, keyFile)
+}
+
+// Verifies content, already read from file, against the detached signature next to it
+func verifyDetached(file string, content []byte, keyFile string) (string, error) {
+	key, err := readPublicKey(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := os.ReadFile(file + signatureExtension)
+	if err != nil {
+		return "", err
+	}
+
+	return minisignVerify(key, content, sig)
+}
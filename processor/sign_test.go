@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/blake2b-simd"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Builds minisign secret and public key files the same way minisign -G does
+func makeMinisignKeys(t *testing.T, password string) ([]byte, []byte) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	keyNum := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	checksum := blake2b.New256()
+	checksum.Write(minisignAlgEd)
+	checksum.Write(keyNum)
+	checksum.Write(priv)
+	keyNumSk := append(append(append([]byte{}, keyNum...), priv...), checksum.Sum(nil)...)
+
+	kdf := minisignKdfNone
+	salt := make([]byte, 32)
+	limits := make([]byte, 16)
+	if password != "" {
+		kdf = minisignKdfScrypt
+		_, _ = rand.Read(salt)
+		binary.LittleEndian.PutUint64(limits[0:8], 32768)
+		binary.LittleEndian.PutUint64(limits[8:16], 1<<24)
+		n, r, p := scryptParams(32768, 1<<24)
+		stream, err := scrypt.Key([]byte(password), salt, n, r, p, len(keyNumSk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range keyNumSk {
+			keyNumSk[i] ^= stream[i]
+		}
+	}
+
+	raw := append(append(append(append(append([]byte{}, minisignAlgEd...), kdf...), minisignChecksumAlg...), salt...), limits...)
+	raw = append(raw, keyNumSk...)
+	secret := "untrusted comment: test secret key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+	public := "untrusted comment: test public key\n" + base64.StdEncoding.EncodeToString(append(append(append([]byte{}, minisignAlgEd...), keyNum...), pub...)) + "\n"
+	return []byte(secret), []byte(public)
+}
+
+func TestSignAndVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	secret, public := makeMinisignKeys(t, "")
+	_ = os.WriteFile(filepath.Join(dir, "key"), secret, 0600)
+	_ = os.WriteFile(filepath.Join(dir, "key.pub"), public, 0600)
+	manifest := filepath.Join(dir, "manifest")
+	_ = os.WriteFile(manifest, []byte("5d41402abc4b2a76b9719d911017c592  a\n"), 0600)
+
+	if err := signFile(manifest, filepath.Join(dir, "key")); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if _, err := verifyFileSignature(manifest, filepath.Join(dir, "key.pub")); err != nil {
+		t.Errorf("Expected signature to verify got %s", err.Error())
+	}
+
+	_ = os.WriteFile(manifest, []byte("00000000000000000000000000000000  a\n"), 0600)
+	if _, err := verifyFileSignature(manifest, filepath.Join(dir, "key.pub")); err == nil {
+		t.Error("Expected tampered manifest to fail verification")
+	}
+}
+
+func TestVerifyDetachedUsesContentRead(t *testing.T) {
+	dir := t.TempDir()
+	secret, public := makeMinisignKeys(t, "")
+	_ = os.WriteFile(filepath.Join(dir, "key"), secret, 0600)
+	_ = os.WriteFile(filepath.Join(dir, "key.pub"), public, 0600)
+	manifest := filepath.Join(dir, "manifest")
+	content := []byte("5d41402abc4b2a76b9719d911017c592  a\n")
+	_ = os.WriteFile(manifest, content, 0600)
+
+	if err := signFile(manifest, filepath.Join(dir, "key")); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	// Swapping the file after it was read must not change what was verified
+	_ = os.WriteFile(manifest, []byte("00000000000000000000000000000000  a\n"), 0600)
+	if _, err := verifyDetached(manifest, content, filepath.Join(dir, "key.pub")); err != nil {
+		t.Errorf("Expected signature to verify got %s", err.Error())
+	}
+	if _, err := verifyDetached(manifest, []byte("00000000000000000000000000000000  a\n"), filepath.Join(dir, "key.pub")); err == nil {
+		t.Error("Expected tampered content to fail verification")
+	}
+}
+
+func TestParseEncryptedSecretKey(t *testing.T) {
+	secret, _ := makeMinisignKeys(t, "hunter2")
+
+	if _, err := parseMinisignSecretKey(secret, ""); err == nil {
+		t.Error("Expected error without a password")
+	}
+	if _, err := parseMinisignSecretKey(secret, "wrong"); err == nil {
+		t.Error("Expected checksum error with the wrong password")
+	}
+	if _, err := parseMinisignSecretKey(secret, "hunter2"); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+}
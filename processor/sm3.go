@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// SM3 as specified in GB/T 32905-2016, implemented here as the only Go modules for it
+// bring in the whole of the Chinese national cryptography suite.
+
+const (
+	sm3Size      = 32
+	sm3BlockSize = 64
+)
+
+var sm3IV = [8]uint32{0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600, 0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e}
+
+type sm3Digest struct {
+	h      [8]uint32
+	buf    [sm3BlockSize]byte
+	nx     int
+	length uint64
+}
+
+func newSM3() hash.Hash {
+	d := &sm3Digest{}
+	d.Reset()
+	return d
+}
+
+func (d *sm3Digest) Reset() {
+	d.h = sm3IV
+	d.nx = 0
+	d.length = 0
+}
+
+func (d *sm3Digest) Size() int { return sm3Size }
+
+func (d *sm3Digest) BlockSize() int { return sm3BlockSize }
+
+func sm3P0(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 9) ^ bits.RotateLeft32(x, 17) }
+
+func sm3P1(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) }
+
+func (d *sm3Digest) block(p []byte) {
+	var w [68]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	for j := 16; j < 68; j++ {
+		w[j] = sm3P1(w[j-16]^w[j-9]^bits.RotateLeft32(w[j-3], 15)) ^ bits.RotateLeft32(w[j-13], 7) ^ w[j-6]
+	}
+
+	a, b, c, dd, e, f, g, h := d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7]
+	for j := 0; j < 64; j++ {
+		var t, ff, gg uint32
+		if j < 16 {
+			t = 0x79cc4519
+			ff = a ^ b ^ c
+			gg = e ^ f ^ g
+		} else {
+			t = 0x7a879d8a
+			ff = (a & b) | (a & c) | (b & c)
+			gg = (e & f) | (^e & g)
+		}
+
+		ss1 := bits.RotateLeft32(bits.RotateLeft32(a, 12)+e+bits.RotateLeft32(t, j%32), 7)
+		ss2 := ss1 ^ bits.RotateLeft32(a, 12)
+		tt1 := ff + dd + ss2 + (w[j] ^ w[j+4])
+		tt2 := gg + h + ss1 + w[j]
+
+		dd, c, b, a = c, bits.RotateLeft32(b, 9), a, tt1
+		h, g, f, e = g, bits.RotateLeft32(f, 19), e, sm3P0(tt2)
+	}
+
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
+
+func (d *sm3Digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.length += uint64(n)
+
+	if d.nx > 0 {
+		c := copy(d.buf[d.nx:], p)
+		d.nx += c
+		p = p[c:]
+		if d.nx == sm3BlockSize {
+			d.block(d.buf[:])
+			d.nx = 0
+		}
+	}
+
+	for len(p) >= sm3BlockSize {
+		d.block(p[:sm3BlockSize])
+		p = p[sm3BlockSize:]
+	}
+
+	if len(p) > 0 {
+		d.nx = copy(d.buf[:], p)
+	}
+	return n, nil
+}
+
+func (d *sm3Digest) Sum(in []byte) []byte {
+	// Work on a copy so the caller can keep writing
+	c := *d
+
+	// Pad with a one bit then zeros until 8 bytes remain for the 64 bit length
+	var pad [sm3BlockSize * 2]byte
+	pad[0] = 0x80
+	padLen := sm3BlockSize - 8 - c.nx
+	if padLen <= 0 {
+		padLen += sm3BlockSize
+	}
+
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], c.length<<3)
+
+	_, _ = c.Write(pad[:padLen])
+	_, _ = c.Write(length[:])
+
+	out := make([]byte, sm3Size)
+	for i := 0; i < 8; i++ {
+		binary.BigEndian.PutUint32(out[i*4:], c.h[i])
+	}
+	return append(in, out...)
+}
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSM3(t *testing.T) {
+	var cases = []struct {
+		input    string
+		expected string
+	}{
+		{"", "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+		{strings.Repeat("a", 1000), "f4bedca973227d45c5b822551d2e762d4cfb0e9af70b241452545727b5fb046f"},
+	}
+
+	for _, c := range cases {
+		d := newSM3()
+		d.Write([]byte(c.input))
+		res := hex.EncodeToString(d.Sum(nil))
+
+		if res != c.expected {
+			t.Errorf("Expected %s got %s", c.expected, res)
+		}
+
+		// Writing in pieces must give the same result as a single write
+		split := newSM3()
+		for _, b := range []byte(c.input) {
+			split.Write([]byte{b})
+		}
+		if hex.EncodeToString(split.Sum(nil)) != res {
+			t.Errorf("Expected split writes to match for input of length %d", len(c.input))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"io"
+)
+
+// A region of a file which contains data, anything between regions is a hole
+type sparseRegion struct {
+	start int64
+	end   int64
+}
+
+// Reads a sparse file returning zeros for holes without touching the disk so
+// thin provisioned images are only read where they contain data
+type sparseReader struct {
+	file    io.ReaderAt
+	size    int64
+	offset  int64
+	regions []sparseRegion
+	index   int
+}
+
+func newSparseReader(file io.ReaderAt, size int64, regions []sparseRegion) *sparseReader {
+	return &sparseReader{
+		file:    file,
+		size:    size,
+		regions: regions,
+	}
+}
+
+func (s *sparseReader) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+
+	for s.index < len(s.regions) && s.regions[s.index].end <= s.offset {
+		s.index++
+	}
+
+	if remaining := s.size - s.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	// Inside a hole so fill with zeros up to the start of the next region
+	if s.index >= len(s.regions) || s.offset < s.regions[s.index].start {
+		holeEnd := s.size
+		if s.index < len(s.regions) {
+			holeEnd = s.regions[s.index].start
+		}
+
+		n := int64(len(p))
+		if holeEnd-s.offset < n {
+			n = holeEnd - s.offset
+		}
+		clear(p[:n])
+		s.offset += n
+		return int(n), nil
+	}
+
+	n := int64(len(p))
+	if s.regions[s.index].end-s.offset < n {
+		n = s.regions[s.index].end - s.offset
+	}
+
+	read, err := s.file.ReadAt(p[:n], s.offset)
+	s.offset += int64(read)
+	if err == io.EOF && read > 0 {
+		err = nil
+	}
+	return read, err
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd)
+
+package processor
+
+import (
+	"errors"
+	"os"
+)
+
+func physicalSize(fi os.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+func dataRegions(file *os.File, size int64) ([]sparseRegion, error) {
+	return nil, errors.New("sparse file detection is not supported on this platform")
+}
@@ -0,0 +1,25 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSparseReaderFillsHolesWithZeros(t *testing.T) {
+	content := []byte("abc\x00\x00\x00\x00def\x00\x00")
+	regions := []sparseRegion{{start: 0, end: 3}, {start: 7, end: 10}}
+
+	// Poison the holes in the backing data to prove they are never read
+	backing := []byte("abcXXXXdefYY")
+	r := newSparseReader(bytes.NewReader(backing), int64(len(content)), regions)
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	if !bytes.Equal(out, content) {
+		t.Errorf("Expected %q got %q", content, out)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin || freebsd
+
+package processor
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns the number of bytes actually allocated on disk for the file
+func physicalSize(fi os.FileInfo) (int64, bool) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(stat.Blocks) * 512, true
+}
+
+// Uses SEEK_DATA and SEEK_HOLE to find the parts of the file which contain data
+func dataRegions(file *os.File, size int64) ([]sparseRegion, error) {
+	fd := int(file.Fd())
+	regions := []sparseRegion{}
+
+	var offset int64
+	for offset < size {
+		start, err := unix.Seek(fd, offset, unix.SEEK_DATA)
+		if err != nil {
+			// No more data past the offset so the rest of the file is a hole
+			if err == unix.ENXIO {
+				break
+			}
+			return nil, err
+		}
+
+		end, err := unix.Seek(fd, start, unix.SEEK_HOLE)
+		if err != nil {
+			return nil, err
+		}
+
+		regions = append(regions, sparseRegion{start: start, end: end})
+		offset = end
+	}
+
+	return regions, nil
+}
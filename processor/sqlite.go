@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+// Prefix of --output values naming a SQLite database rather than a file
+const sqliteScheme = "sqlite://"
+
+// The database results are written into when --output is sqlite://path
+var databaseOutput = ""
+
+// Takes the database out of FileOutput as results are not formatted into a file
+func prepareDatabaseOutput() error {
+	if !strings.HasPrefix(FileOutput, sqliteScheme) {
+		return nil
+	}
+
+	path := strings.TrimPrefix(FileOutput, sqliteScheme)
+	switch {
+	case path == "":
+		return errors.New("--output sqlite:// needs a database file such as sqlite://scan.db")
+	case Compress != "", len(Encrypt) != 0, SignKey != "", Append, TeeOutput, PerRootOutput != "", ListOnly:
+		return errors.New("--output sqlite:// cannot be combined with --compress, --encrypt, --sign, --append, --tee, --per-root-output or --list-only")
+	}
+
+	databaseOutput = path
+	FileOutput = ""
+	return nil
+}
+
+// Quotes a column name as custom hashes may be named anything
+func sqliteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Every hash gets a column whether or not this run selected it so databases written by
+// runs with different hashes can be queried the same way
+func sqliteDigestColumns() []string {
+	columns := []string{}
+	for _, info := range HashInfos {
+		columns = append(columns, info.Name)
+	}
+	for _, info := range selectedCustomHashes() {
+		columns = append(columns, info.Name)
+	}
+	return columns
+}
+
+// Creates the tables and indexes, adding the columns of hashes which did not exist when
+// the database was created
+func createSQLiteSchema(db *sql.DB, digests []string) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS scans (id INTEGER PRIMARY KEY, started TEXT, host TEXT, directory TEXT, version TEXT, command TEXT)`,
+		`CREATE TABLE IF NOT EXISTS files (path TEXT PRIMARY KEY, bytes INTEGER, mtime TEXT, error TEXT, scan INTEGER REFERENCES scans(id))`,
+		`CREATE INDEX IF NOT EXISTS files_bytes ON files (bytes)`,
+		`CREATE INDEX IF NOT EXISTS files_scan ON files (scan)`,
+	}
+	for _, s := range statements {
+		if _, err := db.Exec(s); err != nil {
+			return err
+		}
+	}
+
+	if err := addSQLiteColumns(db, "scans", []string{"scan_id"}); err != nil {
+		return err
+	}
+	if err := addSQLiteColumns(db, "files", append([]string{"scan_id"}, digests...)); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS files_scan_id ON files (scan_id)`); err != nil {
+		return err
+	}
+
+	if err := dropPartialIndexes(db); err != nil {
+		return err
+	}
+	for _, d := range digests {
+		index := sqliteIdentifier("files_" + d)
+		if _, err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON files (%s)`, index, sqliteIdentifier(d))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Databases from before scan ids had partial digest indexes which the embedded SQLite
+// crashes on when replacing rows, they are dropped so plain ones are created instead
+func dropPartialIndexes(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'files' AND sql LIKE '% WHERE %'`)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	_ = rows.Close()
+
+	for _, name := range names {
+		if _, err := db.Exec(`DROP INDEX ` + sqliteIdentifier(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds any of the text columns missing from a table created by an older version
+func addSQLiteColumns(db *sql.DB, table string, columns []string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	_ = rows.Close()
+
+	for _, c := range columns {
+		if existing[c] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s TEXT`, table, sqliteIdentifier(c))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Records the run in the scans table returning its id
+func insertSQLiteScan(tx *sql.Tx) (int64, error) {
+	p := scanProvenance()
+	res, err := tx.Exec(`INSERT INTO scans (scan_id, started, host, directory, version, command) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.ScanID, p.Started.Format(time.RFC3339Nano), p.Host, p.Directory, p.Version, strings.Join(p.Command, " "))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Upserts every result into the database at path keyed by the file path so the table
+// holds the latest record of every file seen by any run, returning how many were written.
+// Digests this run did not calculate are cleared as they may no longer match the content.
+func writeSQLite(path string, input chan Result) (int, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	digests := sqliteDigestColumns()
+	if err := createSQLiteSchema(db, digests); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	scan, err := insertSQLiteScan(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	columns := []string{"path", "bytes", "mtime", "error", "scan", "scan_id"}
+	for _, d := range digests {
+		columns = append(columns, sqliteIdentifier(d))
+	}
+	// Every column is written so replacing the row is the same as updating it
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT OR REPLACE INTO files (%s) VALUES (%s)`,
+		strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	count := 0
+	for res := range input {
+		values := []any{res.File, res.Bytes, nil, nil, scan, nil}
+		if res.MTime != nil && !res.MTime.IsZero() {
+			values[2] = res.MTime.Format(time.RFC3339Nano)
+		}
+		if res.Error != "" {
+			values[3] = res.Error
+		}
+		if res.ScanID != "" {
+			values[5] = res.ScanID
+		}
+
+		hashes := calculatedHashes(res)
+		for _, d := range digests {
+			if digest := hashes[d]; digest != "" {
+				values = append(values, digest)
+			} else {
+				values = append(values, nil)
+			}
+		}
+
+		if _, err := stmt.Exec(values...); err != nil {
+			return count, fmt.Errorf("unable to record %s: %w", res.File, err)
+		}
+		count++
+	}
+
+	return count, tx.Commit()
+}
+
+// Writes the results into databaseOutput exiting when the database cannot be written
+func writeDatabaseOutput(input chan Result) {
+	count, err := writeSQLite(databaseOutput, input)
+	if err != nil {
+		printError(fmt.Sprintf("unable to write database %s: %s", databaseOutput, err.Error()))
+		os.Exit(1)
+	}
+	fmt.Printf("%d records written to %s\n", count, databaseOutput)
+}
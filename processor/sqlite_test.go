@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSQLiteUpserts(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	path := filepath.Join(t.TempDir(), "scan.db")
+
+	Hash = []string{HashNames.MD5}
+	first := make(chan Result, 2)
+	first <- Result{File: "a", Bytes: 5, MD5: "5d41402abc4b2a76b9719d911017c592"}
+	first <- Result{File: "b", Error: "permission denied"}
+	close(first)
+	if count, err := writeSQLite(path, first); err != nil || count != 2 {
+		t.Fatalf("expected 2 records got %d %v", count, err)
+	}
+
+	Hash = []string{HashNames.SHA256}
+	second := make(chan Result, 1)
+	second <- Result{File: "a", Bytes: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+	close(second)
+	if _, err := writeSQLite(path, second); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var scans int
+	if err := db.QueryRow(`SELECT count(*) FROM scans`).Scan(&scans); err != nil || scans != 2 {
+		t.Errorf("expected 2 scans got %d %v", scans, err)
+	}
+
+	var md5, sha256 sql.NullString
+	var scan int64
+	if err := db.QueryRow(`SELECT md5, sha256, scan FROM files WHERE path = 'a'`).Scan(&md5, &sha256, &scan); err != nil {
+		t.Fatal(err)
+	}
+	if md5.Valid || sha256.String != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" || scan != 2 {
+		t.Errorf("expected the second run to replace the record got md5 %v sha256 %v scan %d", md5, sha256, scan)
+	}
+
+	var failure string
+	if err := db.QueryRow(`SELECT error FROM files WHERE path = 'b'`).Scan(&failure); err != nil || failure != "permission denied" {
+		t.Errorf("expected the error to be kept got %q %v", failure, err)
+	}
+}
+
+func TestPrepareDatabaseOutput(t *testing.T) {
+	defer func() { FileOutput, databaseOutput, Compress = "", "", "" }()
+
+	FileOutput = "out.txt"
+	if err := prepareDatabaseOutput(); err != nil || databaseOutput != "" || FileOutput != "out.txt" {
+		t.Errorf("expected a plain file to be left alone got %q %q %v", databaseOutput, FileOutput, err)
+	}
+
+	FileOutput = "sqlite://scan.db"
+	if err := prepareDatabaseOutput(); err != nil || databaseOutput != "scan.db" || FileOutput != "" {
+		t.Errorf("expected the database to be taken from --output got %q %q %v", databaseOutput, FileOutput, err)
+	}
+
+	for _, invalid := range []struct{ output, compress string }{{"sqlite://", ""}, {"sqlite://scan.db", "gzip"}} {
+		FileOutput, Compress = invalid.output, invalid.compress
+		if prepareDatabaseOutput() == nil {
+			t.Errorf("expected error for %s with --compress %q", invalid.output, invalid.compress)
+		}
+	}
+}
+
+func TestSQLiteIdentifier(t *testing.T) {
+	if got := sqliteIdentifier(`my"hash`); got != `"my""hash"` {
+		t.Errorf("expected quotes to be doubled got %s", got)
+	}
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// The hashes browsers accept in a subresource integrity value
+func sriHashes() []string {
+	names := []string{}
+	for _, name := range []string{HashNames.SHA256, HashNames.Sha384, HashNames.SHA512} {
+		if hasHash(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Check the options can produce integrity values when the sri formats are selected
+func validateSRI() error {
+	switch strings.ToLower(Format) {
+	case "sri", "sri-json":
+	default:
+		return nil
+	}
+
+	if len(sriHashes()) == 0 {
+		return errors.New("--format sri requires one of the sha256, sha384 or sha512 hashes")
+	}
+	if Encoding != "" && Encoding != "hex" {
+		return errors.New("--format sri always encodes digests as base64 so cannot be combined with --encoding")
+	}
+	return nil
+}
+
+// Builds the integrity attribute for a result such as sha384-<base64>, several are
+// separated by spaces so browsers pick the strongest
+func sriIntegrity(res Result) string {
+	digests := map[string]string{
+		HashNames.SHA256: res.SHA256,
+		HashNames.Sha384: res.Sha384,
+		HashNames.SHA512: res.SHA512,
+	}
+
+	values := []string{}
+	for _, name := range sriHashes() {
+		raw, err := hex.DecodeString(digests[name])
+		if err != nil || len(raw) == 0 {
+			continue
+		}
+		values = append(values, name+"-"+base64.StdEncoding.EncodeToString(raw))
+	}
+	return strings.Join(values, " ")
+}
+
+// One line per file of the integrity value followed by the file like the sum format
+func toSRI(input chan Result) string {
+	var str strings.Builder
+	printed := 0
+
+	for res := range input {
+		// Errors have already been reported on stderr
+		if res.Error != "" {
+			continue
+		}
+
+		str.WriteString(sriIntegrity(res) + "  " + res.File + "\n")
+		streamOutput(&str, &printed)
+	}
+
+	return str.String()
+}
+
+// A JSON object mapping each file to its integrity value
+func toSRIJSON(input chan Result) string {
+	integrity := map[string]string{}
+	for res := range input {
+		if res.Error != "" {
+			continue
+		}
+		integrity[res.File] = sriIntegrity(res)
+	}
+
+	jsonString, _ := json.Marshal(integrity)
+	return string(jsonString)
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSRIIntegrity(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.MD5, HashNames.SHA256, HashNames.Sha384}
+
+	res := Result{
+		File:   "app.js",
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		Sha384: "59e1748777448c69de6b800d7a33bbfb9ff1b463e44354c3553bcdb9c666fa90125a3c79f90397bdf5f6a13de828684f",
+	}
+
+	expected := "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ= sha384-WeF0h3dEjGnea4ANejO7+5/xtGPkQ1TDVTvNucZm+pASWjx5+QOXvfX2oT3oKGhP"
+	if got := sriIntegrity(res); got != expected {
+		t.Errorf("Expected %s got %s", expected, got)
+	}
+
+	input := make(chan Result, 2)
+	input <- res
+	input <- Result{File: "missing", Error: "no such file"}
+	close(input)
+
+	NoStream = true
+	defer func() { NoStream = false }()
+	if got := toSRI(input); got != expected+"  app.js\n" {
+		t.Errorf("Unexpected sri output %q", got)
+	}
+}
+
+func TestSRIJSON(t *testing.T) {
+	previous := Hash
+	defer func() { Hash = previous }()
+	Hash = []string{HashNames.SHA256}
+
+	input := make(chan Result, 1)
+	input <- Result{File: "app.js", SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+	close(input)
+
+	integrity := map[string]string{}
+	if err := json.Unmarshal([]byte(toSRIJSON(input)), &integrity); err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if integrity["app.js"] != "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+		t.Errorf("Unexpected integrity %v", integrity)
+	}
+}
+
+func TestValidateSRI(t *testing.T) {
+	previousHash, previousFormat, previousEncoding := Hash, Format, Encoding
+	defer func() { Hash, Format, Encoding = previousHash, previousFormat, previousEncoding }()
+
+	Format = "sri"
+	Hash = []string{HashNames.MD5}
+	if err := validateSRI(); err == nil {
+		t.Error("Expected error without a sha2 hash")
+	}
+
+	Hash = []string{HashNames.SHA512}
+	if err := validateSRI(); err != nil {
+		t.Errorf("Unexpected error %s", err.Error())
+	}
+
+	Encoding = "base32"
+	if err := validateSRI(); err == nil {
+		t.Error("Expected error with another encoding")
+	}
+}
@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"hash"
+	"strconv"
+)
+
+// ssdeep context triggered piecewise hashing as implemented by libfuzzy. Every candidate
+// block size is tracked at once so content can be streamed without knowing its length,
+// with the smallest block sizes dropped as soon as the data outgrows them. Sum returns
+// the digest as the usual blocksize:hash:hash text rather than binary.
+
+const (
+	ssdeepRollingWindow  = 7
+	ssdeepMinBlockSize   = 3
+	ssdeepHashPrime      = 0x01000193
+	ssdeepHashInit       = 0x28021967
+	ssdeepNumBlockHashes = 31
+	ssdeepSpamSumLength  = 64
+	ssdeepBase64         = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+type ssdeepRoll struct {
+	window     [ssdeepRollingWindow]byte
+	h1, h2, h3 uint32
+	n          uint32
+}
+
+func (r *ssdeepRoll) hash(c byte) {
+	r.h2 -= r.h1
+	r.h2 += ssdeepRollingWindow * uint32(c)
+
+	r.h1 += uint32(c)
+	r.h1 -= uint32(r.window[r.n%ssdeepRollingWindow])
+
+	r.window[r.n%ssdeepRollingWindow] = c
+	r.n++
+
+	r.h3 <<= 5
+	r.h3 ^= uint32(c)
+}
+
+func (r *ssdeepRoll) sum() uint32 {
+	return r.h1 + r.h2 + r.h3
+}
+
+type ssdeepBlockHash struct {
+	h, halfh   uint32
+	digest     [ssdeepSpamSumLength]byte
+	halfdigest byte
+	dlen       int
+}
+
+type ssdeepDigest struct {
+	bhstart, bhend int
+	bh             [ssdeepNumBlockHashes]ssdeepBlockHash
+	total          uint64
+	roll           ssdeepRoll
+}
+
+func newSsdeep() hash.Hash {
+	d := &ssdeepDigest{}
+	d.Reset()
+	return d
+}
+
+func ssdeepBlockSize(index int) uint32 {
+	return ssdeepMinBlockSize << index
+}
+
+func (d *ssdeepDigest) Reset() {
+	*d = ssdeepDigest{bhend: 1}
+	d.bh[0].h = ssdeepHashInit
+	d.bh[0].halfh = ssdeepHashInit
+}
+
+// Digests are at most two 64 character hashes and the block size
+func (d *ssdeepDigest) Size() int { return 148 }
+
+func (d *ssdeepDigest) BlockSize() int { return 1 }
+
+// Starts tracking the next block size from the state of the largest one
+func (d *ssdeepDigest) fork() {
+	if d.bhend >= ssdeepNumBlockHashes {
+		return
+	}
+	prev := &d.bh[d.bhend-1]
+	d.bh[d.bhend] = ssdeepBlockHash{h: prev.h, halfh: prev.halfh}
+	d.bhend++
+}
+
+// Stops tracking the smallest block size once the data is clearly too long for it
+func (d *ssdeepDigest) reduce() {
+	if d.bhend-d.bhstart < 2 {
+		return
+	}
+	if uint64(ssdeepBlockSize(d.bhstart))*ssdeepSpamSumLength >= d.total {
+		return
+	}
+	if d.bh[d.bhstart+1].dlen < ssdeepSpamSumLength/2 {
+		return
+	}
+	d.bhstart++
+}
+
+func (d *ssdeepDigest) step(c byte) {
+	d.roll.hash(c)
+	h := d.roll.sum()
+
+	for i := d.bhstart; i < d.bhend; i++ {
+		d.bh[i].h = d.bh[i].h*ssdeepHashPrime ^ uint32(c)
+		d.bh[i].halfh = d.bh[i].halfh*ssdeepHashPrime ^ uint32(c)
+	}
+
+	for i := d.bhstart; i < d.bhend; i++ {
+		// A trigger for a block size is also one for every smaller size
+		bs := ssdeepBlockSize(i)
+		if h%bs != bs-1 {
+			break
+		}
+
+		b := &d.bh[i]
+		if b.dlen == 0 {
+			d.fork()
+		}
+		b.digest[b.dlen] = ssdeepBase64[b.h%64]
+		b.halfdigest = ssdeepBase64[b.halfh%64]
+		if b.dlen < ssdeepSpamSumLength-1 {
+			// The last character keeps being overwritten once the digest is full
+			b.dlen++
+			b.digest[b.dlen] = 0
+			b.h = ssdeepHashInit
+			if b.dlen < ssdeepSpamSumLength/2 {
+				b.halfh = ssdeepHashInit
+				b.halfdigest = 0
+			}
+		} else {
+			d.reduce()
+		}
+	}
+}
+
+func (d *ssdeepDigest) Write(p []byte) (int, error) {
+	d.total += uint64(len(p))
+	for _, c := range p {
+		d.step(c)
+	}
+	return len(p), nil
+}
+
+func (d *ssdeepDigest) Sum(in []byte) []byte {
+	bi := d.bhstart
+	h := d.roll.sum()
+
+	// Start from the block size the length suggests then settle on one that produced
+	// at least half a digest
+	for uint64(ssdeepBlockSize(bi))*ssdeepSpamSumLength < d.total && bi < ssdeepNumBlockHashes-1 {
+		bi++
+	}
+	for bi >= d.bhend {
+		bi--
+	}
+	for bi > d.bhstart && d.bh[bi].dlen < ssdeepSpamSumLength/2 {
+		bi--
+	}
+
+	out := strconv.AppendUint(in, uint64(ssdeepBlockSize(bi)), 10)
+	out = append(out, ':')
+
+	b := &d.bh[bi]
+	out = append(out, b.digest[:b.dlen]...)
+	if h != 0 {
+		out = append(out, ssdeepBase64[b.h%64])
+	} else if b.digest[b.dlen] != 0 {
+		out = append(out, b.digest[b.dlen])
+	}
+	out = append(out, ':')
+
+	if bi < d.bhend-1 {
+		b = &d.bh[bi+1]
+		n := b.dlen
+		if n > ssdeepSpamSumLength/2-1 {
+			n = ssdeepSpamSumLength/2 - 1
+		}
+		out = append(out, b.digest[:n]...)
+		if h != 0 {
+			out = append(out, ssdeepBase64[b.halfh%64])
+		} else if b.halfdigest != 0 {
+			out = append(out, b.halfdigest)
+		}
+	} else if h != 0 {
+		out = append(out, ssdeepBase64[b.h%64])
+	}
+	return out
+}
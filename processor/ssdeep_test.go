@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Alternating runs of letters and random bytes, or zeros in place of the letters for odd
+// seeds, so every block size and the empty rolling hash case are exercised
+func ssdeepTestData(seed int64, size int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	b := make([]byte, size)
+	for i := range b {
+		if (i/1000)%2 == 0 {
+			b[i] = byte('a' + r.Intn(26))
+			if seed%2 == 1 {
+				b[i] = 0
+			}
+		} else {
+			b[i] = byte(r.Intn(256))
+		}
+	}
+	return b
+}
+
+func TestSsdeep(t *testing.T) {
+	var cases = []struct {
+		input    []byte
+		expected string
+	}{
+		{nil, "3::"},
+		{[]byte("a"), "3:E:E"},
+		{make([]byte, 10), "3::"},
+		{ssdeepTestData(2, 100), "3:JMWFPXX4hNXPl1qyoDdnRBesIKrXdyWL3n:Dsl1DednrbXH"},
+		{ssdeepTestData(5, 10000), "96:+LbcGyUJtNoPbIpMphWfpoE1lkWyaW7jU0HeQvOANQBafIzMDtLgNNi:ecGyi4bNhWfu0lDyaWNHDSBafhOi"},
+		{ssdeepTestData(8, 1<<20), "24576:iDPs/rrdN91VuUtRlKUAniuYvffFK4hZjftbT0IZT5jM:iDPO1VuEXuYvffFhZDt52"},
+	}
+
+	for _, c := range cases {
+		d := newSsdeep()
+		d.Write(c.input)
+		if res := string(d.Sum(nil)); res != c.expected {
+			t.Errorf("Expected %s got %s", c.expected, res)
+		}
+
+		// Writing in pieces must give the same result as a single write
+		split := newSsdeep()
+		split.Write(c.input[:len(c.input)/3])
+		split.Write(c.input[len(c.input)/3:])
+		if res := string(split.Sum(nil)); res != c.expected {
+			t.Errorf("Expected %s got %s when split", c.expected, res)
+		}
+	}
+}
+
+func TestSsdeepSimilarContent(t *testing.T) {
+	a := ssdeepTestData(8, 200000)
+	b := append([]byte{}, a...)
+	copy(b[100000:], strings.Repeat("x", 500))
+
+	da, db := newSsdeep(), newSsdeep()
+	da.Write(a)
+	db.Write(b)
+
+	// Only the pieces around the change should differ
+	ha, hb := strings.Split(string(da.Sum(nil)), ":"), strings.Split(string(db.Sum(nil)), ":")
+	if ha[0] != hb[0] || ha[1] == hb[1] {
+		t.Fatalf("expected same block size and a changed digest got %v %v", ha, hb)
+	}
+	if ha[1][:10] != hb[1][:10] {
+		t.Errorf("expected the start of the digests to match got %s %s", ha[1], hb[1])
+	}
+}
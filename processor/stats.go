@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var statsMutex sync.Mutex
+var statsStart time.Time
+var hashTimes = map[string]*hashTime{}
+var workerTimes = map[int]*workerTime{}
+
+type hashTime struct {
+	bytes int64
+	cpu   time.Duration
+}
+
+type workerTime struct {
+	files int64
+	bytes int64
+	busy  time.Duration
+}
+
+// Clears anything recorded by a previous run and starts the wall clock
+func resetStats() {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	statsStart = time.Now()
+	hashTimes = map[string]*hashTime{}
+	workerTimes = map[int]*workerTime{}
+	resetQueueStats()
+}
+
+// Adds time spent by one algorithm hashing size bytes
+func recordHashTime(name string, size int, elapsed time.Duration) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	h, ok := hashTimes[name]
+	if !ok {
+		h = &hashTime{}
+		hashTimes[name] = h
+	}
+	h.bytes += int64(size)
+	h.cpu += elapsed
+}
+
+// Adds a finished file to the worker which produced it
+func recordWorkerResult(worker int, r Result, busy time.Duration) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	w, ok := workerTimes[worker]
+	if !ok {
+		w = &workerTime{}
+		workerTimes[worker] = w
+	}
+	w.files++
+	w.bytes += r.Bytes
+	w.busy += busy
+}
+
+// Measures every write into the wrapped hash
+type timedHash struct {
+	hash.Hash
+	name string
+}
+
+func (t *timedHash) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.Hash.Write(p)
+	recordHashTime(t.name, n, time.Since(start))
+	return n, err
+}
+
+// Wraps the hash so its time is recorded when --stats is set, otherwise returns it unchanged
+func timeHash(name string, h hash.Hash) hash.Hash {
+	if !Stats {
+		return h
+	}
+	return &timedHash{Hash: h, name: name}
+}
+
+func perSecond(value float64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return value / seconds
+}
+
+// Totals everything recorded since resetStats
+func collectStats() ScanStats {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	stats := ScanStats{ScanID: scanID, WallSeconds: time.Since(statsStart).Seconds()}
+
+	for _, info := range HashInfos {
+		h, ok := hashTimes[info.Name]
+		if !ok || h.bytes == 0 {
+			continue
+		}
+		stats.Hashes = append(stats.Hashes, HashStats{
+			Algorithm:  info.Name,
+			Bytes:      h.bytes,
+			CPUSeconds: h.cpu.Seconds(),
+			MBPerSec:   perSecond(float64(h.bytes)/1_000_000, h.cpu.Seconds()),
+		})
+	}
+
+	for worker, w := range workerTimes {
+		stats.Files += w.files
+		stats.Bytes += w.bytes
+		stats.Workers = append(stats.Workers, WorkerStats{
+			Worker:      worker,
+			Files:       w.files,
+			Bytes:       w.bytes,
+			BusySeconds: w.busy.Seconds(),
+			FilesPerSec: perSecond(float64(w.files), w.busy.Seconds()),
+			MBPerSec:    perSecond(float64(w.bytes)/1_000_000, w.busy.Seconds()),
+		})
+	}
+	sort.Slice(stats.Workers, func(i, j int) bool {
+		return stats.Workers[i].Worker < stats.Workers[j].Worker
+	})
+
+	stats.FilesPerSec = perSecond(float64(stats.Files), stats.WallSeconds)
+	stats.MBPerSec = perSecond(float64(stats.Bytes)/1_000_000, stats.WallSeconds)
+	stats.Queue = collectQueueStats()
+	return stats
+}
+
+// Renders the statistics as a small table for the terminal
+func formatStats(stats ScanStats) string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("files %d bytes %d wall %.3fs %.1f files/s %.2f MB/s\n", stats.Files, stats.Bytes, stats.WallSeconds, stats.FilesPerSec, stats.MBPerSec))
+
+	if len(stats.Hashes) != 0 {
+		str.WriteString(fmt.Sprintf("%-12s %14s %10s %10s\n", "algorithm", "bytes", "cpu s", "MB/s"))
+		for _, h := range stats.Hashes {
+			str.WriteString(fmt.Sprintf("%-12s %14d %10.3f %10.2f\n", h.Algorithm, h.Bytes, h.CPUSeconds, h.MBPerSec))
+		}
+	}
+
+	if len(stats.Workers) != 0 {
+		str.WriteString(fmt.Sprintf("%-12s %8s %14s %10s %10s %10s\n", "worker", "files", "bytes", "busy s", "files/s", "MB/s"))
+		for _, w := range stats.Workers {
+			str.WriteString(fmt.Sprintf("%-12d %8d %14d %10.3f %10.1f %10.2f\n", w.Worker, w.Files, w.Bytes, w.BusySeconds, w.FilesPerSec, w.MBPerSec))
+		}
+	}
+
+	if q := stats.Queue; q != nil {
+		str.WriteString(fmt.Sprintf("queue limit %d to %d max %d depth max %d avg %.1f bytes max %d grows %d shrinks %d\n", q.InitialLimit, q.FinalLimit, q.MaxLimit, q.MaxDepth, q.AverageDepth, q.MaxQueuedBytes, q.Grows, q.Shrinks))
+		str.WriteString(fmt.Sprintf("queue stalls walker %d workers %d memory %d\n", q.WalkerStalls, q.WorkerStalls, q.BudgetStalls))
+	}
+	if stats.ScanID != "" {
+		str.WriteString(fmt.Sprintf("scan %s\n", stats.ScanID))
+	}
+	return str.String()
+}
@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"crypto/md5"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeHashDisabled(t *testing.T) {
+	h := md5.New()
+	if timeHash(HashNames.MD5, h) != h {
+		t.Error("expected hash to be returned unchanged")
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	Stats = true
+	defer func() {
+		Stats = false
+		resetStats()
+	}()
+	resetStats()
+
+	h := timeHash(HashNames.MD5, md5.New())
+	_, _ = h.Write([]byte("hello"))
+	_, _ = h.Write([]byte("world"))
+	recordWorkerResult(1, Result{Bytes: 10}, time.Second)
+	recordWorkerResult(0, Result{Bytes: 5}, time.Second)
+	recordWorkerResult(1, Result{Bytes: 10}, time.Second)
+
+	stats := collectStats()
+	if stats.Files != 3 || stats.Bytes != 25 {
+		t.Error("unexpected totals", stats.Files, stats.Bytes)
+	}
+	if len(stats.Hashes) != 1 || stats.Hashes[0].Algorithm != "md5" || stats.Hashes[0].Bytes != 10 {
+		t.Error("unexpected hash stats", stats.Hashes)
+	}
+	if len(stats.Workers) != 2 || stats.Workers[0].Worker != 0 || stats.Workers[1].Files != 2 || stats.Workers[1].FilesPerSec != 1 {
+		t.Error("unexpected worker stats", stats.Workers)
+	}
+
+	out := formatStats(stats)
+	if !strings.HasPrefix(out, "files 3 bytes 25 ") || !strings.Contains(out, "md5") {
+		t.Error("unexpected output", out)
+	}
+}
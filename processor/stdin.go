@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"errors"
+	"os"
+)
+
+// Stats stdin, swapped out in tests
+var stdinStat = func() (os.FileInfo, error) { return os.Stdin.Stat() }
+
+// Decides whether content piped to stdin is hashed. Without files, --input or
+// --from-manifest stdin is read when it is not a terminal, which misfires under some CI
+// runners and Windows services where stdin is neither a terminal nor anything piped in
+// and hashit waits on it forever, so --stdin and --no-stdin settle it explicitly.
+func detectStandardInput() (bool, error) {
+	given := len(DirFilePaths) != 0 || FileInput != "" || FromManifest != ""
+	switch {
+	case ForceStdin && NoStdin:
+		return false, errors.New("--stdin and --no-stdin cannot be used together")
+	case ForceStdin && given:
+		return false, errors.New("--stdin cannot be combined with files, --input or --from-manifest")
+	case ForceStdin:
+		return true, nil
+	case NoStdin, given:
+		return false, nil
+	}
+
+	stat, err := stdinStat()
+	if err != nil {
+		// Services can run without any stdin at all
+		printDebug("unable to check stdin, scanning the current directory", "error", err.Error())
+		return false, nil
+	}
+	if stat.Mode()&os.ModeCharDevice != 0 {
+		return false, nil
+	}
+	printVerbose("stdin is not a terminal so hashing what is piped to it, use --no-stdin to scan the current directory instead")
+	return true, nil
+}
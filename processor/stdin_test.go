@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type stdinInfo struct {
+	os.FileInfo
+	mode os.FileMode
+}
+
+func (s stdinInfo) Mode() os.FileMode { return s.mode }
+
+func TestDetectStandardInput(t *testing.T) {
+	previousStat, previousPaths, previousInput := stdinStat, DirFilePaths, FileInput
+	previousManifest, previousForce, previousNo := FromManifest, ForceStdin, NoStdin
+	defer func() {
+		stdinStat, DirFilePaths, FileInput = previousStat, previousPaths, previousInput
+		FromManifest, ForceStdin, NoStdin = previousManifest, previousForce, previousNo
+	}()
+
+	terminal := func() (os.FileInfo, error) { return stdinInfo{mode: os.ModeDevice | os.ModeCharDevice}, nil }
+	piped := func() (os.FileInfo, error) { return stdinInfo{mode: os.ModeNamedPipe}, nil }
+	missing := func() (os.FileInfo, error) { return nil, errors.New("invalid handle") }
+
+	cases := []struct {
+		name     string
+		stat     func() (os.FileInfo, error)
+		paths    []string
+		input    string
+		force    bool
+		no       bool
+		expected bool
+	}{
+		{"terminal", terminal, nil, "", false, false, false},
+		{"piped", piped, nil, "", false, false, true},
+		{"no stdin handle", missing, nil, "", false, false, false},
+		{"paths given", piped, []string{"."}, "", false, false, false},
+		{"input given", piped, nil, "list.txt", false, false, false},
+		{"no-stdin", piped, nil, "", false, true, false},
+		{"stdin on a terminal", terminal, nil, "", true, false, true},
+		{"stdin without a handle", missing, nil, "", true, false, true},
+	}
+	for _, c := range cases {
+		stdinStat, DirFilePaths, FileInput, FromManifest = c.stat, c.paths, c.input, ""
+		ForceStdin, NoStdin = c.force, c.no
+		got, err := detectStandardInput()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err.Error())
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected %v got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestDetectStandardInputConflicts(t *testing.T) {
+	previousPaths, previousForce, previousNo := DirFilePaths, ForceStdin, NoStdin
+	defer func() { DirFilePaths, ForceStdin, NoStdin = previousPaths, previousForce, previousNo }()
+
+	DirFilePaths, ForceStdin, NoStdin = nil, true, true
+	if _, err := detectStandardInput(); err == nil {
+		t.Error("Expected --stdin and --no-stdin together to be refused")
+	}
+	DirFilePaths, NoStdin = []string{"."}, false
+	if _, err := detectStandardInput(); err == nil {
+		t.Error("Expected --stdin with paths to be refused")
+	}
+}
@@ -0,0 +1,235 @@
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+)
+
+// Streebog as specified in GOST R 34.11-2012 and RFC 6986. The byte arrays hold the
+// 512 bit vectors of the standard least significant byte first, which is also the order
+// message bytes are read in and digests are written out. The linear transformation is
+// built at startup as each group of eight rows of its matrix is the first row repeatedly
+// divided by x in GF(2^8), so only those first rows are kept as constants.
+
+const (
+	streebogBlockSize = 64
+)
+
+var streebogPi = [256]byte{
+	252, 238, 221, 17, 207, 110, 49, 22, 251, 196, 250, 218, 35, 197, 4, 77,
+	233, 119, 240, 219, 147, 46, 153, 186, 23, 54, 241, 187, 20, 205, 95, 193,
+	249, 24, 101, 90, 226, 92, 239, 33, 129, 28, 60, 66, 139, 1, 142, 79,
+	5, 132, 2, 174, 227, 106, 143, 160, 6, 11, 237, 152, 127, 212, 211, 31,
+	235, 52, 44, 81, 234, 200, 72, 171, 242, 42, 104, 162, 253, 58, 206, 204,
+	181, 112, 14, 86, 8, 12, 118, 18, 191, 114, 19, 71, 156, 183, 93, 135,
+	21, 161, 150, 41, 16, 123, 154, 199, 243, 145, 120, 111, 157, 158, 178, 177,
+	50, 117, 25, 61, 255, 53, 138, 126, 109, 84, 198, 128, 195, 189, 13, 87,
+	223, 245, 36, 169, 62, 168, 67, 201, 215, 121, 214, 246, 124, 34, 185, 3,
+	224, 15, 236, 222, 122, 148, 176, 188, 220, 232, 40, 80, 78, 51, 10, 74,
+	167, 151, 96, 115, 30, 0, 98, 68, 26, 184, 56, 130, 100, 159, 38, 65,
+	173, 69, 70, 146, 39, 94, 85, 47, 140, 163, 165, 125, 105, 213, 149, 59,
+	7, 88, 179, 64, 134, 172, 29, 247, 48, 55, 107, 228, 136, 217, 231, 137,
+	225, 27, 131, 73, 76, 63, 248, 254, 141, 83, 170, 144, 202, 216, 133, 97,
+	32, 113, 103, 164, 45, 43, 9, 91, 203, 155, 37, 208, 190, 229, 108, 82,
+	89, 166, 116, 210, 230, 244, 180, 192, 209, 102, 175, 194, 57, 75, 99, 182,
+}
+
+// First row of each group of eight rows of the linear transformation matrix
+var streebogARows = [8]uint64{
+	0x8e20faa72ba0b470, 0xa011d380818e8f40, 0x90dab52a387ae76f, 0x9d4df05d5f661451,
+	0x86275df09ce8aaa8, 0x456c34887a3805b9, 0xe4fa2054a80b329c, 0x70a6a56e2440598e,
+}
+
+// Round constants written most significant byte first as they appear in RFC 6986
+var streebogCHex = [12]string{
+	"b1085bda1ecadae9ebcb2f81c0657c1f2f6a76432e45d016714eb88d7585c4fc4b7ce09192676901a2422a08a460d31505767436cc744d23dd806559f2a64507",
+	"6fa3b58aa99d2f1a4fe39d460f70b5d7f3feea720a232b9861d55e0f16b501319ab5176b12d699585cb561c2db0aa7ca55dda21bd7cbcd56e679047021b19bb7",
+	"f574dcac2bce2fc70a39fc286a3d843506f15e5f529c1f8bf2ea7514b1297b7bd3e20fe490359eb1c1c93a376062db09c2b6f443867adb31991e96f50aba0ab2",
+	"ef1fdfb3e81566d2f948e1a05d71e4dd488e857e335c3c7d9d721cad685e353fa9d72c82ed03d675d8b71333935203be3453eaa193e837f1220cbebc84e3d12e",
+	"4bea6bacad4747999a3f410c6ca923637f151c1f1686104a359e35d7800fffbdbfcd1747253af5a3dfff00b723271a167a56a27ea9ea63f5601758fd7c6cfe57",
+	"ae4faeae1d3ad3d96fa4c33b7a3039c02d66c4f95142a46c187f9ab49af08ec6cffaa6b71c9ab7b40af21f66c2bec6b6bf71c57236904f35fa68407a46647d6e",
+	"f4c70e16eeaac5ec51ac86febf240954399ec6c7e6bf87c9d3473e33197a93c90992abc52d822c3706476983284a05043517454ca23c4af38886564d3a14d493",
+	"9b1f5b424d93c9a703e7aa020c6e41414eb7f8719c36de1e89b4443b4ddbc49af4892bcb929b069069d18d2bd1a5c42f36acc2355951a8d9a47f0dd4bf02e71e",
+	"378f5a541631229b944c9ad8ec165fde3a7d3a1b258942243cd955b7e00d0984800a440bdbb2ceb17b2b8a9aa6079c540e38dc92cb1f2a607261445183235adb",
+	"abbedea680056f52382ae548b2e4f3f38941e71cff8a78db1fffe18a1b3361039fe76702af69334b7a1e6c303b7652f43698fad1153bb6c374b4c7fb98459ced",
+	"7bcd9ed0efc889fb3002c6cd635afe94d8fa6bbbebab076120018021148466798a1d71efea48b9caefbacd1d7d476e98dea2594ac06fd85d6bcaa4cd81f32d1b",
+	"378ee767f11631bad21380b00449b17acda43c32bcdf1d77f82012d430219f9b5d80ef9d1891cc86e71da4aa88e12852faf417d5d9b21b9948bc924af11bd720",
+}
+
+var streebogC [12][streebogBlockSize]byte
+
+// Combines the substitution and linear transformation for each byte of a 64 bit word
+var streebogTables [8][256]uint64
+
+func init() {
+	var a [64]uint64
+	for g, row := range streebogARows {
+		for r := 0; r < 8; r++ {
+			a[g*8+r] = row
+			// Divide every byte by x modulo x^8 + x^6 + x^5 + x^4 + 1
+			var next uint64
+			for i := 0; i < 8; i++ {
+				b := byte(row >> (8 * i))
+				if b&1 != 0 {
+					b = b>>1 ^ 0x8e
+				} else {
+					b >>= 1
+				}
+				next |= uint64(b) << (8 * i)
+			}
+			row = next
+		}
+	}
+
+	// Bit 63 - i of a word selects row i
+	for j := 0; j < 8; j++ {
+		for x := 0; x < 256; x++ {
+			var v uint64
+			s := streebogPi[x]
+			for k := 0; k < 8; k++ {
+				if s>>k&1 != 0 {
+					v ^= a[63-8*j-k]
+				}
+			}
+			streebogTables[j][x] = v
+		}
+	}
+
+	for i, c := range streebogCHex {
+		b, _ := hex.DecodeString(c)
+		for j := range b {
+			streebogC[i][streebogBlockSize-1-j] = b[j]
+		}
+	}
+}
+
+// The substitution, transposition and linear transformation applied as one step
+func streebogLPS(in *[streebogBlockSize]byte) {
+	var out [8]uint64
+	for w := 0; w < 8; w++ {
+		var v uint64
+		for j := 0; j < 8; j++ {
+			v ^= streebogTables[j][in[8*j+w]]
+		}
+		out[w] = v
+	}
+	for w := 0; w < 8; w++ {
+		binary.LittleEndian.PutUint64(in[w*8:], out[w])
+	}
+}
+
+func streebogXor(dst *[streebogBlockSize]byte, a, b *[streebogBlockSize]byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
+
+// Adds b to a as 512 bit little endian integers modulo 2^512
+func streebogAdd(a *[streebogBlockSize]byte, b []byte) {
+	var carry uint16
+	for i := 0; i < streebogBlockSize; i++ {
+		carry += uint16(a[i])
+		if i < len(b) {
+			carry += uint16(b[i])
+		}
+		a[i] = byte(carry)
+		carry >>= 8
+	}
+}
+
+type streebogDigest struct {
+	size  int
+	h     [streebogBlockSize]byte
+	n     [streebogBlockSize]byte
+	sigma [streebogBlockSize]byte
+	buf   [streebogBlockSize]byte
+	nx    int
+}
+
+func newStreebog256() hash.Hash {
+	d := &streebogDigest{size: 32}
+	d.Reset()
+	return d
+}
+
+func newStreebog512() hash.Hash {
+	d := &streebogDigest{size: 64}
+	d.Reset()
+	return d
+}
+
+func (d *streebogDigest) Reset() {
+	// The 256 bit variant starts from every byte set to one and the 512 bit from zero
+	var iv byte
+	if d.size == 32 {
+		iv = 1
+	}
+	for i := range d.h {
+		d.h[i] = iv
+	}
+	d.n = [streebogBlockSize]byte{}
+	d.sigma = [streebogBlockSize]byte{}
+	d.nx = 0
+}
+
+func (d *streebogDigest) Size() int { return d.size }
+
+func (d *streebogDigest) BlockSize() int { return streebogBlockSize }
+
+// Compression function g_N(h, m)
+func (d *streebogDigest) compress(n *[streebogBlockSize]byte, m *[streebogBlockSize]byte) {
+	var k, state [streebogBlockSize]byte
+	streebogXor(&k, &d.h, n)
+	streebogLPS(&k)
+
+	streebogXor(&state, &k, m)
+	for i := 0; i < 12; i++ {
+		streebogLPS(&state)
+		streebogXor(&k, &k, &streebogC[i])
+		streebogLPS(&k)
+		streebogXor(&state, &state, &k)
+	}
+
+	streebogXor(&d.h, &d.h, &state)
+	streebogXor(&d.h, &d.h, m)
+}
+
+func (d *streebogDigest) block(m *[streebogBlockSize]byte, bits uint16) {
+	d.compress(&d.n, m)
+	streebogAdd(&d.n, []byte{byte(bits), byte(bits >> 8)})
+	streebogAdd(&d.sigma, m[:])
+}
+
+func (d *streebogDigest) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		c := copy(d.buf[d.nx:], p)
+		d.nx += c
+		p = p[c:]
+		if d.nx == streebogBlockSize {
+			d.block(&d.buf, streebogBlockSize*8)
+			d.nx = 0
+		}
+	}
+	return n, nil
+}
+
+func (d *streebogDigest) Sum(in []byte) []byte {
+	// Work on a copy so the caller can keep writing
+	c := *d
+
+	// The remaining bytes are followed by a single one bit then zeros
+	var m [streebogBlockSize]byte
+	copy(m[:], c.buf[:c.nx])
+	m[c.nx] = 1
+	c.block(&m, uint16(c.nx*8))
+
+	var zero [streebogBlockSize]byte
+	c.compress(&zero, &c.n)
+	c.compress(&zero, &c.sigma)
+
+	// The 256 bit digest is the most significant half
+	return append(in, c.h[streebogBlockSize-c.size:]...)
+}
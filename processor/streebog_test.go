@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"encoding/hex"
+	"hash"
+	"strings"
+	"testing"
+)
+
+func TestStreebog(t *testing.T) {
+	// M1 and M2 from RFC 6986 with the digests in the byte order they are output
+	m1 := "012345678901234567890123456789012345678901234567890123456789012"
+	m2, _ := hex.DecodeString("d1e520e2e5f2f0e82c20d1f2f0e8e1eee6e820e2edf3f6e82c20e2e5fef2fa20f120eceef0ff20f1f2f0e5ebe0ece820ede020f5f0e0e1f0fbff20efebfaeafb20c8e3eef0e5e2fb")
+
+	var cases = []struct {
+		newHash  func() hash.Hash
+		input    string
+		expected string
+	}{
+		{newStreebog256, "", "3f539a213e97c802cc229d474c6aa32a825a360b2a933a949fd925208d9ce1bb"},
+		{newStreebog512, "", "8e945da209aa869f0455928529bcae4679e9873ab707b55315f56ceb98bef0a7362f715528356ee83cda5f2aac4c6ad2ba3a715c1bcd81cb8e9f90bf4c1c1a8a"},
+		{newStreebog256, m1, "9d151eefd8590b89daa6ba6cb74af9275dd051026bb149a452fd84e5e57b5500"},
+		{newStreebog512, m1, "1b54d01a4af5b9d5cc3d86d68d285462b19abc2475222f35c085122be4ba1ffa00ad30f8767b3a82384c6574f024c311e2a481332b08ef7f41797891c1646f48"},
+		{newStreebog256, string(m2), "9dd2fe4e90409e5da87f53976d7405b0c0cac628fc669a741d50063c557e8f50"},
+		{newStreebog512, string(m2), "1e88e62226bfca6f9994f1f2d51569e0daf8475a3b0fe61a5300eee46d961376035fe83549ada2b8620fcd7c496ce5b33f0cb9dddc2b6460143b03dabac9fb28"},
+		{newStreebog512, strings.Repeat("a", 1000), ""},
+	}
+
+	for _, c := range cases {
+		d := c.newHash()
+		d.Write([]byte(c.input))
+		res := hex.EncodeToString(d.Sum(nil))
+
+		if c.expected != "" && res != c.expected {
+			t.Errorf("Expected %s got %s", c.expected, res)
+		}
+
+		// Writing in pieces must give the same result as a single write
+		split := c.newHash()
+		for _, b := range []byte(c.input) {
+			split.Write([]byte{b})
+		}
+		if hex.EncodeToString(split.Sum(nil)) != res {
+			t.Errorf("Expected split writes to match for input of length %d", len(c.input))
+		}
+	}
+}
@@ -4,21 +4,136 @@ import "time"
 
 // Holds the result after processing the hashes for the file
 type Result struct {
-	File       string
-	CRC32      string
-	XxHash64   string
-	MD4        string
-	MD5        string
-	SHA1       string
-	SHA256     string
-	SHA512     string
-	Blake2b256 string
-	Blake2b512 string
-	Blake3     string
-	Sha3224    string
-	Sha3256    string
-	Sha3384    string
-	Sha3512    string
+	File        string
+	CRC32       string
+	XxHash64    string
+	MD4         string
+	MD5         string
+	SHA1        string
+	SHA256      string
+	SHA512      string
+	Blake2b256  string
+	Blake2b512  string
+	Blake3      string
+	Sha3224     string
+	Sha3256     string
+	Sha3384     string
+	Sha3512     string
+	Sha224      string
+	Sha384      string
+	Sha512256   string
+	Ripemd160   string
+	Whirlpool   string
+	SM3         string
+	Streebog256 string
+	Streebog512 string
+	// Entropy in bits per byte and the ssdeep and TLSH similarity digests
+	Entropy string
+	Ssdeep  string
+	TLSH    string
+	Bytes   int64
+	// Set when the file takes up less space on disk than its size such as sparse files
+	PhysicalBytes int64 `json:",omitempty"`
+	MTime         *time.Time
+	// Set with --mtime where the platform and filesystem record when the file was created
+	BirthTime *time.Time `json:",omitempty"`
+	// Set with --provenance to when the file finished hashing
+	HashedAt *time.Time `json:",omitempty"`
+	// The id of the run which produced the record
+	ScanID string `json:",omitempty"`
+	// Set for files with more than one hard link so links to the same content can be told apart from copies
+	Device uint64 `json:",omitempty"`
+	Inode  uint64 `json:",omitempty"`
+	Links  uint64 `json:",omitempty"`
+	// The first path seen for the inode when this result was reused rather than hashed again
+	HardLinkOf string            `json:",omitempty"`
+	Error      string            `json:",omitempty"`
+	Normalized string            `json:",omitempty"`
+	Labels     map[string]string `json:",omitempty"`
+
+	AzureContentMD5 string       `json:",omitempty"`
+	AzureBlocks     []AzureBlock `json:",omitempty"`
+	Pieces          []string     `json:",omitempty"`
+	ZstdFrames      []ZstdFrame  `json:",omitempty"`
+	Xattr           string       `json:",omitempty"`
+	// Set with --detail-threshold to full, or sampled for files over it which only have
+	// a quick hash and sample digests rather than the selected hashes
+	Detail    string   `json:",omitempty"`
+	QuickHash string   `json:",omitempty"`
+	Samples   []Sample `json:",omitempty"`
+	// Digests from hashes added with RegisterHash keyed by name
+	Custom   map[string]string `json:",omitempty"`
+	Original *Result           `json:",omitempty"`
+	// Set with a baseline to new, unchanged, changed or unknown along with when the baseline was taken
+	Change   string     `json:",omitempty"`
+	LastSeen *time.Time `json:",omitempty"`
+	// Set with --detect-type to the MIME type and a description of recognised formats
+	ContentType string `json:",omitempty"`
+	Magic       string `json:",omitempty"`
+}
+
+// Describes a supported hash algorithm
+type HashInfo struct {
+	Name          string `json:"name"`
+	Display       string `json:"display"`
+	Bits          int    `json:"digestBits"`
+	Cryptographic bool   `json:"cryptographic"`
+	// Set for measures of the content such as entropy and similarity digests which are
+	// not checksums so are left out of all and sum files
+	Analysis bool `json:"analysis,omitempty"`
+}
+
+// Reported to OnProgress after each file has been processed by Run
+type ProgressEvent struct {
+	File      string
+	FilesDone int64
+	BytesDone int64
+	Errors    int64
+}
+
+// Time spent in one hash algorithm across every file when --stats is set
+type HashStats struct {
+	Algorithm  string
 	Bytes      int64
-	MTime      *time.Time
+	CPUSeconds float64
+	MBPerSec   float64
+}
+
+// Work done by a single worker when --stats is set, rates are over the time it was busy
+type WorkerStats struct {
+	Worker      int
+	Files       int64
+	Bytes       int64
+	BusySeconds float64
+	FilesPerSec float64
+	MBPerSec    float64
+}
+
+// How the queue between the walker and workers behaved when --stats is set, stalls
+// count the times the walker waited on a full queue or the workers on an empty one
+type QueueStats struct {
+	InitialLimit   int
+	FinalLimit     int
+	MaxLimit       int
+	MaxDepth       int
+	AverageDepth   float64
+	MaxQueuedBytes int64
+	Grows          int
+	Shrinks        int
+	WalkerStalls   int64
+	WorkerStalls   int64
+	BudgetStalls   int64
+}
+
+// Throughput for a whole run when --stats is set
+type ScanStats struct {
+	ScanID      string `json:",omitempty"`
+	Files       int64
+	Bytes       int64
+	WallSeconds float64
+	FilesPerSec float64
+	MBPerSec    float64
+	Hashes      []HashStats
+	Workers     []WorkerStats
+	Queue       *QueueStats `json:",omitempty"`
 }
@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Digest over the paths, sizes and mtimes seen in a no content run, set once
+// the results channel returned by recordStructure has been closed
+var structureSHA256 = ""
+
+// Passes results through while recording their metadata so a single digest of the
+// structure can be produced. Entries are sorted so the digest does not depend on the
+// order the workers finished in.
+func recordStructure(input chan Result) chan Result {
+	output := make(chan Result, FileListQueueSize)
+
+	go func() {
+		entries := []string{}
+		for res := range input {
+			if res.Error == "" {
+				mtime := ""
+				if res.MTime != nil {
+					mtime = res.MTime.UTC().Format(time.RFC3339Nano)
+				}
+				entries = append(entries, fmt.Sprintf("%s\t%d\t%s", res.File, res.Bytes, mtime))
+			}
+			output <- res
+		}
+
+		sort.Strings(entries)
+		sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+		structureSHA256 = hex.EncodeToString(sum[:])
+		close(output)
+	}()
+
+	return output
+}
+
+// Produces a result from the file metadata alone without opening or reading it
+func processNoContent(file string) Result {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return newErrorResult(file, err)
+	}
+
+	mtime := fi.ModTime()
+	return Result{
+		File:  file,
+		Bytes: fi.Size(),
+		MTime: &mtime,
+	}
+}
@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"testing"
+)
+
+func TestRecordStructureIsOrderIndependent(t *testing.T) {
+	digest := func(files ...string) string {
+		input := make(chan Result, len(files))
+		for _, f := range files {
+			input <- Result{File: f, Bytes: 1}
+		}
+		close(input)
+
+		for range recordStructure(input) {
+		}
+		return structureSHA256
+	}
+
+	first := digest("a", "b", "c")
+	second := digest("c", "a", "b")
+	third := digest("a", "b")
+
+	if first != second {
+		t.Errorf("Expected %s to equal %s", first, second)
+	}
+
+	if first == third {
+		t.Error("Expected different structures to have different digests")
+	}
+}
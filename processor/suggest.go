@@ -0,0 +1,224 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Security levels accepted by Suggest
+const (
+	SecurityNone   = "none"
+	SecurityLegacy = "legacy"
+	SecurityStrong = "strong"
+)
+
+// How long each candidate algorithm is benchmarked for
+var suggestBenchDuration = 100 * time.Millisecond
+
+// Limits on how much of the workload is sampled
+const (
+	suggestMaxFiles     = 10000
+	suggestMaxReadBytes = 256 * 1024 * 1024
+)
+
+// SuggestCandidate is the measured speed of a single algorithm
+type SuggestCandidate struct {
+	Name string `json:"name"`
+	// Throughput of a single core hashing buffers the size of a typical file
+	MBPerSecond float64 `json:"mbPerSecond"`
+	// Throughput once hashing across all cores and limited by storage
+	EffectiveMBPerSecond float64 `json:"effectiveMBPerSecond"`
+}
+
+// SuggestReport describes the sampled workload and the recommended algorithms
+type SuggestReport struct {
+	Files            int                `json:"files"`
+	Bytes            int64              `json:"bytes"`
+	MedianBytes      int64              `json:"medianBytes"`
+	ReadMBPerSecond  float64            `json:"readMBPerSecond"`
+	Security         string             `json:"security"`
+	Candidates       []SuggestCandidate `json:"candidates"`
+	Recommended      string             `json:"recommended"`
+	StorageBound     bool               `json:"storageBound"`
+	StorageBoundSet  []string           `json:"storageBoundSet,omitempty"`
+	BenchmarkedBytes int64              `json:"benchmarkedBytes"`
+}
+
+// Reports if the algorithm is acceptable at the security level. Strong requires a
+// cryptographic hash with at least 112 bits of collision resistance which rules out
+// MD4, MD5, SHA1 and RIPEMD-160.
+func meetsSecurity(info HashInfo, security string) bool {
+	switch security {
+	case SecurityNone:
+		return true
+	case SecurityLegacy:
+		return info.Cryptographic
+	case SecurityStrong:
+		return info.Cryptographic && info.Bits >= 224
+	}
+	return false
+}
+
+// Walks the path collecting file sizes and timing reads of up to suggestMaxReadBytes
+func sampleWorkload(path string) (SuggestReport, error) {
+	report := SuggestReport{}
+	sizes := []int64{}
+	files := []string{}
+
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable parts of the tree are not important for a sample
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(sizes) >= suggestMaxFiles {
+			return filepath.SkipAll
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		sizes = append(sizes, fi.Size())
+		files = append(files, p)
+		report.Bytes += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Files = len(sizes)
+	if len(sizes) != 0 {
+		sorted := append([]int64{}, sizes...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		report.MedianBytes = sorted[len(sorted)/2]
+	}
+
+	var read int64
+	buf := make([]byte, 1024*1024)
+	startTime := time.Now()
+	for _, f := range files {
+		if read >= suggestMaxReadBytes {
+			break
+		}
+		file, err := os.Open(f)
+		if err != nil {
+			continue
+		}
+		n, _ := io.CopyBuffer(io.Discard, io.LimitReader(file, suggestMaxReadBytes-read), buf)
+		file.Close()
+		read += n
+	}
+	if elapsed := time.Since(startTime).Seconds(); read > 0 && elapsed > 0 {
+		report.ReadMBPerSecond = float64(read) / elapsed / 1_000_000
+	}
+
+	return report, nil
+}
+
+// Times hashing a buffer of bufSize bytes with a single algorithm returning MB/s
+func benchmarkHash(name string, bufSize int64) float64 {
+	return measureHash(name, bufSize, 1, suggestBenchDuration)
+}
+
+func buildSuggestion(report SuggestReport, security string) SuggestReport {
+	report.Security = security
+
+	// Benchmark using buffers the size of a typical file so per file overhead counts
+	bufSize := report.MedianBytes
+	if bufSize < 1024 {
+		bufSize = 1024
+	}
+	if bufSize > 4*1024*1024 {
+		bufSize = 4 * 1024 * 1024
+	}
+	report.BenchmarkedBytes = bufSize
+
+	cores := float64(runtime.NumCPU())
+	for _, info := range HashInfos {
+		if !meetsSecurity(info, security) {
+			continue
+		}
+
+		speed := benchmarkHash(info.Name, bufSize)
+		effective := speed * cores
+		if report.ReadMBPerSecond > 0 && effective > report.ReadMBPerSecond {
+			effective = report.ReadMBPerSecond
+		}
+
+		report.Candidates = append(report.Candidates, SuggestCandidate{
+			Name:                 info.Name,
+			MBPerSecond:          speed,
+			EffectiveMBPerSecond: effective,
+		})
+	}
+
+	sort.SliceStable(report.Candidates, func(i, j int) bool {
+		return report.Candidates[i].MBPerSecond > report.Candidates[j].MBPerSecond
+	})
+
+	if len(report.Candidates) != 0 {
+		report.Recommended = report.Candidates[0].Name
+	}
+
+	// When storage is the bottleneck every algorithm able to keep up is as good as the fastest
+	if report.ReadMBPerSecond > 0 {
+		for _, c := range report.Candidates {
+			if c.MBPerSecond*cores >= report.ReadMBPerSecond {
+				report.StorageBoundSet = append(report.StorageBoundSet, c.Name)
+			}
+		}
+		report.StorageBound = len(report.StorageBoundSet) != 0
+	}
+
+	return report
+}
+
+// Suggest samples the files under path, benchmarks the algorithms meeting the security
+// level and prints which is the fastest for the workload
+func Suggest(path string, security string, asJSON bool) {
+	security = strings.ToLower(security)
+	switch security {
+	case SecurityNone, SecurityLegacy, SecurityStrong:
+	default:
+		printError(fmt.Sprintf("unknown security level: %s, expected none, legacy or strong", security))
+		os.Exit(1)
+	}
+
+	report, err := sampleWorkload(path)
+	if err != nil {
+		printError(fmt.Sprintf("failed to sample %s: %s", path, err.Error()))
+		os.Exit(1)
+	}
+
+	report = buildSuggestion(report, security)
+
+	if asJSON {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Printf("workload: %d files, %d bytes, median %d bytes, read %.1f MB/s\n", report.Files, report.Bytes, report.MedianBytes, report.ReadMBPerSecond)
+	fmt.Printf("security: %s\n", report.Security)
+	for _, c := range report.Candidates {
+		fmt.Printf("%11s %10.1f MB/s per core %10.1f MB/s effective\n", c.Name, c.MBPerSecond, c.EffectiveMBPerSecond)
+	}
+	fmt.Printf("recommended: --hash %s\n", report.Recommended)
+	if report.StorageBound && len(report.StorageBoundSet) > 1 {
+		fmt.Printf("storage bound, these keep up with reads: %s\n", strings.Join(report.StorageBoundSet, ","))
+	}
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMeetsSecurity(t *testing.T) {
+	md5 := HashInfo{Name: HashNames.MD5, Bits: 128, Cryptographic: true}
+	sha256 := HashInfo{Name: HashNames.SHA256, Bits: 256, Cryptographic: true}
+	crc := HashInfo{Name: HashNames.CRC32, Bits: 32, Cryptographic: false}
+
+	if !meetsSecurity(crc, SecurityNone) || meetsSecurity(crc, SecurityLegacy) {
+		t.Error("Expected crc32 to only meet none")
+	}
+	if !meetsSecurity(md5, SecurityLegacy) || meetsSecurity(md5, SecurityStrong) {
+		t.Error("Expected md5 to meet legacy but not strong")
+	}
+	if !meetsSecurity(sha256, SecurityStrong) {
+		t.Error("Expected sha256 to meet strong")
+	}
+}
+
+func TestBuildSuggestion(t *testing.T) {
+	suggestBenchDuration = time.Millisecond
+	defer func() { suggestBenchDuration = 100 * time.Millisecond }()
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a"), []byte("hello world"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "b"), make([]byte, 4096), 0600)
+
+	report, err := sampleWorkload(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if report.Files != 2 || report.Bytes != 4107 {
+		t.Errorf("Unexpected workload %+v", report)
+	}
+
+	report = buildSuggestion(report, SecurityStrong)
+	for _, c := range report.Candidates {
+		if c.Name == HashNames.MD5 || c.Name == HashNames.SHA1 {
+			t.Errorf("Expected %s to be excluded at strong", c.Name)
+		}
+	}
+	if report.Recommended == "" || report.Recommended != report.Candidates[0].Name {
+		t.Errorf("Expected the fastest candidate to be recommended got %s", report.Recommended)
+	}
+}
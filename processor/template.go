@@ -0,0 +1,128 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Compiled from Template when the template format is selected
+var outputTemplate *template.Template
+
+// What each result is rendered with. Every Result field is available directly such as
+// {{.SHA256}} or {{.Bytes}} along with details about the file and the run.
+type templateContext struct {
+	Result
+	// The path as supplied, the same as File, then split into its directory and name
+	Path string
+	Dir  string
+	Name string
+	// Every calculated digest keyed by hash name so hashes can be ranged over
+	Hashes map[string]string
+	// Counts from 1 in the order results are written
+	Index int
+
+	Host      string
+	Directory string
+	Version   string
+	Started   time.Time
+}
+
+// Helpers for quoting values for the places templates are commonly fed into
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v any) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"csv": func(s string) string {
+		if !strings.ContainsAny(s, ",\"\r\n") {
+			return s
+		}
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	},
+	"sql": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	},
+	"shell": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	},
+}
+
+// Compiles Template so mistakes are reported before any files are hashed
+func parseOutputTemplate() error {
+	outputTemplate = nil
+	if strings.ToLower(Format) != "template" {
+		if Template != "" {
+			return errors.New("--template requires --format template")
+		}
+		return nil
+	}
+	if Template == "" {
+		return errors.New("--format template requires a template supplied with --template")
+	}
+
+	t, err := template.New("output").Funcs(templateFuncs).Parse(Template)
+	if err != nil {
+		return err
+	}
+	outputTemplate = t
+	return nil
+}
+
+// Renders each result with the user supplied template adding a newline after each
+// unless the template already ends with one
+func toTemplate(input chan Result) (string, bool) {
+	var str strings.Builder
+	printed := 0
+	valid := true
+
+	pwd, host, _ := runContext()
+	started := time.Now().UTC()
+	newline := !strings.HasSuffix(Template, "\n")
+
+	index := 0
+	for res := range input {
+		// Errors have already been reported so are left out as with sum
+		if res.Error != "" {
+			continue
+		}
+		if len(labels) != 0 {
+			res.Labels = labels
+		}
+
+		index++
+		ctx := templateContext{
+			Result:    res,
+			Path:      res.File,
+			Dir:       filepath.Dir(res.File),
+			Name:      filepath.Base(res.File),
+			Hashes:    calculatedHashes(res),
+			Index:     index,
+			Host:      host,
+			Directory: pwd,
+			Version:   Version,
+			Started:   started,
+		}
+
+		var line strings.Builder
+		if err := outputTemplate.Execute(&line, ctx); err != nil {
+			printError(fmt.Sprintf("unable to render template for %s: %s", res.File, err.Error()))
+			valid = false
+			continue
+		}
+		str.WriteString(line.String())
+		if newline {
+			str.WriteString("\n")
+		}
+
+		streamOutput(&str, &printed)
+	}
+
+	return str.String(), valid
+}
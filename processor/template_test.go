@@ -0,0 +1,58 @@
+package processor
+
+import "testing"
+
+func TestToTemplate(t *testing.T) {
+	previousFormat, previousTemplate, previousHash := Format, Template, Hash
+	defer func() {
+		Format, Template, Hash = previousFormat, previousTemplate, previousHash
+		outputTemplate = nil
+	}()
+	Format = "template"
+	Template = "{{.Index}} {{shell .Name}} {{.Hashes.md5}} {{.Bytes}} {{sql .Dir}}"
+	Hash = []string{HashNames.MD5}
+	NoStream = true
+	defer func() { NoStream = false }()
+
+	if err := parseOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+
+	input := make(chan Result, 3)
+	input <- Result{File: "it's/a.txt", MD5: "5d41402abc4b2a76b9719d911017c592", Bytes: 5}
+	input <- Result{File: "missing", Error: "no such file"}
+	input <- Result{File: "b", MD5: "7d793037a0760186574b0282f2f435e7", Bytes: 5}
+	close(input)
+
+	out, valid := toTemplate(input)
+	expected := "1 'a.txt' 5d41402abc4b2a76b9719d911017c592 5 'it''s'\n" +
+		"2 'b' 7d793037a0760186574b0282f2f435e7 5 '.'\n"
+	if !valid || out != expected {
+		t.Errorf("expected %q got %q", expected, out)
+	}
+}
+
+func TestParseOutputTemplate(t *testing.T) {
+	previousFormat, previousTemplate := Format, Template
+	defer func() {
+		Format, Template = previousFormat, previousTemplate
+		outputTemplate = nil
+	}()
+
+	cases := []struct {
+		format, template string
+		valid            bool
+	}{
+		{"text", "", true},
+		{"template", "{{.Path}}", true},
+		{"template", "", false},
+		{"template", "{{.Path", false},
+		{"text", "{{.Path}}", false},
+	}
+	for _, c := range cases {
+		Format, Template = c.format, c.template
+		if err := parseOutputTemplate(); (err == nil) != c.valid {
+			t.Errorf("%+v expected valid %v got %v", c, c.valid, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/djherbis/times"
+)
+
+// Layout of the hashdeep mtime column keeping nanoseconds when the filesystem records them,
+// trailing zeros are trimmed so whole second times are written as before
+const hashdeepTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// Reads the modification time to the nanosecond along with the creation time where the
+// platform records one, which is statx on Linux, the file information on Windows and the
+// birth time on macOS and the BSDs
+func fileTimes(path string) (time.Time, *time.Time, error) {
+	stat, err := times.Stat(longPath(path))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	var birth *time.Time
+	// Filesystems without a creation time report it as zero through statx
+	if stat.HasBirthTime() && !stat.BirthTime().IsZero() {
+		b := stat.BirthTime()
+		birth = &b
+	}
+	return stat.ModTime(), birth, nil
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTimesNanoseconds(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	expected := time.Date(2024, 1, 31, 12, 30, 45, 123456789, time.UTC)
+	if err := os.Chtimes(file, expected, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime, birth, err := fileTimes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Some filesystems such as FAT only keep coarser times
+	if mtime.Nanosecond() == 0 {
+		t.Skip("filesystem does not record sub-second times")
+	}
+	if !mtime.Equal(expected) {
+		t.Errorf("expected %s got %s", expected, mtime)
+	}
+	if birth != nil && birth.After(time.Now()) {
+		t.Errorf("expected a birth time in the past got %s", birth)
+	}
+}
+
+func TestFileTimesMissing(t *testing.T) {
+	if _, _, err := fileTimes("this-path-does-not-exist"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestHashdeepTimeLayout(t *testing.T) {
+	whole := time.Date(2024, 1, 31, 12, 30, 45, 0, time.UTC)
+	if got := whole.Format(hashdeepTimeLayout); got != "2024-01-31 12:30:45" {
+		t.Errorf("expected whole seconds to be unchanged got %s", got)
+	}
+
+	fine := time.Date(2024, 1, 31, 12, 30, 45, 120000000, time.UTC)
+	if got := fine.Format(hashdeepTimeLayout); got != "2024-01-31 12:30:45.12" {
+		t.Errorf("expected trailing zeros trimmed got %s", got)
+	}
+}
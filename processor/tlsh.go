@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"encoding/hex"
+	"hash"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TLSH locality sensitive hashing in its default form of 128 buckets with a one byte
+// checksum. Every five byte window feeds six byte triplets into the buckets, the digest
+// then records which quartile each bucket landed in along with the length and checksum.
+// Sum returns the T1 prefixed hex digest, or TNULL when there was too little content
+// or too little variety in it to be meaningful.
+
+const (
+	tlshWindow        = 5
+	tlshBuckets       = 256
+	tlshEffBuckets    = 128
+	tlshCodeSize      = 32
+	tlshMinDataLength = 50
+	tlshNull          = "TNULL"
+)
+
+// Pearson hashing permutation
+var tlshVTable = [256]byte{
+	1, 87, 49, 12, 176, 178, 102, 166, 121, 193, 6, 84, 249, 230, 44, 163,
+	14, 197, 213, 181, 161, 85, 218, 80, 64, 239, 24, 226, 236, 142, 38, 200,
+	110, 177, 104, 103, 141, 253, 255, 50, 77, 101, 81, 18, 45, 96, 31, 222,
+	25, 107, 190, 70, 86, 237, 240, 34, 72, 242, 20, 214, 244, 227, 149, 235,
+	97, 234, 57, 22, 60, 250, 82, 175, 208, 5, 127, 199, 111, 62, 135, 248,
+	174, 169, 211, 58, 66, 154, 106, 195, 245, 171, 17, 187, 182, 179, 0, 243,
+	132, 56, 148, 75, 128, 133, 158, 100, 130, 126, 91, 13, 153, 246, 216, 219,
+	119, 68, 223, 78, 83, 88, 201, 99, 122, 11, 92, 32, 136, 114, 52, 10,
+	138, 30, 48, 183, 156, 35, 61, 26, 143, 74, 251, 94, 129, 162, 63, 152,
+	170, 7, 115, 167, 241, 206, 3, 150, 55, 59, 151, 220, 90, 53, 23, 131,
+	125, 173, 15, 238, 79, 95, 89, 16, 105, 137, 225, 224, 217, 160, 37, 123,
+	118, 73, 2, 157, 46, 116, 9, 145, 134, 228, 207, 212, 202, 215, 69, 229,
+	27, 188, 67, 124, 168, 252, 42, 4, 29, 108, 21, 247, 19, 205, 39, 203,
+	233, 40, 186, 147, 198, 192, 155, 33, 164, 191, 98, 204, 165, 180, 117, 76,
+	140, 36, 210, 172, 41, 54, 159, 8, 185, 232, 113, 196, 231, 47, 146, 120,
+	51, 65, 28, 144, 254, 221, 93, 189, 194, 139, 112, 43, 71, 109, 184, 209,
+}
+
+func tlshMapping(salt, i, j, k byte) byte {
+	h := tlshVTable[salt]
+	h = tlshVTable[h^i]
+	h = tlshVTable[h^j]
+	return tlshVTable[h^k]
+}
+
+type tlshDigest struct {
+	buckets  [tlshBuckets]uint32
+	window   [tlshWindow]byte
+	checksum byte
+	length   uint64
+}
+
+func newTLSH() hash.Hash {
+	return &tlshDigest{}
+}
+
+func (d *tlshDigest) Reset() {
+	*d = tlshDigest{}
+}
+
+// The T1 prefix followed by the hex of the checksum, length, quartile ratios and body
+func (d *tlshDigest) Size() int { return 2 + (3+tlshCodeSize)*2 }
+
+func (d *tlshDigest) BlockSize() int { return 1 }
+
+func (d *tlshDigest) Write(p []byte) (int, error) {
+	w := &d.window
+	for _, c := range p {
+		j := int(d.length % tlshWindow)
+		w[j] = c
+		if d.length >= tlshWindow-1 {
+			j1 := (j + tlshWindow - 1) % tlshWindow
+			j2 := (j + tlshWindow - 2) % tlshWindow
+			j3 := (j + tlshWindow - 3) % tlshWindow
+			j4 := (j + tlshWindow - 4) % tlshWindow
+
+			d.checksum = tlshMapping(0, w[j], w[j1], d.checksum)
+
+			d.buckets[tlshMapping(2, w[j], w[j1], w[j2])]++
+			d.buckets[tlshMapping(3, w[j], w[j1], w[j3])]++
+			d.buckets[tlshMapping(5, w[j], w[j2], w[j3])]++
+			d.buckets[tlshMapping(7, w[j], w[j2], w[j4])]++
+			d.buckets[tlshMapping(11, w[j], w[j1], w[j4])]++
+			d.buckets[tlshMapping(13, w[j], w[j3], w[j4])]++
+		}
+		d.length++
+	}
+	return len(p), nil
+}
+
+// Log of the length on a scale which is finer for small inputs
+func tlshLengthCapture(length uint64) byte {
+	l := math.Log(float64(length))
+	var i int
+	switch {
+	case length <= 656:
+		i = int(math.Floor(l / 0.4054651))
+	case length <= 3199:
+		i = int(math.Floor(l/0.26236426 - 8.72777))
+	default:
+		i = int(math.Floor(l/0.095310180 - 62.5472))
+	}
+	return byte(i & 0xff)
+}
+
+func tlshSwapNibbles(b byte) byte {
+	return b>>4 | b<<4
+}
+
+func (d *tlshDigest) Sum(in []byte) []byte {
+	if d.length < tlshMinDataLength || d.length > math.MaxUint32 {
+		return append(in, tlshNull...)
+	}
+
+	sorted := make([]uint32, tlshEffBuckets)
+	copy(sorted, d.buckets[:tlshEffBuckets])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	q1 := sorted[tlshEffBuckets/4-1]
+	q2 := sorted[tlshEffBuckets/2-1]
+	q3 := sorted[tlshEffBuckets-tlshEffBuckets/4-1]
+
+	// More than half the buckets need something in them
+	nonzero := 0
+	for _, count := range d.buckets[:tlshEffBuckets] {
+		if count > 0 {
+			nonzero++
+		}
+	}
+	if nonzero <= tlshEffBuckets/2 || q3 == 0 {
+		return append(in, tlshNull...)
+	}
+
+	var code [tlshCodeSize]byte
+	for i := range code {
+		var h byte
+		for j := 0; j < 4; j++ {
+			k := d.buckets[4*i+j]
+			switch {
+			case q3 < k:
+				h += 3 << (j * 2)
+			case q2 < k:
+				h += 2 << (j * 2)
+			case q1 < k:
+				h += 1 << (j * 2)
+			}
+		}
+		code[i] = h
+	}
+
+	q1Ratio := byte(uint32(float32(q1*100)/float32(q3)) % 16)
+	q2Ratio := byte(uint32(float32(q2*100)/float32(q3)) % 16)
+
+	// The header bytes have their nibbles swapped and the body is written last bucket first
+	out := make([]byte, 0, 3+tlshCodeSize)
+	out = append(out, tlshSwapNibbles(d.checksum), tlshSwapNibbles(tlshLengthCapture(d.length)), q1Ratio<<4|q2Ratio)
+	for i := tlshCodeSize - 1; i >= 0; i-- {
+		out = append(out, code[i])
+	}
+	return append(in, "T1"+strings.ToUpper(hex.EncodeToString(out))...)
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLSHTooLittleContent(t *testing.T) {
+	for _, input := range []string{"", "short", strings.Repeat("a", 1000)} {
+		d := newTLSH()
+		d.Write([]byte(input))
+		if res := string(d.Sum(nil)); res != tlshNull {
+			t.Errorf("expected %s for %q got %s", tlshNull, input[:min(len(input), 10)], res)
+		}
+	}
+}
+
+func TestTLSH(t *testing.T) {
+	input := ssdeepTestData(2, 4000)
+	d := newTLSH()
+	d.Write(input)
+	res := string(d.Sum(nil))
+
+	if len(res) != d.Size() || !strings.HasPrefix(res, "T1") {
+		t.Fatalf("expected a %d character T1 digest got %s", d.Size(), res)
+	}
+	if expected := "T18A816D39E73B2B1B5D360F146828D5ED8BA4C52E31D97048DA5D8361823547977232E6"; res != expected {
+		t.Errorf("Expected %s got %s", expected, res)
+	}
+
+	split := newTLSH()
+	split.Write(input[:3])
+	split.Write(input[3:])
+	if s := string(split.Sum(nil)); s != res {
+		t.Errorf("Expected %s got %s when split", res, s)
+	}
+}
+
+func TestTLSHSimilarContent(t *testing.T) {
+	a := ssdeepTestData(2, 20000)
+	b := append([]byte{}, a...)
+	copy(b[10000:], strings.Repeat("x", 100))
+	c := ssdeepTestData(4, 20000)
+
+	digest := func(input []byte) string {
+		d := newTLSH()
+		d.Write(input)
+		return string(d.Sum(nil))
+	}
+	differing := func(x, y string) int {
+		n := 0
+		for i := range x {
+			if x[i] != y[i] {
+				n++
+			}
+		}
+		return n
+	}
+
+	near, far := differing(digest(a), digest(b)), differing(digest(a), digest(c))
+	if near >= far {
+		t.Errorf("expected a small edit to change fewer characters than different content got %d and %d", near, far)
+	}
+}
@@ -0,0 +1,347 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Splits the file into pieces of pieceLength bytes returning the hex digest of each
+// piece. Unlike a multi file torrent pieces never span files.
+func computePieces(filename string, pieceLength int64, algorithm string) ([]string, error) {
+	newHash, err := pieceHashFunc(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(longPath(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := limitReader(file)
+	pieces := []string{}
+	buf := make([]byte, pieceLength)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			h := newHash()
+			h.Write(buf[:n])
+			pieces = append(pieces, hex.EncodeToString(h.Sum(nil)))
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pieces, nil
+}
+
+func pieceHashFunc(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	}
+	return nil, fmt.Errorf("unsupported piece hash: %s, expected sha1 or sha256", algorithm)
+}
+
+// A single file described by torrent metainfo
+type torrentFile struct {
+	Path    string
+	Length  int64
+	Padding bool
+}
+
+// The parts of a v1 .torrent info dictionary needed to verify data on disk
+type torrentInfo struct {
+	Name        string
+	PieceLength int64
+	Pieces      [][]byte
+	Files       []torrentFile
+	// Single file torrents have no directory for the content
+	SingleFile bool
+}
+
+func parseTorrent(data []byte) (torrentInfo, error) {
+	ti := torrentInfo{}
+
+	decoded, err := bencodeDecode(data)
+	if err != nil {
+		return ti, err
+	}
+	meta, ok := decoded.(map[string]interface{})
+	if !ok {
+		return ti, errors.New("torrent: metainfo is not a dictionary")
+	}
+	info, ok := meta["info"].(map[string]interface{})
+	if !ok {
+		return ti, errors.New("torrent: missing info dictionary")
+	}
+
+	ti.Name, _ = info["name"].(string)
+	ti.PieceLength, _ = info["piece length"].(int64)
+	if ti.PieceLength <= 0 {
+		return ti, errors.New("torrent: missing or invalid piece length")
+	}
+
+	pieces, ok := info["pieces"].(string)
+	if !ok || len(pieces)%sha1.Size != 0 {
+		return ti, errors.New("torrent: missing or invalid v1 pieces, v2 only torrents are not supported")
+	}
+	for i := 0; i < len(pieces); i += sha1.Size {
+		ti.Pieces = append(ti.Pieces, []byte(pieces[i:i+sha1.Size]))
+	}
+
+	if length, ok := info["length"].(int64); ok {
+		ti.SingleFile = true
+		ti.Files = append(ti.Files, torrentFile{Path: ti.Name, Length: length})
+		return ti, nil
+	}
+
+	files, ok := info["files"].([]interface{})
+	if !ok {
+		return ti, errors.New("torrent: info has neither length nor files")
+	}
+	for _, f := range files {
+		entry, ok := f.(map[string]interface{})
+		if !ok {
+			return ti, errors.New("torrent: invalid file entry")
+		}
+		length, _ := entry["length"].(int64)
+		parts, _ := entry["path"].([]interface{})
+		path := []string{}
+		for _, p := range parts {
+			s, _ := p.(string)
+			path = append(path, s)
+		}
+		attr, _ := entry["attr"].(string)
+		ti.Files = append(ti.Files, torrentFile{
+			Path:    filepath.Join(path...),
+			Length:  length,
+			Padding: strings.Contains(attr, "p"),
+		})
+	}
+
+	return ti, nil
+}
+
+// TorrentFileReport is the verification status of a single file in the torrent
+type TorrentFileReport struct {
+	File         string
+	Status       string
+	FailedPieces []int `json:",omitempty"`
+}
+
+// TorrentReport summarises verifying data on disk against a .torrent
+type TorrentReport struct {
+	Torrent      string
+	Pieces       int
+	FailedPieces []int `json:",omitempty"`
+	Files        []TorrentFileReport
+}
+
+// Locate a torrent file on disk. Root may be the directory containing the torrent's
+// content, the content directory itself or for single file torrents the file.
+func torrentFilePath(ti torrentInfo, root string, tf torrentFile) string {
+	if ti.SingleFile {
+		if fi, err := os.Stat(root); err == nil && !fi.IsDir() {
+			return root
+		}
+		return filepath.Join(root, tf.Path)
+	}
+	if fi, err := os.Stat(filepath.Join(root, ti.Name)); err == nil && fi.IsDir() {
+		return filepath.Join(root, ti.Name, tf.Path)
+	}
+	return filepath.Join(root, tf.Path)
+}
+
+// Reads the files in torrent order hashing pieces which may span files. Missing or
+// short files are treated as zeros so later pieces stay aligned.
+func verifyTorrent(ti torrentInfo, root string) TorrentReport {
+	report := TorrentReport{Pieces: len(ti.Pieces)}
+
+	// Which files contributed to the piece currently being hashed
+	touching := []int{}
+	failed := map[int][]int{}
+	status := make([]string, len(ti.Files))
+
+	piece := 0
+	h := sha1.New()
+	var filled int64
+
+	finishPiece := func() {
+		if piece >= len(ti.Pieces) || !bytes.Equal(h.Sum(nil), ti.Pieces[piece]) {
+			report.FailedPieces = append(report.FailedPieces, piece)
+			for _, f := range touching {
+				failed[f] = append(failed[f], piece)
+			}
+		}
+		piece++
+		h.Reset()
+		filled = 0
+		touching = touching[:0]
+	}
+
+	buf := make([]byte, 1024*1024)
+	zeros := make([]byte, len(buf))
+
+	for i, tf := range ti.Files {
+		status[i] = "OK"
+
+		var file *os.File
+		var reader io.Reader
+		if !tf.Padding {
+			var err error
+			file, err = os.Open(torrentFilePath(ti, root, tf))
+			if err != nil {
+				status[i] = "MISSING"
+			} else {
+				reader = file
+				if fi, err := file.Stat(); err == nil && fi.Size() != tf.Length {
+					status[i] = "SIZE MISMATCH"
+				}
+			}
+		}
+
+		remaining := tf.Length
+		for remaining > 0 {
+			want := ti.PieceLength - filled
+			if want > remaining {
+				want = remaining
+			}
+			if want > int64(len(buf)) {
+				want = int64(len(buf))
+			}
+
+			chunk := zeros[:want]
+			if reader != nil {
+				n, err := io.ReadFull(reader, buf[:want])
+				if err != nil {
+					// Short read so pad the rest with zeros and stop reading this file
+					copy(buf[n:want], zeros)
+					reader = nil
+					if status[i] == "OK" {
+						status[i] = "SIZE MISMATCH"
+					}
+				}
+				chunk = buf[:want]
+			}
+
+			if len(touching) == 0 || touching[len(touching)-1] != i {
+				touching = append(touching, i)
+			}
+			h.Write(chunk)
+			filled += want
+			remaining -= want
+
+			if filled == ti.PieceLength {
+				finishPiece()
+			}
+		}
+
+		if file != nil {
+			file.Close()
+		}
+	}
+	if filled > 0 {
+		finishPiece()
+	}
+
+	// More pieces in the torrent than data means the files are not what was described
+	for ; piece < len(ti.Pieces); piece++ {
+		report.FailedPieces = append(report.FailedPieces, piece)
+	}
+
+	for i, tf := range ti.Files {
+		if tf.Padding {
+			continue
+		}
+		if status[i] == "OK" && len(failed[i]) != 0 {
+			status[i] = "FAILED"
+		}
+		report.Files = append(report.Files, TorrentFileReport{
+			File:         tf.Path,
+			Status:       status[i],
+			FailedPieces: failed[i],
+		})
+	}
+
+	return report
+}
+
+// Verifies the data in root against the torrent at torrentPath printing which files
+// and pieces fail, returning the exit code
+func processTorrent(torrentPath string, root string) int {
+	data, err := os.ReadFile(torrentPath)
+	if err != nil {
+		printError(fmt.Sprintf("failed to read torrent: %s, %s", torrentPath, err.Error()))
+		return 1
+	}
+
+	ti, err := parseTorrent(data)
+	if err != nil {
+		printError(fmt.Sprintf("failed to parse torrent: %s, %s", torrentPath, err.Error()))
+		return 1
+	}
+
+	report := verifyTorrent(ti, root)
+	report.Torrent = torrentPath
+
+	if strings.ToLower(Format) == "json" {
+		out, _ := json.Marshal(report)
+		fmt.Println(string(out))
+	} else {
+		for _, f := range report.Files {
+			if len(f.FailedPieces) != 0 {
+				fmt.Printf("%s: %s (pieces %s)\n", f.File, f.Status, formatPieceList(f.FailedPieces))
+			} else {
+				fmt.Printf("%s: %s\n", f.File, f.Status)
+			}
+		}
+		fmt.Printf("%d of %d pieces ok\n", report.Pieces-len(report.FailedPieces), report.Pieces)
+	}
+
+	if len(report.FailedPieces) != 0 {
+		return 1
+	}
+	for _, f := range report.Files {
+		if f.Status != "OK" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Collapses runs of consecutive piece indexes so 1,2,3,7 prints as 1-3,7
+func formatPieceList(pieces []int) string {
+	parts := []string{}
+	for i := 0; i < len(pieces); {
+		j := i
+		for j+1 < len(pieces) && pieces[j+1] == pieces[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprintf("%d", pieces[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", pieces[i], pieces[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
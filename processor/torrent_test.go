@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Builds a multi file v1 torrent for the supplied contents using a 4 byte piece length
+func makeTestTorrent(name string, files []string, contents []string) []byte {
+	data := ""
+	list := ""
+	for i, f := range files {
+		data += contents[i]
+		list += fmt.Sprintf("d6:lengthi%de4:pathl%d:%see", len(contents[i]), len(f), f)
+	}
+
+	pieces := ""
+	for i := 0; i < len(data); i += 4 {
+		end := i + 4
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha1.Sum([]byte(data[i:end]))
+		pieces += string(sum[:])
+	}
+
+	return []byte(fmt.Sprintf("d4:infod5:filesl%se4:name%d:%s12:piece lengthi4e6:pieces%d:%see",
+		list, len(name), name, len(pieces), pieces))
+}
+
+func TestVerifyTorrent(t *testing.T) {
+	root := t.TempDir()
+	content := filepath.Join(root, "data")
+	_ = os.Mkdir(content, 0700)
+	_ = os.WriteFile(filepath.Join(content, "a"), []byte("hello"), 0600)
+	_ = os.WriteFile(filepath.Join(content, "b"), []byte("world!"), 0600)
+
+	ti, err := parseTorrent(makeTestTorrent("data", []string{"a", "b"}, []string{"hello", "world!"}))
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	report := verifyTorrent(ti, root)
+	if report.Pieces != 3 || len(report.FailedPieces) != 0 {
+		t.Errorf("Expected all 3 pieces ok got %+v", report)
+	}
+
+	// Corrupt the last byte of b which only touches the final piece
+	_ = os.WriteFile(filepath.Join(content, "b"), []byte("world?"), 0600)
+	report = verifyTorrent(ti, content)
+	if len(report.FailedPieces) != 1 || report.FailedPieces[0] != 2 {
+		t.Errorf("Expected piece 2 to fail got %v", report.FailedPieces)
+	}
+	if report.Files[0].Status != "OK" || report.Files[1].Status != "FAILED" {
+		t.Errorf("Unexpected file status %+v", report.Files)
+	}
+}
+
+func TestVerifyTorrentMissingFile(t *testing.T) {
+	root := t.TempDir()
+	_ = os.WriteFile(filepath.Join(root, "a"), []byte("hello"), 0600)
+
+	ti, _ := parseTorrent(makeTestTorrent("data", []string{"a", "b"}, []string{"hello", "world!"}))
+	report := verifyTorrent(ti, root)
+
+	// Piece 1 spans both files so a fails along with b
+	if report.Files[0].Status != "FAILED" || report.Files[1].Status != "MISSING" {
+		t.Errorf("Unexpected file status %+v", report.Files)
+	}
+}
+
+func TestComputePieces(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, []byte("hello world"), 0600)
+
+	pieces, err := computePieces(file, 5, "sha256")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+
+	// sha256 of hello
+	if len(pieces) != 3 || pieces[0] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected pieces %v", pieces)
+	}
+
+	if _, err := computePieces(file, 5, "md5"); err == nil {
+		t.Error("Expected error for unsupported piece hash")
+	}
+}
+
+func TestFormatPieceList(t *testing.T) {
+	if got := formatPieceList([]int{1, 2, 3, 7, 9, 10}); got != "1-3,7,9-10" {
+		t.Errorf("Expected 1-3,7,9-10 got %s", got)
+	}
+}
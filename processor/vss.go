@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A shadow copy standing in for a volume while it is scanned
+type shadowMapping struct {
+	original string
+	snapshot string
+	id       string
+}
+
+// Scan roots naming an existing shadow copy such as vss://HarddiskVolumeShadowCopy3/Users
+const vssScheme = "vss://"
+
+// Creates a shadow copy of the volume of each path returning the paths to scan inside the
+// snapshots and the mappings needed to report and clean them up
+func snapshotPaths(paths []string) ([]string, []shadowMapping, error) {
+	volumes := map[string]shadowMapping{}
+	mappings := []shadowMapping{}
+	snapshotted := []string{}
+
+	for _, p := range paths {
+		// Already a snapshot so left for shadowRootPaths
+		if strings.HasPrefix(p, vssScheme) {
+			snapshotted = append(snapshotted, p)
+			continue
+		}
+
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, mappings, err
+		}
+		volume := filepath.VolumeName(abs)
+		if volume == "" {
+			return nil, mappings, fmt.Errorf("unable to determine the volume of %s", p)
+		}
+
+		m, ok := volumes[volume]
+		if !ok {
+			device, id, err := createShadowCopy(volume)
+			if err != nil {
+				return nil, mappings, fmt.Errorf("unable to create shadow copy of %s: %w", volume, err)
+			}
+			m = shadowMapping{original: volume, snapshot: device, id: id}
+			volumes[volume] = m
+			mappings = append(mappings, m)
+			if Verbose {
+				printVerbose(fmt.Sprintf("created shadow copy %s of %s", device, volume))
+			}
+		}
+
+		snapshotted = append(snapshotted, m.snapshot+strings.TrimPrefix(abs, volume))
+	}
+
+	return snapshotted, mappings, nil
+}
+
+// Splits a vss:// root into the shadow copy name and the path inside it
+func parseShadowRoot(root string) (string, string, error) {
+	rest := strings.TrimPrefix(root, vssScheme)
+	name, path, _ := strings.Cut(strings.ReplaceAll(rest, `\`, "/"), "/")
+	prefix := "HarddiskVolumeShadowCopy"
+	if len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) || strings.Trim(name[len(prefix):], "0123456789") != "" {
+		return "", "", fmt.Errorf("invalid shadow copy %s expected vss://HarddiskVolumeShadowCopyN/path", root)
+	}
+	return name, strings.ReplaceAll(path, "/", `\`), nil
+}
+
+// Points vss:// roots at existing shadow copies so files can be hashed as they were when
+// the snapshot was taken. Results are reported under the drive the shadow copy was taken
+// of so they compare directly against a scan of the live volume, or under the vss:// name
+// when the volume has no drive letter.
+func shadowRootPaths(paths []string) ([]string, []shadowMapping, error) {
+	mappings := []shadowMapping{}
+	seen := map[string]bool{}
+	resolved := []string{}
+
+	for _, p := range paths {
+		if !strings.HasPrefix(p, vssScheme) {
+			resolved = append(resolved, p)
+			continue
+		}
+
+		name, path, err := parseShadowRoot(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		device := `\\?\GLOBALROOT\Device\` + name
+		if !seen[name] {
+			original, err := shadowCopyVolume(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to read shadow copy %s: %w", name, err)
+			}
+			if original == "" {
+				original = vssScheme + name
+			}
+			// No id as the shadow copy belongs to whoever created it and is kept
+			mappings = append(mappings, shadowMapping{original: original, snapshot: device})
+			seen[name] = true
+			printVerbose("reading shadow copy", "device", device, "volume", original)
+		}
+		resolved = append(resolved, device+`\`+path)
+	}
+
+	return resolved, mappings, nil
+}
+
+// Removes the shadow copies created for the scan
+func releaseSnapshots(mappings []shadowMapping) {
+	for _, m := range mappings {
+		if m.id == "" {
+			continue
+		}
+		if err := deleteShadowCopy(m.id); err != nil {
+			printError(fmt.Sprintf("unable to delete shadow copy %s: %s", m.id, err.Error()))
+		}
+	}
+}
+
+// Reports results with the original volume path rather than the snapshot device
+func unsnapshotPaths(input chan Result, mappings []shadowMapping) chan Result {
+	output := make(chan Result, FileListQueueSize)
+	go func() {
+		for res := range input {
+			for _, m := range mappings {
+				if strings.HasPrefix(res.File, m.snapshot) {
+					res.File = m.original + strings.TrimPrefix(res.File, m.snapshot)
+					break
+				}
+			}
+			output <- res
+		}
+		close(output)
+	}()
+	return output
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package processor
+
+import "errors"
+
+var errShadowCopyUnsupported = errors.New("volume shadow copies are only supported on Windows")
+
+func createShadowCopy(volume string) (string, string, error) {
+	return "", "", errShadowCopyUnsupported
+}
+
+func deleteShadowCopy(id string) error {
+	return errShadowCopyUnsupported
+}
+
+func shadowCopyVolume(name string) (string, error) {
+	return "", errShadowCopyUnsupported
+}
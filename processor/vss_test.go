@@ -0,0 +1,62 @@
+package processor
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestUnsnapshotPaths(t *testing.T) {
+	input := make(chan Result, 2)
+	input <- Result{File: `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3\Windows\System32\config\SAM`}
+	input <- Result{File: `D:\other`}
+	close(input)
+
+	mappings := []shadowMapping{{original: "C:", snapshot: `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3`}}
+	output := unsnapshotPaths(input, mappings)
+
+	if r := <-output; r.File != `C:\Windows\System32\config\SAM` {
+		t.Errorf("Expected original path got %s", r.File)
+	}
+	if r := <-output; r.File != `D:\other` {
+		t.Errorf("Expected unmapped path unchanged got %s", r.File)
+	}
+}
+
+func TestParseShadowRoot(t *testing.T) {
+	name, path, err := parseShadowRoot("vss://HarddiskVolumeShadowCopy3/Users/alice")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if name != "HarddiskVolumeShadowCopy3" || path != `Users\alice` {
+		t.Errorf("Expected shadow copy 3 and Users\\alice got %s %s", name, path)
+	}
+
+	if _, path, _ := parseShadowRoot("vss://harddiskvolumeshadowcopy12"); path != "" {
+		t.Errorf("Expected the root of the shadow copy got %s", path)
+	}
+
+	for _, root := range []string{"vss://", "vss://HarddiskVolumeShadowCopy", "vss://HarddiskVolumeShadowCopyX/a", "vss://HarddiskVolume1/a"} {
+		if _, _, err := parseShadowRoot(root); err == nil {
+			t.Errorf("Expected error for %s", root)
+		}
+	}
+}
+
+func TestShadowRootPathsLeavesOtherPaths(t *testing.T) {
+	paths, mappings, err := shadowRootPaths([]string{"a", `C:\b`})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	if len(mappings) != 0 || len(paths) != 2 || paths[0] != "a" || paths[1] != `C:\b` {
+		t.Errorf("Expected paths unchanged got %v %v", paths, mappings)
+	}
+}
+
+func TestShadowRootPathsUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shadow copies are supported on windows")
+	}
+	if _, _, err := shadowRootPaths([]string{"vss://HarddiskVolumeShadowCopy3/Users"}); err == nil {
+		t.Error("Expected error reading a shadow copy off windows")
+	}
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Uses WMI through PowerShell as there is no shadow copy API in the standard library.
+// Creating shadow copies requires an elevated prompt.
+func createShadowCopy(volume string) (string, string, error) {
+	script := fmt.Sprintf(`$r = (Get-WmiObject -List Win32_ShadowCopy).Create("%s\", "ClientAccessible"); `+
+		`if ($r.ReturnValue -ne 0) { exit $r.ReturnValue }; `+
+		`$s = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq $r.ShadowID }; `+
+		`Write-Output $s.ID; Write-Output $s.DeviceObject`, volume)
+
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Output()
+	if err != nil {
+		return "", "", err
+	}
+
+	lines := strings.Fields(string(out))
+	if len(lines) != 2 {
+		return "", "", errors.New("unexpected output creating shadow copy")
+	}
+	// The device object is already returned as a \\?\GLOBALROOT path that can be opened
+	return lines[1], lines[0], nil
+}
+
+func deleteShadowCopy(id string) error {
+	script := fmt.Sprintf(`Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq "%s" } | ForEach-Object { $_.Delete() }`, id)
+	return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Run()
+}
+
+// Finds the drive letter of the volume a shadow copy was taken of, empty when the volume
+// is mounted without one
+func shadowCopyVolume(name string) (string, error) {
+	script := fmt.Sprintf(`$s = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.DeviceObject -like "*\Device\%s" }; `+
+		`if (-not $s) { exit 2 }; `+
+		`$v = Get-WmiObject Win32_Volume | Where-Object { $_.DeviceID -eq $s.VolumeName }; `+
+		`Write-Output $v.DriveLetter`, name)
+
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+		return "", errors.New("no such shadow copy")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
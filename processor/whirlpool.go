@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// Whirlpool as specified in ISO/IEC 10118-3, there is no maintained Go module for it
+// so it is implemented here. The lookup tables are built from the mini boxes at
+// startup rather than pasted in as several kilobytes of constants.
+
+const (
+	whirlpoolSize      = 64
+	whirlpoolBlockSize = 64
+	whirlpoolRounds    = 10
+)
+
+var whirlpoolSbox [256]byte
+var whirlpoolTables [8][256]uint64
+var whirlpoolRC [whirlpoolRounds + 1]uint64
+
+func init() {
+	e := [16]byte{0x1, 0xB, 0x9, 0xC, 0xD, 0x6, 0xF, 0x3, 0xE, 0x8, 0x7, 0x4, 0xA, 0x2, 0x5, 0x0}
+	r := [16]byte{0x7, 0xC, 0xB, 0xD, 0xE, 0x4, 0x9, 0xF, 0x6, 0x3, 0x8, 0xA, 0x2, 0x5, 0x1, 0x0}
+	var eInv [16]byte
+	for i, v := range e {
+		eInv[v] = byte(i)
+	}
+
+	for u := 0; u < 256; u++ {
+		a := e[u>>4]
+		b := eInv[u&0xF]
+		x := r[a^b]
+		whirlpoolSbox[u] = e[a^x]<<4 | eInv[b^x]
+	}
+
+	// Multiplication in GF(2^8) with the reduction polynomial x^8 + x^4 + x^3 + x^2 + 1
+	mul := func(a, b byte) byte {
+		var p byte
+		for b != 0 {
+			if b&1 != 0 {
+				p ^= a
+			}
+			carry := a & 0x80
+			a <<= 1
+			if carry != 0 {
+				a ^= 0x1D
+			}
+			b >>= 1
+		}
+		return p
+	}
+
+	// Each table combines the substitution with column k of the circulant diffusion matrix
+	c := [8]byte{1, 1, 4, 1, 8, 5, 2, 9}
+	for k := 0; k < 8; k++ {
+		for x := 0; x < 256; x++ {
+			var v uint64
+			for j := 0; j < 8; j++ {
+				v |= uint64(mul(whirlpoolSbox[x], c[(j-k+8)%8])) << (56 - 8*j)
+			}
+			whirlpoolTables[k][x] = v
+		}
+	}
+
+	for round := 1; round <= whirlpoolRounds; round++ {
+		var v uint64
+		for j := 0; j < 8; j++ {
+			v |= uint64(whirlpoolSbox[8*(round-1)+j]) << (56 - 8*j)
+		}
+		whirlpoolRC[round] = v
+	}
+}
+
+// Applies the substitution, cyclic permutation and linear diffusion layers
+func whirlpoolRound(in [8]uint64) [8]uint64 {
+	var out [8]uint64
+	for i := 0; i < 8; i++ {
+		var v uint64
+		for k := 0; k < 8; k++ {
+			v ^= whirlpoolTables[k][byte(in[(i-k+8)%8]>>(56-8*k))]
+		}
+		out[i] = v
+	}
+	return out
+}
+
+type whirlpoolDigest struct {
+	h      [8]uint64
+	buf    [whirlpoolBlockSize]byte
+	nx     int
+	length uint64
+}
+
+func newWhirlpool() hash.Hash {
+	d := &whirlpoolDigest{}
+	d.Reset()
+	return d
+}
+
+func (d *whirlpoolDigest) Reset() {
+	d.h = [8]uint64{}
+	d.nx = 0
+	d.length = 0
+}
+
+func (d *whirlpoolDigest) Size() int { return whirlpoolSize }
+
+func (d *whirlpoolDigest) BlockSize() int { return whirlpoolBlockSize }
+
+func (d *whirlpoolDigest) block(p []byte) {
+	var m, state [8]uint64
+	for i := 0; i < 8; i++ {
+		m[i] = binary.BigEndian.Uint64(p[i*8:])
+		state[i] = m[i] ^ d.h[i]
+	}
+
+	k := d.h
+	for round := 1; round <= whirlpoolRounds; round++ {
+		k = whirlpoolRound(k)
+		k[0] ^= whirlpoolRC[round]
+
+		state = whirlpoolRound(state)
+		for i := 0; i < 8; i++ {
+			state[i] ^= k[i]
+		}
+	}
+
+	// Miyaguchi-Preneel compression
+	for i := 0; i < 8; i++ {
+		d.h[i] ^= state[i] ^ m[i]
+	}
+}
+
+func (d *whirlpoolDigest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.length += uint64(n)
+
+	if d.nx > 0 {
+		c := copy(d.buf[d.nx:], p)
+		d.nx += c
+		p = p[c:]
+		if d.nx == whirlpoolBlockSize {
+			d.block(d.buf[:])
+			d.nx = 0
+		}
+	}
+
+	for len(p) >= whirlpoolBlockSize {
+		d.block(p[:whirlpoolBlockSize])
+		p = p[whirlpoolBlockSize:]
+	}
+
+	if len(p) > 0 {
+		d.nx = copy(d.buf[:], p)
+	}
+	return n, nil
+}
+
+func (d *whirlpoolDigest) Sum(in []byte) []byte {
+	// Work on a copy so the caller can keep writing
+	c := *d
+
+	// Pad with a one bit then zeros until 32 bytes remain for the 256 bit length
+	var pad [whirlpoolBlockSize * 2]byte
+	pad[0] = 0x80
+	padLen := whirlpoolBlockSize - 32 - c.nx
+	if padLen <= 0 {
+		padLen += whirlpoolBlockSize
+	}
+
+	var length [32]byte
+	binary.BigEndian.PutUint64(length[16:], c.length>>61)
+	binary.BigEndian.PutUint64(length[24:], c.length<<3)
+
+	_, _ = c.Write(pad[:padLen])
+	_, _ = c.Write(length[:])
+
+	out := make([]byte, whirlpoolSize)
+	for i := 0; i < 8; i++ {
+		binary.BigEndian.PutUint64(out[i*8:], c.h[i])
+	}
+	return append(in, out...)
+}
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestWhirlpool(t *testing.T) {
+	var cases = []struct {
+		input    string
+		expected string
+	}{
+		{"", "19fa61d75522a4669b44e39c1d2e1726c530232130d407f89afee0964997f7a73e83be698b288febcf88e3e03c4f0757ea8964e59b63d93708b138cc42a66eb3"},
+		{"abc", "4e2448a4c6f486bb16b6562c73b4020bf3043e3a731bce721ae1b303d97e6d4c7181eebdb6c57e277d0e34957114cbd6c797fc9d95d8b582d225292076d4eef5"},
+		{"The quick brown fox jumps over the lazy dog", "b97de512e91e3828b40d2b0fdce9ceb3c4a71f9bea8d88e75c4fa854df36725fd2b52eb6544edcacd6f8beddfea403cb55ae31f03ad62a5ef54e42ee82c3fb35"},
+		{strings.Repeat("a", 1000), ""},
+	}
+
+	for _, c := range cases {
+		d := newWhirlpool()
+		d.Write([]byte(c.input))
+		res := hex.EncodeToString(d.Sum(nil))
+
+		if c.expected != "" && res != c.expected {
+			t.Errorf("Expected %s got %s", c.expected, res)
+		}
+
+		// Writing in pieces must give the same result as a single write
+		split := newWhirlpool()
+		for _, b := range []byte(c.input) {
+			split.Write([]byte{b})
+		}
+		if hex.EncodeToString(split.Sum(nil)) != res {
+			t.Errorf("Expected split writes to match for input of length %d", len(c.input))
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -18,11 +19,11 @@ import (
 	"time"
 
 	"github.com/cespare/xxhash/v2"
-	"github.com/djherbis/times"
 	"github.com/gosuri/uiprogress"
 	"github.com/minio/blake2b-simd"
 	"github.com/zeebo/blake3"
 	"golang.org/x/crypto/md4"
+	"golang.org/x/crypto/ripemd160"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -30,7 +31,29 @@ const (
 	UiBarMax = 1024 // 1024 should be dividable by most things
 )
 
-func fileProcessorWorker(input chan string, output chan Result) {
+// Runs NoThreads workers over the input closing output once they have all finished
+func startWorkers(input chan string, output chan Result) {
+	var wg sync.WaitGroup
+	for i := 0; i < NoThreads; i++ {
+		wg.Add(1)
+		go func() {
+			if timeoutPerFile > 0 {
+				guardedWorker(i, input, output, fileProcessorWorker)
+			} else {
+				fileProcessorWorker(i, input, output)
+			}
+			wg.Done()
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(output)
+	}()
+}
+
+func fileProcessorWorker(worker int, input chan string, output chan Result) {
+	defer abortOnPanic()
 
 	var bar *uiprogress.Bar
 	filename := ""
@@ -41,35 +64,92 @@ func fileProcessorWorker(input chan string, output chan Result) {
 		})
 	}
 
+	// Time from picking up a file to handing on its result is counted as busy for --stats
+	var itemStart time.Time
+	emit := func(r Result) {
+		releaseFileSlot()
+		if Stats {
+			recordWorkerResult(worker, r, time.Since(itemStart))
+		}
+		output <- r
+	}
+
 	for res := range input {
+		itemStart = time.Now()
+		acquireFileSlot()
 		if Debug {
-			printDebug(fmt.Sprintf("processing %s", res))
+			printDebug("processing", "worker", worker, "file", res)
+		}
+
+		if NoContent {
+			emit(processNoContent(res))
+			continue
+		}
+
+		if reason, skip := exceedsLimits(res); skip {
+			emit(newSkippedResult(res, reason))
+			continue
 		}
 
 		// Open the file and determine if we should read it from disk or memory map
 		// based on how large it is reported as being
-		file, err := os.OpenFile(res, os.O_RDONLY, 0644)
+		file, err := openFile(res)
 		if err != nil {
-			printError(fmt.Sprintf("Unable to process file %s with error %s", res, err.Error()))
+			emit(newErrorResult(res, err))
+			continue
+		}
+
+		if reason, locked := lockedReason(file); locked {
+			_ = file.Close()
+			emit(newSkippedResult(res, reason))
 			continue
 		}
 
 		var mtime time.Time
+		var birth *time.Time
 		if MTime {
-			stat, err := times.Stat(res)
+			mtime, birth, err = fileTimes(res)
 			if err != nil {
-				printError(fmt.Sprintf("Unable to read mtime file %s with error %s", res, err.Error()))
-				return
+				emit(newErrorResult(res, err))
+				_ = file.Close()
+				continue
 			}
-			mtime = stat.ModTime()
 		}
 
 		fi, err := file.Stat()
 		if err != nil {
-			printError(fmt.Sprintf("Unable to get file info for file %s with error %s", res, err.Error()))
+			emit(newErrorResult(res, err))
+			_ = file.Close()
 			continue
 		}
 
+		// Hard linked paths share content so only the first one seen is hashed
+		var link *linkEntry
+		if device, inode, links, ok := fileIdentity(fi); ok && links > 1 && !NoLinkDedupe {
+			var owner bool
+			link, owner = claimLink(device, inode, res)
+			if !owner {
+				_ = file.Close()
+				r := link.wait(res)
+				if r.Error != "" {
+					emit(newErrorResult(res, errors.New(r.Error)))
+				} else {
+					emit(r)
+				}
+				continue
+			}
+		}
+		send := func(r Result) {
+			if detailThresholdBytes >= 0 && r.Error == "" && r.Detail == "" {
+				r.Detail = DetailFull
+			}
+			stampHashed(&r)
+			if link != nil {
+				link.finish(r)
+			}
+			emit(r)
+		}
+
 		// update the ui if required
 		if Progress && bar != nil {
 			split := strings.Split(file.Name(), "/")
@@ -80,27 +160,99 @@ func fileProcessorWorker(input chan string, output chan Result) {
 
 		fsize := fi.Size()
 
+		key := ""
+		if resultCache != nil {
+			key = cacheKey(res, fsize, fi.ModTime())
+			if r, ok := resultCache.Get(key); ok {
+				if Debug {
+					printDebug("using cached result", "worker", worker, "file", res)
+				}
+				r.File = res
+				send(r)
+				_ = file.Close()
+				continue
+			}
+		}
+
+		if detailThresholdBytes >= 0 && fsize > detailThresholdBytes {
+			if Debug {
+				printDebug("sampling", "worker", worker, "file", res, "bytes", fsize)
+			}
+			r, err := processSampled(res, file, fsize)
+			if err == nil {
+				r.MTime = &mtime
+				r.BirthTime = birth
+				if resultCache != nil {
+					resultCache.Put(key, r)
+				}
+				send(r)
+			} else {
+				send(newErrorResult(res, err))
+			}
+			_ = file.Close()
+			continue
+		}
+
 		if fsize > StreamSize {
 			if Debug {
-				printDebug(fmt.Sprintf("%s bytes=%d using scanner", res, fsize))
+				printDebug("using scanner", "worker", worker, "file", res, "bytes", fsize)
 			}
 
 			fileStartTime := makeTimestampMilli()
-			r, err := processScanner(res, int(fsize), bar)
+			// When there are fewer files waiting than workers the cores would otherwise sit
+			// idle so let BLAKE3 split this file across all of them
+			parallelBlake3 := Blake3ParallelSize > 0 && fsize >= Blake3ParallelSize && len(input) < NoThreads
+
+			r, err := processScanner(res, int(fsize), bar, TextNormalize, parallelBlake3)
 			if Trace {
-				printTrace(fmt.Sprintf("milliseconds processMemoryMap: %s: %d", res, makeTimestampMilli()-fileStartTime))
+				printTrace("processScanner", "worker", worker, "file", res, "duration", time.Duration(makeTimestampMilli()-fileStartTime)*time.Millisecond)
+			}
+
+			// Keeping the original means a second pass over the file without normalization
+			if err == nil && TextNormalize != "" && KeepOriginal {
+				var original Result
+				original, err = processScanner(res, int(fsize), bar, "", parallelBlake3)
+				r.Normalized = TextNormalize
+				r.Original = &original
 			}
 
 			if err == nil {
 				r.File = res
 				r.Bytes = fsize
 				r.MTime = &mtime
-				output <- r
+				r.BirthTime = birth
+				if physical, ok := physicalSize(fi); ok && physical < fsize {
+					r.PhysicalBytes = physical
+				}
+				if device, inode, links, ok := fileIdentity(fi); ok && links > 1 {
+					r.Device, r.Inode, r.Links = device, inode, links
+				}
+				if AzureBlockSize > 0 {
+					r.AzureBlocks, r.AzureContentMD5, err = computeAzureBlocks(res, AzureBlockSize)
+				}
+				if err == nil && PieceLength > 0 {
+					r.Pieces, err = computePieces(res, PieceLength, PieceHash)
+				}
+				if err == nil && ZstdFrames {
+					r.ZstdFrames, err = computeZstdFrames(res)
+				}
+				if err == nil && (StoreXattr || VerifyXattr) {
+					err = applyXattrs(&r)
+				}
+			}
+
+			if err == nil {
+				if resultCache != nil {
+					resultCache.Put(key, r)
+				}
+				send(r)
+			} else {
+				send(newErrorResult(res, err))
 			}
 
 		} else {
 			if Debug {
-				printDebug(fmt.Sprintf("%s bytes=%d using read file", res, fsize))
+				printDebug("using read file", "worker", worker, "file", res, "bytes", fsize)
 			}
 
 			fileStartTime := makeTimestampNano()
@@ -109,15 +261,37 @@ func fileProcessorWorker(input chan string, output chan Result) {
 			if size := fsize + bytes.MinRead; size > n {
 				n = size
 			}
-			content, _ := readAll(file, n)
+			content, err := readAll(limitReader(file), n)
+			if err != nil {
+				send(newErrorResult(res, err))
+				_ = file.Close()
+				continue
+			}
 
 			var r Result
+			var original Result
+			raw := content
+
+			if TextNormalize != "" {
+				content = normalizeNewlines(content, TextNormalize)
+			}
 
 			// For larger files if we have more than one hash try parallel
 			if fsize > 200000 && len(Hash) >= 1 && !hasHash("all") {
 				r, err = processReadFileParallel(res, &content)
+				if err == nil && TextNormalize != "" && KeepOriginal {
+					original, err = processReadFileParallel(res, &raw)
+				}
 			} else {
 				r, err = processReadFile(res, &content)
+				if err == nil && TextNormalize != "" && KeepOriginal {
+					original, err = processReadFile(res, &raw)
+				}
+			}
+
+			if TextNormalize != "" && KeepOriginal {
+				r.Normalized = TextNormalize
+				r.Original = &original
 			}
 
 			if Progress {
@@ -125,14 +299,45 @@ func fileProcessorWorker(input chan string, output chan Result) {
 			}
 
 			if Trace {
-				printTrace(fmt.Sprintf("nanoseconds processReadFileParallel: %s: %d", res, makeTimestampNano()-fileStartTime))
+				printTrace("processReadFileParallel", "worker", worker, "file", res, "duration", time.Duration(makeTimestampNano()-fileStartTime))
 			}
 
 			if err == nil {
 				r.File = res
 				r.Bytes = fsize
 				r.MTime = &mtime
-				output <- r
+				r.BirthTime = birth
+				if DetectType {
+					r.ContentType, r.Magic = detectType(raw)
+				}
+				if physical, ok := physicalSize(fi); ok && physical < fsize {
+					r.PhysicalBytes = physical
+				}
+				if device, inode, links, ok := fileIdentity(fi); ok && links > 1 {
+					r.Device, r.Inode, r.Links = device, inode, links
+				}
+				if AzureBlockSize > 0 {
+					// The content is already in memory so there is no need to read it again
+					r.AzureBlocks, r.AzureContentMD5, err = azureBlocks(bytes.NewReader(raw), AzureBlockSize)
+				}
+				if err == nil && PieceLength > 0 {
+					r.Pieces, err = computePieces(res, PieceLength, PieceHash)
+				}
+				if err == nil && ZstdFrames {
+					r.ZstdFrames, err = computeZstdFrames(res)
+				}
+				if err == nil && (StoreXattr || VerifyXattr) {
+					err = applyXattrs(&r)
+				}
+			}
+
+			if err == nil {
+				if resultCache != nil {
+					resultCache.Put(key, r)
+				}
+				send(r)
+			} else {
+				send(newErrorResult(res, err))
 			}
 		}
 		_ = file.Close()
@@ -141,28 +346,100 @@ func fileProcessorWorker(input chan string, output chan Result) {
 
 // TODO compare this to memory maps
 // Random tests indicate that mmap is faster when not in power save mode
-func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, error) {
-	file, err := os.Open(filename)
+func processScanner(filename string, fsize int, bar *uiprogress.Bar, normalize string, parallelBlake3 bool) (Result, error) {
+	file, err := openFile(filename)
 	if err != nil {
-		printError(fmt.Sprintf("opening file %s: %s", filename, err.Error()))
 		return Result{}, err
 	}
 	defer file.Close()
 
-	crc32_d := crc32.NewIEEE()
-	xxhash64_d := xxhash.New()
-	md4_d := md4.New()
-	md5_d := md5.New()
-	sha1_d := sha1.New()
-	sha256_d := sha256.New()
-	sha512_d := sha512.New()
-	blake2b_256_d := blake2b.New256()
-	blake2b_512_d := blake2b.New512()
-	blake3_d := blake3.New()
-	sha3_224_d := sha3.New224()
-	sha3_256_d := sha3.New256()
-	sha3_384_d := sha3.New384()
-	sha3_512_d := sha3.New512()
+	// Where the file takes up less space on disk than its size it is probably sparse
+	// so only read the regions with data and feed zeros to the hashers for the holes
+	reader := limitReader(file)
+	if !NoSparse {
+		if fi, err := file.Stat(); err == nil {
+			if physical, ok := physicalSize(fi); ok && physical < fi.Size() {
+				if regions, err := dataRegions(file, fi.Size()); err == nil {
+					if Debug {
+						printDebug(fmt.Sprintf("%s sparse with %d data regions", filename, len(regions)))
+					}
+					reader = newSparseReader(limitReaderAt(file), fi.Size(), regions)
+				}
+			}
+		}
+	}
+
+	// Normalization changes the content as it is read so it cannot be split up
+	var at io.ReaderAt
+	if parallelBlake3 && normalize == "" {
+		at = limitReaderAt(file)
+	}
+
+	if !DetectType {
+		return processStream(filename, reader, at, fsize, bar, normalize)
+	}
+
+	sniff := &sniffReader{r: reader}
+	r, err := processStream(filename, sniff, at, fsize, bar, normalize)
+	r.ContentType, r.Magic = detectType(sniff.head)
+	return r, err
+}
+
+// Hashes everything read from reader. When at is set BLAKE3 reads the content from it
+// in parallel segments rather than being fed from reader.
+func processStream(filename string, reader io.Reader, at io.ReaderAt, fsize int, bar *uiprogress.Bar, normalize string) (Result, error) {
+	parallelBlake3 := at != nil
+
+	// BLAKE3 can split the file across the worker threads itself so run it alongside the
+	// streamed hashes rather than feeding it chunks
+	var blake3Sum []byte
+	var blake3Err error
+	var blake3Wg sync.WaitGroup
+	if hasHash(HashNames.Blake3) && parallelBlake3 {
+		blake3Wg.Add(1)
+		go func() {
+			startTime := makeTimestampMilli()
+			blake3Sum, blake3Err = blake3Parallel(at, int64(fsize), NoThreads, Blake3SegmentSize)
+			if Stats {
+				recordHashTime(HashNames.Blake3, fsize, time.Duration(makeTimestampMilli()-startTime)*time.Millisecond)
+			}
+			if Trace {
+				printTrace(fmt.Sprintf("milliseconds processing parallel blake3: %s: %d", filename, makeTimestampMilli()-startTime))
+			}
+			blake3Wg.Done()
+		}()
+	}
+
+	var normalizer *newlineNormalizer
+	if normalize != "" {
+		normalizer = newNewlineNormalizer(normalize)
+	}
+
+	crc32_d := timeHash(HashNames.CRC32, crc32.NewIEEE())
+	xxhash64_d := timeHash(HashNames.XxHash64, xxhash.New())
+	md4_d := timeHash(HashNames.MD4, md4.New())
+	md5_d := timeHash(HashNames.MD5, md5.New())
+	sha1_d := timeHash(HashNames.SHA1, sha1.New())
+	sha256_d := timeHash(HashNames.SHA256, sha256.New())
+	sha512_d := timeHash(HashNames.SHA512, sha512.New())
+	blake2b_256_d := timeHash(HashNames.Blake2b256, blake2b.New256())
+	blake2b_512_d := timeHash(HashNames.Blake2b512, blake2b.New512())
+	blake3_d := timeHash(HashNames.Blake3, blake3.New())
+	sha3_224_d := timeHash(HashNames.Sha3224, sha3.New224())
+	sha3_256_d := timeHash(HashNames.Sha3256, sha3.New256())
+	sha3_384_d := timeHash(HashNames.Sha3384, sha3.New384())
+	sha3_512_d := timeHash(HashNames.Sha3512, sha3.New512())
+	sha224_d := timeHash(HashNames.Sha224, sha256.New224())
+	sha384_d := timeHash(HashNames.Sha384, sha512.New384())
+	sha512_256_d := timeHash(HashNames.Sha512256, sha512.New512_256())
+	ripemd160_d := timeHash(HashNames.Ripemd160, ripemd160.New())
+	whirlpool_d := timeHash(HashNames.Whirlpool, newWhirlpool())
+	sm3_d := timeHash(HashNames.SM3, newSM3())
+	streebog256_d := timeHash(HashNames.Streebog256, newStreebog256())
+	streebog512_d := timeHash(HashNames.Streebog512, newStreebog512())
+	entropy_d := timeHash(HashNames.Entropy, newEntropy())
+	ssdeep_d := timeHash(HashNames.Ssdeep, newSsdeep())
+	tlsh_d := timeHash(HashNames.TLSH, newTLSH())
 
 	crc32c := make(chan []byte, 10)
 	xxhash64c := make(chan []byte, 10)
@@ -178,6 +455,17 @@ func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, er
 	sha3_256_c := make(chan []byte, 10)
 	sha3_384_c := make(chan []byte, 10)
 	sha3_512_c := make(chan []byte, 10)
+	sha224_c := make(chan []byte, 10)
+	sha384_c := make(chan []byte, 10)
+	sha512_256_c := make(chan []byte, 10)
+	ripemd160_c := make(chan []byte, 10)
+	whirlpool_c := make(chan []byte, 10)
+	sm3_c := make(chan []byte, 10)
+	streebog256_c := make(chan []byte, 10)
+	streebog512_c := make(chan []byte, 10)
+	entropy_c := make(chan []byte, 10)
+	ssdeep_c := make(chan []byte, 10)
+	tlsh_c := make(chan []byte, 10)
 
 	var wg sync.WaitGroup
 
@@ -271,7 +559,7 @@ func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, er
 		}()
 	}
 
-	if hasHash(HashNames.Blake3) {
+	if hasHash(HashNames.Blake3) && !parallelBlake3 {
 		wg.Add(1)
 		go func() {
 			for b := range blake3c {
@@ -317,17 +605,139 @@ func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, er
 			wg.Done()
 		}()
 	}
+	if hasHash(HashNames.Sha224) {
+		wg.Add(1)
+		go func() {
+			for b := range sha224_c {
+				sha224_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Sha384) {
+		wg.Add(1)
+		go func() {
+			for b := range sha384_c {
+				sha384_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Sha512256) {
+		wg.Add(1)
+		go func() {
+			for b := range sha512_256_c {
+				sha512_256_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Ripemd160) {
+		wg.Add(1)
+		go func() {
+			for b := range ripemd160_c {
+				ripemd160_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Whirlpool) {
+		wg.Add(1)
+		go func() {
+			for b := range whirlpool_c {
+				whirlpool_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.SM3) {
+		wg.Add(1)
+		go func() {
+			for b := range sm3_c {
+				sm3_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog256) {
+		wg.Add(1)
+		go func() {
+			for b := range streebog256_c {
+				streebog256_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog512) {
+		wg.Add(1)
+		go func() {
+			for b := range streebog512_c {
+				streebog512_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Entropy) {
+		wg.Add(1)
+		go func() {
+			for b := range entropy_c {
+				entropy_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Ssdeep) {
+		wg.Add(1)
+		go func() {
+			for b := range ssdeep_c {
+				ssdeep_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.TLSH) {
+		wg.Add(1)
+		go func() {
+			for b := range tlsh_c {
+				tlsh_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	custom := newCustomHashers()
+	custom_c := make(chan []byte, 10)
+	if len(custom) != 0 {
+		wg.Add(1)
+		go func() {
+			for b := range custom_c {
+				for _, h := range custom {
+					h.hash.Write(b)
+				}
+			}
+			wg.Done()
+		}()
+	}
+
+	// When BLAKE3 is reading the file itself and nothing else wants the content skip
+	// the streamed read so the file is not read and rate limited twice
+	streamed := !parallelBlake3 || DetectType || len(custom) != 0 || streamedHashSelected()
 
 	sum := 0
-	data := make([]byte, 4_194_304)
-	for {
-		n, err := file.Read(data)
+	var readErr error
+	data := make([]byte, scannerReadSize)
+	for streamed {
+		n, err := reader.Read(data)
 		if err != nil && err != io.EOF {
-			printError(fmt.Sprintf("reading file %s: %s", filename, err.Error()))
-			return Result{}, err
+			// Stop reading but let the hashing goroutines drain before reporting
+			readErr = err
+			break
 		}
 
-		if Progress {
+		if Progress && bar != nil {
 			sum += n
 
 			percentage := float64(sum) / float64(fsize)
@@ -342,50 +752,94 @@ func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, er
 
 		// Need to make a copy here as it can be modified before
 		// the goroutine processes it in the channel
-		tmp := make([]byte, len(data))
-		copy(tmp, data)
+		var tmp []byte
+		if normalizer != nil {
+			tmp = normalizer.Transform(data[:n])
+			if err == io.EOF {
+				tmp = append(tmp, normalizer.Flush()...)
+			}
+		} else {
+			tmp = make([]byte, n)
+			copy(tmp, data[:n])
+		}
 
 		if hasHash(HashNames.CRC32) {
-			crc32c <- tmp[:n]
+			crc32c <- tmp
 		}
 		if hasHash(HashNames.XxHash64) {
-			xxhash64c <- tmp[:n]
+			xxhash64c <- tmp
 		}
 		if hasHash(HashNames.MD4) {
-			md4c <- tmp[:n]
+			md4c <- tmp
 		}
 		if hasHash(HashNames.MD5) {
-			md5c <- tmp[:n]
+			md5c <- tmp
 		}
 		if hasHash(HashNames.SHA1) {
-			sha1c <- tmp[:n]
+			sha1c <- tmp
 		}
 		if hasHash(HashNames.SHA256) {
-			sha256c <- tmp[:n]
+			sha256c <- tmp
 		}
 		if hasHash(HashNames.SHA512) {
-			sha512c <- tmp[:n]
+			sha512c <- tmp
 		}
 		if hasHash(HashNames.Blake2b256) {
-			blake2b_256_c <- tmp[:n]
+			blake2b_256_c <- tmp
 		}
 		if hasHash(HashNames.Blake2b512) {
-			blake2b_512_c <- tmp[:n]
+			blake2b_512_c <- tmp
 		}
-		if hasHash(HashNames.Blake3) {
-			blake3c <- tmp[:n]
+		if hasHash(HashNames.Blake3) && !parallelBlake3 {
+			blake3c <- tmp
 		}
 		if hasHash(HashNames.Sha3224) {
-			sha3_224_c <- tmp[:n]
+			sha3_224_c <- tmp
 		}
 		if hasHash(HashNames.Sha3256) {
-			sha3_256_c <- tmp[:n]
+			sha3_256_c <- tmp
 		}
 		if hasHash(HashNames.Sha3384) {
-			sha3_384_c <- tmp[:n]
+			sha3_384_c <- tmp
 		}
 		if hasHash(HashNames.Sha3512) {
-			sha3_512_c <- tmp[:n]
+			sha3_512_c <- tmp
+		}
+		if hasHash(HashNames.Sha224) {
+			sha224_c <- tmp
+		}
+		if hasHash(HashNames.Sha384) {
+			sha384_c <- tmp
+		}
+		if hasHash(HashNames.Sha512256) {
+			sha512_256_c <- tmp
+		}
+		if hasHash(HashNames.Ripemd160) {
+			ripemd160_c <- tmp
+		}
+		if hasHash(HashNames.Whirlpool) {
+			whirlpool_c <- tmp
+		}
+		if hasHash(HashNames.SM3) {
+			sm3_c <- tmp
+		}
+		if hasHash(HashNames.Streebog256) {
+			streebog256_c <- tmp
+		}
+		if hasHash(HashNames.Streebog512) {
+			streebog512_c <- tmp
+		}
+		if hasHash(HashNames.Entropy) {
+			entropy_c <- tmp
+		}
+		if hasHash(HashNames.Ssdeep) {
+			ssdeep_c <- tmp
+		}
+		if hasHash(HashNames.TLSH) {
+			tlsh_c <- tmp
+		}
+		if len(custom) != 0 {
+			custom_c <- tmp
 		}
 
 		if err == io.EOF {
@@ -407,48 +861,109 @@ func processScanner(filename string, fsize int, bar *uiprogress.Bar) (Result, er
 	close(sha3_256_c)
 	close(sha3_384_c)
 	close(sha3_512_c)
+	close(sha224_c)
+	close(sha384_c)
+	close(sha512_256_c)
+	close(ripemd160_c)
+	close(whirlpool_c)
+	close(sm3_c)
+	close(streebog256_c)
+	close(streebog512_c)
+	close(entropy_c)
+	close(ssdeep_c)
+	close(tlsh_c)
+	close(custom_c)
 
 	wg.Wait()
+	blake3Wg.Wait()
+
+	if !streamed && Progress && bar != nil {
+		_ = bar.Set(UiBarMax)
+	}
+
+	if readErr != nil {
+		return Result{}, readErr
+	}
+
+	if blake3Err != nil {
+		return Result{}, blake3Err
+	}
+
+	blake3Hex := hex.EncodeToString(blake3_d.Sum(nil))
+	if blake3Sum != nil {
+		blake3Hex = hex.EncodeToString(blake3Sum)
+	}
 
 	return Result{
-		File:       filename,
-		Bytes:      0,
-		CRC32:      hex.EncodeToString(crc32_d.Sum(nil)),
-		XxHash64:   hex.EncodeToString(xxhash64_d.Sum(nil)),
-		MD4:        hex.EncodeToString(md4_d.Sum(nil)),
-		MD5:        hex.EncodeToString(md5_d.Sum(nil)),
-		SHA1:       hex.EncodeToString(sha1_d.Sum(nil)),
-		SHA256:     hex.EncodeToString(sha256_d.Sum(nil)),
-		SHA512:     hex.EncodeToString(sha512_d.Sum(nil)),
-		Blake2b256: hex.EncodeToString(blake2b_256_d.Sum(nil)),
-		Blake2b512: hex.EncodeToString(blake2b_512_d.Sum(nil)),
-		Blake3:     hex.EncodeToString(blake3_d.Sum(nil)),
-		Sha3224:    hex.EncodeToString(sha3_224_d.Sum(nil)),
-		Sha3256:    hex.EncodeToString(sha3_256_d.Sum(nil)),
-		Sha3384:    hex.EncodeToString(sha3_384_d.Sum(nil)),
-		Sha3512:    hex.EncodeToString(sha3_512_d.Sum(nil)),
+		File:        filename,
+		Bytes:       0,
+		CRC32:       hex.EncodeToString(crc32_d.Sum(nil)),
+		XxHash64:    hex.EncodeToString(xxhash64_d.Sum(nil)),
+		MD4:         hex.EncodeToString(md4_d.Sum(nil)),
+		MD5:         hex.EncodeToString(md5_d.Sum(nil)),
+		SHA1:        hex.EncodeToString(sha1_d.Sum(nil)),
+		SHA256:      hex.EncodeToString(sha256_d.Sum(nil)),
+		SHA512:      hex.EncodeToString(sha512_d.Sum(nil)),
+		Blake2b256:  hex.EncodeToString(blake2b_256_d.Sum(nil)),
+		Blake2b512:  hex.EncodeToString(blake2b_512_d.Sum(nil)),
+		Blake3:      blake3Hex,
+		Sha3224:     hex.EncodeToString(sha3_224_d.Sum(nil)),
+		Sha3256:     hex.EncodeToString(sha3_256_d.Sum(nil)),
+		Sha3384:     hex.EncodeToString(sha3_384_d.Sum(nil)),
+		Sha3512:     hex.EncodeToString(sha3_512_d.Sum(nil)),
+		Sha224:      hex.EncodeToString(sha224_d.Sum(nil)),
+		Sha384:      hex.EncodeToString(sha384_d.Sum(nil)),
+		Sha512256:   hex.EncodeToString(sha512_256_d.Sum(nil)),
+		Ripemd160:   hex.EncodeToString(ripemd160_d.Sum(nil)),
+		Whirlpool:   hex.EncodeToString(whirlpool_d.Sum(nil)),
+		SM3:         hex.EncodeToString(sm3_d.Sum(nil)),
+		Streebog256: hex.EncodeToString(streebog256_d.Sum(nil)),
+		Streebog512: hex.EncodeToString(streebog512_d.Sum(nil)),
+		Entropy:     string(entropy_d.Sum(nil)),
+		Ssdeep:      string(ssdeep_d.Sum(nil)),
+		TLSH:        string(tlsh_d.Sum(nil)),
+		Custom:      sumCustomHashers(custom),
 	}, nil
 }
 
 func processStandardInput(output chan Result) {
 	total, nChunks := int64(0), int64(0)
-	r := bufio.NewReader(os.Stdin)
-	buf := make([]byte, 0, 4*1024)
-
-	crc32_d := crc32.NewIEEE()
-	xxhash64_d := xxhash.New()
-	md4_d := md4.New()
-	md5_d := md5.New()
-	sha1_d := sha1.New()
-	sha256_d := sha256.New()
-	sha512_d := sha512.New()
-	blake2b_256_d := blake2b.New256()
-	blake2b_512_d := blake2b.New512()
-	blake3_d := blake3.New()
-	sha3_224_d := sha3.New224()
-	sha3_256_d := sha3.New256()
-	sha3_384_d := sha3.New384()
-	sha3_512_d := sha3.New512()
+	var in io.Reader = os.Stdin
+	var sniff *sniffReader
+	if DetectType {
+		sniff = &sniffReader{r: in}
+		in = sniff
+	}
+	if TextNormalize != "" {
+		in = newNormalizingReader(in, TextNormalize)
+	}
+	r := bufio.NewReader(in)
+
+	crc32_d := timeHash(HashNames.CRC32, crc32.NewIEEE())
+	xxhash64_d := timeHash(HashNames.XxHash64, xxhash.New())
+	md4_d := timeHash(HashNames.MD4, md4.New())
+	md5_d := timeHash(HashNames.MD5, md5.New())
+	sha1_d := timeHash(HashNames.SHA1, sha1.New())
+	sha256_d := timeHash(HashNames.SHA256, sha256.New())
+	sha512_d := timeHash(HashNames.SHA512, sha512.New())
+	blake2b_256_d := timeHash(HashNames.Blake2b256, blake2b.New256())
+	blake2b_512_d := timeHash(HashNames.Blake2b512, blake2b.New512())
+	blake3_d := timeHash(HashNames.Blake3, blake3.New())
+	sha3_224_d := timeHash(HashNames.Sha3224, sha3.New224())
+	sha3_256_d := timeHash(HashNames.Sha3256, sha3.New256())
+	sha3_384_d := timeHash(HashNames.Sha3384, sha3.New384())
+	sha3_512_d := timeHash(HashNames.Sha3512, sha3.New512())
+	sha224_d := timeHash(HashNames.Sha224, sha256.New224())
+	sha384_d := timeHash(HashNames.Sha384, sha512.New384())
+	sha512_256_d := timeHash(HashNames.Sha512256, sha512.New512_256())
+	ripemd160_d := timeHash(HashNames.Ripemd160, ripemd160.New())
+	whirlpool_d := timeHash(HashNames.Whirlpool, newWhirlpool())
+	sm3_d := timeHash(HashNames.SM3, newSM3())
+	streebog256_d := timeHash(HashNames.Streebog256, newStreebog256())
+	streebog512_d := timeHash(HashNames.Streebog512, newStreebog512())
+	entropy_d := timeHash(HashNames.Entropy, newEntropy())
+	ssdeep_d := timeHash(HashNames.Ssdeep, newSsdeep())
+	tlsh_d := timeHash(HashNames.TLSH, newTLSH())
 
 	crc32c := make(chan []byte, 10)
 	xxhash64c := make(chan []byte, 10)
@@ -464,6 +979,17 @@ func processStandardInput(output chan Result) {
 	sha3_256_c := make(chan []byte, 10)
 	sha3_384_c := make(chan []byte, 10)
 	sha3_512_c := make(chan []byte, 10)
+	sha224_c := make(chan []byte, 10)
+	sha384_c := make(chan []byte, 10)
+	sha512_256_c := make(chan []byte, 10)
+	ripemd160_c := make(chan []byte, 10)
+	whirlpool_c := make(chan []byte, 10)
+	sm3_c := make(chan []byte, 10)
+	streebog256_c := make(chan []byte, 10)
+	streebog512_c := make(chan []byte, 10)
+	entropy_c := make(chan []byte, 10)
+	ssdeep_c := make(chan []byte, 10)
+	tlsh_c := make(chan []byte, 10)
 
 	var wg sync.WaitGroup
 
@@ -603,9 +1129,127 @@ func processStandardInput(output chan Result) {
 			wg.Done()
 		}()
 	}
+	if hasHash(HashNames.Sha224) {
+		wg.Add(1)
+		go func() {
+			for b := range sha224_c {
+				sha224_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Sha384) {
+		wg.Add(1)
+		go func() {
+			for b := range sha384_c {
+				sha384_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Sha512256) {
+		wg.Add(1)
+		go func() {
+			for b := range sha512_256_c {
+				sha512_256_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Ripemd160) {
+		wg.Add(1)
+		go func() {
+			for b := range ripemd160_c {
+				ripemd160_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Whirlpool) {
+		wg.Add(1)
+		go func() {
+			for b := range whirlpool_c {
+				whirlpool_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.SM3) {
+		wg.Add(1)
+		go func() {
+			for b := range sm3_c {
+				sm3_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog256) {
+		wg.Add(1)
+		go func() {
+			for b := range streebog256_c {
+				streebog256_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog512) {
+		wg.Add(1)
+		go func() {
+			for b := range streebog512_c {
+				streebog512_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Entropy) {
+		wg.Add(1)
+		go func() {
+			for b := range entropy_c {
+				entropy_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Ssdeep) {
+		wg.Add(1)
+		go func() {
+			for b := range ssdeep_c {
+				ssdeep_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.TLSH) {
+		wg.Add(1)
+		go func() {
+			for b := range tlsh_c {
+				tlsh_d.Write(b)
+			}
+			wg.Done()
+		}()
+	}
+
+	custom := newCustomHashers()
+	custom_c := make(chan []byte, 10)
+	if len(custom) != 0 {
+		wg.Add(1)
+		go func() {
+			for b := range custom_c {
+				for _, h := range custom {
+					h.hash.Write(b)
+				}
+			}
+			wg.Done()
+		}()
+	}
 
 	for {
-		n, err := r.Read(buf[:cap(buf)])
+		// The hashers read each chunk concurrently so it cannot be reused for the next read
+		buf := make([]byte, 4*1024)
+		n, err := r.Read(buf)
 		buf = buf[:n]
 
 		if n == 0 {
@@ -665,6 +1309,42 @@ func processStandardInput(output chan Result) {
 		if hasHash(HashNames.Sha3512) {
 			sha3_512_c <- buf
 		}
+		if hasHash(HashNames.Sha224) {
+			sha224_c <- buf
+		}
+		if hasHash(HashNames.Sha384) {
+			sha384_c <- buf
+		}
+		if hasHash(HashNames.Sha512256) {
+			sha512_256_c <- buf
+		}
+		if hasHash(HashNames.Ripemd160) {
+			ripemd160_c <- buf
+		}
+		if hasHash(HashNames.Whirlpool) {
+			whirlpool_c <- buf
+		}
+		if hasHash(HashNames.SM3) {
+			sm3_c <- buf
+		}
+		if hasHash(HashNames.Streebog256) {
+			streebog256_c <- buf
+		}
+		if hasHash(HashNames.Streebog512) {
+			streebog512_c <- buf
+		}
+		if hasHash(HashNames.Entropy) {
+			entropy_c <- buf
+		}
+		if hasHash(HashNames.Ssdeep) {
+			ssdeep_c <- buf
+		}
+		if hasHash(HashNames.TLSH) {
+			tlsh_c <- buf
+		}
+		if len(custom) != 0 {
+			custom_c <- buf
+		}
 
 		if err != nil && err != io.EOF {
 			log.Fatal(err)
@@ -685,27 +1365,55 @@ func processStandardInput(output chan Result) {
 	close(sha3_256_c)
 	close(sha3_384_c)
 	close(sha3_512_c)
+	close(sha224_c)
+	close(sha384_c)
+	close(sha512_256_c)
+	close(ripemd160_c)
+	close(whirlpool_c)
+	close(sm3_c)
+	close(streebog256_c)
+	close(streebog512_c)
+	close(entropy_c)
+	close(ssdeep_c)
+	close(tlsh_c)
+	close(custom_c)
 
 	wg.Wait()
 
-	output <- Result{
-		File:       "stdin",
-		Bytes:      total,
-		CRC32:      hex.EncodeToString(crc32_d.Sum(nil)),
-		XxHash64:   hex.EncodeToString(xxhash64_d.Sum(nil)),
-		MD4:        hex.EncodeToString(md4_d.Sum(nil)),
-		MD5:        hex.EncodeToString(md5_d.Sum(nil)),
-		SHA1:       hex.EncodeToString(sha1_d.Sum(nil)),
-		SHA256:     hex.EncodeToString(sha256_d.Sum(nil)),
-		SHA512:     hex.EncodeToString(sha512_d.Sum(nil)),
-		Blake2b256: hex.EncodeToString(blake2b_256_d.Sum(nil)),
-		Blake2b512: hex.EncodeToString(blake2b_512_d.Sum(nil)),
-		Blake3:     hex.EncodeToString(blake3_d.Sum(nil)),
-		Sha3224:    hex.EncodeToString(sha3_224_d.Sum(nil)),
-		Sha3256:    hex.EncodeToString(sha3_256_d.Sum(nil)),
-		Sha3384:    hex.EncodeToString(sha3_384_d.Sum(nil)),
-		Sha3512:    hex.EncodeToString(sha3_512_d.Sum(nil)),
+	res := Result{
+		File:        "stdin",
+		Bytes:       total,
+		CRC32:       hex.EncodeToString(crc32_d.Sum(nil)),
+		XxHash64:    hex.EncodeToString(xxhash64_d.Sum(nil)),
+		MD4:         hex.EncodeToString(md4_d.Sum(nil)),
+		MD5:         hex.EncodeToString(md5_d.Sum(nil)),
+		SHA1:        hex.EncodeToString(sha1_d.Sum(nil)),
+		SHA256:      hex.EncodeToString(sha256_d.Sum(nil)),
+		SHA512:      hex.EncodeToString(sha512_d.Sum(nil)),
+		Blake2b256:  hex.EncodeToString(blake2b_256_d.Sum(nil)),
+		Blake2b512:  hex.EncodeToString(blake2b_512_d.Sum(nil)),
+		Blake3:      hex.EncodeToString(blake3_d.Sum(nil)),
+		Sha3224:     hex.EncodeToString(sha3_224_d.Sum(nil)),
+		Sha3256:     hex.EncodeToString(sha3_256_d.Sum(nil)),
+		Sha3384:     hex.EncodeToString(sha3_384_d.Sum(nil)),
+		Sha3512:     hex.EncodeToString(sha3_512_d.Sum(nil)),
+		Sha224:      hex.EncodeToString(sha224_d.Sum(nil)),
+		Sha384:      hex.EncodeToString(sha384_d.Sum(nil)),
+		Sha512256:   hex.EncodeToString(sha512_256_d.Sum(nil)),
+		Ripemd160:   hex.EncodeToString(ripemd160_d.Sum(nil)),
+		Whirlpool:   hex.EncodeToString(whirlpool_d.Sum(nil)),
+		SM3:         hex.EncodeToString(sm3_d.Sum(nil)),
+		Streebog256: hex.EncodeToString(streebog256_d.Sum(nil)),
+		Streebog512: hex.EncodeToString(streebog512_d.Sum(nil)),
+		Entropy:     string(entropy_d.Sum(nil)),
+		Ssdeep:      string(ssdeep_d.Sum(nil)),
+		TLSH:        string(tlsh_d.Sum(nil)),
+		Custom:      sumCustomHashers(custom),
 	}
+	if sniff != nil {
+		res.ContentType, res.Magic = detectType(sniff.head)
+	}
+	output <- res
 
 	close(output)
 }
@@ -724,7 +1432,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := crc32.NewIEEE()
+			d := timeHash(HashNames.CRC32, crc32.NewIEEE())
 			d.Write(*content)
 			result.CRC32 = hex.EncodeToString(d.Sum(nil))
 
@@ -739,7 +1447,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := xxhash.New()
+			d := timeHash(HashNames.XxHash64, xxhash.New())
 			d.Write(*content)
 			result.XxHash64 = hex.EncodeToString(d.Sum(nil))
 
@@ -754,7 +1462,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := md4.New()
+			d := timeHash(HashNames.MD4, md4.New())
 			d.Write(*content)
 			result.MD4 = hex.EncodeToString(d.Sum(nil))
 
@@ -769,7 +1477,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := md5.New()
+			d := timeHash(HashNames.MD5, md5.New())
 			d.Write(*content)
 			result.MD5 = hex.EncodeToString(d.Sum(nil))
 
@@ -784,7 +1492,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha1.New()
+			d := timeHash(HashNames.SHA1, sha1.New())
 			d.Write(*content)
 			result.SHA1 = hex.EncodeToString(d.Sum(nil))
 
@@ -799,7 +1507,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha256.New()
+			d := timeHash(HashNames.SHA256, sha256.New())
 			d.Write(*content)
 			result.SHA256 = hex.EncodeToString(d.Sum(nil))
 
@@ -814,7 +1522,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha512.New()
+			d := timeHash(HashNames.SHA512, sha512.New())
 			d.Write(*content)
 			result.SHA512 = hex.EncodeToString(d.Sum(nil))
 
@@ -829,7 +1537,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := blake2b.New256()
+			d := timeHash(HashNames.Blake2b256, blake2b.New256())
 			d.Write(*content)
 			result.Blake2b256 = hex.EncodeToString(d.Sum(nil))
 
@@ -844,7 +1552,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := blake2b.New512()
+			d := timeHash(HashNames.Blake2b512, blake2b.New512())
 			d.Write(*content)
 			result.Blake2b512 = hex.EncodeToString(d.Sum(nil))
 
@@ -855,11 +1563,26 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		}()
 	}
 
+	if hasHash(HashNames.Blake3) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Blake3, blake3.New())
+			d.Write(*content)
+			result.Blake3 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing blake3: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
 	if hasHash(HashNames.Sha3224) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha3.New224()
+			d := timeHash(HashNames.Sha3224, sha3.New224())
 			d.Write(*content)
 			result.Sha3224 = hex.EncodeToString(d.Sum(nil))
 
@@ -874,7 +1597,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha3.New256()
+			d := timeHash(HashNames.Sha3256, sha3.New256())
 			d.Write(*content)
 			result.Sha3256 = hex.EncodeToString(d.Sum(nil))
 
@@ -889,7 +1612,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha3.New384()
+			d := timeHash(HashNames.Sha3384, sha3.New384())
 			d.Write(*content)
 			result.Sha3384 = hex.EncodeToString(d.Sum(nil))
 
@@ -904,7 +1627,7 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		wg.Add(1)
 		go func() {
 			startTime = makeTimestampNano()
-			d := sha3.New512()
+			d := timeHash(HashNames.Sha3512, sha3.New512())
 			d.Write(*content)
 			result.Sha3512 = hex.EncodeToString(d.Sum(nil))
 
@@ -915,6 +1638,179 @@ func processReadFileParallel(filename string, content *[]byte) (Result, error) {
 		}()
 	}
 
+	if hasHash(HashNames.Sha224) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Sha224, sha256.New224())
+			d.Write(*content)
+			result.Sha224 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing sha224: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Sha384) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Sha384, sha512.New384())
+			d.Write(*content)
+			result.Sha384 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing sha384: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Sha512256) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Sha512256, sha512.New512_256())
+			d.Write(*content)
+			result.Sha512256 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing sha512/256: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Ripemd160) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Ripemd160, ripemd160.New())
+			d.Write(*content)
+			result.Ripemd160 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing ripemd160: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Whirlpool) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Whirlpool, newWhirlpool())
+			d.Write(*content)
+			result.Whirlpool = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing whirlpool: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.SM3) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.SM3, newSM3())
+			d.Write(*content)
+			result.SM3 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing sm3: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog256) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Streebog256, newStreebog256())
+			d.Write(*content)
+			result.Streebog256 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing streebog256: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if hasHash(HashNames.Streebog512) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Streebog512, newStreebog512())
+			d.Write(*content)
+			result.Streebog512 = hex.EncodeToString(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing streebog512: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Entropy) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Entropy, newEntropy())
+			d.Write(*content)
+			result.Entropy = string(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing entropy: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.Ssdeep) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.Ssdeep, newSsdeep())
+			d.Write(*content)
+			result.Ssdeep = string(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing ssdeep: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+	if hasHash(HashNames.TLSH) {
+		wg.Add(1)
+		go func() {
+			startTime = makeTimestampNano()
+			d := timeHash(HashNames.TLSH, newTLSH())
+			d.Write(*content)
+			result.TLSH = string(d.Sum(nil))
+
+			if Trace {
+				printTrace(fmt.Sprintf("nanoseconds processing tlsh: %s: %d", filename, makeTimestampNano()-startTime))
+			}
+			wg.Done()
+		}()
+	}
+
+	if custom := newCustomHashers(); len(custom) != 0 {
+		wg.Add(1)
+		go func() {
+			for _, h := range custom {
+				h.hash.Write(*content)
+			}
+			result.Custom = sumCustomHashers(custom)
+			wg.Done()
+		}()
+	}
+
 	wg.Wait()
 	return result, nil
 }
@@ -925,7 +1821,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.CRC32) {
 		startTime := makeTimestampNano()
-		d := crc32.NewIEEE()
+		d := timeHash(HashNames.CRC32, crc32.NewIEEE())
 		d.Write(*content)
 		result.CRC32 = hex.EncodeToString(d.Sum(nil))
 
@@ -936,7 +1832,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.XxHash64) {
 		startTime := makeTimestampNano()
-		d := xxhash.New()
+		d := timeHash(HashNames.XxHash64, xxhash.New())
 		d.Write(*content)
 		result.XxHash64 = hex.EncodeToString(d.Sum(nil))
 
@@ -947,7 +1843,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.MD4) {
 		startTime := makeTimestampNano()
-		d := md4.New()
+		d := timeHash(HashNames.MD4, md4.New())
 		d.Write(*content)
 		result.MD4 = hex.EncodeToString(d.Sum(nil))
 
@@ -958,7 +1854,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.MD5) {
 		startTime := makeTimestampNano()
-		d := md5.New()
+		d := timeHash(HashNames.MD5, md5.New())
 		d.Write(*content)
 		result.MD5 = hex.EncodeToString(d.Sum(nil))
 
@@ -969,7 +1865,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.SHA1) {
 		startTime := makeTimestampNano()
-		d := sha1.New()
+		d := timeHash(HashNames.SHA1, sha1.New())
 		d.Write(*content)
 		result.SHA1 = hex.EncodeToString(d.Sum(nil))
 
@@ -980,7 +1876,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.SHA256) {
 		startTime := makeTimestampNano()
-		d := sha256.New()
+		d := timeHash(HashNames.SHA256, sha256.New())
 		d.Write(*content)
 		result.SHA256 = hex.EncodeToString(d.Sum(nil))
 
@@ -991,7 +1887,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.SHA512) {
 		startTime := makeTimestampNano()
-		d := sha512.New()
+		d := timeHash(HashNames.SHA512, sha512.New())
 		d.Write(*content)
 		result.SHA512 = hex.EncodeToString(d.Sum(nil))
 
@@ -1002,7 +1898,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Blake2b256) {
 		startTime := makeTimestampNano()
-		d := blake2b.New256()
+		d := timeHash(HashNames.Blake2b256, blake2b.New256())
 		d.Write(*content)
 		result.Blake2b256 = hex.EncodeToString(d.Sum(nil))
 
@@ -1013,7 +1909,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Blake2b512) {
 		startTime := makeTimestampNano()
-		d := blake2b.New512()
+		d := timeHash(HashNames.Blake2b512, blake2b.New512())
 		d.Write(*content)
 		result.Blake2b512 = hex.EncodeToString(d.Sum(nil))
 
@@ -1024,7 +1920,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Blake3) {
 		startTime := makeTimestampNano()
-		d := blake3.New()
+		d := timeHash(HashNames.Blake3, blake3.New())
 		d.Write(*content)
 		result.Blake3 = hex.EncodeToString(d.Sum(nil))
 
@@ -1035,7 +1931,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Sha3224) {
 		startTime := makeTimestampNano()
-		d := sha3.New224()
+		d := timeHash(HashNames.Sha3224, sha3.New224())
 		d.Write(*content)
 		result.Sha3224 = hex.EncodeToString(d.Sum(nil))
 
@@ -1046,7 +1942,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Sha3256) {
 		startTime := makeTimestampNano()
-		d := sha3.New256()
+		d := timeHash(HashNames.Sha3256, sha3.New256())
 		d.Write(*content)
 		result.Sha3256 = hex.EncodeToString(d.Sum(nil))
 
@@ -1057,7 +1953,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Sha3384) {
 		startTime := makeTimestampNano()
-		d := sha3.New384()
+		d := timeHash(HashNames.Sha3384, sha3.New384())
 		d.Write(*content)
 		result.Sha3384 = hex.EncodeToString(d.Sum(nil))
 
@@ -1068,7 +1964,7 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 
 	if hasHash(HashNames.Sha3512) {
 		startTime := makeTimestampNano()
-		d := sha3.New512()
+		d := timeHash(HashNames.Sha3512, sha3.New512())
 		d.Write(*content)
 		result.Sha3512 = hex.EncodeToString(d.Sum(nil))
 
@@ -1077,6 +1973,126 @@ func processReadFile(filename string, content *[]byte) (Result, error) {
 		}
 	}
 
+	if hasHash(HashNames.Sha224) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Sha224, sha256.New224())
+		d.Write(*content)
+		result.Sha224 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing sha224: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Sha384) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Sha384, sha512.New384())
+		d.Write(*content)
+		result.Sha384 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing sha384: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Sha512256) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Sha512256, sha512.New512_256())
+		d.Write(*content)
+		result.Sha512256 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing sha512/256: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Ripemd160) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Ripemd160, ripemd160.New())
+		d.Write(*content)
+		result.Ripemd160 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing ripemd160: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Whirlpool) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Whirlpool, newWhirlpool())
+		d.Write(*content)
+		result.Whirlpool = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing whirlpool: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.SM3) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.SM3, newSM3())
+		d.Write(*content)
+		result.SM3 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing sm3: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Streebog256) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Streebog256, newStreebog256())
+		d.Write(*content)
+		result.Streebog256 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing streebog256: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	if hasHash(HashNames.Streebog512) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Streebog512, newStreebog512())
+		d.Write(*content)
+		result.Streebog512 = hex.EncodeToString(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing streebog512: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+	if hasHash(HashNames.Entropy) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Entropy, newEntropy())
+		d.Write(*content)
+		result.Entropy = string(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing entropy: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+	if hasHash(HashNames.Ssdeep) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.Ssdeep, newSsdeep())
+		d.Write(*content)
+		result.Ssdeep = string(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing ssdeep: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+	if hasHash(HashNames.TLSH) {
+		startTime := makeTimestampNano()
+		d := timeHash(HashNames.TLSH, newTLSH())
+		d.Write(*content)
+		result.TLSH = string(d.Sum(nil))
+
+		if Trace {
+			printTrace(fmt.Sprintf("nanoseconds processing tlsh: %s: %d", filename, makeTimestampNano()-startTime))
+		}
+	}
+
+	result.Custom = customHashes(*content)
+
 	return result, nil
 }
 
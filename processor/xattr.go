@@ -0,0 +1,159 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// Prefix of the extended attribute or alternate data stream each hash is stored under
+const xattrPrefix = "user.hashit."
+
+// Count of files whose stored hashes no longer match their content
+var xattrFailCount int64
+
+var errXattrMissing = errors.New("no stored hash")
+
+// Every hash calculated for the result keyed by its name
+func calculatedHashes(res Result) map[string]string {
+	hashes := map[string]string{}
+	if hasHash(HashNames.CRC32) {
+		hashes[HashNames.CRC32] = res.CRC32
+	}
+	if hasHash(HashNames.XxHash64) {
+		hashes[HashNames.XxHash64] = res.XxHash64
+	}
+	if hasHash(HashNames.MD4) {
+		hashes[HashNames.MD4] = res.MD4
+	}
+	if hasHash(HashNames.MD5) {
+		hashes[HashNames.MD5] = res.MD5
+	}
+	if hasHash(HashNames.SHA1) {
+		hashes[HashNames.SHA1] = res.SHA1
+	}
+	if hasHash(HashNames.SHA256) {
+		hashes[HashNames.SHA256] = res.SHA256
+	}
+	if hasHash(HashNames.SHA512) {
+		hashes[HashNames.SHA512] = res.SHA512
+	}
+	if hasHash(HashNames.Blake2b256) {
+		hashes[HashNames.Blake2b256] = res.Blake2b256
+	}
+	if hasHash(HashNames.Blake2b512) {
+		hashes[HashNames.Blake2b512] = res.Blake2b512
+	}
+	if hasHash(HashNames.Blake3) {
+		hashes[HashNames.Blake3] = res.Blake3
+	}
+	if hasHash(HashNames.Sha3224) {
+		hashes[HashNames.Sha3224] = res.Sha3224
+	}
+	if hasHash(HashNames.Sha3256) {
+		hashes[HashNames.Sha3256] = res.Sha3256
+	}
+	if hasHash(HashNames.Sha3384) {
+		hashes[HashNames.Sha3384] = res.Sha3384
+	}
+	if hasHash(HashNames.Sha3512) {
+		hashes[HashNames.Sha3512] = res.Sha3512
+	}
+	if hasHash(HashNames.Sha224) {
+		hashes[HashNames.Sha224] = res.Sha224
+	}
+	if hasHash(HashNames.Sha384) {
+		hashes[HashNames.Sha384] = res.Sha384
+	}
+	if hasHash(HashNames.Sha512256) {
+		hashes[HashNames.Sha512256] = res.Sha512256
+	}
+	if hasHash(HashNames.Ripemd160) {
+		hashes[HashNames.Ripemd160] = res.Ripemd160
+	}
+	if hasHash(HashNames.Whirlpool) {
+		hashes[HashNames.Whirlpool] = res.Whirlpool
+	}
+	if hasHash(HashNames.SM3) {
+		hashes[HashNames.SM3] = res.SM3
+	}
+	if hasHash(HashNames.Streebog256) {
+		hashes[HashNames.Streebog256] = res.Streebog256
+	}
+	if hasHash(HashNames.Streebog512) {
+		hashes[HashNames.Streebog512] = res.Streebog512
+	}
+	if hasHash(HashNames.Entropy) {
+		hashes[HashNames.Entropy] = res.Entropy
+	}
+	if hasHash(HashNames.Ssdeep) {
+		hashes[HashNames.Ssdeep] = res.Ssdeep
+	}
+	if hasHash(HashNames.TLSH) {
+		hashes[HashNames.TLSH] = res.TLSH
+	}
+	for _, info := range selectedCustomHashes() {
+		hashes[info.Name] = res.Custom[info.Name]
+	}
+	return hashes
+}
+
+// Writes every calculated hash into the file's extended attributes
+func storeXattrs(res Result) error {
+	for name, value := range calculatedHashes(res) {
+		if err := setXattr(res.File, xattrPrefix+name, value); err != nil {
+			return fmt.Errorf("unable to store %s: %w", xattrPrefix+name, err)
+		}
+	}
+	return nil
+}
+
+// Compares the calculated hashes against those previously stored returning OK,
+// FAILED when any differ or MISSING when none were stored
+func verifyXattrs(res Result) (string, error) {
+	hashes := calculatedHashes(res)
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	found := 0
+	for _, name := range names {
+		stored, err := getXattr(res.File, xattrPrefix+name)
+		if errors.Is(err, errXattrMissing) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to read %s: %w", xattrPrefix+name, err)
+		}
+
+		found++
+		if stored != hashes[name] {
+			atomic.AddInt64(&xattrFailCount, 1)
+			printError(fmt.Sprintf("%s: stored %s does not match content", res.File, name))
+			return "FAILED", nil
+		}
+	}
+
+	if found == 0 {
+		return "MISSING", nil
+	}
+	return "OK", nil
+}
+
+// Stores or verifies hashes in extended attributes as requested
+func applyXattrs(r *Result) error {
+	if VerifyXattr {
+		status, err := verifyXattrs(*r)
+		if err != nil {
+			return err
+		}
+		r.Xattr = status
+	}
+	if StoreXattr {
+		return storeXattrs(*r)
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+package processor
+
+import "golang.org/x/sys/unix"
+
+// Error returned by getxattr when the attribute has not been set
+const xattrNotFound = unix.ENODATA
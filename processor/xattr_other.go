@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || windows)
+
+package processor
+
+import "errors"
+
+var errXattrUnsupported = errors.New("extended attributes are not supported on this platform")
+
+func setXattr(path string, name string, value string) error {
+	return errXattrUnsupported
+}
+
+func getXattr(path string, name string) (string, error) {
+	return "", errXattrUnsupported
+}
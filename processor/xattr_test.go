@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreAndVerifyXattrs(t *testing.T) {
+	Hash = []string{HashNames.MD5, HashNames.SHA256}
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, []byte("hello"), 0600)
+
+	res := Result{
+		File:   file,
+		MD5:    "5d41402abc4b2a76b9719d911017c592",
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+
+	status, err := verifyXattrs(res)
+	if err != nil {
+		t.Skipf("Extended attributes unavailable %s", err.Error())
+	}
+	if status != "MISSING" {
+		t.Errorf("Expected MISSING got %s", status)
+	}
+
+	if err := storeXattrs(res); err != nil {
+		t.Skipf("Extended attributes unavailable %s", err.Error())
+	}
+
+	status, _ = verifyXattrs(res)
+	if status != "OK" {
+		t.Errorf("Expected OK got %s", status)
+	}
+
+	res.SHA256 = "changed"
+	status, _ = verifyXattrs(res)
+	if status != "FAILED" {
+		t.Errorf("Expected FAILED got %s", status)
+	}
+	xattrFailCount = 0
+}
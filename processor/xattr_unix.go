@@ -0,0 +1,30 @@
+//go:build linux || darwin
+
+package processor
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func setXattr(path string, name string, value string) error {
+	return unix.Setxattr(path, name, []byte(value), 0)
+}
+
+func getXattr(path string, name string) (string, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if errors.Is(err, xattrNotFound) {
+		return "", errXattrMissing
+	}
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Getxattr(path, name, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:size]), nil
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package processor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// NTFS has no extended attributes so each hash goes into an alternate data stream
+func setXattr(path string, name string, value string) error {
+	return os.WriteFile(path+":"+name, []byte(value), 0600)
+}
+
+func getXattr(path string, name string) (string, error) {
+	b, err := os.ReadFile(path + ":" + name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", errXattrMissing
+	}
+	return string(b), err
+}
@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A frame of a Zstandard file in the seekable format, sizes are as listed in its seek table
+type ZstdFrame struct {
+	Offset           int64
+	Size             int64
+	DecompressedSize int64
+	// Digest of the compressed bytes so a damaged frame can be found and copied from a replica
+	SHA256 string
+}
+
+const (
+	zstdFrameMagic     = 0xFD2FB528
+	zstdSkippableMagic = 0x184D2A5E
+	zstdSeekableMagic  = 0x8F92EAB1
+	// Number of frames, descriptor and magic at the very end of the file
+	zstdSeekFooterSize = 9
+	// Magic and size of the skippable frame holding the seek table
+	zstdSkippableHeaderSize = 8
+)
+
+var errNotSeekable = errors.New("not a seekable zstd file")
+
+// Reads the seek table from the end of a seekable format file returning the offset and
+// sizes of every frame
+func readZstdSeekTable(r io.ReaderAt, size int64) ([]ZstdFrame, error) {
+	if size < zstdSkippableHeaderSize+zstdSeekFooterSize {
+		return nil, errNotSeekable
+	}
+
+	footer := make([]byte, zstdSeekFooterSize)
+	if _, err := r.ReadAt(footer, size-zstdSeekFooterSize); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != zstdSeekableMagic {
+		return nil, errNotSeekable
+	}
+
+	count := int64(binary.LittleEndian.Uint32(footer[:4]))
+	descriptor := footer[4]
+	if descriptor&0x7c != 0 {
+		return nil, errors.New("seek table descriptor has reserved bits set")
+	}
+	entrySize := int64(8)
+	if descriptor&0x80 != 0 {
+		// Each entry also has the checksum of the decompressed frame
+		entrySize = 12
+	}
+
+	tableSize := count*entrySize + zstdSeekFooterSize
+	start := size - tableSize - zstdSkippableHeaderSize
+	if start < 0 {
+		return nil, errors.New("seek table is larger than the file")
+	}
+
+	table := make([]byte, tableSize+zstdSkippableHeaderSize)
+	if _, err := r.ReadAt(table, start); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(table) != zstdSkippableMagic || int64(binary.LittleEndian.Uint32(table[4:])) != tableSize {
+		return nil, errors.New("seek table is not in a skippable frame")
+	}
+
+	frames := make([]ZstdFrame, 0, count)
+	var offset int64
+	for i := int64(0); i < count; i++ {
+		entry := table[zstdSkippableHeaderSize+i*entrySize:]
+		f := ZstdFrame{
+			Offset:           offset,
+			Size:             int64(binary.LittleEndian.Uint32(entry)),
+			DecompressedSize: int64(binary.LittleEndian.Uint32(entry[4:])),
+		}
+		offset += f.Size
+		frames = append(frames, f)
+	}
+	if offset != start {
+		return nil, fmt.Errorf("seek table lists %d compressed bytes but the frames take %d", offset, start)
+	}
+	return frames, nil
+}
+
+// Digests every frame of a .zst file in the seekable format, nil for any other file
+func computeZstdFrames(filename string) ([]ZstdFrame, error) {
+	if !strings.EqualFold(filepath.Ext(filename), ".zst") {
+		return nil, nil
+	}
+
+	file, err := os.Open(longPath(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err := readZstdSeekTable(file, fi.Size())
+	if errors.Is(err, errNotSeekable) {
+		printDebug("no seek table", "file", filename)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid zstd seek table: %w", err)
+	}
+
+	magic := make([]byte, 4)
+	for i := range frames {
+		section := io.NewSectionReader(limitReaderAt(file), frames[i].Offset, frames[i].Size)
+		if _, err := section.ReadAt(magic, 0); err != nil || binary.LittleEndian.Uint32(magic) != zstdFrameMagic {
+			// Still digested so it shows up as differing from a good replica
+			printDebug("frame does not start with the zstd magic", "file", filename, "frame", i)
+		}
+
+		h := sha256.New()
+		if _, err := io.Copy(h, section); err != nil {
+			return nil, err
+		}
+		frames[i].SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+	return frames, nil
+}
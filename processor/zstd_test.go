@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Builds a seekable format file from frames which only need to start with the zstd magic
+// as they are never decompressed
+func seekableZstd(frames [][]byte, checksums bool) []byte {
+	var out bytes.Buffer
+	for _, f := range frames {
+		out.Write(f)
+	}
+
+	entrySize := 8
+	var descriptor byte
+	if checksums {
+		entrySize = 12
+		descriptor = 0x80
+	}
+
+	le := binary.LittleEndian
+	_ = binary.Write(&out, le, uint32(zstdSkippableMagic))
+	_ = binary.Write(&out, le, uint32(len(frames)*entrySize+zstdSeekFooterSize))
+	for _, f := range frames {
+		_ = binary.Write(&out, le, uint32(len(f)))
+		_ = binary.Write(&out, le, uint32(len(f)*2))
+		if checksums {
+			_ = binary.Write(&out, le, uint32(0))
+		}
+	}
+	_ = binary.Write(&out, le, uint32(len(frames)))
+	out.WriteByte(descriptor)
+	_ = binary.Write(&out, le, uint32(zstdSeekableMagic))
+	return out.Bytes()
+}
+
+func zstdTestFrame(content string) []byte {
+	return append([]byte{0x28, 0xb5, 0x2f, 0xfd}, content...)
+}
+
+func TestComputeZstdFrames(t *testing.T) {
+	frames := [][]byte{zstdTestFrame("first"), zstdTestFrame("second frame")}
+	dir := t.TempDir()
+
+	for _, checksums := range []bool{false, true} {
+		file := filepath.Join(dir, "data.zst")
+		_ = os.WriteFile(file, seekableZstd(frames, checksums), 0600)
+
+		got, err := computeZstdFrames(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 frames got %d", len(got))
+		}
+
+		sum := sha256.Sum256(frames[1])
+		if got[1].Offset != 9 || got[1].Size != 16 || got[1].DecompressedSize != 32 || got[1].SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("unexpected second frame %+v", got[1])
+		}
+	}
+}
+
+func TestComputeZstdFramesNotSeekable(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain.zst")
+	_ = os.WriteFile(plain, zstdTestFrame("no seek table"), 0600)
+	if got, err := computeZstdFrames(plain); got != nil || err != nil {
+		t.Errorf("expected nothing for a file without a seek table got %v %v", got, err)
+	}
+
+	other := filepath.Join(dir, "data.bin")
+	_ = os.WriteFile(other, seekableZstd([][]byte{zstdTestFrame("a")}, false), 0600)
+	if got, _ := computeZstdFrames(other); got != nil {
+		t.Errorf("expected files without the .zst extension to be left alone got %v", got)
+	}
+}
+
+func TestComputeZstdFramesTruncated(t *testing.T) {
+	data := seekableZstd([][]byte{zstdTestFrame("first"), zstdTestFrame("second")}, false)
+	// Losing bytes from a frame leaves the seek table disagreeing with the file
+	data = append(data[:3], data[5:]...)
+
+	file := filepath.Join(t.TempDir(), "data.zst")
+	_ = os.WriteFile(file, data, 0600)
+	if _, err := computeZstdFrames(file); err == nil {
+		t.Error("expected an error when the frames do not fill the file")
+	}
+}
@@ -0,0 +1,149 @@
+// Package walker walks a directory tree in any fs.FS, leaving what to descend into and
+// which files to report to pluggable filters, so directories, archives and images can
+// all share one traversal
+package walker
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// Action is what a Filter decides should happen to an entry
+type Action int
+
+const (
+	// Continue keeps the entry, descending into it when it is a directory
+	Continue Action = iota
+	// Skip leaves out the entry and everything beneath it
+	Skip
+	// Stop ends the walk
+	Stop
+)
+
+// Filter is called for every entry, including the root, with its slash separated path
+// in the FS. The first filter not returning Continue decides.
+type Filter func(path string, d fs.DirEntry) Action
+
+// SymlinkPolicy is how the walk treats symbolic links
+type SymlinkPolicy int
+
+const (
+	// SymlinksAsFiles reports links as they are without following them, as fs.WalkDir does
+	SymlinksAsFiles SymlinkPolicy = iota
+	// SymlinksSkip leaves links out entirely
+	SymlinksSkip
+	// SymlinksFollow reports links to files and descends into links to directories,
+	// refusing to enter a directory already being walked so loops end
+	SymlinksFollow
+)
+
+// ErrSymlinkLoop is passed to Error for a link leading back to a directory being walked
+var ErrSymlinkLoop = errors.New("symlink loop")
+
+// Walker holds the options for walking, the zero value reports every file
+type Walker struct {
+	Filters  []Filter
+	Symlinks SymlinkPolicy
+	// MaxDepth stops descending below this many levels, files in the root are at 1
+	MaxDepth int
+	// Error is called for entries which could not be read, the walk carries on past them
+	Error func(path string, err error)
+}
+
+var errStop = errors.New("stop")
+
+// Walk calls found for every file under root in fsys, in lexical order, that the filters
+// keep. It stops early when ctx is done or found returns an error, which is returned.
+func (w Walker) Walk(ctx context.Context, fsys fs.FS, root string, found func(path string, d fs.DirEntry) error) error {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		w.error(root, err)
+		return nil
+	}
+
+	err = w.walk(ctx, fsys, root, fs.FileInfoToDirEntry(info), 0, nil, found)
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+func (w Walker) error(path string, err error) {
+	if w.Error != nil {
+		w.Error(path, err)
+	}
+}
+
+func (w Walker) walk(ctx context.Context, fsys fs.FS, name string, d fs.DirEntry, depth int, ancestors []fs.FileInfo, found func(path string, d fs.DirEntry) error) error {
+	if ctx.Err() != nil {
+		return errStop
+	}
+
+	for _, f := range w.Filters {
+		switch f(name, d) {
+		case Skip:
+			return nil
+		case Stop:
+			return errStop
+		}
+	}
+
+	isDir := d.IsDir()
+	if d.Type()&fs.ModeSymlink != 0 {
+		switch w.Symlinks {
+		case SymlinksSkip:
+			return nil
+		case SymlinksFollow:
+			info, err := fs.Stat(fsys, name)
+			if err != nil {
+				w.error(name, err)
+				return nil
+			}
+			isDir = info.IsDir()
+			if isDir && isAncestor(info, ancestors) {
+				w.error(name, ErrSymlinkLoop)
+				return nil
+			}
+		}
+	}
+
+	if !isDir {
+		return found(name, d)
+	}
+	// Directories at the limit could only hold files deeper than it
+	if w.MaxDepth > 0 && depth >= w.MaxDepth {
+		return nil
+	}
+
+	if w.Symlinks == SymlinksFollow {
+		if info, err := fs.Stat(fsys, name); err == nil {
+			ancestors = append(ancestors, info)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		w.error(name, err)
+		return nil
+	}
+	for _, e := range entries {
+		child := path.Join(name, e.Name())
+		if err := w.walk(ctx, fsys, child, e, depth+1, ancestors, found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Only directories on disk can be told apart, other filesystems have no links to loop
+func isAncestor(info fs.FileInfo, ancestors []fs.FileInfo) bool {
+	for _, a := range ancestors {
+		if os.SameFile(info, a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package walker
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"a.txt":           {Data: []byte("a")},
+		"dir/b.txt":       {Data: []byte("b")},
+		"dir/deep/c.txt":  {Data: []byte("c")},
+		"skip/d.txt":      {Data: []byte("d")},
+		"link":            {Data: []byte("dir"), Mode: fs.ModeSymlink},
+		"dir/deep/e.data": {Data: []byte("e")},
+	}
+}
+
+func walk(t *testing.T, w Walker, fsys fs.FS) []string {
+	files := []string{}
+	err := w.Walk(context.Background(), fsys, ".", func(path string, d fs.DirEntry) error {
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err.Error())
+	}
+	return files
+}
+
+func TestWalkFilters(t *testing.T) {
+	w := Walker{Filters: []Filter{
+		func(path string, d fs.DirEntry) Action {
+			if path == "skip" {
+				return Skip
+			}
+			return Continue
+		},
+		func(path string, d fs.DirEntry) Action {
+			if !d.IsDir() && filepath.Ext(path) == ".data" {
+				return Skip
+			}
+			return Continue
+		},
+	}}
+
+	expected := []string{"a.txt", "dir/b.txt", "dir/deep/c.txt", "link"}
+	if files := walk(t, w, testFS()); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v got %v", expected, files)
+	}
+}
+
+func TestWalkMaxDepthAndSymlinks(t *testing.T) {
+	w := Walker{MaxDepth: 2, Symlinks: SymlinksSkip}
+	expected := []string{"a.txt", "dir/b.txt", "skip/d.txt"}
+	if files := walk(t, w, testFS()); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v got %v", expected, files)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	w := Walker{Filters: []Filter{func(path string, d fs.DirEntry) Action {
+		if path == "dir" {
+			return Stop
+		}
+		return Continue
+	}}}
+	if files := walk(t, w, testFS()); !reflect.DeepEqual(files, []string{"a.txt"}) {
+		t.Errorf("Expected the walk to stop at dir got %v", files)
+	}
+}
+
+func TestWalkFoundError(t *testing.T) {
+	sentinel := errors.New("enough")
+	err := Walker{}.Walk(context.Background(), testFS(), ".", func(path string, d fs.DirEntry) error {
+		return sentinel
+	})
+	if !errors.Is(err, sentinel) {
+		t.Errorf("Expected the error from found got %v", err)
+	}
+}
+
+func TestWalkMissingRoot(t *testing.T) {
+	errs := []string{}
+	w := Walker{Error: func(path string, err error) { errs = append(errs, path) }}
+	if files := walk(t, w, fstest.MapFS{}); len(files) != 0 {
+		t.Errorf("Expected no files got %v", files)
+	}
+	_ = w.Walk(context.Background(), fstest.MapFS{}, "missing", func(string, fs.DirEntry) error { return nil })
+	if !reflect.DeepEqual(errs, []string{"missing"}) {
+		t.Errorf("Expected the missing root to be reported got %v", errs)
+	}
+}
+
+func TestWalkFollowSymlinkLoop(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "tree", "sub"), 0700)
+	_ = os.WriteFile(filepath.Join(dir, "tree", "sub", "f"), []byte("f"), 0600)
+	if err := os.Symlink(filepath.Join(dir, "tree"), filepath.Join(dir, "tree", "sub", "loop")); err != nil {
+		t.Skipf("unable to create symlink: %s", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "tree", "sub"), filepath.Join(dir, "tree", "other")); err != nil {
+		t.Skipf("unable to create symlink: %s", err)
+	}
+
+	loops := []string{}
+	w := Walker{Symlinks: SymlinksFollow, Error: func(path string, err error) {
+		if errors.Is(err, ErrSymlinkLoop) {
+			loops = append(loops, path)
+		}
+	}}
+	files := walk(t, w, os.DirFS(filepath.Join(dir, "tree")))
+
+	expected := []string{"other/f", "sub/f"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v got %v", expected, files)
+	}
+	if len(loops) != 2 {
+		t.Errorf("Expected both paths back to the root to be reported as loops got %v", loops)
+	}
+}